@@ -15,8 +15,25 @@
 package loadbalancingexporter
 
 import (
+	"time"
+
 	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/exporter/otlpexporter"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/k8sconfig"
+)
+
+const (
+	// traceIDRouting routes the traces and logs based on their trace IDs. This is the default for traces and logs.
+	traceIDRouting = "traceID"
+	// svcRouting routes the traces and metrics based on the "service.name" resource attribute.
+	svcRouting = "service"
+	// metricNameRouting routes the metrics based on their names.
+	metricNameRouting = "metric"
+	// resourceAttributeRouting routes the metrics based on the value of the resource attribute named by RoutingAttribute.
+	resourceAttributeRouting = "resource_attribute"
+	// seriesRouting routes each data point based on its series identity: resource attributes, metric name and labels.
+	seriesRouting = "series"
 )
 
 // Config defines configuration for the exporter.
@@ -24,6 +41,24 @@ type Config struct {
 	config.ExporterSettings `mapstructure:",squash"`
 	Protocol                Protocol         `mapstructure:"protocol"`
 	Resolver                ResolverSettings `mapstructure:"resolver"`
+
+	// RoutingKey determines which part of the data is used to select the backend. Traces can be routed by
+	// "traceID" (default) or "service", while metrics can be routed by "service", "metric", "resource_attribute"
+	// or "series" (default). As the same exporter might be used in several pipelines, traces are routed by their
+	// trace IDs when a metrics-only routing key is set. Logs are always routed by their trace IDs.
+	RoutingKey string `mapstructure:"routing_key"`
+
+	// RoutingAttribute is the name of the resource attribute used when RoutingKey is "resource_attribute".
+	RoutingAttribute string `mapstructure:"routing_attribute"`
+
+	// DrainTimeout is how long to wait for the requests in flight to a removed backend to finish before
+	// shutting its exporter down. When not specified, 10 seconds is used.
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+
+	// Failover makes the exporter send the data to the next backend in the ring when the export to the
+	// selected backend fails. The sending queue and the retries of the OTLP exporters for the backends
+	// are disabled when it is set.
+	Failover bool `mapstructure:"failover"`
 }
 
 // Protocol holds the individual protocol-specific settings. Only OTLP is supported at the moment.
@@ -35,6 +70,7 @@ type Protocol struct {
 type ResolverSettings struct {
 	Static *StaticResolver `mapstructure:"static"`
 	DNS    *DNSResolver    `mapstructure:"dns"`
+	K8sSvc *K8sSvcResolver `mapstructure:"k8s"`
 }
 
 // StaticResolver defines the configuration for the resolver providing a fixed list of backends
@@ -47,3 +83,18 @@ type DNSResolver struct {
 	Hostname string `mapstructure:"hostname"`
 	Port     string `mapstructure:"port"`
 }
+
+// K8sSvcResolver defines the configuration for the resolver watching the endpoints of a Kubernetes service
+type K8sSvcResolver struct {
+	k8sconfig.APIConfig `mapstructure:",squash"`
+
+	// Service is the name of the service to watch, optionally followed by its namespace, like "lb-backends.observability".
+	// When the namespace isn't specified, the "default" namespace is used.
+	Service string `mapstructure:"service"`
+
+	// Port is the port to use for the exporters for the resolved addresses. When not specified, the default port 4317 is used.
+	Port string `mapstructure:"port"`
+
+	// UseEndpointSlices makes the resolver watch the EndpointSlices for the service, instead of its Endpoints object.
+	UseEndpointSlices bool `mapstructure:"use_endpoint_slices"`
+}
@@ -17,8 +17,6 @@ package loadbalancingexporter
 import (
 	"hash/crc32"
 	"sort"
-
-	"go.opentelemetry.io/collector/model/pdata"
 )
 
 const maxPositions uint32 = 36000 // 360 degrees with two decimal places
@@ -48,17 +46,39 @@ func newHashRing(endpoints []string) *hashRing {
 	}
 }
 
-// endpointFor calculates which backend is responsible for the given traceID
-func (h *hashRing) endpointFor(traceID pdata.TraceID) string {
-	b := traceID.Bytes()
+// endpointFor calculates which backend is responsible for the given identifier, such as a trace ID or a routing key
+func (h *hashRing) endpointFor(identifier []byte) string {
 	hasher := crc32.NewIEEE()
-	hasher.Write(b[:])
+	hasher.Write(identifier)
 	hash := hasher.Sum32()
 	pos := hash % maxPositions
 
 	return h.findEndpoint(position(pos))
 }
 
+// endpointsFor returns up to count distinct endpoints for the given identifier, in the order they appear in the
+// ring starting from the identifier position. The first endpoint is the same one returned by endpointFor.
+func (h *hashRing) endpointsFor(identifier []byte, count int) []string {
+	hasher := crc32.NewIEEE()
+	hasher.Write(identifier)
+	pos := position(hasher.Sum32() % maxPositions)
+
+	// the first item at or after the position, wrapping around to the first item of the ring
+	start := sort.Search(len(h.items), func(i int) bool {
+		return h.items[i].pos >= pos
+	})
+
+	var endpoints []string
+	for i := 0; i < len(h.items) && len(endpoints) < count; i++ {
+		endpoint := h.items[(start+i)%len(h.items)].endpoint
+		if !endpointFound(endpoint, endpoints) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	return endpoints
+}
+
 // findEndpoint returns the "next" endpoint starting from the given position
 func (h *hashRing) findEndpoint(pos position) string {
 	ringSize := len(h.items)
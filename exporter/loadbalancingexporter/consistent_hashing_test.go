@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/model/pdata"
 )
 
@@ -48,7 +49,8 @@ func TestEndpointFor(t *testing.T) {
 	} {
 		t.Run(fmt.Sprintf("Endpoint for traceID %s", tt.traceID.HexString()), func(t *testing.T) {
 			// test
-			endpoint := ring.endpointFor(tt.traceID)
+			b := tt.traceID.Bytes()
+			endpoint := ring.endpointFor(b[:])
 
 			// verify
 			assert.Equal(t, tt.expected, endpoint)
@@ -57,6 +59,46 @@ func TestEndpointFor(t *testing.T) {
 	}
 }
 
+func TestEndpointsFor(t *testing.T) {
+	// prepare
+	endpoints := []string{"endpoint-1", "endpoint-2", "endpoint-3"}
+	ring := newHashRing(endpoints)
+
+	for i := 0; i < 100; i++ {
+		identifier := []byte(fmt.Sprintf("identifier-%d", i))
+
+		// test
+		res := ring.endpointsFor(identifier, 2)
+
+		// verify
+		require.Len(t, res, 2)
+		assert.Equal(t, ring.endpointFor(identifier), res[0])
+		assert.NotEqual(t, res[0], res[1])
+	}
+}
+
+func TestEndpointsForMoreThanAvailable(t *testing.T) {
+	// prepare
+	ring := newHashRing([]string{"endpoint-1", "endpoint-2"})
+
+	// test
+	res := ring.endpointsFor([]byte("identifier"), 5)
+
+	// verify
+	assert.ElementsMatch(t, []string{"endpoint-1", "endpoint-2"}, res)
+}
+
+func TestEndpointsForEmptyRing(t *testing.T) {
+	// prepare
+	ring := newHashRing([]string{})
+
+	// test
+	res := ring.endpointsFor([]byte("identifier"), 2)
+
+	// verify
+	assert.Empty(t, res)
+}
+
 func TestPositionsFor(t *testing.T) {
 	// prepare
 	endpoint := "host1"
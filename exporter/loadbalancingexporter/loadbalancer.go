@@ -20,20 +20,28 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
-	"go.opentelemetry.io/collector/model/pdata"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/k8sconfig"
 )
 
 const (
-	defaultPort = "4317"
+	defaultPort         = "4317"
+	defaultDrainTimeout = 10 * time.Second
 )
 
 var (
 	errNoResolver                = errors.New("no resolvers specified for the exporter")
 	errMultipleResolversProvided = errors.New("only one resolver should be specified")
+	errNoBackends                = errors.New("no backends available to send the data to")
 )
 
 var _ loadBalancer = (*loadBalancerImp)(nil)
@@ -42,8 +50,16 @@ type componentFactory func(ctx context.Context, endpoint string) (component.Expo
 
 type loadBalancer interface {
 	component.Component
-	Endpoint(traceID pdata.TraceID) string
+	Endpoint(identifier []byte) string
 	Exporter(endpoint string) (component.Exporter, error)
+
+	// Route returns the endpoints to try, in order, for the given identifier. It has the endpoint responsible
+	// for the identifier, followed by the next one in the ring when failover is enabled.
+	Route(identifier []byte) []string
+
+	// Consume calls the consume function with the exporter for the first endpoint in the route, moving on
+	// to the next endpoints when it fails.
+	Consume(ctx context.Context, route []string, consume func(context.Context, component.Exporter) error) error
 }
 
 type loadBalancerImp struct {
@@ -56,6 +72,14 @@ type loadBalancerImp struct {
 	componentFactory componentFactory
 	exporters        map[string]component.Exporter
 
+	// inflight tracks the requests being sent to each endpoint, so that removed exporters can be drained
+	inflight     map[string]*sync.WaitGroup
+	inflightLock sync.Mutex
+	drainTimeout time.Duration
+	drainWg      sync.WaitGroup
+
+	failover bool
+
 	stopped    bool
 	updateLock sync.RWMutex
 }
@@ -64,7 +88,13 @@ type loadBalancerImp struct {
 func newLoadBalancer(params component.ExporterCreateSettings, cfg config.Exporter, factory componentFactory) (*loadBalancerImp, error) {
 	oCfg := cfg.(*Config)
 
-	if oCfg.Resolver.DNS != nil && oCfg.Resolver.Static != nil {
+	numResolvers := 0
+	for _, configured := range []bool{oCfg.Resolver.Static != nil, oCfg.Resolver.DNS != nil, oCfg.Resolver.K8sSvc != nil} {
+		if configured {
+			numResolvers++
+		}
+	}
+	if numResolvers > 1 {
 		return nil, errMultipleResolversProvided
 	}
 
@@ -86,15 +116,41 @@ func newLoadBalancer(params component.ExporterCreateSettings, cfg config.Exporte
 		}
 	}
 
+	if oCfg.Resolver.K8sSvc != nil {
+		k8sLogger := params.Logger.With(zap.String("resolver", "k8s service"))
+
+		apiCfg := oCfg.Resolver.K8sSvc.APIConfig
+		if apiCfg.AuthType == "" {
+			apiCfg.AuthType = k8sconfig.AuthTypeServiceAccount
+		}
+		client, err := k8sconfig.MakeClient(apiCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err = newK8sResolver(k8sLogger, client, oCfg.Resolver.K8sSvc.Service, oCfg.Resolver.K8sSvc.Port, oCfg.Resolver.K8sSvc.UseEndpointSlices)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if res == nil {
 		return nil, errNoResolver
 	}
 
+	drainTimeout := oCfg.DrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+
 	return &loadBalancerImp{
 		logger:           params.Logger,
 		res:              res,
 		componentFactory: factory,
 		exporters:        map[string]component.Exporter{},
+		inflight:         map[string]*sync.WaitGroup{},
+		drainTimeout:     drainTimeout,
+		failover:         oCfg.Failover,
 	}, nil
 }
 
@@ -150,12 +206,45 @@ func endpointWithPort(endpoint string) string {
 }
 
 func (lb *loadBalancerImp) removeExtraExporters(ctx context.Context, endpoints []string) {
-	for existing := range lb.exporters {
+	for existing, exp := range lb.exporters {
 		if !endpointFound(existing, endpoints) {
-			lb.exporters[existing].Shutdown(ctx)
+			// no new requests will reach this exporter from now on, but the ones in flight might still be
+			// running: we let them finish before shutting the exporter down
+			inflight := lb.inflight[existing]
 			delete(lb.exporters, existing)
+			delete(lb.inflight, existing)
+
+			lb.drainWg.Add(1)
+			go lb.drainAndShutdown(ctx, existing, exp, inflight)
+		}
+	}
+}
+
+// drainAndShutdown waits for the requests in flight to the removed exporter to finish, up to the drain timeout,
+// and shuts the exporter down
+func (lb *loadBalancerImp) drainAndShutdown(ctx context.Context, endpoint string, exp component.Exporter, inflight *sync.WaitGroup) {
+	defer lb.drainWg.Done()
+
+	if inflight != nil {
+		drained := make(chan struct{})
+		go func() {
+			inflight.Wait()
+			close(drained)
+		}()
+
+		timer := time.NewTimer(lb.drainTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-drained:
+		case <-timer.C:
+			lb.logger.Warn("timed out waiting for the requests to the removed backend to finish", zap.String("endpoint", endpoint))
 		}
 	}
+
+	if err := exp.Shutdown(ctx); err != nil {
+		lb.logger.Warn("failed to shutdown the exporter for the removed backend", zap.String("endpoint", endpoint), zap.Error(err))
+	}
 }
 
 func endpointFound(endpoint string, endpoints []string) bool {
@@ -168,22 +257,113 @@ func endpointFound(endpoint string, endpoints []string) bool {
 	return false
 }
 
-func (lb *loadBalancerImp) Shutdown(context.Context) error {
+func (lb *loadBalancerImp) Shutdown(ctx context.Context) error {
+	// stop the resolver first, so that the backends don't change anymore
+	var errs []error
+	if err := lb.res.shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
 	lb.stopped = true
-	return nil
+	lb.drainWg.Wait()
+
+	lb.updateLock.Lock()
+	defer lb.updateLock.Unlock()
+	for endpoint, exp := range lb.exporters {
+		if err := exp.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown the exporter for the endpoint %q: %w", endpoint, err))
+		}
+	}
+	lb.exporters = map[string]component.Exporter{}
+	lb.inflight = map[string]*sync.WaitGroup{}
+
+	return consumererror.Combine(errs)
+}
+
+func (lb *loadBalancerImp) Endpoint(identifier []byte) string {
+	lb.updateLock.RLock()
+	defer lb.updateLock.RUnlock()
+
+	return lb.ring.endpointFor(identifier)
 }
 
-func (lb *loadBalancerImp) Endpoint(traceID pdata.TraceID) string {
+func (lb *loadBalancerImp) Route(identifier []byte) []string {
+	count := 1
+	if lb.failover {
+		count = 2
+	}
+
 	lb.updateLock.RLock()
 	defer lb.updateLock.RUnlock()
 
-	return lb.ring.endpointFor(traceID)
+	if lb.ring == nil {
+		return nil
+	}
+	return lb.ring.endpointsFor(identifier, count)
+}
+
+func (lb *loadBalancerImp) Consume(ctx context.Context, route []string, consume func(context.Context, component.Exporter) error) error {
+	if len(route) == 0 {
+		return errNoBackends
+	}
+
+	var err error
+	for i, endpoint := range route {
+		if i > 0 {
+			lb.logger.Debug("failing over to the next backend in the ring", zap.String("failed", route[i-1]), zap.String("endpoint", endpoint), zap.Error(err))
+			fCtx, _ := tag.New(ctx, tag.Upsert(tag.MustNewKey("endpoint"), route[i-1]))
+			stats.Record(fCtx, mNumFailovers.M(1))
+		}
+
+		if err = lb.consumeWith(ctx, endpoint, consume); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// consumeWith calls the consume function with the exporter for the endpoint, keeping track of the request
+// while it's in flight and recording its outcome
+func (lb *loadBalancerImp) consumeWith(ctx context.Context, endpoint string, consume func(context.Context, component.Exporter) error) error {
+	lb.updateLock.RLock()
+	exp, found := lb.exporters[endpoint]
+	var inflight *sync.WaitGroup
+	if found {
+		// the update lock guarantees that the exporter isn't being removed while we register the request
+		lb.inflightLock.Lock()
+		inflight = lb.inflight[endpoint]
+		if inflight == nil {
+			inflight = &sync.WaitGroup{}
+			lb.inflight[endpoint] = inflight
+		}
+		inflight.Add(1)
+		lb.inflightLock.Unlock()
+	}
+	lb.updateLock.RUnlock()
+
+	if !found {
+		// something is really wrong... how come we couldn't find the exporter??
+		return fmt.Errorf("couldn't find the exporter for the endpoint %q", endpoint)
+	}
+	defer inflight.Done()
+
+	start := time.Now()
+	err := consume(ctx, exp)
+	duration := time.Since(start)
+	ctx, _ = tag.New(ctx, tag.Upsert(tag.MustNewKey("endpoint"), endpoint))
+
+	if err == nil {
+		sCtx, _ := tag.New(ctx, tag.Upsert(tag.MustNewKey("success"), "true"))
+		stats.Record(sCtx, mBackendLatency.M(duration.Milliseconds()))
+	} else {
+		fCtx, _ := tag.New(ctx, tag.Upsert(tag.MustNewKey("success"), "false"))
+		stats.Record(fCtx, mBackendLatency.M(duration.Milliseconds()))
+	}
+
+	return err
 }
 
 func (lb *loadBalancerImp) Exporter(endpoint string) (component.Exporter, error) {
-	// NOTE: make rolling updates of next tier of collectors work. currently this may cause
-	// data loss because the latest batches sent to outdated backend will never find their way out.
-	// for details: https://github.com/open-telemetry/opentelemetry-collector-contrib/issues/1690
 	lb.updateLock.RLock()
 	exp, found := lb.exporters[endpoint]
 	lb.updateLock.RUnlock()
@@ -17,7 +17,11 @@ package loadbalancingexporter
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,6 +32,10 @@ import (
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
 	"go.opentelemetry.io/collector/exporter/otlpexporter"
 	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/k8sconfig"
 )
 
 func TestNewLoadBalancerNoResolver(t *testing.T) {
@@ -112,6 +120,73 @@ func TestWithDNSResolver(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestWithK8sResolver(t *testing.T) {
+	require.NoError(t, os.Setenv("KUBERNETES_SERVICE_HOST", "127.0.0.1"))
+	require.NoError(t, os.Setenv("KUBERNETES_SERVICE_PORT", "6443"))
+	defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	defer os.Unsetenv("KUBERNETES_SERVICE_PORT")
+
+	cfg := &Config{
+		Resolver: ResolverSettings{
+			K8sSvc: &K8sSvcResolver{
+				APIConfig: k8sconfig.APIConfig{AuthType: k8sconfig.AuthTypeNone},
+				Service:   "lb.observability",
+			},
+		},
+	}
+	p, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, nil)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	// test
+	res, ok := p.res.(*k8sResolver)
+
+	// verify
+	require.True(t, ok)
+	assert.Equal(t, "lb", res.svcName)
+	assert.Equal(t, "observability", res.svcNs)
+	assert.Equal(t, defaultPort, res.port)
+}
+
+func TestNewLoadBalancerInvalidK8sResolver(t *testing.T) {
+	require.NoError(t, os.Setenv("KUBERNETES_SERVICE_HOST", "127.0.0.1"))
+	require.NoError(t, os.Setenv("KUBERNETES_SERVICE_PORT", "6443"))
+	defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	defer os.Unsetenv("KUBERNETES_SERVICE_PORT")
+
+	for _, tt := range []struct {
+		desc string
+		cfg  *K8sSvcResolver
+		err  error
+	}{
+		{
+			"no service",
+			&K8sSvcResolver{APIConfig: k8sconfig.APIConfig{AuthType: k8sconfig.AuthTypeNone}},
+			errNoSvc,
+		},
+		{
+			"invalid auth type",
+			&K8sSvcResolver{APIConfig: k8sconfig.APIConfig{AuthType: "invalid"}, Service: "lb"},
+			fmt.Errorf("invalid authType for kubernetes: %v", "invalid"),
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfg := &Config{
+				Resolver: ResolverSettings{
+					K8sSvc: tt.cfg,
+				},
+			}
+
+			// test
+			p, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, nil)
+
+			// verify
+			assert.Nil(t, p)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
 func TestMultipleResolvers(t *testing.T) {
 	cfg := &Config{
 		Resolver: ResolverSettings{
@@ -167,6 +242,55 @@ func TestLoadBalancerShutdown(t *testing.T) {
 	assert.Nil(t, res)
 }
 
+func TestLoadBalancerShutdownStopsResolver(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	p, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, nil)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	expectedErr := errors.New("some expected err")
+	shutdown := false
+	p.res = &mockResolver{
+		onShutdown: func(context.Context) error {
+			shutdown = true
+			return expectedErr
+		},
+	}
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+
+	// test
+	res := p.Shutdown(context.Background())
+
+	// verify
+	assert.True(t, shutdown)
+	assert.Equal(t, expectedErr, res)
+}
+
+func TestLoadBalancerShutdownStopsK8sInformer(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	p, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, nil)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	client := fake.NewSimpleClientset(endpointsFor("lb", "observability", nil, nil))
+	res, err := newK8sResolver(zap.NewNop(), client, "lb.observability", "", false)
+	require.NoError(t, err)
+	p.res = res
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+
+	// test
+	require.NoError(t, p.Shutdown(context.Background()))
+
+	// verify
+	select {
+	case <-res.stopCh:
+	default:
+		t.Fatal("the informer of the k8s resolver wasn't stopped")
+	}
+}
+
 func TestOnBackendChanges(t *testing.T) {
 	// prepare
 	cfg := simpleConfig()
@@ -208,6 +332,169 @@ func TestRemoveExtraExporters(t *testing.T) {
 	assert.NotContains(t, p.exporters, "endpoint-2")
 }
 
+func TestRemoveExtraExportersDrainsInflightRequests(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	p, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, nil)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	var shutdown int64
+	p.exporters["endpoint-1:4317"] = newNopMockExporter()
+	p.exporters["endpoint-2:4317"] = componenthelper.New(componenthelper.WithShutdown(func(context.Context) error {
+		atomic.StoreInt64(&shutdown, 1)
+		return nil
+	}))
+
+	// a request to the endpoint-2:4317 is in flight
+	started := make(chan struct{})
+	release := make(chan struct{})
+	consumed := make(chan error)
+	go func() {
+		consumed <- p.Consume(context.Background(), []string{"endpoint-2:4317"}, func(context.Context, component.Exporter) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// test
+	p.onBackendChanges([]string{"endpoint-1:4317"})
+
+	// verify
+	assert.NotContains(t, p.exporters, "endpoint-2:4317")
+	time.Sleep(50 * time.Millisecond)
+	assert.EqualValues(t, 0, atomic.LoadInt64(&shutdown), "the exporter shouldn't be shut down while requests are in flight")
+
+	close(release)
+	assert.NoError(t, <-consumed)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&shutdown) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// new requests don't reach the removed exporter anymore
+	err = p.Consume(context.Background(), []string{"endpoint-2:4317"}, func(context.Context, component.Exporter) error {
+		return nil
+	})
+	assert.EqualError(t, err, fmt.Sprintf("couldn't find the exporter for the endpoint %q", "endpoint-2:4317"))
+}
+
+func TestRemoveExtraExportersDrainTimeout(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	cfg.DrainTimeout = 10 * time.Millisecond
+	p, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, nil)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	var shutdown int64
+	p.exporters["endpoint-1"] = componenthelper.New(componenthelper.WithShutdown(func(context.Context) error {
+		atomic.StoreInt64(&shutdown, 1)
+		return nil
+	}))
+
+	// a request to the endpoint-1 is stuck
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	go p.Consume(context.Background(), []string{"endpoint-1"}, func(context.Context, component.Exporter) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	// test
+	p.removeExtraExporters(context.Background(), []string{})
+
+	// verify
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&shutdown) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, p.Shutdown(context.Background()))
+}
+
+func TestConsumeWithFailover(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	cfg.Failover = true
+	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		return newNopMockExporter(), nil
+	}
+	p, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, componentFactory)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	p.onBackendChanges([]string{"endpoint-1", "endpoint-2"})
+	failing := newNopMockExporter()
+	healthy := newNopMockExporter()
+
+	route := p.Route([]byte("identifier"))
+	require.Len(t, route, 2)
+	p.exporters[route[0]] = failing
+	p.exporters[route[1]] = healthy
+
+	// test
+	var used []component.Exporter
+	err = p.Consume(context.Background(), route, func(_ context.Context, exp component.Exporter) error {
+		used = append(used, exp)
+		if exp == failing {
+			return errors.New("some expected error")
+		}
+		return nil
+	})
+
+	// verify
+	assert.NoError(t, err)
+	assert.Equal(t, []component.Exporter{failing, healthy}, used)
+}
+
+func TestConsumeWithFailoverAllFailing(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	cfg.Failover = true
+	p, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, nil)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	p.exporters["endpoint-1"] = newNopMockExporter()
+	p.exporters["endpoint-2"] = newNopMockExporter()
+
+	// test
+	calls := 0
+	err = p.Consume(context.Background(), []string{"endpoint-1", "endpoint-2"}, func(context.Context, component.Exporter) error {
+		calls++
+		return fmt.Errorf("failure %d", calls)
+	})
+
+	// verify
+	assert.EqualError(t, err, "failure 2")
+	assert.Equal(t, 2, calls)
+}
+
+func TestRouteWithoutFailover(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		return newNopMockExporter(), nil
+	}
+	p, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, componentFactory)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	// before the first resolution, there are no backends to route to
+	assert.Empty(t, p.Route([]byte("identifier")))
+	assert.Equal(t, errNoBackends, p.Consume(context.Background(), nil, nil))
+
+	// test
+	p.onBackendChanges([]string{"endpoint-1", "endpoint-2"})
+	route := p.Route([]byte("identifier"))
+
+	// verify
+	assert.Equal(t, []string{p.Endpoint([]byte("identifier"))}, route)
+}
+
 func TestAddMissingExporters(t *testing.T) {
 	// prepare
 	cfg := simpleConfig()
@@ -343,7 +630,9 @@ func TestFailedExporterInRing(t *testing.T) {
 
 	// test
 	// this trace ID will reach the endpoint-2 -- see the consistent hashing tests for more info
-	_, err = p.Exporter(p.Endpoint(pdata.NewTraceID([16]byte{128, 128, 0, 0})))
+	traceID := pdata.NewTraceID([16]byte{128, 128, 0, 0})
+	b := traceID.Bytes()
+	_, err = p.Exporter(p.Endpoint(b[:]))
 
 	// verify
 	assert.Error(t, err)
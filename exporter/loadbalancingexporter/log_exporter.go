@@ -19,10 +19,7 @@ import (
 	"fmt"
 	"math/rand"
 	"sync"
-	"time"
 
-	"go.opencensus.io/stats"
-	"go.opencensus.io/tag"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/consumer"
@@ -67,10 +64,10 @@ func (e *logExporterImp) Start(ctx context.Context, host component.Host) error {
 	return e.loadBalancer.Start(ctx, host)
 }
 
-func (e *logExporterImp) Shutdown(context.Context) error {
+func (e *logExporterImp) Shutdown(ctx context.Context) error {
 	e.stopped = true
 	e.shutdownWg.Wait()
-	return nil
+	return e.loadBalancer.Shutdown(ctx)
 }
 
 func (e *logExporterImp) ConsumeLogs(ctx context.Context, ld pdata.Logs) error {
@@ -95,32 +92,17 @@ func (e *logExporterImp) consumeLog(ctx context.Context, ld pdata.Logs) error {
 		balancingKey = random()
 	}
 
-	endpoint := e.loadBalancer.Endpoint(balancingKey)
-	exp, err := e.loadBalancer.Exporter(endpoint)
-	if err != nil {
-		return err
-	}
-
-	le, ok := exp.(component.LogsExporter)
-	if !ok {
-		expectType := (*component.LogsExporter)(nil)
-		return fmt.Errorf("unable to export logs, unexpected exporter type: expected %T but got %T", expectType, exp)
-	}
-
-	start := time.Now()
-	err = le.ConsumeLogs(ctx, ld)
-	duration := time.Since(start)
-	ctx, _ = tag.New(ctx, tag.Upsert(tag.MustNewKey("endpoint"), endpoint))
-
-	if err == nil {
-		sCtx, _ := tag.New(ctx, tag.Upsert(tag.MustNewKey("success"), "true"))
-		stats.Record(sCtx, mBackendLatency.M(duration.Milliseconds()))
-	} else {
-		fCtx, _ := tag.New(ctx, tag.Upsert(tag.MustNewKey("success"), "false"))
-		stats.Record(fCtx, mBackendLatency.M(duration.Milliseconds()))
-	}
+	b := balancingKey.Bytes()
+	route := e.loadBalancer.Route(b[:])
+	return e.loadBalancer.Consume(ctx, route, func(ctx context.Context, exp component.Exporter) error {
+		le, ok := exp.(component.LogsExporter)
+		if !ok {
+			expectType := (*component.LogsExporter)(nil)
+			return fmt.Errorf("unable to export logs, unexpected exporter type: expected %T but got %T", expectType, exp)
+		}
 
-	return err
+		return le.ConsumeLogs(ctx, ld)
+	})
 }
 
 func traceIDFromLogs(ld pdata.Logs) pdata.TraceID {
@@ -0,0 +1,292 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancingexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/otlpexporter"
+	"go.opentelemetry.io/collector/model/pdata"
+	conventions "go.opentelemetry.io/collector/translator/conventions/v1.5.0"
+	tracetranslator "go.opentelemetry.io/collector/translator/trace"
+)
+
+var _ component.MetricsExporter = (*metricExporterImp)(nil)
+
+var (
+	errNoRoutingAttribute = errors.New("the routing_attribute must be specified when the routing_key is \"resource_attribute\"")
+)
+
+type metricExporterImp struct {
+	loadBalancer loadBalancer
+
+	routingKey       string
+	routingAttribute string
+
+	stopped    bool
+	shutdownWg sync.WaitGroup
+}
+
+// Create new metrics exporter
+func newMetricsExporter(params component.ExporterCreateSettings, cfg config.Exporter) (*metricExporterImp, error) {
+	oCfg := cfg.(*Config)
+
+	routingKey, routingAttribute := oCfg.RoutingKey, oCfg.RoutingAttribute
+	switch routingKey {
+	case "":
+		routingKey = seriesRouting
+	case metricNameRouting, seriesRouting:
+	case svcRouting:
+		routingKey, routingAttribute = resourceAttributeRouting, conventions.AttributeServiceName
+	case resourceAttributeRouting:
+		if routingAttribute == "" {
+			return nil, errNoRoutingAttribute
+		}
+	default:
+		return nil, fmt.Errorf("unsupported routing_key for metrics: %q", routingKey)
+	}
+
+	exporterFactory := otlpexporter.NewFactory()
+
+	lb, err := newLoadBalancer(params, cfg, func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		oCfg := buildExporterConfig(cfg.(*Config), endpoint)
+		return exporterFactory.CreateMetricsExporter(ctx, params, &oCfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &metricExporterImp{
+		loadBalancer:     lb,
+		routingKey:       routingKey,
+		routingAttribute: routingAttribute,
+	}, nil
+}
+
+func (e *metricExporterImp) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+func (e *metricExporterImp) Start(ctx context.Context, host component.Host) error {
+	return e.loadBalancer.Start(ctx, host)
+}
+
+func (e *metricExporterImp) Shutdown(ctx context.Context) error {
+	e.stopped = true
+	e.shutdownWg.Wait()
+	return e.loadBalancer.Shutdown(ctx)
+}
+
+func (e *metricExporterImp) ConsumeMetrics(ctx context.Context, md pdata.Metrics) error {
+	var errors []error
+	batches := e.splitMetrics(md)
+	for _, batch := range batches {
+		if err := e.consumeMetric(ctx, batch); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return consumererror.Combine(errors)
+}
+
+func (e *metricExporterImp) consumeMetric(ctx context.Context, batch *metricsBatch) error {
+	return e.loadBalancer.Consume(ctx, batch.route, func(ctx context.Context, exp component.Exporter) error {
+		me, ok := exp.(component.MetricsExporter)
+		if !ok {
+			expectType := (*component.MetricsExporter)(nil)
+			return fmt.Errorf("unable to export metrics, unexpected exporter type: expected %T but got %T", expectType, exp)
+		}
+
+		return me.ConsumeMetrics(ctx, batch.Metrics)
+	})
+}
+
+// metricsBatch holds the metrics to be sent through the same route
+type metricsBatch struct {
+	pdata.Metrics
+	route []string
+}
+
+// splitMetrics groups the metrics from the batch by the route for their routing key, keyed by the endpoints
+// in the route. The resource and instrumentation library of each metric are kept, so that each route
+// receives a single batch with the same structure as the original one.
+func (e *metricExporterImp) splitMetrics(md pdata.Metrics) map[string]*metricsBatch {
+	batches := map[string]*metricsBatch{}
+
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		resourceKey := e.resourceKey(rm.Resource())
+		rmsByRoute := map[string]pdata.ResourceMetrics{}
+
+		ilms := rm.InstrumentationLibraryMetrics()
+		for j := 0; j < ilms.Len(); j++ {
+			ilm := ilms.At(j)
+			ilmsByRoute := map[string]pdata.InstrumentationLibraryMetrics{}
+
+			destination := func(route []string) pdata.MetricSlice {
+				key := strings.Join(route, ",")
+				if dest, found := ilmsByRoute[key]; found {
+					return dest.Metrics()
+				}
+
+				destRM, found := rmsByRoute[key]
+				if !found {
+					batch, found := batches[key]
+					if !found {
+						batch = &metricsBatch{Metrics: pdata.NewMetrics(), route: route}
+						batches[key] = batch
+					}
+					destRM = batch.ResourceMetrics().AppendEmpty()
+					rm.Resource().CopyTo(destRM.Resource())
+					rmsByRoute[key] = destRM
+				}
+
+				dest := destRM.InstrumentationLibraryMetrics().AppendEmpty()
+				ilm.InstrumentationLibrary().CopyTo(dest.InstrumentationLibrary())
+				ilmsByRoute[key] = dest
+				return dest.Metrics()
+			}
+
+			metrics := ilm.Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				switch e.routingKey {
+				case seriesRouting:
+					e.splitSeries(resourceKey, metric, destination)
+				case metricNameRouting:
+					route := e.loadBalancer.Route([]byte(metric.Name()))
+					metric.CopyTo(destination(route).AppendEmpty())
+				default:
+					route := e.loadBalancer.Route([]byte(resourceKey))
+					metric.CopyTo(destination(route).AppendEmpty())
+				}
+			}
+		}
+	}
+
+	return batches
+}
+
+// splitSeries routes each data point of the metric according to its series identity
+func (e *metricExporterImp) splitSeries(resourceKey string, metric pdata.Metric, destination func([]string) pdata.MetricSlice) {
+	metricsByRoute := map[string]pdata.Metric{}
+	metricFor := func(labels pdata.StringMap) pdata.Metric {
+		route := e.loadBalancer.Route([]byte(seriesIdentity(resourceKey, metric.Name(), labels)))
+		key := strings.Join(route, ",")
+		if dest, found := metricsByRoute[key]; found {
+			return dest
+		}
+
+		dest := destination(route).AppendEmpty()
+		copyMetricDescriptor(metric, dest)
+		metricsByRoute[key] = dest
+		return dest
+	}
+
+	switch metric.DataType() {
+	case pdata.MetricDataTypeGauge:
+		dps := metric.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dps.At(i).CopyTo(metricFor(dps.At(i).LabelsMap()).Gauge().DataPoints().AppendEmpty())
+		}
+	case pdata.MetricDataTypeSum:
+		dps := metric.Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dps.At(i).CopyTo(metricFor(dps.At(i).LabelsMap()).Sum().DataPoints().AppendEmpty())
+		}
+	case pdata.MetricDataTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dps.At(i).CopyTo(metricFor(dps.At(i).LabelsMap()).Histogram().DataPoints().AppendEmpty())
+		}
+	case pdata.MetricDataTypeSummary:
+		dps := metric.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dps.At(i).CopyTo(metricFor(dps.At(i).LabelsMap()).Summary().DataPoints().AppendEmpty())
+		}
+	}
+}
+
+// resourceKey returns the part of the routing key derived from the resource. For the "resource_attribute"
+// routing, this is the value of the configured attribute, or an empty string when the attribute is missing.
+// For the "series" routing, this is the identity of the resource, made of all its attributes.
+func (e *metricExporterImp) resourceKey(resource pdata.Resource) string {
+	switch e.routingKey {
+	case resourceAttributeRouting:
+		if attr, found := resource.Attributes().Get(e.routingAttribute); found {
+			return tracetranslator.AttributeValueToString(attr)
+		}
+		return ""
+	case seriesRouting:
+		keys := make([]string, 0, resource.Attributes().Len())
+		values := map[string]string{}
+		resource.Attributes().Range(func(k string, v pdata.AttributeValue) bool {
+			keys = append(keys, k)
+			values[k] = tracetranslator.AttributeValueToString(v)
+			return true
+		})
+		return joinSorted(keys, values)
+	}
+	return ""
+}
+
+// seriesIdentity returns the identity of the series for a data point with the given labels
+func seriesIdentity(resourceKey string, name string, labels pdata.StringMap) string {
+	keys := make([]string, 0, labels.Len())
+	values := map[string]string{}
+	labels.Range(func(k string, v string) bool {
+		keys = append(keys, k)
+		values[k] = v
+		return true
+	})
+	return resourceKey + "|" + name + "|" + joinSorted(keys, values)
+}
+
+func joinSorted(keys []string, values map[string]string) string {
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(values[k])
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+func copyMetricDescriptor(src pdata.Metric, dest pdata.Metric) {
+	dest.SetName(src.Name())
+	dest.SetDescription(src.Description())
+	dest.SetUnit(src.Unit())
+	dest.SetDataType(src.DataType())
+
+	switch src.DataType() {
+	case pdata.MetricDataTypeSum:
+		dest.Sum().SetAggregationTemporality(src.Sum().AggregationTemporality())
+		dest.Sum().SetIsMonotonic(src.Sum().IsMonotonic())
+	case pdata.MetricDataTypeHistogram:
+		dest.Histogram().SetAggregationTemporality(src.Histogram().AggregationTemporality())
+	}
+}
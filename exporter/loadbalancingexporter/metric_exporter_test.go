@@ -0,0 +1,528 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancingexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenthelper"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+func TestNewMetricsExporter(t *testing.T) {
+	for _, tt := range []struct {
+		desc   string
+		config *Config
+		err    error
+	}{
+		{
+			"simple",
+			simpleConfig(),
+			nil,
+		},
+		{
+			"empty",
+			&Config{},
+			errNoResolver,
+		},
+		{
+			"resource attribute without attribute name",
+			func() *Config {
+				cfg := simpleConfig()
+				cfg.RoutingKey = resourceAttributeRouting
+				return cfg
+			}(),
+			errNoRoutingAttribute,
+		},
+		{
+			"unsupported routing key",
+			func() *Config {
+				cfg := simpleConfig()
+				cfg.RoutingKey = "span"
+				return cfg
+			}(),
+			fmt.Errorf("unsupported routing_key for metrics: %q", "span"),
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			// test
+			_, err := newMetricsExporter(componenttest.NewNopExporterCreateSettings(), tt.config)
+
+			// verify
+			require.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestMetricsExporterServiceRouting(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	cfg.RoutingKey = svcRouting
+
+	// test
+	p, err := newMetricsExporter(componenttest.NewNopExporterCreateSettings(), cfg)
+
+	// verify
+	require.NoError(t, err)
+	assert.Equal(t, resourceAttributeRouting, p.routingKey)
+	assert.Equal(t, "service.name", p.routingAttribute)
+}
+
+func TestMetricsExporterStart(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		me   *metricExporterImp
+		err  error
+	}{
+		{
+			"ok",
+			func() *metricExporterImp {
+				p, _ := newMetricsExporter(componenttest.NewNopExporterCreateSettings(), simpleConfig())
+				return p
+			}(),
+			nil,
+		},
+		{
+			"error",
+			func() *metricExporterImp {
+				lb, _ := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), simpleConfig(), nil)
+				p, _ := newMetricsExporter(componenttest.NewNopExporterCreateSettings(), simpleConfig())
+
+				lb.res = &mockResolver{
+					onStart: func(context.Context) error {
+						return errors.New("some expected err")
+					},
+				}
+				p.loadBalancer = lb
+
+				return p
+			}(),
+			errors.New("some expected err"),
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			p := tt.me
+
+			// test
+			res := p.Start(context.Background(), componenttest.NewNopHost())
+			defer p.Shutdown(context.Background())
+
+			// verify
+			require.Equal(t, tt.err, res)
+		})
+	}
+}
+
+func TestMetricsExporterShutdown(t *testing.T) {
+	p, err := newMetricsExporter(componenttest.NewNopExporterCreateSettings(), simpleConfig())
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	// test
+	res := p.Shutdown(context.Background())
+
+	// verify
+	assert.Nil(t, res)
+}
+
+func TestConsumeMetrics(t *testing.T) {
+	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		return newNopMockMetricsExporter(), nil
+	}
+	lb, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), simpleConfig(), componentFactory)
+	require.NotNil(t, lb)
+	require.NoError(t, err)
+
+	p, err := newMetricsExporter(componenttest.NewNopExporterCreateSettings(), simpleConfig())
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	// pre-load an exporter here, so that we don't use the actual OTLP exporter
+	sink := new(consumertest.MetricsSink)
+	lb.exporters["endpoint-1"] = newMockMetricsExporter(sink.ConsumeMetrics)
+	lb.res = &mockResolver{
+		triggerCallbacks: true,
+		onResolve: func(ctx context.Context) ([]string, error) {
+			return []string{"endpoint-1"}, nil
+		},
+	}
+	p.loadBalancer = lb
+
+	err = p.Start(context.Background(), componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer p.Shutdown(context.Background())
+
+	// test
+	res := p.ConsumeMetrics(context.Background(), simpleMetrics(10))
+
+	// verify
+	assert.Nil(t, res)
+	require.Len(t, sink.AllMetrics(), 1)
+	assert.Equal(t, 10, sink.AllMetrics()[0].DataPointCount())
+}
+
+func TestConsumeMetricsExporterNotFound(t *testing.T) {
+	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		return newNopMockMetricsExporter(), nil
+	}
+	lb, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), simpleConfig(), componentFactory)
+	require.NotNil(t, lb)
+	require.NoError(t, err)
+
+	p, err := newMetricsExporter(componenttest.NewNopExporterCreateSettings(), simpleConfig())
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	lb.res = &mockResolver{
+		triggerCallbacks: true,
+		onResolve: func(ctx context.Context) ([]string, error) {
+			return []string{"endpoint-1"}, nil
+		},
+	}
+	p.loadBalancer = lb
+
+	err = p.Start(context.Background(), componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer p.Shutdown(context.Background())
+
+	// test
+	res := p.ConsumeMetrics(context.Background(), simpleMetrics(1))
+
+	// verify
+	assert.Error(t, res)
+	assert.EqualError(t, res, fmt.Sprintf("couldn't find the exporter for the endpoint %q", "endpoint-1"))
+}
+
+func TestConsumeMetricsUnexpectedExporterType(t *testing.T) {
+	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		return newNopMockExporter(), nil
+	}
+	lb, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), simpleConfig(), componentFactory)
+	require.NotNil(t, lb)
+	require.NoError(t, err)
+
+	p, err := newMetricsExporter(componenttest.NewNopExporterCreateSettings(), simpleConfig())
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	// pre-load an exporter here, so that we don't use the actual OTLP exporter
+	lb.exporters["endpoint-1"] = newNopMockExporter()
+	lb.res = &mockResolver{
+		triggerCallbacks: true,
+		onResolve: func(ctx context.Context) ([]string, error) {
+			return []string{"endpoint-1"}, nil
+		},
+	}
+	p.loadBalancer = lb
+
+	err = p.Start(context.Background(), componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer p.Shutdown(context.Background())
+
+	// test
+	res := p.ConsumeMetrics(context.Background(), simpleMetrics(1))
+
+	// verify
+	assert.Error(t, res)
+	assert.EqualError(t, res, fmt.Sprintf("unable to export metrics, unexpected exporter type: expected *component.MetricsExporter but got %T", newNopMockExporter()))
+}
+
+func TestSplitMetricsBySeries(t *testing.T) {
+	// prepare
+	p := metricsExporterWithEndpoints(t, simpleConfig(), "endpoint-1", "endpoint-2")
+	md := simpleMetrics(50)
+
+	// test
+	batches := p.splitMetrics(md)
+
+	// verify
+	require.Len(t, batches, 2, "the series should have been spread among both endpoints")
+	total := 0
+	for _, batch := range batches {
+		total += batch.DataPointCount()
+
+		// the structure of the original batch is kept
+		require.Equal(t, 1, batch.ResourceMetrics().Len())
+		rm := batch.ResourceMetrics().At(0)
+		assert.Equal(t, 1, rm.Resource().Attributes().Len())
+		require.Equal(t, 1, rm.InstrumentationLibraryMetrics().Len())
+		ilm := rm.InstrumentationLibraryMetrics().At(0)
+		assert.Equal(t, "library", ilm.InstrumentationLibrary().Name())
+		require.Equal(t, 1, ilm.Metrics().Len())
+		metric := ilm.Metrics().At(0)
+		assert.Equal(t, "requests", metric.Name())
+		assert.Equal(t, "1", metric.Unit())
+		assert.Equal(t, pdata.AggregationTemporalityCumulative, metric.Sum().AggregationTemporality())
+		assert.True(t, metric.Sum().IsMonotonic())
+	}
+	assert.Equal(t, 50, total)
+
+	// the same series always land on the same endpoint, regardless of the other series in the batch
+	for endpoint, batch := range batches {
+		dps := batch.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			single := simpleMetrics(0)
+			dp := single.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Sum().DataPoints().AppendEmpty()
+			dps.At(i).CopyTo(dp)
+
+			res := p.splitMetrics(single)
+			require.Len(t, res, 1)
+			assert.Contains(t, res, endpoint)
+		}
+	}
+}
+
+func TestSplitMetricsByMetricName(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	cfg.RoutingKey = metricNameRouting
+	p := metricsExporterWithEndpoints(t, cfg, "endpoint-1", "endpoint-2")
+
+	md := pdata.NewMetrics()
+	for i := 0; i < 2; i++ {
+		simpleMetrics(10).ResourceMetrics().MoveAndAppendTo(md.ResourceMetrics())
+	}
+	metrics := md.ResourceMetrics().At(1).InstrumentationLibraryMetrics().At(0).Metrics()
+	for i := 0; i < 20; i++ {
+		metric := metrics.AppendEmpty()
+		metric.SetName(fmt.Sprintf("metric-%d", i))
+		metric.SetDataType(pdata.MetricDataTypeGauge)
+		metric.Gauge().DataPoints().AppendEmpty().SetDoubleVal(float64(i))
+	}
+
+	// test
+	batches := p.splitMetrics(md)
+
+	// verify
+	require.Len(t, batches, 2)
+	total := 0
+	seen := map[string]string{}
+	for endpoint, batch := range batches {
+		total += batch.DataPointCount()
+		rms := batch.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			metrics := rms.At(i).InstrumentationLibraryMetrics().At(0).Metrics()
+			for j := 0; j < metrics.Len(); j++ {
+				name := metrics.At(j).Name()
+				if previous, found := seen[name]; found {
+					assert.Equal(t, previous, endpoint, "metric %q was sent to more than one endpoint", name)
+				}
+				seen[name] = endpoint
+			}
+		}
+	}
+	assert.Equal(t, 40, total)
+	assert.Len(t, seen, 21)
+}
+
+func TestSplitMetricsByResourceAttribute(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	cfg.RoutingKey = resourceAttributeRouting
+	cfg.RoutingAttribute = "service.name"
+	p := metricsExporterWithEndpoints(t, cfg, "endpoint-1", "endpoint-2")
+
+	md := pdata.NewMetrics()
+	for i := 0; i < 20; i++ {
+		rm := simpleMetrics(2).ResourceMetrics().At(0)
+		rm.Resource().Attributes().UpsertString("service.name", fmt.Sprintf("service-%d", i%5))
+		rm.CopyTo(md.ResourceMetrics().AppendEmpty())
+	}
+	noAttribute := simpleMetrics(2).ResourceMetrics().At(0)
+	noAttribute.Resource().Attributes().Delete("service.name")
+	noAttribute.CopyTo(md.ResourceMetrics().AppendEmpty())
+
+	// test
+	batches := p.splitMetrics(md)
+
+	// verify
+	require.Len(t, batches, 2)
+	total := 0
+	seen := map[string]string{}
+	for endpoint, batch := range batches {
+		total += batch.DataPointCount()
+		rms := batch.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			service := ""
+			if attr, found := rms.At(i).Resource().Attributes().Get("service.name"); found {
+				service = attr.StringVal()
+			}
+			if previous, found := seen[service]; found {
+				assert.Equal(t, previous, endpoint, "service %q was sent to more than one endpoint", service)
+			}
+			seen[service] = endpoint
+
+			// all the data points for the resource are kept together
+			assert.Equal(t, 2, rms.At(i).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Sum().DataPoints().Len())
+		}
+	}
+	assert.Equal(t, 42, total)
+	assert.Len(t, seen, 6)
+}
+
+func TestSplitSeriesForAllMetricTypes(t *testing.T) {
+	// prepare
+	p := metricsExporterWithEndpoints(t, simpleConfig(), "endpoint-1", "endpoint-2")
+
+	md := pdata.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().InstrumentationLibraryMetrics().AppendEmpty().Metrics()
+
+	gauge := metrics.AppendEmpty()
+	gauge.SetName("gauge")
+	gauge.SetDataType(pdata.MetricDataTypeGauge)
+
+	histogram := metrics.AppendEmpty()
+	histogram.SetName("histogram")
+	histogram.SetDataType(pdata.MetricDataTypeHistogram)
+	histogram.Histogram().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+
+	summary := metrics.AppendEmpty()
+	summary.SetName("summary")
+	summary.SetDataType(pdata.MetricDataTypeSummary)
+
+	for i := 0; i < 20; i++ {
+		gauge.Gauge().DataPoints().AppendEmpty().LabelsMap().Insert("id", fmt.Sprintf("%d", i))
+		histogram.Histogram().DataPoints().AppendEmpty().LabelsMap().Insert("id", fmt.Sprintf("%d", i))
+		summary.Summary().DataPoints().AppendEmpty().LabelsMap().Insert("id", fmt.Sprintf("%d", i))
+	}
+
+	// test
+	batches := p.splitMetrics(md)
+
+	// verify
+	require.Len(t, batches, 2)
+	total := 0
+	for _, batch := range batches {
+		total += batch.DataPointCount()
+		metrics := batch.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+		for i := 0; i < metrics.Len(); i++ {
+			if metrics.At(i).DataType() == pdata.MetricDataTypeHistogram {
+				assert.Equal(t, pdata.AggregationTemporalityDelta, metrics.At(i).Histogram().AggregationTemporality())
+			}
+		}
+	}
+	assert.Equal(t, 60, total)
+}
+
+func TestSeriesIdentity(t *testing.T) {
+	first := pdata.NewStringMap()
+	first.Insert("a", "1")
+	first.Insert("b", "2")
+
+	second := pdata.NewStringMap()
+	second.Insert("b", "2")
+	second.Insert("a", "1")
+
+	other := pdata.NewStringMap()
+	other.Insert("a", "1")
+	other.Insert("b", "3")
+
+	assert.Equal(t, seriesIdentity("res", "metric", first), seriesIdentity("res", "metric", second))
+	assert.NotEqual(t, seriesIdentity("res", "metric", first), seriesIdentity("res", "metric", other))
+	assert.NotEqual(t, seriesIdentity("res", "metric", first), seriesIdentity("res", "other", first))
+	assert.NotEqual(t, seriesIdentity("res", "metric", first), seriesIdentity("other", "metric", first))
+}
+
+func metricsExporterWithEndpoints(t *testing.T, cfg *Config, endpoints ...string) *metricExporterImp {
+	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		return newNopMockMetricsExporter(), nil
+	}
+	lb, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, componentFactory)
+	require.NotNil(t, lb)
+	require.NoError(t, err)
+
+	p, err := newMetricsExporter(componenttest.NewNopExporterCreateSettings(), cfg)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	lb.res = &mockResolver{
+		triggerCallbacks: true,
+		onResolve: func(ctx context.Context) ([]string, error) {
+			return endpoints, nil
+		},
+	}
+	p.loadBalancer = lb
+
+	err = p.Start(context.Background(), componenttest.NewNopHost())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		p.Shutdown(context.Background())
+	})
+
+	return p
+}
+
+func simpleMetrics(numPoints int) pdata.Metrics {
+	md := pdata.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().UpsertString("service.name", "service-1")
+	ilm := rm.InstrumentationLibraryMetrics().AppendEmpty()
+	ilm.InstrumentationLibrary().SetName("library")
+
+	metric := ilm.Metrics().AppendEmpty()
+	metric.SetName("requests")
+	metric.SetUnit("1")
+	metric.SetDataType(pdata.MetricDataTypeSum)
+	metric.Sum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	metric.Sum().SetIsMonotonic(true)
+	for i := 0; i < numPoints; i++ {
+		dp := metric.Sum().DataPoints().AppendEmpty()
+		dp.LabelsMap().Insert("id", fmt.Sprintf("%d", i))
+		dp.SetIntVal(int64(i))
+	}
+
+	return md
+}
+
+type mockMetricsExporter struct {
+	component.Component
+	ConsumeMetricsFn func(ctx context.Context, md pdata.Metrics) error
+}
+
+func newMockMetricsExporter(consumeMetricsFn func(ctx context.Context, md pdata.Metrics) error) component.MetricsExporter {
+	return &mockMetricsExporter{
+		Component:        componenthelper.New(),
+		ConsumeMetricsFn: consumeMetricsFn,
+	}
+}
+
+func newNopMockMetricsExporter() component.MetricsExporter {
+	return &mockMetricsExporter{
+		Component: componenthelper.New(),
+		ConsumeMetricsFn: func(ctx context.Context, md pdata.Metrics) error {
+			return nil
+		},
+	}
+}
+
+func (e *mockMetricsExporter) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+func (e *mockMetricsExporter) ConsumeMetrics(ctx context.Context, md pdata.Metrics) error {
+	if e.ConsumeMetricsFn == nil {
+		return nil
+	}
+	return e.ConsumeMetricsFn(ctx, md)
+}
@@ -0,0 +1,249 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancingexporter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+var _ resolver = (*k8sResolver)(nil)
+
+const (
+	defaultK8sNamespace   = "default"
+	defaultK8sSyncTimeout = 10 * time.Second
+)
+
+var (
+	errNoSvc             = errors.New("no service specified to resolve the backends")
+	errK8sCacheNotSynced = errors.New("timed out waiting for the endpoints of the service to be listed")
+)
+
+type k8sResolver struct {
+	logger *zap.Logger
+
+	svcName     string
+	svcNs       string
+	port        string
+	informer    cache.SharedInformer
+	syncTimeout time.Duration
+
+	endpoints         []string
+	onChangeCallbacks []func([]string)
+
+	stopCh             chan (struct{})
+	stopOnce           sync.Once
+	updateLock         sync.Mutex
+	shutdownWg         sync.WaitGroup
+	changeCallbackLock sync.RWMutex
+}
+
+func newK8sResolver(logger *zap.Logger, client k8s.Interface, service string, port string, useEndpointSlices bool) (*k8sResolver, error) {
+	if len(service) == 0 {
+		return nil, errNoSvc
+	}
+
+	nAndNS := strings.SplitN(service, ".", 2)
+	name, ns := nAndNS[0], defaultK8sNamespace
+	if len(nAndNS) > 1 && nAndNS[1] != "" {
+		ns = nAndNS[1]
+	}
+
+	if port == "" {
+		port = defaultPort
+	}
+
+	var lw cache.ListerWatcher
+	var objType runtime.Object
+	if useEndpointSlices {
+		selector := labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: name}).String()
+		lw = &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector
+				return client.DiscoveryV1().EndpointSlices(ns).List(context.Background(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector
+				return client.DiscoveryV1().EndpointSlices(ns).Watch(context.Background(), options)
+			},
+		}
+		objType = &discoveryv1.EndpointSlice{}
+	} else {
+		selector := fields.OneTermEqualSelector("metadata.name", name).String()
+		lw = &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = selector
+				return client.CoreV1().Endpoints(ns).List(context.Background(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = selector
+				return client.CoreV1().Endpoints(ns).Watch(context.Background(), options)
+			},
+		}
+		objType = &corev1.Endpoints{}
+	}
+
+	return &k8sResolver{
+		logger:      logger,
+		svcName:     name,
+		svcNs:       ns,
+		port:        port,
+		informer:    cache.NewSharedInformer(lw, objType, 0),
+		syncTimeout: defaultK8sSyncTimeout,
+		stopCh:      make(chan struct{}),
+	}, nil
+}
+
+func (r *k8sResolver) start(ctx context.Context) error {
+	r.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: r.onEvent,
+		UpdateFunc: func(_, newObj interface{}) {
+			r.onEvent(newObj)
+		},
+		DeleteFunc: r.onEvent,
+	})
+	go r.informer.Run(r.stopCh)
+
+	syncCtx, cancel := context.WithTimeout(ctx, r.syncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), r.informer.HasSynced) {
+		return errK8sCacheNotSynced
+	}
+
+	_, err := r.resolve(ctx)
+	return err
+}
+
+func (r *k8sResolver) shutdown(ctx context.Context) error {
+	r.changeCallbackLock.Lock()
+	r.onChangeCallbacks = nil
+	r.changeCallbackLock.Unlock()
+
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	r.shutdownWg.Wait()
+	return nil
+}
+
+// onEvent is called by the informer whenever the watched objects change, so that the new list of
+// backends is propagated right away.
+func (r *k8sResolver) onEvent(interface{}) {
+	if _, err := r.resolve(context.Background()); err != nil {
+		r.logger.Warn("failed to resolve", zap.Error(err))
+	}
+}
+
+func (r *k8sResolver) resolve(ctx context.Context) ([]string, error) {
+	r.shutdownWg.Add(1)
+	defer r.shutdownWg.Done()
+
+	// the context to use for all metrics in this function
+	mCtx, _ := tag.New(ctx, tag.Upsert(tag.MustNewKey("resolver"), "k8s"))
+
+	// the informer's store has the latest known state of the watched objects
+	seen := map[string]bool{}
+	var backends []string
+	for _, obj := range r.informer.GetStore().List() {
+		for _, addr := range r.readyAddresses(obj) {
+			backend := net.JoinHostPort(addr, r.port)
+			if !seen[backend] {
+				seen[backend] = true
+				backends = append(backends, backend)
+			}
+		}
+	}
+
+	successCtx, _ := tag.New(mCtx, tag.Upsert(tag.MustNewKey("success"), "true"))
+	stats.Record(successCtx, mNumResolutions.M(1))
+
+	// keep it always in the same order
+	sort.Strings(backends)
+
+	// resolutions are triggered by both the informer and the start function, so we hold the lock
+	// until the callbacks are done to make sure they see the changes in order
+	r.updateLock.Lock()
+	defer r.updateLock.Unlock()
+
+	if equalStringSlice(r.endpoints, backends) {
+		return backends, nil
+	}
+
+	// the list has changed!
+	r.endpoints = backends
+	stats.Record(mCtx, mNumBackends.M(int64(len(backends))))
+
+	// propagate the change
+	r.changeCallbackLock.RLock()
+	for _, callback := range r.onChangeCallbacks {
+		callback(backends)
+	}
+	r.changeCallbackLock.RUnlock()
+
+	return backends, nil
+}
+
+// readyAddresses returns the addresses from the given Endpoints or EndpointSlice that are ready to receive data
+func (r *k8sResolver) readyAddresses(obj interface{}) []string {
+	var addrs []string
+	switch o := obj.(type) {
+	case *corev1.Endpoints:
+		if o.Name != r.svcName {
+			return nil
+		}
+		for _, subset := range o.Subsets {
+			// not ready addresses are listed separately and are left out on purpose
+			for _, addr := range subset.Addresses {
+				addrs = append(addrs, addr.IP)
+			}
+		}
+	case *discoveryv1.EndpointSlice:
+		if o.Labels[discoveryv1.LabelServiceName] != r.svcName {
+			return nil
+		}
+		for _, endpoint := range o.Endpoints {
+			// a nil condition should be interpreted as ready
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			addrs = append(addrs, endpoint.Addresses...)
+		}
+	}
+	return addrs
+}
+
+func (r *k8sResolver) onChange(f func([]string)) {
+	r.changeCallbackLock.Lock()
+	defer r.changeCallbackLock.Unlock()
+	r.onChangeCallbacks = append(r.onChangeCallbacks, f)
+}
@@ -0,0 +1,234 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadbalancingexporter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestK8sInitialResolution(t *testing.T) {
+	// prepare
+	client := fake.NewSimpleClientset(
+		endpointsFor("lb", "observability", []string{"10.0.0.2", "10.0.0.1"}, []string{"10.0.0.3"}),
+		endpointsFor("other", "observability", []string{"10.0.1.1"}, nil),
+	)
+	res, err := newK8sResolver(zap.NewNop(), client, "lb.observability", "", false)
+	require.NoError(t, err)
+
+	var resolved []string
+	res.onChange(func(endpoints []string) {
+		resolved = endpoints
+	})
+
+	// test
+	err = res.start(context.Background())
+	defer res.shutdown(context.Background())
+
+	// verify
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:4317", "10.0.0.2:4317"}, resolved)
+}
+
+func TestK8sResolutionWithPort(t *testing.T) {
+	// prepare
+	client := fake.NewSimpleClientset(
+		endpointsFor("lb", "default", []string{"10.0.0.1", "fd00::1"}, nil),
+	)
+	res, err := newK8sResolver(zap.NewNop(), client, "lb", "55690", false)
+	require.NoError(t, err)
+
+	// test
+	err = res.start(context.Background())
+	defer res.shutdown(context.Background())
+
+	// verify
+	require.NoError(t, err)
+	resolved, err := res.resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:55690", "[fd00::1]:55690"}, resolved)
+}
+
+func TestK8sOnChange(t *testing.T) {
+	// prepare
+	ep := endpointsFor("lb", "observability", []string{"10.0.0.1"}, nil)
+	client := fake.NewSimpleClientset(ep)
+	res, err := newK8sResolver(zap.NewNop(), client, "lb.observability", "", false)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var resolved []string
+	counter := 0
+	res.onChange(func(endpoints []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		resolved = endpoints
+		counter++
+	})
+	current := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return resolved
+	}
+
+	require.NoError(t, res.start(context.Background()))
+	defer res.shutdown(context.Background())
+	require.Equal(t, []string{"10.0.0.1:4317"}, current())
+
+	// test: a new pod becomes ready, and the previous one goes away
+	updated := endpointsFor("lb", "observability", []string{"10.0.0.2", "10.0.0.3"}, []string{"10.0.0.1"})
+	_, err = client.CoreV1().Endpoints("observability").Update(context.Background(), updated, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	// verify
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"10.0.0.2:4317", "10.0.0.3:4317"}, current())
+	}, time.Second, 10*time.Millisecond)
+
+	// test: the service is removed
+	err = client.CoreV1().Endpoints("observability").Delete(context.Background(), "lb", metav1.DeleteOptions{})
+	require.NoError(t, err)
+
+	// verify
+	assert.Eventually(t, func() bool {
+		return len(current()) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, counter)
+}
+
+func TestK8sEndpointSlices(t *testing.T) {
+	// prepare
+	notReady := false
+	client := fake.NewSimpleClientset(
+		endpointSliceFor("lb-abc", "lb", "observability", discoveryv1.Endpoint{Addresses: []string{"10.0.0.1"}}),
+		endpointSliceFor("lb-def", "lb", "observability",
+			discoveryv1.Endpoint{Addresses: []string{"10.0.0.2"}},
+			discoveryv1.Endpoint{Addresses: []string{"10.0.0.3"}, Conditions: discoveryv1.EndpointConditions{Ready: &notReady}},
+		),
+		endpointSliceFor("other-abc", "other", "observability", discoveryv1.Endpoint{Addresses: []string{"10.0.1.1"}}),
+	)
+	res, err := newK8sResolver(zap.NewNop(), client, "lb.observability", "", true)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var resolved []string
+	res.onChange(func(endpoints []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		resolved = endpoints
+	})
+	current := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return resolved
+	}
+
+	// test
+	require.NoError(t, res.start(context.Background()))
+	defer res.shutdown(context.Background())
+
+	// verify
+	assert.Equal(t, []string{"10.0.0.1:4317", "10.0.0.2:4317"}, current())
+
+	// test: one of the slices is removed
+	err = client.DiscoveryV1().EndpointSlices("observability").Delete(context.Background(), "lb-abc", metav1.DeleteOptions{})
+	require.NoError(t, err)
+
+	// verify
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"10.0.0.2:4317"}, current())
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestK8sResolverNotSynced(t *testing.T) {
+	// prepare
+	res, err := newK8sResolver(zap.NewNop(), fake.NewSimpleClientset(), "lb", "", false)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// test
+	err = res.start(ctx)
+	defer res.shutdown(context.Background())
+
+	// verify
+	assert.Equal(t, errK8sCacheNotSynced, err)
+}
+
+func TestK8sErrNoSvc(t *testing.T) {
+	// test
+	res, err := newK8sResolver(zap.NewNop(), fake.NewSimpleClientset(), "", "", false)
+
+	// verify
+	assert.Nil(t, res)
+	assert.Equal(t, errNoSvc, err)
+}
+
+func TestK8sShutdownIsIdempotent(t *testing.T) {
+	// prepare
+	res, err := newK8sResolver(zap.NewNop(), fake.NewSimpleClientset(), "lb", "", false)
+	require.NoError(t, err)
+	require.NoError(t, res.start(context.Background()))
+
+	// test and verify
+	assert.NoError(t, res.shutdown(context.Background()))
+	assert.NoError(t, res.shutdown(context.Background()))
+}
+
+func endpointsFor(name, ns string, ready []string, notReady []string) *corev1.Endpoints {
+	subset := corev1.EndpointSubset{}
+	for _, ip := range ready {
+		subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{IP: ip})
+	}
+	for _, ip := range notReady {
+		subset.NotReadyAddresses = append(subset.NotReadyAddresses, corev1.EndpointAddress{IP: ip})
+	}
+
+	return &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Subsets: []corev1.EndpointSubset{subset},
+	}
+}
+
+func endpointSliceFor(name, svc, ns string, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Labels: map[string]string{
+				discoveryv1.LabelServiceName: svc,
+			},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints:   endpoints,
+	}
+}
@@ -19,16 +19,14 @@ import (
 	"errors"
 	"fmt"
 	"sync"
-	"time"
 
-	"go.opencensus.io/stats"
-	"go.opencensus.io/tag"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/exporter/otlpexporter"
 	"go.opentelemetry.io/collector/model/pdata"
+	conventions "go.opentelemetry.io/collector/translator/conventions/v1.5.0"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/batchpersignal"
 )
@@ -41,6 +39,7 @@ var (
 
 type traceExporterImp struct {
 	loadBalancer loadBalancer
+	routingKey   string
 
 	stopped    bool
 	shutdownWg sync.WaitGroup
@@ -48,6 +47,16 @@ type traceExporterImp struct {
 
 // Create new traces exporter
 func newTracesExporter(params component.ExporterCreateSettings, cfg config.Exporter) (*traceExporterImp, error) {
+	routingKey := traceIDRouting
+	switch key := cfg.(*Config).RoutingKey; key {
+	case svcRouting:
+		routingKey = svcRouting
+	case "", traceIDRouting, metricNameRouting, resourceAttributeRouting, seriesRouting:
+		// the metrics-only routing keys might be set when the exporter is shared with a metrics pipeline
+	default:
+		return nil, fmt.Errorf("unsupported routing_key for traces: %q", key)
+	}
+
 	exporterFactory := otlpexporter.NewFactory()
 
 	lb, err := newLoadBalancer(params, cfg, func(ctx context.Context, endpoint string) (component.Exporter, error) {
@@ -60,6 +69,7 @@ func newTracesExporter(params component.ExporterCreateSettings, cfg config.Expor
 
 	return &traceExporterImp{
 		loadBalancer: lb,
+		routingKey:   routingKey,
 	}, nil
 }
 
@@ -67,6 +77,12 @@ func buildExporterConfig(cfg *Config, endpoint string) otlpexporter.Config {
 	oCfg := cfg.Protocol.OTLP
 	oCfg.ExporterSettings = config.NewExporterSettings(config.NewID("otlp"))
 	oCfg.Endpoint = endpoint
+	if cfg.Failover {
+		// the failures have to be reported synchronously to be sent to the next backend, instead of
+		// being queued and retried by the exporter of the failing backend
+		oCfg.QueueSettings.Enabled = false
+		oCfg.RetrySettings.Enabled = false
+	}
 	return oCfg
 }
 
@@ -78,15 +94,20 @@ func (e *traceExporterImp) Start(ctx context.Context, host component.Host) error
 	return e.loadBalancer.Start(ctx, host)
 }
 
-func (e *traceExporterImp) Shutdown(context.Context) error {
+func (e *traceExporterImp) Shutdown(ctx context.Context) error {
 	e.stopped = true
 	e.shutdownWg.Wait()
-	return nil
+	return e.loadBalancer.Shutdown(ctx)
 }
 
 func (e *traceExporterImp) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
 	var errors []error
-	batches := batchpersignal.SplitTraces(td)
+	var batches []pdata.Traces
+	if e.routingKey == svcRouting {
+		batches = splitTracesByService(td)
+	} else {
+		batches = batchpersignal.SplitTraces(td)
+	}
 	for _, batch := range batches {
 		if err := e.consumeTrace(ctx, batch); err != nil {
 			errors = append(errors, err)
@@ -97,37 +118,28 @@ func (e *traceExporterImp) ConsumeTraces(ctx context.Context, td pdata.Traces) e
 }
 
 func (e *traceExporterImp) consumeTrace(ctx context.Context, td pdata.Traces) error {
-	traceID := traceIDFromTraces(td)
-	if traceID == pdata.InvalidTraceID() {
-		return errNoTracesInBatch
-	}
-
-	endpoint := e.loadBalancer.Endpoint(traceID)
-	exp, err := e.loadBalancer.Exporter(endpoint)
-	if err != nil {
-		return err
-	}
-
-	te, ok := exp.(component.TracesExporter)
-	if !ok {
-		expectType := (*component.TracesExporter)(nil)
-		return fmt.Errorf("expected %T but got %T", expectType, exp)
-	}
-
-	start := time.Now()
-	err = te.ConsumeTraces(ctx, td)
-	duration := time.Since(start)
-	ctx, _ = tag.New(ctx, tag.Upsert(tag.MustNewKey("endpoint"), endpoint))
-
-	if err == nil {
-		sCtx, _ := tag.New(ctx, tag.Upsert(tag.MustNewKey("success"), "true"))
-		stats.Record(sCtx, mBackendLatency.M(duration.Milliseconds()))
+	var routingID []byte
+	if e.routingKey == svcRouting {
+		routingID = []byte(serviceNameFromTraces(td))
 	} else {
-		fCtx, _ := tag.New(ctx, tag.Upsert(tag.MustNewKey("success"), "false"))
-		stats.Record(fCtx, mBackendLatency.M(duration.Milliseconds()))
+		traceID := traceIDFromTraces(td)
+		if traceID == pdata.InvalidTraceID() {
+			return errNoTracesInBatch
+		}
+		b := traceID.Bytes()
+		routingID = b[:]
 	}
 
-	return err
+	route := e.loadBalancer.Route(routingID)
+	return e.loadBalancer.Consume(ctx, route, func(ctx context.Context, exp component.Exporter) error {
+		te, ok := exp.(component.TracesExporter)
+		if !ok {
+			expectType := (*component.TracesExporter)(nil)
+			return fmt.Errorf("expected %T but got %T", expectType, exp)
+		}
+
+		return te.ConsumeTraces(ctx, td)
+	})
 }
 
 func traceIDFromTraces(td pdata.Traces) pdata.TraceID {
@@ -148,3 +160,44 @@ func traceIDFromTraces(td pdata.Traces) pdata.TraceID {
 
 	return spans.At(0).TraceID()
 }
+
+// splitTracesByService returns one pdata.Traces for each service in the given pdata.Traces input, holding
+// all the resource spans for that service. Resources without a service name are grouped together.
+func splitTracesByService(td pdata.Traces) []pdata.Traces {
+	var result []pdata.Traces
+	batches := map[string]pdata.Traces{}
+
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		svc := serviceNameFromResource(rs.Resource())
+
+		batch, found := batches[svc]
+		if !found {
+			batch = pdata.NewTraces()
+			batches[svc] = batch
+			result = append(result, batch)
+		}
+		rs.CopyTo(batch.ResourceSpans().AppendEmpty())
+	}
+
+	return result
+}
+
+func serviceNameFromTraces(td pdata.Traces) string {
+	rs := td.ResourceSpans()
+	if rs.Len() == 0 {
+		return ""
+	}
+
+	return serviceNameFromResource(rs.At(0).Resource())
+}
+
+func serviceNameFromResource(resource pdata.Resource) string {
+	svc, found := resource.Attributes().Get(conventions.AttributeServiceName)
+	if !found {
+		return ""
+	}
+
+	return svc.StringVal()
+}
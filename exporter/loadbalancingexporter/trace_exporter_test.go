@@ -36,6 +36,8 @@ import (
 	"go.opentelemetry.io/collector/consumer/consumertest"
 	"go.opentelemetry.io/collector/exporter/otlpexporter"
 	"go.opentelemetry.io/collector/model/pdata"
+	"go.opentelemetry.io/collector/receiver/otlpreceiver"
+	"go.opentelemetry.io/collector/testutil"
 	"go.uber.org/zap"
 )
 
@@ -55,6 +57,33 @@ func TestNewTracesExporter(t *testing.T) {
 			&Config{},
 			errNoResolver,
 		},
+		{
+			"service routing",
+			func() *Config {
+				cfg := simpleConfig()
+				cfg.RoutingKey = svcRouting
+				return cfg
+			}(),
+			nil,
+		},
+		{
+			"metrics-only routing key",
+			func() *Config {
+				cfg := simpleConfig()
+				cfg.RoutingKey = seriesRouting
+				return cfg
+			}(),
+			nil,
+		},
+		{
+			"unsupported routing key",
+			func() *Config {
+				cfg := simpleConfig()
+				cfg.RoutingKey = "span"
+				return cfg
+			}(),
+			fmt.Errorf("unsupported routing_key for traces: %q", "span"),
+		},
 	} {
 		t.Run(tt.desc, func(t *testing.T) {
 			// test
@@ -123,6 +152,50 @@ func TestTracesExporterShutdown(t *testing.T) {
 	assert.Nil(t, res)
 }
 
+func TestTracesExporterShutdownStopsBackends(t *testing.T) {
+	var shutdown int64
+	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		return &mockTracesExporter{
+			Component: componenthelper.New(componenthelper.WithShutdown(func(context.Context) error {
+				atomic.AddInt64(&shutdown, 1)
+				return nil
+			})),
+		}, nil
+	}
+	lb, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), simpleConfig(), componentFactory)
+	require.NotNil(t, lb)
+	require.NoError(t, err)
+
+	p, err := newTracesExporter(componenttest.NewNopExporterCreateSettings(), simpleConfig())
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	resolverShutdown := false
+	lb.res = &mockResolver{
+		triggerCallbacks: true,
+		onResolve: func(ctx context.Context) ([]string, error) {
+			return []string{"endpoint-1:4317", "endpoint-2:4317"}, nil
+		},
+		onShutdown: func(context.Context) error {
+			resolverShutdown = true
+			return nil
+		},
+	}
+	p.loadBalancer = lb
+
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	require.Len(t, lb.exporters, 2)
+
+	// test
+	res := p.Shutdown(context.Background())
+
+	// verify
+	assert.Nil(t, res)
+	assert.True(t, resolverShutdown)
+	assert.EqualValues(t, 2, atomic.LoadInt64(&shutdown))
+	assert.Empty(t, lb.exporters)
+}
+
 func TestConsumeTraces(t *testing.T) {
 	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
 		return newNopMockTracesExporter(), nil
@@ -251,6 +324,71 @@ func TestBuildExporterConfig(t *testing.T) {
 	assert.Equal(t, defaultCfg.RetrySettings, exporterCfg.RetrySettings)
 }
 
+func TestBuildExporterConfigWithFailover(t *testing.T) {
+	// prepare
+	cfg := createDefaultConfig().(*Config)
+	cfg.Failover = true
+	defaultCfg := otlpexporter.NewFactory().CreateDefaultConfig().(*otlpexporter.Config)
+	require.True(t, defaultCfg.QueueSettings.Enabled)
+	require.True(t, defaultCfg.RetrySettings.Enabled)
+
+	// test
+	exporterCfg := buildExporterConfig(cfg, "the-endpoint")
+
+	// verify
+	assert.False(t, exporterCfg.QueueSettings.Enabled)
+	assert.False(t, exporterCfg.RetrySettings.Enabled)
+	assert.Equal(t, defaultCfg.TimeoutSettings, exporterCfg.TimeoutSettings)
+
+	// the configuration of the load balancer isn't changed
+	assert.True(t, cfg.Protocol.OTLP.QueueSettings.Enabled)
+	assert.True(t, cfg.Protocol.OTLP.RetrySettings.Enabled)
+}
+
+func TestConsumeTracesWithFailoverAndDefaultOTLP(t *testing.T) {
+	// prepare
+	sink := new(consumertest.TracesSink)
+	receiverFactory := otlpreceiver.NewFactory()
+	receiverCfg := receiverFactory.CreateDefaultConfig().(*otlpreceiver.Config)
+	receiverCfg.GRPC.NetAddr.Endpoint = testutil.GetAvailableLocalAddress(t)
+	receiverCfg.HTTP = nil
+	receiver, err := receiverFactory.CreateTracesReceiver(context.Background(), componenttest.NewNopReceiverCreateSettings(), receiverCfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, receiver.Start(context.Background(), componenttest.NewNopHost()))
+	defer receiver.Shutdown(context.Background())
+
+	// nothing listens on the unavailable backend
+	unavailable := testutil.GetAvailableLocalAddress(t)
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Protocol.OTLP.TLSSetting.Insecure = true
+	cfg.Resolver.Static = &StaticResolver{Hostnames: []string{unavailable, receiverCfg.GRPC.NetAddr.Endpoint}}
+	cfg.Failover = true
+
+	p, err := newTracesExporter(componenttest.NewNopExporterCreateSettings(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	defer p.Shutdown(context.Background())
+
+	// the unavailable backend is responsible for the trace
+	td := simpleTraces()
+	span := td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0)
+	for i := byte(1); ; i++ {
+		b := [16]byte{i}
+		if p.loadBalancer.Endpoint(b[:]) == unavailable {
+			span.SetTraceID(pdata.NewTraceID(b))
+			break
+		}
+	}
+
+	// test
+	err = p.ConsumeTraces(context.Background(), td)
+
+	// verify
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return sink.SpanCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
 func TestBatchWithTwoTraces(t *testing.T) {
 	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
 		return newNopMockTracesExporter(), nil
@@ -284,6 +422,141 @@ func TestBatchWithTwoTraces(t *testing.T) {
 	assert.Len(t, sink.AllTraces(), 2)
 }
 
+func TestConsumeTracesByService(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	cfg.RoutingKey = svcRouting
+
+	sinks := map[string]*consumertest.TracesSink{
+		"endpoint-1:4317": new(consumertest.TracesSink),
+		"endpoint-2:4317": new(consumertest.TracesSink),
+	}
+	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		return newMockTracesExporter(sinks[endpoint].ConsumeTraces), nil
+	}
+	lb, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, componentFactory)
+	require.NotNil(t, lb)
+	require.NoError(t, err)
+
+	p, err := newTracesExporter(componenttest.NewNopExporterCreateSettings(), cfg)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	lb.res = &mockResolver{
+		triggerCallbacks: true,
+		onResolve: func(ctx context.Context) ([]string, error) {
+			return []string{"endpoint-1:4317", "endpoint-2:4317"}, nil
+		},
+	}
+	p.loadBalancer = lb
+
+	err = p.Start(context.Background(), componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer p.Shutdown(context.Background())
+
+	batch := pdata.NewTraces()
+	for i := 0; i < 30; i++ {
+		td := randomTraces()
+		td.ResourceSpans().At(0).Resource().Attributes().UpsertString("service.name", fmt.Sprintf("service-%d", i%10))
+		td.ResourceSpans().MoveAndAppendTo(batch.ResourceSpans())
+	}
+
+	// test
+	err = p.ConsumeTraces(context.Background(), batch)
+
+	// verify
+	require.NoError(t, err)
+	seen := map[string]string{}
+	total := 0
+	for endpoint, sink := range sinks {
+		assert.NotEmpty(t, sink.AllTraces(), "the services should have been spread among both endpoints")
+		total += sink.SpanCount()
+		for _, td := range sink.AllTraces() {
+			// each batch holds all the resource spans of a single service
+			assert.Equal(t, 3, td.ResourceSpans().Len())
+			svc := serviceNameFromTraces(td)
+			for i := 0; i < td.ResourceSpans().Len(); i++ {
+				assert.Equal(t, svc, serviceNameFromResource(td.ResourceSpans().At(i).Resource()))
+			}
+			assert.NotContains(t, seen, svc)
+			seen[svc] = endpoint
+		}
+	}
+	assert.Equal(t, 30, total)
+	assert.Len(t, seen, 10)
+}
+
+func TestConsumeTracesWithFailover(t *testing.T) {
+	// prepare
+	cfg := simpleConfig()
+	cfg.Failover = true
+
+	sink := new(consumertest.TracesSink)
+	componentFactory := func(ctx context.Context, endpoint string) (component.Exporter, error) {
+		return newMockTracesExporter(sink.ConsumeTraces), nil
+	}
+	lb, err := newLoadBalancer(componenttest.NewNopExporterCreateSettings(), cfg, componentFactory)
+	require.NotNil(t, lb)
+	require.NoError(t, err)
+
+	p, err := newTracesExporter(componenttest.NewNopExporterCreateSettings(), cfg)
+	require.NotNil(t, p)
+	require.NoError(t, err)
+
+	lb.res = &mockResolver{
+		triggerCallbacks: true,
+		onResolve: func(ctx context.Context) ([]string, error) {
+			return []string{"endpoint-1:4317", "endpoint-2:4317"}, nil
+		},
+	}
+	p.loadBalancer = lb
+
+	err = p.Start(context.Background(), componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer p.Shutdown(context.Background())
+
+	// the backend responsible for the trace is failing
+	td := simpleTraces()
+	b := td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).TraceID().Bytes()
+	endpoint := lb.Endpoint(b[:])
+	lb.updateLock.Lock()
+	lb.exporters[endpoint] = newMockTracesExporter(func(context.Context, pdata.Traces) error {
+		return errors.New("some expected error")
+	})
+	lb.updateLock.Unlock()
+
+	// test
+	err = p.ConsumeTraces(context.Background(), td)
+
+	// verify
+	assert.NoError(t, err)
+	assert.Len(t, sink.AllTraces(), 1)
+}
+
+func TestSplitTracesByService(t *testing.T) {
+	// prepare
+	batch := pdata.NewTraces()
+	for _, svc := range []string{"service-1", "service-2", "service-1", ""} {
+		td := randomTraces()
+		if svc != "" {
+			td.ResourceSpans().At(0).Resource().Attributes().UpsertString("service.name", svc)
+		}
+		td.ResourceSpans().MoveAndAppendTo(batch.ResourceSpans())
+	}
+
+	// test
+	res := splitTracesByService(batch)
+
+	// verify
+	require.Len(t, res, 3)
+	assert.Equal(t, "service-1", serviceNameFromTraces(res[0]))
+	assert.Equal(t, 2, res[0].ResourceSpans().Len())
+	assert.Equal(t, "service-2", serviceNameFromTraces(res[1]))
+	assert.Equal(t, 1, res[1].ResourceSpans().Len())
+	assert.Equal(t, "", serviceNameFromTraces(res[2]))
+	assert.Equal(t, 1, res[2].ResourceSpans().Len())
+}
+
 func TestNoTracesInBatch(t *testing.T) {
 	for _, tt := range []struct {
 		desc  string
@@ -15,39 +15,165 @@
 package filestorage
 
 import (
+	"bytes"
 	"context"
+	"crypto/cipher"
 	"errors"
+	"fmt"
+	"os"
+	"sync"
 	"time"
 
 	"go.etcd.io/bbolt"
 	"go.opentelemetry.io/collector/extension/storage"
+	"go.uber.org/zap"
 )
 
+// defaultBucket is the bucket used before clients had their own namespace,
+// its data is moved to the bucket of the client when it is first opened.
 var defaultBucket = []byte(`default`)
 
+// metadataBucket holds the settings the file was written with, which must not
+// change between runs.
+var metadataBucket = []byte(`metadata`)
+
+// encryptionMarker is the metadata key telling whether the values are encrypted.
+// It holds a value sealed with the key when they are, and noEncryption otherwise.
+var (
+	encryptionMarker = []byte(`encryption`)
+	noEncryption     = []byte(`none`)
+)
+
+var (
+	errWrittenWithoutEncryption = errors.New("the storage file was written without encryption, it must be removed to enable encryption")
+	errWrittenWithEncryption    = errors.New("the storage file was written with encryption, it must be removed to disable encryption")
+	errWrittenWithAnotherKey    = errors.New("the storage file was written with another encryption key")
+)
+
+type clientOptions struct {
+	timeout    time.Duration
+	fsync      bool
+	compaction CompactionConfig
+	aead       cipher.AEAD
+}
+
 type fileStorageClient struct {
-	db *bbolt.DB
+	logger  *zap.Logger
+	path    string
+	bucket  []byte
+	options clientOptions
+
+	// mu guards db, which is replaced when the file is compacted. When the file
+	// can't be reopened after a compaction, db is nil and failure holds the reason.
+	mu      sync.RWMutex
+	db      *bbolt.DB
+	failure error
+
+	stopCompaction chan struct{}
+	compactionWg   sync.WaitGroup
 }
 
-func newClient(filePath string, timeout time.Duration) (*fileStorageClient, error) {
-	options := &bbolt.Options{
-		Timeout: timeout,
-		NoSync:  true,
+func newClient(logger *zap.Logger, filePath string, bucket []byte, options clientOptions) (*fileStorageClient, error) {
+	if len(bucket) == 0 {
+		return nil, errors.New("bucket name must not be empty")
 	}
-	db, err := bbolt.Open(filePath, 0600, options)
+
+	recoverCompaction(logger, filePath)
+
+	db, err := bbolt.Open(filePath, 0600, boltOptions(options))
 	if err != nil {
 		return nil, err
 	}
 
 	initBucket := func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(defaultBucket)
-		return err
+		if tx.Bucket(bucket) == nil && !bytes.Equal(bucket, defaultBucket) {
+			if legacy := tx.Bucket(defaultBucket); legacy != nil {
+				if err := migrateBucket(tx, legacy, bucket); err != nil {
+					return err
+				}
+			}
+		}
+		b, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+		return checkEncryption(tx, b, options.aead)
 	}
 	if err := db.Update(initBucket); err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	return &fileStorageClient{db}, nil
+	client := &fileStorageClient{
+		logger:  logger,
+		path:    filePath,
+		bucket:  bucket,
+		options: options,
+		db:      db,
+	}
+	if options.compaction.Enabled {
+		client.startCompaction()
+	}
+	return client, nil
+}
+
+func boltOptions(options clientOptions) *bbolt.Options {
+	return &bbolt.Options{
+		Timeout: options.timeout,
+		NoSync:  !options.fsync,
+	}
+}
+
+// migrateBucket copies the data of the legacy bucket to a new bucket and deletes the legacy one.
+func migrateBucket(tx *bbolt.Tx, legacy *bbolt.Bucket, name []byte) error {
+	bucket, err := tx.CreateBucket(name)
+	if err != nil {
+		return err
+	}
+	err = legacy.ForEach(func(k, v []byte) error {
+		return bucket.Put(k, v)
+	})
+	if err != nil {
+		return err
+	}
+	return tx.DeleteBucket(defaultBucket)
+}
+
+// checkEncryption makes sure the values of the file are encrypted with the given
+// key, or not encrypted when it's nil, recording it for new files.
+func checkEncryption(tx *bbolt.Tx, bucket *bbolt.Bucket, aead cipher.AEAD) error {
+	metadata, err := tx.CreateBucketIfNotExists(metadataBucket)
+	if err != nil {
+		return err
+	}
+
+	marker := metadata.Get(encryptionMarker)
+	if marker == nil {
+		// Files written by previous versions have no marker and unencrypted values
+		if aead == nil {
+			return metadata.Put(encryptionMarker, noEncryption)
+		}
+		if key, _ := bucket.Cursor().First(); key != nil {
+			return errWrittenWithoutEncryption
+		}
+		if marker, err = encrypt(aead, string(encryptionMarker), encryptionMarker); err != nil {
+			return err
+		}
+		return metadata.Put(encryptionMarker, marker)
+	}
+
+	encrypted := string(marker) != string(noEncryption)
+	switch {
+	case !encrypted && aead != nil:
+		return errWrittenWithoutEncryption
+	case encrypted && aead == nil:
+		return errWrittenWithEncryption
+	case encrypted:
+		if _, err := decrypt(aead, string(encryptionMarker), marker); err != nil {
+			return errWrittenWithAnotherKey
+		}
+	}
+	return nil
 }
 
 // Get will retrieve data from storage that corresponds to the specified key
@@ -73,19 +199,42 @@ func (c *fileStorageClient) Delete(ctx context.Context, key string) error {
 
 // Batch executes the specified operations in order. Get operation results are updated in place
 func (c *fileStorageClient) Batch(_ context.Context, ops ...storage.Operation) error {
+	// Values are encrypted before opening the transaction to keep it short
+	var sealed [][]byte
+	if c.options.aead != nil {
+		sealed = make([][]byte, len(ops))
+		for i, op := range ops {
+			if op.Type != storage.Set {
+				continue
+			}
+			value, err := encrypt(c.options.aead, op.Key, op.Value)
+			if err != nil {
+				return err
+			}
+			sealed[i] = value
+		}
+	}
+
 	batch := func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(defaultBucket)
+		bucket := tx.Bucket(c.bucket)
 		if bucket == nil {
 			return errors.New("storage not initialized")
 		}
 
 		var err error
-		for _, op := range ops {
+		for i, op := range ops {
 			switch op.Type {
 			case storage.Get:
 				op.Value = bucket.Get([]byte(op.Key))
+				if c.options.aead != nil {
+					op.Value, err = decrypt(c.options.aead, op.Key, op.Value)
+				}
 			case storage.Set:
-				err = bucket.Put([]byte(op.Key), op.Value)
+				value := op.Value
+				if sealed != nil {
+					value = sealed[i]
+				}
+				err = bucket.Put([]byte(op.Key), value)
 			case storage.Delete:
 				err = bucket.Delete([]byte(op.Key))
 			default:
@@ -100,10 +249,194 @@ func (c *fileStorageClient) Batch(_ context.Context, ops ...storage.Operation) e
 		return nil
 	}
 
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.db == nil {
+		return c.failure
+	}
 	return c.db.Update(batch)
 }
 
 // Close will close the database
 func (c *fileStorageClient) Close(_ context.Context) error {
+	if c.stopCompaction != nil {
+		close(c.stopCompaction)
+		c.compactionWg.Wait()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.db == nil {
+		return nil
+	}
 	return c.db.Close()
 }
+
+func (c *fileStorageClient) startCompaction() {
+	c.stopCompaction = make(chan struct{})
+	c.compactionWg.Add(1)
+	go func() {
+		defer c.compactionWg.Done()
+
+		ticker := time.NewTicker(c.options.compaction.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.compactIfNeeded(); err != nil {
+					c.logger.Warn("Failed to compact the storage file", zap.String("path", c.path), zap.Error(err))
+				}
+			case <-c.stopCompaction:
+				return
+			}
+		}
+	}()
+}
+
+func (c *fileStorageClient) compactIfNeeded() error {
+	needed, err := c.needsCompaction()
+	if err != nil || !needed {
+		return err
+	}
+	return c.compact()
+}
+
+// needsCompaction reports whether the file is above the size threshold and the
+// share of its free pages is above the ratio threshold.
+func (c *fileStorageClient) needsCompaction() (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.db == nil {
+		return false, nil
+	}
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return false, err
+	}
+	size := info.Size()
+	if size == 0 || size < c.options.compaction.SizeThresholdMiB*1024*1024 {
+		return false, nil
+	}
+
+	// The page size is read within a transaction, which prevents writes from
+	// remapping the file meanwhile
+	var pageSize int
+	if err = c.db.View(func(*bbolt.Tx) error {
+		pageSize = c.db.Info().PageSize
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	stats := c.db.Stats()
+	free := int64(stats.FreePageN+stats.PendingPageN) * int64(pageSize)
+	return float64(free) >= c.options.compaction.FreeRatioThreshold*float64(size), nil
+}
+
+// compact copies the data to a new file next to the current one, then replaces
+// the current file with it. Operations are blocked while compacting. The current
+// file is kept as a backup until the compacted one is opened, and restored if
+// anything fails.
+func (c *fileStorageClient) compact() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.path
+	compactedPath := path + ".compact"
+	backupPath := path + ".backup"
+	if err := os.Remove(compactedPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// The compacted file is always synced, as it replaces the current one
+	compactedDB, err := bbolt.Open(compactedPath, 0600, &bbolt.Options{Timeout: c.options.timeout})
+	if err != nil {
+		return err
+	}
+	if err = bbolt.Compact(compactedDB, c.db, c.options.compaction.MaxTransactionSize); err != nil {
+		compactedDB.Close()
+		os.Remove(compactedPath)
+		return err
+	}
+	if err = compactedDB.Close(); err != nil {
+		os.Remove(compactedPath)
+		return err
+	}
+
+	originalSize := fileSize(path)
+	if err = c.db.Close(); err != nil {
+		os.Remove(compactedPath)
+		return err
+	}
+	c.db = nil
+
+	if err = os.Rename(path, backupPath); err != nil {
+		os.Remove(compactedPath)
+		return c.reopen(err)
+	}
+	if err = os.Rename(compactedPath, path); err != nil {
+		return c.restore(backupPath, err)
+	}
+	db, err := bbolt.Open(path, 0600, boltOptions(c.options))
+	if err != nil {
+		return c.restore(backupPath, err)
+	}
+	c.db = db
+	os.Remove(backupPath)
+
+	c.logger.Debug("Compacted the storage file",
+		zap.String("path", path),
+		zap.Int64("original_size", originalSize),
+		zap.Int64("compacted_size", fileSize(path)))
+	return nil
+}
+
+// restore puts back the file saved before compacting and reopens it, returning
+// the error that made the compaction fail.
+func (c *fileStorageClient) restore(backupPath string, cause error) error {
+	if err := os.Rename(backupPath, c.path); err != nil {
+		c.logger.Error("Failed to restore the storage file after a failed compaction",
+			zap.String("path", c.path), zap.String("backup_path", backupPath), zap.Error(err))
+	}
+	os.Remove(c.path + ".compact")
+	return c.reopen(cause)
+}
+
+// reopen opens the current file again after a failed compaction, returning the
+// error that made it fail. When the file can't be opened, the client is marked
+// as failed and all the following operations return an error.
+func (c *fileStorageClient) reopen(cause error) error {
+	db, err := bbolt.Open(c.path, 0600, boltOptions(c.options))
+	if err != nil {
+		c.failure = fmt.Errorf("failed to reopen the storage file after compacting it: %w", err)
+		c.logger.Error("The storage client is no longer usable", zap.String("path", c.path), zap.Error(c.failure))
+		return c.failure
+	}
+	c.db = db
+	return cause
+}
+
+// recoverCompaction puts back the file saved by an interrupted compaction, when
+// the file itself is missing, and removes the leftovers of the compaction.
+func recoverCompaction(logger *zap.Logger, path string) {
+	backupPath := path + ".backup"
+	if _, err := os.Stat(backupPath); err != nil {
+		return
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err = os.Rename(backupPath, path); err != nil {
+			logger.Warn("Failed to restore the storage file saved before compacting it", zap.String("path", path), zap.Error(err))
+			return
+		}
+	}
+	os.Remove(backupPath)
+	os.Remove(path + ".compact")
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -15,7 +15,10 @@
 package filestorage
 
 import (
+	"bytes"
 	"context"
+	"crypto/cipher"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -26,13 +29,16 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.etcd.io/bbolt"
 	"go.opentelemetry.io/collector/extension/storage"
+	"go.uber.org/zap"
 )
 
+var testBucket = []byte(`test_bucket`)
+
 func TestClientOperations(t *testing.T) {
 	tempDir := newTempDir(t)
 	dbFile := filepath.Join(tempDir, "my_db")
 
-	client, err := newClient(dbFile, time.Second)
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -67,7 +73,7 @@ func TestClientBatchOperations(t *testing.T) {
 	tempDir := newTempDir(t)
 	dbFile := filepath.Join(tempDir, "my_db")
 
-	client, err := newClient(dbFile, time.Second)
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -146,7 +152,7 @@ func TestNewClientTransactionErrors(t *testing.T) {
 		{
 			name: "get",
 			setup: func(tx *bbolt.Tx) error {
-				return tx.DeleteBucket(defaultBucket)
+				return tx.DeleteBucket(testBucket)
 			},
 			validate: func(t *testing.T, c *fileStorageClient) {
 				value, err := c.Get(context.Background(), testKey)
@@ -158,7 +164,7 @@ func TestNewClientTransactionErrors(t *testing.T) {
 		{
 			name: "set",
 			setup: func(tx *bbolt.Tx) error {
-				return tx.DeleteBucket(defaultBucket)
+				return tx.DeleteBucket(testBucket)
 			},
 			validate: func(t *testing.T, c *fileStorageClient) {
 				err := c.Set(context.Background(), testKey, testValue)
@@ -169,7 +175,7 @@ func TestNewClientTransactionErrors(t *testing.T) {
 		{
 			name: "delete",
 			setup: func(tx *bbolt.Tx) error {
-				return tx.DeleteBucket(defaultBucket)
+				return tx.DeleteBucket(testBucket)
 			},
 			validate: func(t *testing.T, c *fileStorageClient) {
 				err := c.Delete(context.Background(), testKey)
@@ -185,7 +191,7 @@ func TestNewClientTransactionErrors(t *testing.T) {
 			tempDir := newTempDir(t)
 			dbFile := filepath.Join(tempDir, "my_db")
 
-			client, err := newClient(dbFile, timeout)
+			client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: timeout})
 			require.NoError(t, err)
 
 			// Create a problem
@@ -198,24 +204,351 @@ func TestNewClientTransactionErrors(t *testing.T) {
 }
 
 func TestNewClientErrorsOnInvalidBucket(t *testing.T) {
-	temp := defaultBucket
-	defaultBucket = nil
-
 	tempDir := newTempDir(t)
 	dbFile := filepath.Join(tempDir, "my_db")
 
-	client, err := newClient(dbFile, time.Second)
+	client, err := newClient(zap.NewNop(), dbFile, nil, clientOptions{timeout: time.Second})
 	require.Error(t, err)
 	require.Nil(t, client)
+}
+
+func TestNewClientMigratesDefaultBucket(t *testing.T) {
+	tempDir := newTempDir(t)
+	dbFile := filepath.Join(tempDir, "my_db")
+	ctx := context.Background()
+
+	legacy, err := newClient(zap.NewNop(), dbFile, defaultBucket, clientOptions{timeout: time.Second})
+	require.NoError(t, err)
+	require.NoError(t, legacy.Set(ctx, "testKey", []byte("testValue")))
+	require.NoError(t, legacy.Close(ctx))
+
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
+	require.NoError(t, err)
+	defer client.Close(ctx)
+
+	value, err := client.Get(ctx, "testKey")
+	require.NoError(t, err)
+	require.Equal(t, []byte("testValue"), value)
+
+	require.NoError(t, client.db.View(func(tx *bbolt.Tx) error {
+		require.Nil(t, tx.Bucket(defaultBucket))
+		return nil
+	}))
+}
+
+func TestNewClientFSync(t *testing.T) {
+	tempDir := newTempDir(t)
+	ctx := context.Background()
+
+	client, err := newClient(zap.NewNop(), filepath.Join(tempDir, "no_sync"), testBucket, clientOptions{timeout: time.Second})
+	require.NoError(t, err)
+	require.True(t, client.db.NoSync)
+	require.NoError(t, client.Close(ctx))
+
+	client, err = newClient(zap.NewNop(), filepath.Join(tempDir, "sync"), testBucket, clientOptions{timeout: time.Second, fsync: true})
+	require.NoError(t, err)
+	require.False(t, client.db.NoSync)
+	require.NoError(t, client.Close(ctx))
+}
+
+func TestClientEncryption(t *testing.T) {
+	tempDir := newTempDir(t)
+	dbFile := filepath.Join(tempDir, "my_db")
+	ctx := context.Background()
+
+	aead := newTestCipher(t, bytes.Repeat([]byte{1}, 32))
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second, aead: aead})
+	require.NoError(t, err)
+
+	testKey := "testKey"
+	testValue := []byte("testValue")
+	require.NoError(t, client.Set(ctx, testKey, testValue))
+
+	value, err := client.Get(ctx, testKey)
+	require.NoError(t, err)
+	require.Equal(t, testValue, value)
+
+	// Empty and missing values are kept apart
+	require.NoError(t, client.Set(ctx, "empty", []byte{}))
+	value, err = client.Get(ctx, "empty")
+	require.NoError(t, err)
+	require.Equal(t, []byte{}, value)
+	value, err = client.Get(ctx, "missing")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	// The value is not stored in plain text
+	require.NoError(t, client.db.View(func(tx *bbolt.Tx) error {
+		stored := tx.Bucket(testBucket).Get([]byte(testKey))
+		require.NotNil(t, stored)
+		require.False(t, bytes.Contains(stored, testValue))
+		return nil
+	}))
+	require.NoError(t, client.Close(ctx))
+
+	// A value cannot be read with another key
+	otherAEAD := newTestCipher(t, bytes.Repeat([]byte{2}, 32))
+	client, err = newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second, aead: aead})
+	require.NoError(t, err)
+	defer client.Close(ctx)
+
+	require.NoError(t, client.db.View(func(tx *bbolt.Tx) error {
+		value, err = decrypt(otherAEAD, testKey, tx.Bucket(testBucket).Get([]byte(testKey)))
+		return nil
+	}))
+	require.ErrorIs(t, err, errDecrypt)
+	require.Nil(t, value)
+}
+
+func TestNewClientChecksEncryption(t *testing.T) {
+	aead := newTestCipher(t, bytes.Repeat([]byte{1}, 32))
+	otherAEAD := newTestCipher(t, bytes.Repeat([]byte{2}, 32))
+
+	tests := []struct {
+		name      string
+		writeAEAD cipher.AEAD
+		readAEAD  cipher.AEAD
+		wantErr   error
+	}{
+		{
+			name: "without encryption",
+		},
+		{
+			name:      "with encryption",
+			writeAEAD: aead,
+			readAEAD:  aead,
+		},
+		{
+			name:     "encryption enabled",
+			readAEAD: aead,
+			wantErr:  errWrittenWithoutEncryption,
+		},
+		{
+			name:      "encryption disabled",
+			writeAEAD: aead,
+			wantErr:   errWrittenWithEncryption,
+		},
+		{
+			name:      "key changed",
+			writeAEAD: aead,
+			readAEAD:  otherAEAD,
+			wantErr:   errWrittenWithAnotherKey,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbFile := filepath.Join(newTempDir(t), "my_db")
+			ctx := context.Background()
+
+			client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second, aead: test.writeAEAD})
+			require.NoError(t, err)
+			require.NoError(t, client.Set(ctx, "testKey", []byte("testValue")))
+			require.NoError(t, client.Close(ctx))
+
+			client, err = newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second, aead: test.readAEAD})
+			if test.wantErr != nil {
+				require.Equal(t, test.wantErr, err)
+				require.Nil(t, client)
+				return
+			}
+			require.NoError(t, err)
+			defer client.Close(ctx)
+
+			value, err := client.Get(ctx, "testKey")
+			require.NoError(t, err)
+			require.Equal(t, []byte("testValue"), value)
+		})
+	}
+}
+
+func TestNewClientEnablesEncryptionOnEmptyLegacyFile(t *testing.T) {
+	dbFile := filepath.Join(newTempDir(t), "my_db")
+	ctx := context.Background()
+
+	// Files written by previous versions have no metadata
+	db, err := bbolt.Open(dbFile, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		_, err = tx.CreateBucket(defaultBucket)
+		return err
+	}))
+	require.NoError(t, db.Close())
+
+	aead := newTestCipher(t, bytes.Repeat([]byte{1}, 32))
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second, aead: aead})
+	require.NoError(t, err)
+	defer client.Close(ctx)
 
-	defaultBucket = temp
+	require.NoError(t, client.Set(ctx, "testKey", []byte("testValue")))
+	value, err := client.Get(ctx, "testKey")
+	require.NoError(t, err)
+	require.Equal(t, []byte("testValue"), value)
+}
+
+func TestClientCompaction(t *testing.T) {
+	tempDir := newTempDir(t)
+	dbFile := filepath.Join(tempDir, "my_db")
+	ctx := context.Background()
+
+	options := clientOptions{
+		timeout: time.Second,
+		compaction: CompactionConfig{
+			SizeThresholdMiB:   0,
+			FreeRatioThreshold: 0.5,
+		},
+	}
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, options)
+	require.NoError(t, err)
+	defer client.Close(ctx)
+
+	needed, err := client.needsCompaction()
+	require.NoError(t, err)
+	require.False(t, needed)
+
+	fillAndEmpty(t, client, 100)
+	require.NoError(t, client.Set(ctx, "kept", []byte("value")))
+
+	sizeBefore := fileSize(dbFile)
+	needed, err = client.needsCompaction()
+	require.NoError(t, err)
+	require.True(t, needed)
+
+	require.NoError(t, client.compactIfNeeded())
+	require.Less(t, fileSize(dbFile), sizeBefore)
+	require.NoFileExists(t, dbFile+".compact")
+
+	// Data survives and the client keeps working
+	value, err := client.Get(ctx, "kept")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	require.NoError(t, client.Set(ctx, "after", []byte("compaction")))
+
+	// The size threshold prevents compacting small files
+	client.options.compaction.SizeThresholdMiB = 1024
+	fillAndEmpty(t, client, 100)
+	needed, err = client.needsCompaction()
+	require.NoError(t, err)
+	require.False(t, needed)
+}
+
+func TestClientCompactionLoop(t *testing.T) {
+	tempDir := newTempDir(t)
+	dbFile := filepath.Join(tempDir, "my_db")
+	ctx := context.Background()
+
+	options := clientOptions{
+		timeout: time.Second,
+		compaction: CompactionConfig{
+			Enabled:            true,
+			CheckInterval:      10 * time.Millisecond,
+			FreeRatioThreshold: 0.5,
+		},
+	}
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, options)
+	require.NoError(t, err)
+
+	fillAndEmpty(t, client, 100)
+	sizeBefore := fileSize(dbFile)
+	require.Eventually(t, func() bool {
+		return fileSize(dbFile) < sizeBefore
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.Set(ctx, "testKey", []byte("testValue")))
+	require.NoError(t, client.Close(ctx))
+}
+
+func TestClientCompactionRestoresFileOnFailure(t *testing.T) {
+	tempDir := newTempDir(t)
+	dbFile := filepath.Join(tempDir, "my_db")
+	ctx := context.Background()
+
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
+	require.NoError(t, err)
+	defer client.Close(ctx)
+	require.NoError(t, client.Set(ctx, "testKey", []byte("testValue")))
+
+	// The current file can't be saved before moving the compacted one in place
+	require.NoError(t, os.MkdirAll(filepath.Join(dbFile+".backup", "not_empty"), 0700))
+
+	require.Error(t, client.compact())
+
+	// The client keeps working with the current file
+	value, err := client.Get(ctx, "testKey")
+	require.NoError(t, err)
+	require.Equal(t, []byte("testValue"), value)
+	require.NoFileExists(t, dbFile+".compact")
+}
+
+func TestClientFailsWhenFileCannotBeReopened(t *testing.T) {
+	tempDir := newTempDir(t)
+	dbFile := filepath.Join(tempDir, "my_db")
+	ctx := context.Background()
+
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
+	require.NoError(t, err)
+	require.NoError(t, client.db.Close())
+	client.db = nil
+
+	// The file is locked by another process, so it can't be reopened in time
+	other, err := bbolt.Open(dbFile, 0600, nil)
+	require.NoError(t, err)
+	defer other.Close()
+	client.options.timeout = 10 * time.Millisecond
+
+	cause := errors.New("compaction failed")
+	err = client.reopen(cause)
+	require.Error(t, err)
+	require.NotEqual(t, cause, err)
+
+	require.Equal(t, err, client.Set(ctx, "testKey", []byte("testValue")))
+	needed, err := client.needsCompaction()
+	require.NoError(t, err)
+	require.False(t, needed)
+	require.NoError(t, client.Close(ctx))
+}
+
+func TestNewClientRecoversInterruptedCompaction(t *testing.T) {
+	tempDir := newTempDir(t)
+	dbFile := filepath.Join(tempDir, "my_db")
+	ctx := context.Background()
+
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
+	require.NoError(t, err)
+	require.NoError(t, client.Set(ctx, "testKey", []byte("testValue")))
+	require.NoError(t, client.Close(ctx))
+
+	// The compaction was interrupted after saving the current file
+	require.NoError(t, os.Rename(dbFile, dbFile+".backup"))
+	require.NoError(t, ioutil.WriteFile(dbFile+".compact", []byte("partial"), 0600))
+
+	client, err = newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
+	require.NoError(t, err)
+	defer client.Close(ctx)
+
+	value, err := client.Get(ctx, "testKey")
+	require.NoError(t, err)
+	require.Equal(t, []byte("testValue"), value)
+	require.NoFileExists(t, dbFile+".backup")
+	require.NoFileExists(t, dbFile+".compact")
+}
+
+func fillAndEmpty(t *testing.T, client *fileStorageClient, count int) {
+	ctx := context.Background()
+	value := bytes.Repeat([]byte("x"), 4096)
+	for i := 0; i < count; i++ {
+		require.NoError(t, client.Set(ctx, fmt.Sprintf("testKey-%d", i), value))
+	}
+	for i := 0; i < count; i++ {
+		require.NoError(t, client.Delete(ctx, fmt.Sprintf("testKey-%d", i)))
+	}
 }
 
 func BenchmarkClientGet(b *testing.B) {
 	tempDir := newTempDir(b)
 	dbFile := filepath.Join(tempDir, "my_db")
 
-	client, err := newClient(dbFile, time.Second)
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
 	require.NoError(b, err)
 
 	ctx := context.Background()
@@ -231,7 +564,7 @@ func BenchmarkClientGet100(b *testing.B) {
 	tempDir := newTempDir(b)
 	dbFile := filepath.Join(tempDir, "my_db")
 
-	client, err := newClient(dbFile, time.Second)
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
 	require.NoError(b, err)
 
 	ctx := context.Background()
@@ -251,7 +584,7 @@ func BenchmarkClientSet(b *testing.B) {
 	tempDir := newTempDir(b)
 	dbFile := filepath.Join(tempDir, "my_db")
 
-	client, err := newClient(dbFile, time.Second)
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
 	require.NoError(b, err)
 
 	ctx := context.Background()
@@ -268,7 +601,7 @@ func BenchmarkClientSet100(b *testing.B) {
 	tempDir := newTempDir(b)
 	dbFile := filepath.Join(tempDir, "my_db")
 
-	client, err := newClient(dbFile, time.Second)
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
 	require.NoError(b, err)
 
 	ctx := context.Background()
@@ -288,7 +621,7 @@ func BenchmarkClientDelete(b *testing.B) {
 	tempDir := newTempDir(b)
 	dbFile := filepath.Join(tempDir, "my_db")
 
-	client, err := newClient(dbFile, time.Second)
+	client, err := newClient(zap.NewNop(), dbFile, testBucket, clientOptions{timeout: time.Second})
 	require.NoError(b, err)
 
 	ctx := context.Background()
@@ -15,15 +15,66 @@
 package filestorage
 
 import (
+	"errors"
 	"time"
 
 	"go.opentelemetry.io/collector/config"
 )
 
-// Config defines configuration for http forwarder extension.
+// Config defines configuration for file storage extension.
 type Config struct {
 	config.ExtensionSettings `mapstructure:",squash"`
 
 	Directory string        `mapstructure:"directory,omitempty"`
 	Timeout   time.Duration `mapstructure:"timeout,omitempty"`
+
+	// FSync makes every transaction sync the database file to disk before returning.
+	FSync bool `mapstructure:"fsync,omitempty"`
+
+	Compaction CompactionConfig `mapstructure:"compaction,omitempty"`
+	Encryption EncryptionConfig `mapstructure:"encryption,omitempty"`
+}
+
+// CompactionConfig defines when the database files are compacted while in use.
+type CompactionConfig struct {
+	Enabled bool `mapstructure:"enabled,omitempty"`
+
+	// CheckInterval is how often the size and free space of each file are checked.
+	CheckInterval time.Duration `mapstructure:"check_interval,omitempty"`
+
+	// SizeThresholdMiB is the file size, in MiB, below which a file is never compacted.
+	SizeThresholdMiB int64 `mapstructure:"size_threshold_mib,omitempty"`
+
+	// FreeRatioThreshold is the fraction of the file held by free pages above which a file is compacted.
+	FreeRatioThreshold float64 `mapstructure:"free_ratio_threshold,omitempty"`
+
+	// MaxTransactionSize is the maximum number of bytes copied in a single transaction while compacting.
+	MaxTransactionSize int64 `mapstructure:"max_transaction_size,omitempty"`
+}
+
+// EncryptionConfig defines the at-rest encryption of the stored values.
+type EncryptionConfig struct {
+	// KeyFile is the path to a file holding a hex encoded AES key.
+	KeyFile string `mapstructure:"key_file,omitempty"`
+}
+
+var _ config.Extension = (*Config)(nil)
+
+// Validate checks if the extension configuration is valid
+func (cfg *Config) Validate() error {
+	if cfg.Compaction.Enabled {
+		if cfg.Compaction.CheckInterval <= 0 {
+			return errors.New("compaction check_interval must be positive")
+		}
+		if cfg.Compaction.SizeThresholdMiB < 0 {
+			return errors.New("compaction size_threshold_mib must not be negative")
+		}
+		if cfg.Compaction.FreeRatioThreshold < 0 || cfg.Compaction.FreeRatioThreshold > 1 {
+			return errors.New("compaction free_ratio_threshold must be between 0 and 1")
+		}
+		if cfg.Compaction.MaxTransactionSize < 0 {
+			return errors.New("compaction max_transaction_size must not be negative")
+		}
+	}
+	return nil
 }
@@ -48,6 +48,79 @@ func TestLoadConfig(t *testing.T) {
 			ExtensionSettings: config.NewExtensionSettings(config.NewIDWithName(typeStr, "all_settings")),
 			Directory:         "/var/lib/otelcol/mydir",
 			Timeout:           2 * time.Second,
+			FSync:             true,
+			Compaction: CompactionConfig{
+				Enabled:            true,
+				CheckInterval:      30 * time.Second,
+				SizeThresholdMiB:   64,
+				FreeRatioThreshold: 0.25,
+				MaxTransactionSize: 1048576,
+			},
+			Encryption: EncryptionConfig{
+				KeyFile: "/etc/otelcol/storage.key",
+			},
 		},
 		ext1)
 }
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   string
+	}{
+		{
+			name:      "compaction disabled",
+			configure: func(cfg *Config) { cfg.Compaction.CheckInterval = 0 },
+		},
+		{
+			name:      "compaction enabled",
+			configure: func(cfg *Config) { cfg.Compaction.Enabled = true },
+		},
+		{
+			name: "invalid check_interval",
+			configure: func(cfg *Config) {
+				cfg.Compaction.Enabled = true
+				cfg.Compaction.CheckInterval = 0
+			},
+			wantErr: "compaction check_interval must be positive",
+		},
+		{
+			name: "invalid size_threshold_mib",
+			configure: func(cfg *Config) {
+				cfg.Compaction.Enabled = true
+				cfg.Compaction.SizeThresholdMiB = -1
+			},
+			wantErr: "compaction size_threshold_mib must not be negative",
+		},
+		{
+			name: "invalid free_ratio_threshold",
+			configure: func(cfg *Config) {
+				cfg.Compaction.Enabled = true
+				cfg.Compaction.FreeRatioThreshold = 1.5
+			},
+			wantErr: "compaction free_ratio_threshold must be between 0 and 1",
+		},
+		{
+			name: "invalid max_transaction_size",
+			configure: func(cfg *Config) {
+				cfg.Compaction.Enabled = true
+				cfg.Compaction.MaxTransactionSize = -1
+			},
+			wantErr: "compaction max_transaction_size must not be negative",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			test.configure(cfg)
+			err := cfg.Validate()
+			if test.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestorage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+var errDecrypt = errors.New("failed to decrypt value, the data may have been written with another key or without encryption")
+
+// newCipherFromKeyFile reads a hex encoded AES-128, AES-192 or AES-256 key from
+// the file and returns an AES-GCM cipher using it.
+func newCipherFromKeyFile(keyFile string) (cipher.AEAD, error) {
+	contents, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+	}
+
+	key, err := hex.DecodeString(string(bytes.TrimSpace(contents)))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key must be hex encoded: %w", err)
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("encryption key must be 16, 24 or 32 bytes long, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt seals the value with a random nonce, prepended to the result. The key
+// is used as additional data so that a value cannot be moved to another key.
+func encrypt(aead cipher.AEAD, key string, value []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, value, []byte(key)), nil
+}
+
+// decrypt opens a value sealed by encrypt. A nil value, meaning the key is
+// missing, is returned as is.
+func decrypt(aead cipher.AEAD, key string, value []byte) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if len(value) < aead.NonceSize() {
+		return nil, errDecrypt
+	}
+	nonce, sealed := value[:aead.NonceSize()], value[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(key))
+	if err != nil {
+		return nil, errDecrypt
+	}
+	if plain == nil {
+		plain = []byte{}
+	}
+	return plain, nil
+}
@@ -0,0 +1,115 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestorage
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCipherFromKeyFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents []byte
+		wantErr  string
+	}{
+		{
+			name:     "256 bits key",
+			contents: []byte(hex.EncodeToString(bytes.Repeat([]byte{0xff}, 32))),
+		},
+		{
+			name:     "128 bits key",
+			contents: []byte(hex.EncodeToString(bytes.Repeat([]byte{0xff}, 16))),
+		},
+		{
+			name:     "key with trailing newline",
+			contents: []byte(hex.EncodeToString(bytes.Repeat([]byte{0xff}, 32)) + "\n"),
+		},
+		{
+			name:     "raw key",
+			contents: bytes.Repeat([]byte{0xff}, 32),
+			wantErr:  "encryption key must be hex encoded: encoding/hex: invalid byte: U+00FF 'ÿ'",
+		},
+		{
+			name:     "invalid length",
+			contents: []byte("abcdef"),
+			wantErr:  "encryption key must be 16, 24 or 32 bytes long, got 3",
+		},
+		{
+			name:    "empty file",
+			wantErr: "encryption key must be 16, 24 or 32 bytes long, got 0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyFile := filepath.Join(newTempDir(t), "key")
+			require.NoError(t, ioutil.WriteFile(keyFile, test.contents, 0600))
+
+			aead, err := newCipherFromKeyFile(keyFile)
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				require.Nil(t, aead)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, aead)
+		})
+	}
+
+	_, err := newCipherFromKeyFile("/not/a/key/file")
+	require.Error(t, err)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	aead := newTestCipher(t, bytes.Repeat([]byte{1}, 32))
+
+	sealed, err := encrypt(aead, "key", []byte("value"))
+	require.NoError(t, err)
+
+	// The nonce is random, so the same value is sealed differently every time
+	sealedAgain, err := encrypt(aead, "key", []byte("value"))
+	require.NoError(t, err)
+	require.NotEqual(t, sealed, sealedAgain)
+
+	value, err := decrypt(aead, "key", sealed)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	// The value is bound to its key
+	_, err = decrypt(aead, "other", sealed)
+	require.ErrorIs(t, err, errDecrypt)
+
+	_, err = decrypt(aead, "key", []byte("plain"))
+	require.ErrorIs(t, err, errDecrypt)
+
+	value, err = decrypt(aead, "key", nil)
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func newTestCipher(t *testing.T, key []byte) cipher.AEAD {
+	keyFile := filepath.Join(newTempDir(t), "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(hex.EncodeToString(key)), 0600))
+	aead, err := newCipherFromKeyFile(keyFile)
+	require.NoError(t, err)
+	return aead
+}
@@ -19,7 +19,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
@@ -29,7 +28,7 @@ import (
 
 type localFileStorage struct {
 	directory string
-	timeout   time.Duration
+	options   clientOptions
 	logger    *zap.Logger
 }
 
@@ -42,9 +41,20 @@ func newLocalFileStorage(logger *zap.Logger, config *Config) (component.Extensio
 		return nil, fmt.Errorf("directory must exist: %v", err)
 	}
 
+	options := clientOptions{
+		timeout:    config.Timeout,
+		fsync:      config.FSync,
+		compaction: config.Compaction,
+	}
+	if config.Encryption.KeyFile != "" {
+		if options.aead, err = newCipherFromKeyFile(config.Encryption.KeyFile); err != nil {
+			return nil, err
+		}
+	}
+
 	return &localFileStorage{
 		directory: filepath.Clean(config.Directory),
-		timeout:   config.Timeout,
+		options:   options,
 		logger:    logger,
 	}, nil
 }
@@ -71,7 +81,8 @@ func (lfs *localFileStorage) GetClient(ctx context.Context, kind component.Kind,
 	}
 	// TODO sanitize rawName
 	absoluteName := filepath.Join(lfs.directory, rawName)
-	return newClient(absoluteName, lfs.timeout)
+	// Each component has its own bucket, named after it
+	return newClient(lfs.logger, absoluteName, []byte(rawName), lfs.options)
 }
 
 func kindString(k component.Kind) string {
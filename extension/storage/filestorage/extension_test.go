@@ -18,6 +18,8 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 
@@ -224,6 +226,59 @@ func TestGetClientErrorsOnDeletedDirectory(t *testing.T) {
 	require.Nil(t, client)
 }
 
+func TestGetClientUsesComponentBucket(t *testing.T) {
+	ctx := context.Background()
+	se := newTestExtension(t)
+
+	client, err := se.GetClient(
+		ctx,
+		component.KindReceiver,
+		newTestEntity("my_component"),
+		"foo",
+	)
+	require.NoError(t, err)
+	defer client.Close(ctx)
+
+	require.Equal(t, []byte("receiver_nop_my_component_foo"), client.(*fileStorageClient).bucket)
+}
+
+func TestExtensionWithEncryption(t *testing.T) {
+	ctx := context.Background()
+	tempDir := newTempDir(t)
+
+	keyFile := filepath.Join(tempDir, "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(strings.Repeat("ab", 32)), 0600))
+
+	f := NewFactory()
+	cfg := f.CreateDefaultConfig().(*Config)
+	cfg.Directory = tempDir
+	cfg.FSync = true
+	cfg.Encryption.KeyFile = keyFile
+
+	extension, err := f.CreateExtension(context.Background(), componenttest.NewNopExtensionCreateSettings(), cfg)
+	require.NoError(t, err)
+
+	client, err := extension.(storage.Extension).GetClient(ctx, component.KindReceiver, newTestEntity("my_component"), "")
+	require.NoError(t, err)
+	defer client.Close(ctx)
+
+	require.NoError(t, client.Set(ctx, "key", []byte("value")))
+	data, err := client.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), data)
+}
+
+func TestNewExtensionErrorsOnInvalidKeyFile(t *testing.T) {
+	f := NewFactory()
+	cfg := f.CreateDefaultConfig().(*Config)
+	cfg.Directory = newTempDir(t)
+	cfg.Encryption.KeyFile = "/not/a/key/file"
+
+	extension, err := f.CreateExtension(context.Background(), componenttest.NewNopExtensionCreateSettings(), cfg)
+	require.Error(t, err)
+	require.Nil(t, extension)
+}
+
 func newTestExtension(t *testing.T) storage.Extension {
 	tempDir, err := ioutil.TempDir("", "")
 	require.NoError(t, err)
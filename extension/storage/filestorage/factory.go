@@ -39,6 +39,12 @@ func createDefaultConfig() config.Extension {
 		ExtensionSettings: config.NewExtensionSettings(config.NewID(typeStr)),
 		Directory:         getDefaultDirectory(),
 		Timeout:           time.Second,
+		Compaction: CompactionConfig{
+			CheckInterval:      time.Minute,
+			SizeThresholdMiB:   16,
+			FreeRatioThreshold: 0.5,
+			MaxTransactionSize: 65536,
+		},
 	}
 }
 
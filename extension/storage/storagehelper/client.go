@@ -0,0 +1,44 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storagehelper provides helpers for components using a storage extension.
+package storagehelper
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/extension/storage"
+)
+
+// GetClient returns a client of the storage extension with the given name for the
+// component of the given kind and ID.
+func GetClient(ctx context.Context, host component.Host, storageName string, kind component.Kind, id config.ComponentID) (storage.Client, error) {
+	var availableExtensions []string
+	for extID, ext := range host.GetExtensions() {
+		availableExtensions = append(availableExtensions, extID.String())
+		if extID.String() != storageName {
+			continue
+		}
+		storageExt, ok := ext.(storage.Extension)
+		if !ok {
+			return nil, fmt.Errorf("the extension %q isn't a storage extension", storageName)
+		}
+		return storageExt.GetClient(ctx, kind, id, "")
+	}
+	return nil, fmt.Errorf("failed to find storage extension: '%s'; please configure storage from one of: %+v",
+		storageName, availableExtensions)
+}
@@ -0,0 +1,67 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagehelper
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/storage/storagetest"
+)
+
+type nonStorageExtension struct {
+	component.Extension
+}
+
+type nonStorageHost struct {
+	component.Host
+}
+
+func (nonStorageHost) GetExtensions() map[config.ComponentID]component.Extension {
+	return map[config.ComponentID]component.Extension{
+		config.NewID("nop"): nonStorageExtension{},
+	}
+}
+
+func TestGetClient(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	ctx := context.Background()
+	host := storagetest.NewStorageHost(t, tempDir, "one", "two")
+
+	client, err := GetClient(ctx, host, "nop/two", component.KindProcessor, config.NewID("test"))
+	require.NoError(t, err)
+	require.NoError(t, client.Set(ctx, "key", []byte("value")))
+	require.NoError(t, client.Close(ctx))
+
+	_, err = GetClient(ctx, host, "nop/three", component.KindProcessor, config.NewID("test"))
+	assert.Error(t, err)
+
+	_, err = GetClient(ctx, nonStorageHost{}, "nop", component.KindProcessor, config.NewID("test"))
+	assert.EqualError(t, err, `the extension "nop" isn't a storage extension`)
+
+	_, err = GetClient(ctx, componenttest.NewNopHost(), "file_storage", component.KindProcessor, config.NewID("test"))
+	assert.EqualError(t, err, "failed to find storage extension: 'file_storage'; please configure storage from one of: []")
+}
@@ -16,6 +16,7 @@ package cumulativetodeltaprocessor
 
 import (
 	"fmt"
+	"regexp"
 
 	"go.opentelemetry.io/collector/config"
 )
@@ -24,15 +25,64 @@ import (
 type Config struct {
 	config.ProcessorSettings `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct
 
-	// List of cumulative sum metrics to convert to delta
+	// List of cumulative sum metrics to convert to delta.
+	// Deprecated: use Include with the strict match type instead.
 	Metrics []string `mapstructure:"metrics"`
+
+	// Include specifies the metrics to convert to delta. When empty, all the monotonic cumulative sums
+	// and the cumulative histograms are converted.
+	Include MetricFilter `mapstructure:"include"`
+
+	// Exclude specifies the metrics not to convert to delta, even if matched by Include.
+	Exclude MetricFilter `mapstructure:"exclude"`
+}
+
+// MetricFilter specifies a set of metrics, either by exact names or by regular expressions.
+type MetricFilter struct {
+	// Metrics is the list of metric names, or regular expressions, to match.
+	Metrics []string `mapstructure:"metrics"`
+
+	// MatchType determines how the Metrics are matched: <strict|regexp>. Defaults to strict.
+	MatchType MatchType `mapstructure:"match_type"`
 }
 
+// MatchType is the type of matching applied to the metric names.
+type MatchType string
+
+const (
+	// StrictMatchType matches the metric names exactly.
+	StrictMatchType MatchType = "strict"
+
+	// RegexpMatchType matches the metric names with regular expressions.
+	RegexpMatchType MatchType = "regexp"
+)
+
 // Validate checks whether the input configuration has all of the required fields for the processor.
 // An error is returned if there are any invalid inputs.
 func (config *Config) Validate() error {
-	if len(config.Metrics) == 0 {
-		return fmt.Errorf("metric names are missing")
+	if len(config.Metrics) > 0 && len(config.Include.Metrics) > 0 {
+		return fmt.Errorf("metrics and include can't be used together, use include only")
+	}
+	if err := config.Include.validate(); err != nil {
+		return fmt.Errorf("invalid include: %w", err)
+	}
+	if err := config.Exclude.validate(); err != nil {
+		return fmt.Errorf("invalid exclude: %w", err)
+	}
+	return nil
+}
+
+func (f *MetricFilter) validate() error {
+	switch f.MatchType {
+	case "", StrictMatchType:
+	case RegexpMatchType:
+		for _, expr := range f.Metrics {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("invalid regexp %q: %w", expr, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported match_type %q, must be one of %q or %q", f.MatchType, StrictMatchType, RegexpMatchType)
 	}
 	return nil
 }
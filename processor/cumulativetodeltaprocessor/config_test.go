@@ -41,10 +41,29 @@ func TestLoadingFullConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			configFile: "config_include_exclude.yaml",
+			expCfg: &Config{
+				ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+				Include: MetricFilter{
+					Metrics: []string{
+						".*_total$",
+						"^system\\.",
+					},
+					MatchType: RegexpMatchType,
+				},
+				Exclude: MetricFilter{
+					Metrics: []string{
+						"system.cpu.time",
+					},
+					MatchType: StrictMatchType,
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
-		t.Run(test.expCfg.ID().String(), func(t *testing.T) {
+		t.Run(test.configFile, func(t *testing.T) {
 			factories, err := componenttest.NopFactories()
 			assert.NoError(t, err)
 
@@ -71,9 +90,18 @@ func TestValidateConfig(t *testing.T) {
 			succeed:    true,
 		},
 		{
-			configName:   "config_missing_name.yaml",
+			configName: "config_missing_name.yaml",
+			succeed:    true,
+		},
+		{
+			configName:   "config_invalid_regexp.yaml",
+			succeed:      false,
+			errorMessage: "invalid include: invalid regexp \"(metric\": error parsing regexp: missing closing ): `(metric`",
+		},
+		{
+			configName:   "config_metrics_and_include.yaml",
 			succeed:      false,
-			errorMessage: "metric names are missing",
+			errorMessage: "metrics and include can't be used together, use include only",
 		},
 	}
 
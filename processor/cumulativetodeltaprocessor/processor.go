@@ -16,29 +16,34 @@ package cumulativetodeltaprocessor
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/model/pdata"
+	tracetranslator "go.opentelemetry.io/collector/translator/trace"
 	"go.uber.org/zap"
 
 	awsmetrics "github.com/open-telemetry/opentelemetry-collector-contrib/internal/aws/metrics"
 )
 
 type cumulativeToDeltaProcessor struct {
-	metrics         map[string]bool
+	include         *metricMatcher
+	exclude         *metricMatcher
 	logger          *zap.Logger
 	deltaCalculator awsmetrics.MetricCalculator
 }
 
 func newCumulativeToDeltaProcessor(config *Config, logger *zap.Logger) *cumulativeToDeltaProcessor {
-	inputMetricSet := make(map[string]bool, len(config.Metrics))
-	for _, name := range config.Metrics {
-		inputMetricSet[name] = true
+	include := config.Include
+	if len(config.Metrics) > 0 {
+		include = MetricFilter{Metrics: config.Metrics, MatchType: StrictMatchType}
 	}
 
 	return &cumulativeToDeltaProcessor{
-		metrics:         inputMetricSet,
+		include:         newMetricMatcher(include),
+		exclude:         newMetricMatcher(config.Exclude),
 		logger:          logger,
 		deltaCalculator: newDeltaCalculator(),
 	}
@@ -54,32 +59,23 @@ func (ctdp *cumulativeToDeltaProcessor) processMetrics(_ context.Context, md pda
 	resourceMetricsSlice := md.ResourceMetrics()
 	for i := 0; i < resourceMetricsSlice.Len(); i++ {
 		rm := resourceMetricsSlice.At(i)
+		resource := resourceKey(rm.Resource())
 		ilms := rm.InstrumentationLibraryMetrics()
 		for j := 0; j < ilms.Len(); j++ {
 			ilm := ilms.At(j)
 			metricSlice := ilm.Metrics()
 			for k := 0; k < metricSlice.Len(); k++ {
 				metric := metricSlice.At(k)
-				if ctdp.metrics[metric.Name()] {
-					if metric.DataType() == pdata.MetricDataTypeSum && metric.Sum().AggregationTemporality() == pdata.AggregationTemporalityCumulative {
-						dataPoints := metric.Sum().DataPoints()
-
-						for l := 0; l < dataPoints.Len(); l++ {
-							fromDataPoint := dataPoints.At(l)
-							labelMap := make(map[string]string)
-
-							fromDataPoint.LabelsMap().Range(func(k string, v string) bool {
-								labelMap[k] = v
-								return true
-							})
-
-							result, _ := ctdp.deltaCalculator.Calculate(metric.Name(), labelMap, fromDataPoint.DoubleVal(), fromDataPoint.Timestamp().AsTime())
-
-							fromDataPoint.SetDoubleVal(result.(delta).value)
-							fromDataPoint.SetStartTimestamp(pdata.TimestampFromTime(result.(delta).prevTimestamp))
-						}
-						metric.Sum().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
-					}
+				if !ctdp.shouldConvert(metric) {
+					continue
+				}
+
+				series := seriesKey(metric.Name(), metric.DataType(), resource)
+				switch metric.DataType() {
+				case pdata.MetricDataTypeSum:
+					ctdp.convertSum(series, metric.Sum())
+				case pdata.MetricDataTypeHistogram:
+					ctdp.convertHistogram(series, metric.Histogram())
 				}
 			}
 		}
@@ -87,6 +83,104 @@ func (ctdp *cumulativeToDeltaProcessor) processMetrics(_ context.Context, md pda
 	return md, nil
 }
 
+// shouldConvert returns whether the metric is a cumulative sum or histogram to convert. When no metrics are
+// explicitly included, all the monotonic cumulative sums and the cumulative histograms are converted.
+func (ctdp *cumulativeToDeltaProcessor) shouldConvert(metric pdata.Metric) bool {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeSum:
+		sum := metric.Sum()
+		if sum.AggregationTemporality() != pdata.AggregationTemporalityCumulative {
+			return false
+		}
+		if ctdp.include.isEmpty() && !sum.IsMonotonic() {
+			return false
+		}
+	case pdata.MetricDataTypeHistogram:
+		if metric.Histogram().AggregationTemporality() != pdata.AggregationTemporalityCumulative {
+			return false
+		}
+	default:
+		return false
+	}
+
+	if !ctdp.include.isEmpty() && !ctdp.include.matches(metric.Name()) {
+		return false
+	}
+	return !ctdp.exclude.matches(metric.Name())
+}
+
+func (ctdp *cumulativeToDeltaProcessor) convertSum(series string, sum pdata.Sum) {
+	dataPoints := sum.DataPoints()
+	for l := 0; l < dataPoints.Len(); l++ {
+		fromDataPoint := dataPoints.At(l)
+		labelMap := labelsToMap(fromDataPoint.LabelsMap())
+
+		if fromDataPoint.Type() == pdata.MetricValueTypeInt {
+			result, _ := ctdp.deltaCalculator.Calculate(series, labelMap, fromDataPoint.IntVal(), fromDataPoint.Timestamp().AsTime())
+			fromDataPoint.SetIntVal(result.(delta).value.(int64))
+			fromDataPoint.SetStartTimestamp(pdata.TimestampFromTime(result.(delta).prevTimestamp))
+			continue
+		}
+
+		result, _ := ctdp.deltaCalculator.Calculate(series, labelMap, fromDataPoint.DoubleVal(), fromDataPoint.Timestamp().AsTime())
+
+		fromDataPoint.SetDoubleVal(result.(delta).value.(float64))
+		fromDataPoint.SetStartTimestamp(pdata.TimestampFromTime(result.(delta).prevTimestamp))
+	}
+	sum.SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+}
+
+func (ctdp *cumulativeToDeltaProcessor) convertHistogram(series string, histogram pdata.Histogram) {
+	dataPoints := histogram.DataPoints()
+	for l := 0; l < dataPoints.Len(); l++ {
+		fromDataPoint := dataPoints.At(l)
+		// the calculator keeps its own copy of the bucket counts, as the data point is sent to the next
+		// consumers, which might modify it
+		bucketCounts := make([]uint64, len(fromDataPoint.BucketCounts()))
+		copy(bucketCounts, fromDataPoint.BucketCounts())
+		value := histogramValue{
+			count:        fromDataPoint.Count(),
+			sum:          fromDataPoint.Sum(),
+			bucketCounts: bucketCounts,
+		}
+
+		result, _ := ctdp.deltaCalculator.Calculate(series, labelsToMap(fromDataPoint.LabelsMap()), value, fromDataPoint.Timestamp().AsTime())
+
+		deltaValue := result.(delta).value.(histogramValue)
+		fromDataPoint.SetCount(deltaValue.count)
+		fromDataPoint.SetSum(deltaValue.sum)
+		copy(fromDataPoint.BucketCounts(), deltaValue.bucketCounts)
+		fromDataPoint.SetStartTimestamp(pdata.TimestampFromTime(result.(delta).prevTimestamp))
+	}
+	histogram.SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+}
+
+// seriesKey identifies the series of a data point along with its labels. The data type and the resource are part
+// of it, so that metrics with the same name from different resources or of different types don't collide.
+func seriesKey(name string, dataType pdata.MetricDataType, resource string) string {
+	return strings.Join([]string{name, dataType.String(), resource}, "\x00")
+}
+
+// resourceKey returns a string identifying the resource, made of its sorted attributes.
+func resourceKey(resource pdata.Resource) string {
+	var attrs []string
+	resource.Attributes().Range(func(k string, v pdata.AttributeValue) bool {
+		attrs = append(attrs, k+"="+tracetranslator.AttributeValueToString(v))
+		return true
+	})
+	sort.Strings(attrs)
+	return strings.Join(attrs, ",")
+}
+
+func labelsToMap(labels pdata.StringMap) map[string]string {
+	labelMap := make(map[string]string)
+	labels.Range(func(k string, v string) bool {
+		labelMap[k] = v
+		return true
+	})
+	return labelMap
+}
+
 // Shutdown is invoked during service shutdown.
 func (ctdp *cumulativeToDeltaProcessor) Shutdown(context.Context) error {
 	return nil
@@ -94,11 +188,25 @@ func (ctdp *cumulativeToDeltaProcessor) Shutdown(context.Context) error {
 
 func newDeltaCalculator() awsmetrics.MetricCalculator {
 	return awsmetrics.NewMetricCalculator(func(prev *awsmetrics.MetricValue, val interface{}, timestamp time.Time) (interface{}, bool) {
-		result := delta{value: val.(float64), prevTimestamp: timestamp}
+		result := delta{value: val, prevTimestamp: timestamp}
 
 		if prev != nil {
-			deltaValue := val.(float64) - prev.RawValue.(float64)
-			result.value = deltaValue
+			// a previous value of another type, such as an int sum turned into a double sum,
+			// is handled as a reset: the current value is kept as is
+			switch v := val.(type) {
+			case int64:
+				if p, ok := prev.RawValue.(int64); ok {
+					result.value = v - p
+				}
+			case float64:
+				if p, ok := prev.RawValue.(float64); ok {
+					result.value = v - p
+				}
+			case histogramValue:
+				if p, ok := prev.RawValue.(histogramValue); ok {
+					result.value = v.sub(p)
+				}
+			}
 			result.prevTimestamp = prev.Timestamp
 			return result, true
 		}
@@ -107,6 +215,35 @@ func newDeltaCalculator() awsmetrics.MetricCalculator {
 }
 
 type delta struct {
-	value         float64
+	value         interface{}
 	prevTimestamp time.Time
 }
+
+// histogramValue holds the cumulative values of a histogram data point.
+type histogramValue struct {
+	count        uint64
+	sum          float64
+	bucketCounts []uint64
+}
+
+// sub returns the difference between the histogram and the previous one. When the histogram has been reset,
+// or its buckets have changed, the histogram itself is returned.
+func (h histogramValue) sub(prev histogramValue) histogramValue {
+	if h.count < prev.count || len(h.bucketCounts) != len(prev.bucketCounts) {
+		return h
+	}
+
+	bucketCounts := make([]uint64, len(h.bucketCounts))
+	for i := range h.bucketCounts {
+		if h.bucketCounts[i] < prev.bucketCounts[i] {
+			return h
+		}
+		bucketCounts[i] = h.bucketCounts[i] - prev.bucketCounts[i]
+	}
+
+	return histogramValue{
+		count:        h.count - prev.count,
+		sum:          h.sum - prev.sum,
+		bucketCounts: bucketCounts,
+	}
+}
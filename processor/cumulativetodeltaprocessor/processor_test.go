@@ -21,6 +21,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/consumer/consumertest"
@@ -36,6 +37,8 @@ type testMetric struct {
 type cumulativeToDeltaTest struct {
 	name       string
 	metrics    []string
+	include    MetricFilter
+	exclude    MetricFilter
 	inMetrics  pdata.Metrics
 	outMetrics pdata.Metrics
 }
@@ -43,17 +46,17 @@ type cumulativeToDeltaTest struct {
 var (
 	testCases = []cumulativeToDeltaTest{
 		{
-			name:    "cumulative_to_delta_expect_same",
+			name:    "cumulative_to_delta_convert_all_by_default",
 			metrics: nil,
 			inMetrics: generateTestMetrics(testMetric{
 				metricNames:  []string{"metric_1", "metric_2"},
-				metricValues: [][]float64{{100}, {4}},
+				metricValues: [][]float64{{100, 200}, {4}},
 				isCumulative: []bool{true, true},
 			}),
 			outMetrics: generateTestMetrics(testMetric{
 				metricNames:  []string{"metric_1", "metric_2"},
-				metricValues: [][]float64{{100}, {4}},
-				isCumulative: []bool{true, true},
+				metricValues: [][]float64{{100, 100}, {4}},
+				isCumulative: []bool{false, false},
 			}),
 		},
 		{
@@ -70,6 +73,78 @@ var (
 				isCumulative: []bool{false, true},
 			}),
 		},
+		{
+			name: "cumulative_to_delta_include_strict",
+			include: MetricFilter{
+				Metrics:   []string{"metric_2"},
+				MatchType: StrictMatchType,
+			},
+			inMetrics: generateTestMetrics(testMetric{
+				metricNames:  []string{"metric_1", "metric_2"},
+				metricValues: [][]float64{{100}, {4, 10}},
+				isCumulative: []bool{true, true},
+			}),
+			outMetrics: generateTestMetrics(testMetric{
+				metricNames:  []string{"metric_1", "metric_2"},
+				metricValues: [][]float64{{100}, {4, 6}},
+				isCumulative: []bool{true, false},
+			}),
+		},
+		{
+			name: "cumulative_to_delta_include_regexp",
+			include: MetricFilter{
+				Metrics:   []string{"^metric_.*"},
+				MatchType: RegexpMatchType,
+			},
+			inMetrics: generateTestMetrics(testMetric{
+				metricNames:  []string{"metric_1", "metric_2", "other_metric"},
+				metricValues: [][]float64{{100, 150}, {4}, {10, 20}},
+				isCumulative: []bool{true, true, true},
+			}),
+			outMetrics: generateTestMetrics(testMetric{
+				metricNames:  []string{"metric_1", "metric_2", "other_metric"},
+				metricValues: [][]float64{{100, 50}, {4}, {10, 20}},
+				isCumulative: []bool{false, false, true},
+			}),
+		},
+		{
+			name: "cumulative_to_delta_exclude_regexp",
+			exclude: MetricFilter{
+				Metrics:   []string{".*_2$"},
+				MatchType: RegexpMatchType,
+			},
+			inMetrics: generateTestMetrics(testMetric{
+				metricNames:  []string{"metric_1", "metric_2"},
+				metricValues: [][]float64{{100, 150}, {4, 10}},
+				isCumulative: []bool{true, true},
+			}),
+			outMetrics: generateTestMetrics(testMetric{
+				metricNames:  []string{"metric_1", "metric_2"},
+				metricValues: [][]float64{{100, 50}, {4, 10}},
+				isCumulative: []bool{false, true},
+			}),
+		},
+		{
+			name: "cumulative_to_delta_include_and_exclude",
+			include: MetricFilter{
+				Metrics:   []string{"metric_1", "metric_2"},
+				MatchType: StrictMatchType,
+			},
+			exclude: MetricFilter{
+				Metrics:   []string{"metric_2"},
+				MatchType: StrictMatchType,
+			},
+			inMetrics: generateTestMetrics(testMetric{
+				metricNames:  []string{"metric_1", "metric_2"},
+				metricValues: [][]float64{{100, 150}, {4, 10}},
+				isCumulative: []bool{true, true},
+			}),
+			outMetrics: generateTestMetrics(testMetric{
+				metricNames:  []string{"metric_1", "metric_2"},
+				metricValues: [][]float64{{100, 50}, {4, 10}},
+				isCumulative: []bool{false, true},
+			}),
+		},
 	}
 )
 
@@ -81,6 +156,8 @@ func TestCumulativeToDeltaProcessor(t *testing.T) {
 			cfg := &Config{
 				ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
 				Metrics:           test.metrics,
+				Include:           test.include,
+				Exclude:           test.exclude,
 			}
 			factory := NewFactory()
 			mgp, err := factory.CreateMetricsProcessor(
@@ -173,3 +250,249 @@ func generateTestMetrics(tm testMetric) pdata.Metrics {
 
 	return md
 }
+
+func TestCumulativeToDeltaProcessorIntSum(t *testing.T) {
+	next := new(consumertest.MetricsSink)
+	ctdp := newTestProcessor(t, &Config{ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr))}, next)
+
+	md := pdata.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("int_sum")
+	m.SetDataType(pdata.MetricDataTypeSum)
+	m.Sum().SetIsMonotonic(true)
+	m.Sum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	for _, value := range []int64{10, 25, 40} {
+		m.Sum().DataPoints().AppendEmpty().SetIntVal(value)
+	}
+
+	require.NoError(t, ctdp.ConsumeMetrics(context.Background(), md))
+
+	got := next.AllMetrics()[0].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0)
+	assert.Equal(t, pdata.AggregationTemporalityDelta, got.Sum().AggregationTemporality())
+	dps := got.Sum().DataPoints()
+	require.Equal(t, 3, dps.Len())
+	assert.Equal(t, int64(10), dps.At(0).IntVal())
+	assert.Equal(t, int64(15), dps.At(1).IntVal())
+	assert.Equal(t, int64(15), dps.At(2).IntVal())
+}
+
+func TestCumulativeToDeltaProcessorNonMonotonicSum(t *testing.T) {
+	md := generateTestMetrics(testMetric{
+		metricNames:  []string{"metric_1"},
+		metricValues: [][]float64{{100, 50}},
+		isCumulative: []bool{true},
+	})
+	md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Sum().SetIsMonotonic(false)
+
+	tests := []struct {
+		name        string
+		include     MetricFilter
+		wantConvert bool
+	}{
+		{
+			name:        "skipped_by_default",
+			wantConvert: false,
+		},
+		{
+			name:        "converted_when_included",
+			include:     MetricFilter{Metrics: []string{"metric_1"}},
+			wantConvert: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := new(consumertest.MetricsSink)
+			ctdp := newTestProcessor(t, &Config{
+				ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+				Include:           test.include,
+			}, next)
+
+			require.NoError(t, ctdp.ConsumeMetrics(context.Background(), md.Clone()))
+
+			sum := next.AllMetrics()[0].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Sum()
+			if test.wantConvert {
+				assert.Equal(t, pdata.AggregationTemporalityDelta, sum.AggregationTemporality())
+				assert.Equal(t, float64(-50), sum.DataPoints().At(1).DoubleVal())
+			} else {
+				assert.Equal(t, pdata.AggregationTemporalityCumulative, sum.AggregationTemporality())
+				assert.Equal(t, float64(50), sum.DataPoints().At(1).DoubleVal())
+			}
+		})
+	}
+}
+
+func TestCumulativeToDeltaProcessorHistogram(t *testing.T) {
+	next := new(consumertest.MetricsSink)
+	ctdp := newTestProcessor(t, &Config{ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr))}, next)
+
+	now := time.Now()
+	md := pdata.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("histogram")
+	m.SetDataType(pdata.MetricDataTypeHistogram)
+	m.Histogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	points := []struct {
+		count        uint64
+		sum          float64
+		bucketCounts []uint64
+	}{
+		{count: 3, sum: 30, bucketCounts: []uint64{1, 2}},
+		{count: 7, sum: 80, bucketCounts: []uint64{3, 4}},
+		// Reset of the histogram, expect the point to be kept as is.
+		{count: 2, sum: 5, bucketCounts: []uint64{2, 0}},
+	}
+	for i, p := range points {
+		dp := m.Histogram().DataPoints().AppendEmpty()
+		dp.SetTimestamp(pdata.TimestampFromTime(now.Add(time.Duration(i) * time.Second)))
+		dp.SetCount(p.count)
+		dp.SetSum(p.sum)
+		dp.SetExplicitBounds([]float64{10})
+		dp.SetBucketCounts(p.bucketCounts)
+	}
+
+	require.NoError(t, ctdp.ConsumeMetrics(context.Background(), md))
+
+	histogram := next.AllMetrics()[0].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Histogram()
+	assert.Equal(t, pdata.AggregationTemporalityDelta, histogram.AggregationTemporality())
+	dps := histogram.DataPoints()
+	require.Equal(t, 3, dps.Len())
+
+	assert.Equal(t, uint64(3), dps.At(0).Count())
+	assert.Equal(t, float64(30), dps.At(0).Sum())
+	assert.Equal(t, []uint64{1, 2}, dps.At(0).BucketCounts())
+
+	assert.Equal(t, uint64(4), dps.At(1).Count())
+	assert.Equal(t, float64(50), dps.At(1).Sum())
+	assert.Equal(t, []uint64{2, 2}, dps.At(1).BucketCounts())
+	assert.Equal(t, pdata.TimestampFromTime(now), dps.At(1).StartTimestamp())
+
+	assert.Equal(t, uint64(2), dps.At(2).Count())
+	assert.Equal(t, float64(5), dps.At(2).Sum())
+	assert.Equal(t, []uint64{2, 0}, dps.At(2).BucketCounts())
+}
+
+func TestCumulativeToDeltaProcessorHistogramKeepsACopy(t *testing.T) {
+	next := new(consumertest.MetricsSink)
+	ctdp := newTestProcessor(t, &Config{ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr))}, next)
+
+	now := time.Now()
+	newHistogram := func(i int, bucketCounts []uint64) pdata.Metrics {
+		md := pdata.NewMetrics()
+		m := md.ResourceMetrics().AppendEmpty().InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+		m.SetName("histogram")
+		m.SetDataType(pdata.MetricDataTypeHistogram)
+		m.Histogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+		dp := m.Histogram().DataPoints().AppendEmpty()
+		dp.SetTimestamp(pdata.TimestampFromTime(now.Add(time.Duration(i) * time.Second)))
+		dp.SetCount(3)
+		dp.SetExplicitBounds([]float64{10})
+		dp.SetBucketCounts(bucketCounts)
+		return md
+	}
+
+	require.NoError(t, ctdp.ConsumeMetrics(context.Background(), newHistogram(0, []uint64{1, 2})))
+
+	// a next consumer modifying the exported point must not change the stored value
+	exported := next.AllMetrics()[0].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Histogram()
+	exported.DataPoints().At(0).BucketCounts()[0] = 100
+
+	require.NoError(t, ctdp.ConsumeMetrics(context.Background(), newHistogram(1, []uint64{2, 3})))
+
+	histogram := next.AllMetrics()[1].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Histogram()
+	assert.Equal(t, []uint64{1, 1}, histogram.DataPoints().At(0).BucketCounts())
+}
+
+func TestCumulativeToDeltaProcessorValueTypeChange(t *testing.T) {
+	next := new(consumertest.MetricsSink)
+	ctdp := newTestProcessor(t, &Config{ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr))}, next)
+
+	md := pdata.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("sum")
+	m.SetDataType(pdata.MetricDataTypeSum)
+	m.Sum().SetIsMonotonic(true)
+	m.Sum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.Sum().DataPoints().AppendEmpty().SetIntVal(10)
+	// The series turns into a double sum, which is handled as a reset
+	m.Sum().DataPoints().AppendEmpty().SetDoubleVal(25)
+	m.Sum().DataPoints().AppendEmpty().SetDoubleVal(40)
+	// And back into an int sum
+	m.Sum().DataPoints().AppendEmpty().SetIntVal(50)
+
+	require.NoError(t, ctdp.ConsumeMetrics(context.Background(), md))
+
+	dps := next.AllMetrics()[0].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Sum().DataPoints()
+	require.Equal(t, 4, dps.Len())
+	assert.Equal(t, int64(10), dps.At(0).IntVal())
+	assert.Equal(t, float64(25), dps.At(1).DoubleVal())
+	assert.Equal(t, float64(15), dps.At(2).DoubleVal())
+	assert.Equal(t, int64(50), dps.At(3).IntVal())
+}
+
+func TestCumulativeToDeltaProcessorSeriesIdentity(t *testing.T) {
+	next := new(consumertest.MetricsSink)
+	ctdp := newTestProcessor(t, &Config{ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr))}, next)
+
+	// The same sum from two resources, and a histogram with the same name and labels from a third one
+	md := pdata.NewMetrics()
+	for _, host := range []string{"host-a", "host-b"} {
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().InsertString("host.name", host)
+		m := rm.InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+		m.SetName("requests")
+		m.SetDataType(pdata.MetricDataTypeSum)
+		m.Sum().SetIsMonotonic(true)
+		m.Sum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+		m.Sum().DataPoints().AppendEmpty().SetDoubleVal(100)
+		m.Sum().DataPoints().AppendEmpty().SetDoubleVal(150)
+	}
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().InsertString("host.name", "host-c")
+	m := rm.InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("requests")
+	m.SetDataType(pdata.MetricDataTypeHistogram)
+	m.Histogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	for _, count := range []uint64{3, 5} {
+		dp := m.Histogram().DataPoints().AppendEmpty()
+		dp.SetCount(count)
+		dp.SetExplicitBounds([]float64{10})
+		dp.SetBucketCounts([]uint64{count, 0})
+	}
+
+	require.NoError(t, ctdp.ConsumeMetrics(context.Background(), md))
+
+	rms := next.AllMetrics()[0].ResourceMetrics()
+	for i := 0; i < 2; i++ {
+		dps := rms.At(i).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Sum().DataPoints()
+		assert.Equal(t, float64(100), dps.At(0).DoubleVal())
+		assert.Equal(t, float64(50), dps.At(1).DoubleVal())
+	}
+	dps := rms.At(2).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Histogram().DataPoints()
+	assert.Equal(t, uint64(3), dps.At(0).Count())
+	assert.Equal(t, uint64(2), dps.At(1).Count())
+}
+
+func TestDeltaCalculatorTypeMismatch(t *testing.T) {
+	calculator := newDeltaCalculator()
+	now := time.Now()
+
+	_, _ = calculator.Calculate("series", nil, int64(10), now)
+	for _, value := range []interface{}{float64(20), histogramValue{count: 3, bucketCounts: []uint64{3}}, int64(40)} {
+		result, done := calculator.Calculate("series", nil, value, now)
+		require.True(t, done)
+		assert.Equal(t, value, result.(delta).value)
+	}
+}
+
+func newTestProcessor(t *testing.T, cfg *Config, next *consumertest.MetricsSink) component.MetricsProcessor {
+	mp, err := NewFactory().CreateMetricsProcessor(
+		context.Background(),
+		componenttest.NewNopProcessorCreateSettings(),
+		cfg,
+		next,
+	)
+	require.NoError(t, err)
+	require.NoError(t, mp.Start(context.Background(), componenttest.NewNopHost()))
+	return mp
+}
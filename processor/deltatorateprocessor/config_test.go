@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,12 +40,35 @@ func TestLoadingFullConfig(t *testing.T) {
 					"metric1",
 					"metric2",
 				},
+				UnitSuffix: defaultUnitSuffix,
+				RateWindow: defaultRateWindow,
+			},
+		},
+		{
+			configFile: "config_include_exclude.yaml",
+			expCfg: &Config{
+				ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+				Include: MetricFilter{
+					Metrics: []string{
+						".*_total$",
+						"^system\\.",
+					},
+					MatchType: RegexpMatchType,
+				},
+				Exclude: MetricFilter{
+					Metrics: []string{
+						"system.cpu.time",
+					},
+					MatchType: StrictMatchType,
+				},
+				UnitSuffix: "/min",
+				RateWindow: time.Minute,
 			},
 		},
 	}
 
 	for _, test := range tests {
-		t.Run(test.expCfg.ID().String(), func(t *testing.T) {
+		t.Run(test.configFile, func(t *testing.T) {
 			factories, err := componenttest.NopFactories()
 			assert.NoError(t, err)
 
@@ -73,7 +97,22 @@ func TestValidateConfig(t *testing.T) {
 		{
 			configName:   "config_missing_name.yaml",
 			succeed:      false,
-			errorMessage: "metric names are missing",
+			errorMessage: "metric names are missing, list the metrics to convert in include",
+		},
+		{
+			configName:   "config_invalid_regexp.yaml",
+			succeed:      false,
+			errorMessage: "invalid include: invalid regexp \"(metric\": error parsing regexp: missing closing ): `(metric`",
+		},
+		{
+			configName:   "config_metrics_and_include.yaml",
+			succeed:      false,
+			errorMessage: "metrics and include can't be used together, use include only",
+		},
+		{
+			configName:   "config_invalid_rate_window.yaml",
+			succeed:      false,
+			errorMessage: "rate_window must be positive, got 0s",
 		},
 	}
 
@@ -13,5 +13,5 @@
 // limitations under the License.
 
 // package deltatorateprocessor implements a processor which
-// converts delta sum metrics to rates.
+// converts delta sum, cumulative sum and gauge metrics to rates.
 package deltatorateprocessor
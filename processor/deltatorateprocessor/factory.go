@@ -17,6 +17,7 @@ package deltatorateprocessor
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
@@ -29,6 +30,11 @@ const (
 	typeStr = "deltatorate"
 )
 
+const (
+	defaultUnitSuffix = "/s"
+	defaultRateWindow = time.Second
+)
+
 var processorCapabilities = consumer.Capabilities{MutatesData: true}
 
 // NewFactory returns a new factory for the Delta to Rate processor.
@@ -42,6 +48,8 @@ func NewFactory() component.ProcessorFactory {
 func createDefaultConfig() config.Processor {
 	return &Config{
 		ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+		UnitSuffix:        defaultUnitSuffix,
+		RateWindow:        defaultRateWindow,
 	}
 }
 
@@ -56,7 +64,9 @@ func createMetricsProcessor(
 		return nil, fmt.Errorf("configuration parsing error")
 	}
 
-	processorConfig.Validate()
+	if err := processorConfig.Validate(); err != nil {
+		return nil, err
+	}
 	metricsProcessor := newDeltaToRateProcessor(processorConfig, params.Logger)
 
 	return processorhelper.NewMetricsProcessor(
@@ -39,6 +39,8 @@ func TestCreateDefaultConfig(t *testing.T) {
 	cfg := factory.CreateDefaultConfig()
 	assert.Equal(t, cfg, &Config{
 		ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+		UnitSuffix:        defaultUnitSuffix,
+		RateWindow:        defaultRateWindow,
 	})
 	assert.NoError(t, configcheck.ValidateConfig(cfg))
 }
@@ -90,3 +92,20 @@ func TestCreateProcessors(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateMetricsProcessorInvalidRegexp(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Include = MetricFilter{
+		Metrics:   []string{"("},
+		MatchType: RegexpMatchType,
+	}
+
+	mp, err := factory.CreateMetricsProcessor(
+		context.Background(),
+		componenttest.NewNopProcessorCreateSettings(),
+		cfg,
+		consumertest.NewNop())
+	assert.Error(t, err)
+	assert.Nil(t, mp)
+}
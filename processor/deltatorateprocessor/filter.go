@@ -0,0 +1,51 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deltatorateprocessor
+
+import (
+	"regexp"
+)
+
+// metricMatcher matches metric names against a MetricFilter.
+type metricMatcher struct {
+	names   map[string]struct{}
+	regexps []*regexp.Regexp
+}
+
+// newMetricMatcher builds the matcher of the given filter, which is expected to have been validated.
+func newMetricMatcher(filter MetricFilter) *metricMatcher {
+	m := &metricMatcher{names: make(map[string]struct{})}
+	for _, metric := range filter.Metrics {
+		if filter.MatchType == RegexpMatchType {
+			m.regexps = append(m.regexps, regexp.MustCompile(metric))
+			continue
+		}
+		m.names[metric] = struct{}{}
+	}
+	return m
+}
+
+// matches returns whether the given metric name is matched.
+func (m *metricMatcher) matches(name string) bool {
+	if _, ok := m.names[name]; ok {
+		return true
+	}
+	for _, re := range m.regexps {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,7 @@ package deltatorateprocessor
 
 import (
 	"context"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/model/pdata"
@@ -23,14 +24,27 @@ import (
 )
 
 type deltaToRateProcessor struct {
-	metrics []string
-	logger  *zap.Logger
+	include    *metricMatcher
+	exclude    *metricMatcher
+	unitSuffix string
+	rateWindow time.Duration
+	tracker    *seriesTracker
+	logger     *zap.Logger
 }
 
 func newDeltaToRateProcessor(config *Config, logger *zap.Logger) *deltaToRateProcessor {
+	include := config.Include
+	if len(config.Metrics) > 0 {
+		include = MetricFilter{Metrics: config.Metrics, MatchType: StrictMatchType}
+	}
+
 	return &deltaToRateProcessor{
-		metrics: config.Metrics,
-		logger:  logger,
+		include:    newMetricMatcher(include),
+		exclude:    newMetricMatcher(config.Exclude),
+		unitSuffix: config.UnitSuffix,
+		rateWindow: config.RateWindow,
+		tracker:    newSeriesTracker(),
+		logger:     logger,
 	}
 }
 
@@ -41,9 +55,132 @@ func (dtrp *deltaToRateProcessor) Start(context.Context, component.Host) error {
 
 // processMetrics implements the ProcessMetricsFunc type.
 func (dtrp *deltaToRateProcessor) processMetrics(_ context.Context, md pdata.Metrics) (pdata.Metrics, error) {
+	now := time.Now()
+	resourceMetricsSlice := md.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		rm := resourceMetricsSlice.At(i)
+		resourceKey := attributesKey(rm.Resource().Attributes())
+		ilms := rm.InstrumentationLibraryMetrics()
+		for j := 0; j < ilms.Len(); j++ {
+			// Metrics without any data point a rate can be computed for yet are dropped
+			ilms.At(j).Metrics().RemoveIf(func(metric pdata.Metric) bool {
+				if !dtrp.shouldConvert(metric) {
+					return false
+				}
+				dtrp.convertToRate(resourceKey, metric, now)
+				return metric.Gauge().DataPoints().Len() == 0
+			})
+		}
+	}
+	dtrp.tracker.removeStale(now)
 	return md, nil
 }
 
+// shouldConvert returns whether the metric is a sum or gauge to convert, matched by the included metrics and
+// not by the excluded ones.
+func (dtrp *deltaToRateProcessor) shouldConvert(metric pdata.Metric) bool {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeSum, pdata.MetricDataTypeGauge:
+	default:
+		return false
+	}
+	return dtrp.include.matches(metric.Name()) && !dtrp.exclude.matches(metric.Name())
+}
+
+// convertToRate replaces the metric by a gauge of the rates of its data points, suffixing its unit.
+// Data points the rate can't be computed for are dropped.
+func (dtrp *deltaToRateProcessor) convertToRate(resourceKey string, metric pdata.Metric, now time.Time) {
+	var dataPoints pdata.NumberDataPointSlice
+	rate := dtrp.gaugeRate
+	switch metric.DataType() {
+	case pdata.MetricDataTypeSum:
+		dataPoints = metric.Sum().DataPoints()
+		rate = dtrp.deltaRate
+		if metric.Sum().AggregationTemporality() == pdata.AggregationTemporalityCumulative {
+			rate = dtrp.cumulativeRate
+		}
+	case pdata.MetricDataTypeGauge:
+		dataPoints = metric.Gauge().DataPoints()
+	}
+
+	rates := pdata.NewNumberDataPointSlice()
+	for i := 0; i < dataPoints.Len(); i++ {
+		dp := dataPoints.At(i)
+		point := seriesPoint{start: dp.StartTimestamp(), timestamp: dp.Timestamp(), value: numberValue(dp)}
+		key := seriesKey{resource: resourceKey, metric: metric.Name(), labels: labelsKey(dp.LabelsMap())}
+		prev, hasPrev := dtrp.tracker.update(key, point, now)
+
+		value, start, ok := rate(point, prev, hasPrev)
+		if !ok {
+			continue
+		}
+		rateDataPoint := rates.AppendEmpty()
+		dp.CopyTo(rateDataPoint)
+		rateDataPoint.SetStartTimestamp(start)
+		rateDataPoint.SetDoubleVal(value)
+	}
+
+	unit := metric.Unit()
+	if unit == "" {
+		unit = "1"
+	}
+	metric.SetUnit(unit + dtrp.unitSuffix)
+	metric.SetDataType(pdata.MetricDataTypeGauge)
+	rates.MoveAndAppendTo(metric.Gauge().DataPoints())
+}
+
+// deltaRate computes the rate of a delta sum point over its interval, or since the previous point of the
+// series when the point has no start timestamp.
+func (dtrp *deltaToRateProcessor) deltaRate(point, prev seriesPoint, hasPrev bool) (float64, pdata.Timestamp, bool) {
+	start := point.start
+	if start == 0 || start >= point.timestamp {
+		if !hasPrev {
+			return 0, 0, false
+		}
+		start = prev.timestamp
+	}
+	return dtrp.perWindow(point.value, start, point.timestamp)
+}
+
+// cumulativeRate computes the rate of a cumulative sum point since the previous point of the series. When the
+// sum has been reset, detected by a new start timestamp, the rate is computed since this start. When only
+// detected by a lower value, the whole value is considered as the increase since the previous point.
+func (dtrp *deltaToRateProcessor) cumulativeRate(point, prev seriesPoint, hasPrev bool) (float64, pdata.Timestamp, bool) {
+	if !hasPrev {
+		return 0, 0, false
+	}
+	if point.start != prev.start && point.start != 0 {
+		return dtrp.perWindow(point.value, point.start, point.timestamp)
+	}
+	if point.value < prev.value {
+		return dtrp.perWindow(point.value, prev.timestamp, point.timestamp)
+	}
+	return dtrp.perWindow(point.value-prev.value, prev.timestamp, point.timestamp)
+}
+
+// gaugeRate computes the rate of change of a gauge point since the previous point of the series.
+func (dtrp *deltaToRateProcessor) gaugeRate(point, prev seriesPoint, hasPrev bool) (float64, pdata.Timestamp, bool) {
+	if !hasPrev {
+		return 0, 0, false
+	}
+	return dtrp.perWindow(point.value-prev.value, prev.timestamp, point.timestamp)
+}
+
+// perWindow returns the rate of the value increase between the start and end timestamps, per rate window.
+func (dtrp *deltaToRateProcessor) perWindow(increase float64, start, end pdata.Timestamp) (float64, pdata.Timestamp, bool) {
+	if end <= start {
+		return 0, 0, false
+	}
+	return increase * float64(dtrp.rateWindow) / float64(end-start), start, true
+}
+
+func numberValue(dp pdata.NumberDataPoint) float64 {
+	if dp.Type() == pdata.MetricValueTypeInt {
+		return float64(dp.IntVal())
+	}
+	return dp.DoubleVal()
+}
+
 // Shutdown is invoked during service shutdown.
 func (dtrp *deltaToRateProcessor) Shutdown(context.Context) error {
 	return nil
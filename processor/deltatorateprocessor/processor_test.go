@@ -0,0 +1,306 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deltatorateprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+type testPoint struct {
+	start  time.Duration
+	offset time.Duration
+	value  float64
+}
+
+var testStart = time.Unix(1628000000, 0)
+
+func generateTestMetrics(name string, dataType pdata.MetricDataType, temporality pdata.AggregationTemporality, points ...testPoint) pdata.Metrics {
+	md := pdata.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName(name)
+	m.SetUnit("By")
+	m.SetDataType(dataType)
+
+	var dps pdata.NumberDataPointSlice
+	if dataType == pdata.MetricDataTypeSum {
+		m.Sum().SetIsMonotonic(true)
+		m.Sum().SetAggregationTemporality(temporality)
+		dps = m.Sum().DataPoints()
+	} else {
+		dps = m.Gauge().DataPoints()
+	}
+	for _, point := range points {
+		dp := dps.AppendEmpty()
+		if point.start >= 0 {
+			dp.SetStartTimestamp(pdata.TimestampFromTime(testStart.Add(point.start)))
+		}
+		dp.SetTimestamp(pdata.TimestampFromTime(testStart.Add(point.offset)))
+		dp.SetDoubleVal(point.value)
+	}
+	return md
+}
+
+func TestDeltaToRateProcessor(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		dataType    pdata.MetricDataType
+		temporality pdata.AggregationTemporality
+		in          [][]testPoint
+		wantUnit    string
+		want        []float64
+		wantStarts  []time.Duration
+	}{
+		{
+			name:        "delta_sum",
+			dataType:    pdata.MetricDataTypeSum,
+			temporality: pdata.AggregationTemporalityDelta,
+			in:          [][]testPoint{{{start: 0, offset: 10 * time.Second, value: 50}, {start: 10 * time.Second, offset: 12 * time.Second, value: 5}}},
+			wantUnit:    "By/s",
+			want:        []float64{5, 2.5},
+			wantStarts:  []time.Duration{0, 10 * time.Second},
+		},
+		{
+			name:        "delta_sum_without_start_timestamp",
+			dataType:    pdata.MetricDataTypeSum,
+			temporality: pdata.AggregationTemporalityDelta,
+			// The first point has nothing to compute a rate since, so it is dropped
+			in:         [][]testPoint{{{start: -1, offset: 10 * time.Second, value: 50}}, {{start: -1, offset: 20 * time.Second, value: 20}}},
+			wantUnit:   "By/s",
+			want:       []float64{2},
+			wantStarts: []time.Duration{10 * time.Second},
+		},
+		{
+			name:        "cumulative_sum",
+			dataType:    pdata.MetricDataTypeSum,
+			temporality: pdata.AggregationTemporalityCumulative,
+			in: [][]testPoint{
+				{{start: 0, offset: 10 * time.Second, value: 100}, {start: 0, offset: 20 * time.Second, value: 150}},
+				{{start: 0, offset: 30 * time.Second, value: 160}},
+			},
+			wantUnit:   "By/s",
+			want:       []float64{5, 1},
+			wantStarts: []time.Duration{10 * time.Second, 20 * time.Second},
+		},
+		{
+			name:        "cumulative_sum_reset",
+			dataType:    pdata.MetricDataTypeSum,
+			temporality: pdata.AggregationTemporalityCumulative,
+			in: [][]testPoint{{
+				{start: 0, offset: 10 * time.Second, value: 100},
+				// Reset detected by the new start timestamp
+				{start: 15 * time.Second, offset: 20 * time.Second, value: 150},
+				// Reset only detected by the lower value, as the source didn't report a new start timestamp
+				{start: 15 * time.Second, offset: 30 * time.Second, value: 40},
+			}},
+			wantUnit:   "By/s",
+			want:       []float64{30, 4},
+			wantStarts: []time.Duration{15 * time.Second, 20 * time.Second},
+		},
+		{
+			name:       "gauge",
+			dataType:   pdata.MetricDataTypeGauge,
+			in:         [][]testPoint{{{start: -1, offset: 10 * time.Second, value: 100}, {start: -1, offset: 20 * time.Second, value: 50}}},
+			wantUnit:   "By/s",
+			want:       []float64{-5},
+			wantStarts: []time.Duration{10 * time.Second},
+		},
+		{
+			name: "rate_window_and_unit_suffix",
+			cfg: Config{
+				UnitSuffix: "/min",
+				RateWindow: time.Minute,
+			},
+			dataType:    pdata.MetricDataTypeSum,
+			temporality: pdata.AggregationTemporalityDelta,
+			in:          [][]testPoint{{{start: 0, offset: 10 * time.Second, value: 50}}},
+			wantUnit:    "By/min",
+			want:        []float64{300},
+			wantStarts:  []time.Duration{0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := test.cfg
+			cfg.ProcessorSettings = config.NewProcessorSettings(config.NewID(typeStr))
+			if cfg.UnitSuffix == "" {
+				cfg.UnitSuffix = defaultUnitSuffix
+			}
+			if cfg.RateWindow == 0 {
+				cfg.RateWindow = defaultRateWindow
+			}
+			cfg.Include = MetricFilter{Metrics: []string{"metric"}}
+			next := new(consumertest.MetricsSink)
+			dtrp := newTestProcessor(t, &cfg, next)
+
+			for _, points := range test.in {
+				require.NoError(t, dtrp.ConsumeMetrics(context.Background(), generateTestMetrics("metric", test.dataType, test.temporality, points...)))
+			}
+
+			var got []float64
+			var gotStarts []time.Duration
+			for _, md := range next.AllMetrics() {
+				ms := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+				if ms.Len() == 0 {
+					continue
+				}
+				m := ms.At(0)
+				require.Equal(t, pdata.MetricDataTypeGauge, m.DataType())
+				assert.Equal(t, test.wantUnit, m.Unit())
+				for i := 0; i < m.Gauge().DataPoints().Len(); i++ {
+					dp := m.Gauge().DataPoints().At(i)
+					got = append(got, dp.DoubleVal())
+					gotStarts = append(gotStarts, dp.StartTimestamp().AsTime().Sub(testStart))
+				}
+			}
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantStarts, gotStarts)
+		})
+	}
+}
+
+func TestDeltaToRateProcessorFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		include     MetricFilter
+		exclude     MetricFilter
+		dataType    pdata.MetricDataType
+		temporality pdata.AggregationTemporality
+		wantConvert bool
+	}{
+		{
+			name:        "include_strict",
+			include:     MetricFilter{Metrics: []string{"metric_1"}},
+			dataType:    pdata.MetricDataTypeSum,
+			temporality: pdata.AggregationTemporalityDelta,
+			wantConvert: true,
+		},
+		{
+			name:        "include_regexp",
+			include:     MetricFilter{Metrics: []string{"^metric_.*"}, MatchType: RegexpMatchType},
+			dataType:    pdata.MetricDataTypeSum,
+			temporality: pdata.AggregationTemporalityDelta,
+			wantConvert: true,
+		},
+		{
+			name:        "not_included",
+			include:     MetricFilter{Metrics: []string{"^other_.*"}, MatchType: RegexpMatchType},
+			dataType:    pdata.MetricDataTypeSum,
+			temporality: pdata.AggregationTemporalityDelta,
+			wantConvert: false,
+		},
+		{
+			name:        "exclude_regexp",
+			include:     MetricFilter{Metrics: []string{".*"}, MatchType: RegexpMatchType},
+			exclude:     MetricFilter{Metrics: []string{".*_1$"}, MatchType: RegexpMatchType},
+			dataType:    pdata.MetricDataTypeSum,
+			temporality: pdata.AggregationTemporalityDelta,
+			wantConvert: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := new(consumertest.MetricsSink)
+			dtrp := newTestProcessor(t, &Config{
+				ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+				Include:           test.include,
+				Exclude:           test.exclude,
+				UnitSuffix:        defaultUnitSuffix,
+				RateWindow:        defaultRateWindow,
+			}, next)
+
+			md := generateTestMetrics("metric_1", test.dataType, test.temporality, testPoint{start: 0, offset: 10 * time.Second, value: 50})
+			require.NoError(t, dtrp.ConsumeMetrics(context.Background(), md))
+
+			m := next.AllMetrics()[0].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0)
+			if test.wantConvert {
+				assert.Equal(t, pdata.MetricDataTypeGauge, m.DataType())
+				assert.Equal(t, "By/s", m.Unit())
+				assert.Equal(t, float64(5), m.Gauge().DataPoints().At(0).DoubleVal())
+			} else {
+				assert.Equal(t, test.dataType, m.DataType())
+				assert.Equal(t, "By", m.Unit())
+			}
+		})
+	}
+}
+
+func TestDeltaToRateProcessorSeries(t *testing.T) {
+	next := new(consumertest.MetricsSink)
+	dtrp := newTestProcessor(t, &Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+		Include:           MetricFilter{Metrics: []string{".*"}, MatchType: RegexpMatchType},
+		UnitSuffix:        defaultUnitSuffix,
+		RateWindow:        defaultRateWindow,
+	}, next)
+
+	// Series are tracked by resource and labels
+	for i, value := range []float64{10, 20} {
+		md := pdata.NewMetrics()
+		for _, host := range []string{"host1", "host2"} {
+			rm := md.ResourceMetrics().AppendEmpty()
+			rm.Resource().Attributes().InsertString("host.name", host)
+			m := rm.InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+			m.SetName("metric")
+			m.SetDataType(pdata.MetricDataTypeSum)
+			m.Sum().SetIsMonotonic(true)
+			m.Sum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+			for j, label := range []string{"a", "b"} {
+				dp := m.Sum().DataPoints().AppendEmpty()
+				dp.LabelsMap().Insert("label", label)
+				dp.SetTimestamp(pdata.TimestampFromTime(testStart.Add(time.Duration(i) * 10 * time.Second)))
+				dp.SetIntVal(int64(value) * int64(j+1))
+			}
+		}
+		require.NoError(t, dtrp.ConsumeMetrics(context.Background(), md))
+	}
+
+	got := next.AllMetrics()
+	require.Equal(t, 2, len(got))
+	// No rates can be computed from the first points
+	for i := 0; i < got[0].ResourceMetrics().Len(); i++ {
+		assert.Equal(t, 0, got[0].ResourceMetrics().At(i).InstrumentationLibraryMetrics().At(0).Metrics().Len())
+	}
+	for i := 0; i < got[1].ResourceMetrics().Len(); i++ {
+		dps := got[1].ResourceMetrics().At(i).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Gauge().DataPoints()
+		require.Equal(t, 2, dps.Len())
+		assert.Equal(t, float64(1), dps.At(0).DoubleVal())
+		assert.Equal(t, float64(2), dps.At(1).DoubleVal())
+	}
+}
+
+func newTestProcessor(t *testing.T, cfg *Config, next *consumertest.MetricsSink) component.MetricsProcessor {
+	mp, err := NewFactory().CreateMetricsProcessor(
+		context.Background(),
+		componenttest.NewNopProcessorCreateSettings(),
+		cfg,
+		next,
+	)
+	require.NoError(t, err)
+	require.NoError(t, mp.Start(context.Background(), componenttest.NewNopHost()))
+	return mp
+}
@@ -0,0 +1,135 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deltatorateprocessor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/model/pdata"
+	tracetranslator "go.opentelemetry.io/collector/translator/trace"
+)
+
+// staleSeriesTimeout is the duration after which the time series that haven't been updated are forgotten.
+const staleSeriesTimeout = 5 * time.Minute
+
+// seriesKey identifies a time series by its resource, metric name and labels.
+type seriesKey struct {
+	resource string
+	metric   string
+	labels   string
+}
+
+// seriesPoint is a point of a time series, as needed to compute rates.
+type seriesPoint struct {
+	start     pdata.Timestamp
+	timestamp pdata.Timestamp
+	value     float64
+}
+
+type trackedSeries struct {
+	last    seriesPoint
+	updated time.Time
+}
+
+// seriesTracker keeps the last point of the time series, so that rates can be computed between consecutive points.
+type seriesTracker struct {
+	mu        sync.Mutex
+	series    map[seriesKey]*trackedSeries
+	lastSweep time.Time
+}
+
+func newSeriesTracker() *seriesTracker {
+	return &seriesTracker{
+		series:    make(map[seriesKey]*trackedSeries),
+		lastSweep: time.Now(),
+	}
+}
+
+// update records the point of the time series, unless it is older than the last one, and returns the previous
+// last point of the series, if any.
+func (t *seriesTracker) update(key seriesKey, point seriesPoint, now time.Time) (seriesPoint, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked, ok := t.series[key]
+	if !ok {
+		t.series[key] = &trackedSeries{last: point, updated: now}
+		return seriesPoint{}, false
+	}
+
+	prev := tracked.last
+	if point.timestamp > prev.timestamp {
+		tracked.last = point
+		tracked.updated = now
+	}
+	return prev, true
+}
+
+// removeStale forgets the time series that haven't been updated for staleSeriesTimeout. The series are only
+// swept once per timeout.
+func (t *seriesTracker) removeStale(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if now.Sub(t.lastSweep) < staleSeriesTimeout {
+		return
+	}
+	for key, tracked := range t.series {
+		if now.Sub(tracked.updated) >= staleSeriesTimeout {
+			delete(t.series, key)
+		}
+	}
+	t.lastSweep = now
+}
+
+// attributesKey builds a key identifying the attributes regardless of their order.
+func attributesKey(attributes pdata.AttributeMap) string {
+	values := make(map[string]string, attributes.Len())
+	attributes.Range(func(k string, v pdata.AttributeValue) bool {
+		values[k] = tracetranslator.AttributeValueToString(v)
+		return true
+	})
+	return mapKey(values)
+}
+
+// labelsKey builds a key identifying the labels regardless of their order.
+func labelsKey(labels pdata.StringMap) string {
+	values := make(map[string]string, labels.Len())
+	labels.Range(func(k string, v string) bool {
+		values[k] = v
+		return true
+	})
+	return mapKey(values)
+}
+
+func mapKey(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(values[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
@@ -54,6 +54,63 @@ func matchingInstrumentationLibraryLogs(rl pdata.ResourceLogs, library pdata.Ins
 	return ill
 }
 
+// matchingInstrumentationLibraryMetrics searches for a pdata.InstrumentationLibraryMetrics instance matching
+// given InstrumentationLibrary. If nothing is found, it creates a new one
+func matchingInstrumentationLibraryMetrics(rm pdata.ResourceMetrics, library pdata.InstrumentationLibrary) pdata.InstrumentationLibraryMetrics {
+	ilms := rm.InstrumentationLibraryMetrics()
+	for i := 0; i < ilms.Len(); i++ {
+		ilm := ilms.At(i)
+		if instrumentationLibrariesEqual(ilm.InstrumentationLibrary(), library) {
+			return ilm
+		}
+	}
+
+	ilm := ilms.AppendEmpty()
+	library.CopyTo(ilm.InstrumentationLibrary())
+	return ilm
+}
+
+// metricsEqual verifies if given metrics have the same name, description, unit and type,
+// so that their data points can be kept in a single metric
+func metricsEqual(m1, m2 pdata.Metric) bool {
+	if m1.Name() != m2.Name() || m1.Description() != m2.Description() || m1.Unit() != m2.Unit() || m1.DataType() != m2.DataType() {
+		return false
+	}
+
+	switch m1.DataType() {
+	case pdata.MetricDataTypeSum:
+		return m1.Sum().AggregationTemporality() == m2.Sum().AggregationTemporality() && m1.Sum().IsMonotonic() == m2.Sum().IsMonotonic()
+	case pdata.MetricDataTypeHistogram:
+		return m1.Histogram().AggregationTemporality() == m2.Histogram().AggregationTemporality()
+	}
+	return true
+}
+
+// matchingMetric searches for a pdata.Metric instance matching given metric. If nothing is found, it creates
+// a new one with the same description and no data points
+func matchingMetric(ilm pdata.InstrumentationLibraryMetrics, metric pdata.Metric) pdata.Metric {
+	metrics := ilm.Metrics()
+	for i := 0; i < metrics.Len(); i++ {
+		if metricsEqual(metrics.At(i), metric) {
+			return metrics.At(i)
+		}
+	}
+
+	m := metrics.AppendEmpty()
+	m.SetName(metric.Name())
+	m.SetDescription(metric.Description())
+	m.SetUnit(metric.Unit())
+	m.SetDataType(metric.DataType())
+	switch metric.DataType() {
+	case pdata.MetricDataTypeSum:
+		m.Sum().SetAggregationTemporality(metric.Sum().AggregationTemporality())
+		m.Sum().SetIsMonotonic(metric.Sum().IsMonotonic())
+	case pdata.MetricDataTypeHistogram:
+		m.Histogram().SetAggregationTemporality(metric.Histogram().AggregationTemporality())
+	}
+	return m
+}
+
 // spansGroupedByAttrs keeps all found grouping attributes for spans, together with the matching records
 type spansGroupedByAttrs struct {
 	pdata.ResourceSpansSlice
@@ -64,6 +121,11 @@ type logsGroupedByAttrs struct {
 	pdata.ResourceLogsSlice
 }
 
+// metricsGroupedByAttrs keeps all found grouping attributes for metrics, together with the matching data points
+type metricsGroupedByAttrs struct {
+	pdata.ResourceMetricsSlice
+}
+
 func newLogsGroupedByAttrs() *logsGroupedByAttrs {
 	return &logsGroupedByAttrs{
 		ResourceLogsSlice: pdata.NewResourceLogsSlice(),
@@ -98,6 +160,23 @@ func (sgba spansGroupedByAttrs) findGroup(baseResource pdata.Resource, attrs pda
 	return pdata.ResourceSpans{}, false
 }
 
+func newMetricsGroupedByAttrs() *metricsGroupedByAttrs {
+	return &metricsGroupedByAttrs{
+		ResourceMetricsSlice: pdata.NewResourceMetricsSlice(),
+	}
+}
+
+// findGroup searches for an existing pdata.ResourceMetrics that contains both the grouped attributes
+// and base resource attributes. Returns the matching pdata.ResourceMetrics and bool value which is set to true if found
+func (mgba metricsGroupedByAttrs) findGroup(baseResource pdata.Resource, attrs pdata.AttributeMap) (pdata.ResourceMetrics, bool) {
+	for i := 0; i < mgba.Len(); i++ {
+		if resourceMatches(mgba.At(i).Resource(), baseResource, attrs) {
+			return mgba.At(i), true
+		}
+	}
+	return pdata.ResourceMetrics{}, false
+}
+
 // resourceMatches verifies if given pdata.Resource matches a composition of another (base) resource and attributes
 func resourceMatches(res pdata.Resource, baseResource pdata.Resource, recordAttrs pdata.AttributeMap) bool {
 	baseAttrs := baseResource.Attributes()
@@ -128,10 +207,11 @@ func resourceMatches(res pdata.Resource, baseResource pdata.Resource, recordAttr
 				}
 			}
 
+			// The base resource value does not matter when it is overridden by the record-level one
 			v2, baseAttrFound := baseAttrs.Get(k1)
 			if baseAttrFound {
 				matchedBaseAttrs++
-				if !v1.Equal(v2) {
+				if !recordAttrFound && !v1.Equal(v2) {
 					matching = false
 					return true
 				}
@@ -187,3 +267,21 @@ func (lgba *logsGroupedByAttrs) attributeGroup(baseResource pdata.Resource, reco
 
 	return res
 }
+
+// attributeGroup searches for a group with matching attributes and returns it. If nothing is found, it is being created
+func (mgba *metricsGroupedByAttrs) attributeGroup(baseResource pdata.Resource, recordAttrs pdata.AttributeMap) pdata.ResourceMetrics {
+	res, found := mgba.findGroup(baseResource, recordAttrs)
+	if !found {
+		res = mgba.AppendEmpty()
+		baseResource.CopyTo(res.Resource())
+
+		// This prioritizes data point labels over resource attributes, if they overlap
+		attrs := res.Resource().Attributes()
+		recordAttrs.Range(func(k string, v pdata.AttributeValue) bool {
+			attrs.Upsert(k, v)
+			return true
+		})
+	}
+
+	return res
+}
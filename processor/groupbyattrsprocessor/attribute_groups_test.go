@@ -122,6 +122,24 @@ func TestResourceAttributeScenarios(t *testing.T) {
 	}
 }
 
+func TestResourceMatchingWithOverriddenAttributes(t *testing.T) {
+	groups := newSpansGroupedByAttrs()
+
+	baseResource := pdata.NewResource()
+	baseResource.Attributes().InsertString("somekey1", "some-value")
+	recordAttrs := pdata.NewAttributeMap()
+	recordAttrs.InsertString("somekey1", "replaced-value")
+
+	replacedResource := pdata.NewResource()
+	replacedResource.Attributes().InsertString("somekey1", "replaced-value")
+
+	// Both lead to the same resource, so they should end up in the same group
+	rs1 := groups.attributeGroup(replacedResource, pdata.NewAttributeMap())
+	rs2 := groups.attributeGroup(baseResource, recordAttrs)
+	assert.Equal(t, 1, groups.Len())
+	assert.Equal(t, rs1, rs2)
+}
+
 func TestInstrumentationLibraryMatching(t *testing.T) {
 	rl := pdata.NewResourceLogs()
 	rs := pdata.NewResourceSpans()
@@ -23,6 +23,7 @@ type Config struct {
 	config.ProcessorSettings `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct
 
 	// GroupByKeys describes the attribute names that are going to be used for grouping.
-	// Must include at least one attribute name.
+	// When empty, no attributes are grouped and the processor only compacts the records,
+	// merging matching resources and instrumentation libraries.
 	GroupByKeys []string `mapstructure:"keys"`
 }
@@ -47,4 +47,11 @@ func TestLoadConfig(t *testing.T) {
 			ProcessorSettings: config.NewProcessorSettings(config.NewIDWithName(typeStr, "custom")),
 			GroupByKeys:       []string{"key1", "key2"},
 		})
+
+	conf = cfg.Processors[config.NewIDWithName(typeStr, "compaction")]
+	assert.Equal(t, conf,
+		&Config{
+			ProcessorSettings: config.NewProcessorSettings(config.NewIDWithName(typeStr, "compaction")),
+			GroupByKeys:       []string{},
+		})
 }
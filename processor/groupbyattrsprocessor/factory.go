@@ -16,7 +16,6 @@ package groupbyattrsprocessor
 
 import (
 	"context"
-	"fmt"
 	"sync"
 
 	"go.opencensus.io/stats/view"
@@ -33,8 +32,7 @@ const (
 )
 
 var (
-	errAtLeastOneAttributeNeeded = fmt.Errorf("option 'groupByKeys' must include at least one non-empty attribute name")
-	consumerCapabilities         = consumer.Capabilities{MutatesData: true}
+	consumerCapabilities = consumer.Capabilities{MutatesData: true}
 )
 
 var once sync.Once
@@ -50,6 +48,7 @@ func NewFactory() component.ProcessorFactory {
 		typeStr,
 		createDefaultConfig,
 		processorhelper.WithTraces(createTracesProcessor),
+		processorhelper.WithMetrics(createMetricsProcessor),
 		processorhelper.WithLogs(createLogsProcessor))
 }
 
@@ -61,7 +60,7 @@ func createDefaultConfig() config.Processor {
 	}
 }
 
-func createGroupByAttrsProcessor(logger *zap.Logger, attributes []string) (*groupByAttrsProcessor, error) {
+func createGroupByAttrsProcessor(logger *zap.Logger, attributes []string) *groupByAttrsProcessor {
 	var nonEmptyAttributes []string
 	presentAttributes := make(map[string]struct{})
 
@@ -77,11 +76,7 @@ func createGroupByAttrsProcessor(logger *zap.Logger, attributes []string) (*grou
 		}
 	}
 
-	if len(nonEmptyAttributes) == 0 {
-		return nil, errAtLeastOneAttributeNeeded
-	}
-
-	return &groupByAttrsProcessor{logger: logger, groupByKeys: nonEmptyAttributes}, nil
+	return &groupByAttrsProcessor{logger: logger, groupByKeys: nonEmptyAttributes}
 }
 
 // createTracesProcessor creates a trace processor based on this config.
@@ -92,10 +87,7 @@ func createTracesProcessor(
 	nextConsumer consumer.Traces) (component.TracesProcessor, error) {
 
 	oCfg := cfg.(*Config)
-	gap, err := createGroupByAttrsProcessor(params.Logger, oCfg.GroupByKeys)
-	if err != nil {
-		return nil, err
-	}
+	gap := createGroupByAttrsProcessor(params.Logger, oCfg.GroupByKeys)
 
 	return processorhelper.NewTracesProcessor(
 		cfg,
@@ -104,7 +96,24 @@ func createTracesProcessor(
 		processorhelper.WithCapabilities(consumerCapabilities))
 }
 
-// createLogsProcessor creates a metrics processor based on this config.
+// createMetricsProcessor creates a metrics processor based on this config.
+func createMetricsProcessor(
+	_ context.Context,
+	params component.ProcessorCreateSettings,
+	cfg config.Processor,
+	nextConsumer consumer.Metrics) (component.MetricsProcessor, error) {
+
+	oCfg := cfg.(*Config)
+	gap := createGroupByAttrsProcessor(params.Logger, oCfg.GroupByKeys)
+
+	return processorhelper.NewMetricsProcessor(
+		cfg,
+		nextConsumer,
+		gap.processMetrics,
+		processorhelper.WithCapabilities(consumerCapabilities))
+}
+
+// createLogsProcessor creates a logs processor based on this config.
 func createLogsProcessor(
 	_ context.Context,
 	params component.ProcessorCreateSettings,
@@ -112,10 +121,7 @@ func createLogsProcessor(
 	nextConsumer consumer.Logs) (component.LogsProcessor, error) {
 
 	oCfg := cfg.(*Config)
-	gap, err := createGroupByAttrsProcessor(params.Logger, oCfg.GroupByKeys)
-	if err != nil {
-		return nil, err
-	}
+	gap := createGroupByAttrsProcessor(params.Logger, oCfg.GroupByKeys)
 
 	return processorhelper.NewLogsProcessor(
 		cfg,
@@ -41,6 +41,11 @@ func TestCreateTestProcessor(t *testing.T) {
 	assert.NotNil(t, tp)
 	assert.Equal(t, true, tp.Capabilities().MutatesData)
 
+	mp, err := createMetricsProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+	assert.NoError(t, err)
+	assert.NotNil(t, mp)
+	assert.Equal(t, true, mp.Capabilities().MutatesData)
+
 	lp, err := createLogsProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
 	assert.NoError(t, err)
 	assert.NotNil(t, lp)
@@ -48,14 +53,14 @@ func TestCreateTestProcessor(t *testing.T) {
 }
 
 func TestNoKeys(t *testing.T) {
-	gbap, err := createGroupByAttrsProcessor(zap.NewNop(), []string{})
-	assert.Error(t, err)
-	assert.Nil(t, gbap)
+	// This is allowed since it means compacting the records
+	gbap := createGroupByAttrsProcessor(zap.NewNop(), []string{})
+	assert.NotNil(t, gbap)
+	assert.Empty(t, gbap.groupByKeys)
 }
 
 func TestDuplicateKeys(t *testing.T) {
-	gbap, err := createGroupByAttrsProcessor(zap.NewNop(), []string{"foo", "foo", ""})
-	assert.NoError(t, err)
+	gbap := createGroupByAttrsProcessor(zap.NewNop(), []string{"foo", "foo", ""})
 	assert.NotNil(t, gbap)
 	assert.EqualValues(t, []string{"foo"}, gbap.groupByKeys)
 }
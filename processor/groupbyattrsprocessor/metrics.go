@@ -27,6 +27,10 @@ var (
 	mNumGroupedLogs     = stats.Int64("num_grouped_logs", "Number of logs that had attributes grouped", stats.UnitDimensionless)
 	mNumNonGroupedLogs  = stats.Int64("num_non_grouped_logs", "Number of logs that did not have attributes grouped", stats.UnitDimensionless)
 	mDistLogGroups      = stats.Int64("log_groups", "Distributon of groups extracted for logs", stats.UnitDimensionless)
+
+	mNumGroupedMetrics    = stats.Int64("num_grouped_metrics", "Number of metric data points that had labels grouped", stats.UnitDimensionless)
+	mNumNonGroupedMetrics = stats.Int64("num_non_grouped_metrics", "Number of metric data points that did not have labels grouped", stats.UnitDimensionless)
+	mDistMetricGroups     = stats.Int64("metric_groups", "Distributon of groups extracted for metrics", stats.UnitDimensionless)
 )
 
 // MetricViews return the metrics views according to given telemetry level.
@@ -70,5 +74,23 @@ func MetricViews() []*view.View {
 			Description: mDistLogGroups.Description(),
 			Aggregation: distributionGroups,
 		},
+		{
+			Name:        obsreport.BuildProcessorCustomMetricName(string(typeStr), mNumGroupedMetrics.Name()),
+			Measure:     mNumGroupedMetrics,
+			Description: mNumGroupedMetrics.Description(),
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        obsreport.BuildProcessorCustomMetricName(string(typeStr), mNumNonGroupedMetrics.Name()),
+			Measure:     mNumNonGroupedMetrics,
+			Description: mNumNonGroupedMetrics.Description(),
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        obsreport.BuildProcessorCustomMetricName(string(typeStr), mDistMetricGroups.Name()),
+			Measure:     mDistMetricGroups,
+			Description: mDistMetricGroups.Description(),
+			Aggregation: distributionGroups,
+		},
 	}
 }
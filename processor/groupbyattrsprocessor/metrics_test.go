@@ -28,6 +28,9 @@ func TestProcessorMetrics(t *testing.T) {
 		"processor/groupbyattrs/num_grouped_logs",
 		"processor/groupbyattrs/num_non_grouped_logs",
 		"processor/groupbyattrs/log_groups",
+		"processor/groupbyattrs/num_grouped_metrics",
+		"processor/groupbyattrs/num_non_grouped_metrics",
+		"processor/groupbyattrs/metric_groups",
 	}
 
 	views := MetricViews()
@@ -109,6 +109,85 @@ func (gap *groupByAttrsProcessor) processLogs(ctx context.Context, ld pdata.Logs
 	return groupedLogs, nil
 }
 
+func (gap *groupByAttrsProcessor) processMetrics(ctx context.Context, md pdata.Metrics) (pdata.Metrics, error) {
+	rms := md.ResourceMetrics()
+	extractedGroups := newMetricsGroupedByAttrs()
+
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+
+		ilms := rm.InstrumentationLibraryMetrics()
+		for j := 0; j < ilms.Len(); j++ {
+			ilm := ilms.At(j)
+			for k := 0; k < ilm.Metrics().Len(); k++ {
+				metric := ilm.Metrics().At(k)
+
+				// Data points are grouped one by one, as each of them might end up under a different resource
+				switch metric.DataType() {
+				case pdata.MetricDataTypeGauge:
+					dps := metric.Gauge().DataPoints()
+					for l := 0; l < dps.Len(); l++ {
+						dp := dps.At(l)
+						groupedMetric := gap.groupedMetric(ctx, extractedGroups, rm.Resource(), ilm.InstrumentationLibrary(), metric, dp.LabelsMap())
+						dp.CopyTo(groupedMetric.Gauge().DataPoints().AppendEmpty())
+					}
+				case pdata.MetricDataTypeSum:
+					dps := metric.Sum().DataPoints()
+					for l := 0; l < dps.Len(); l++ {
+						dp := dps.At(l)
+						groupedMetric := gap.groupedMetric(ctx, extractedGroups, rm.Resource(), ilm.InstrumentationLibrary(), metric, dp.LabelsMap())
+						dp.CopyTo(groupedMetric.Sum().DataPoints().AppendEmpty())
+					}
+				case pdata.MetricDataTypeHistogram:
+					dps := metric.Histogram().DataPoints()
+					for l := 0; l < dps.Len(); l++ {
+						dp := dps.At(l)
+						groupedMetric := gap.groupedMetric(ctx, extractedGroups, rm.Resource(), ilm.InstrumentationLibrary(), metric, dp.LabelsMap())
+						dp.CopyTo(groupedMetric.Histogram().DataPoints().AppendEmpty())
+					}
+				case pdata.MetricDataTypeSummary:
+					dps := metric.Summary().DataPoints()
+					for l := 0; l < dps.Len(); l++ {
+						dp := dps.At(l)
+						groupedMetric := gap.groupedMetric(ctx, extractedGroups, rm.Resource(), ilm.InstrumentationLibrary(), metric, dp.LabelsMap())
+						dp.CopyTo(groupedMetric.Summary().DataPoints().AppendEmpty())
+					}
+				}
+			}
+		}
+	}
+
+	// Copy the grouped data into output
+	groupedMetrics := pdata.NewMetrics()
+	extractedGroups.MoveAndAppendTo(groupedMetrics.ResourceMetrics())
+	stats.Record(ctx, mDistMetricGroups.M(int64(groupedMetrics.ResourceMetrics().Len())))
+
+	return groupedMetrics, nil
+}
+
+// groupedMetric moves the grouped labels of a data point to the resource level and returns the metric,
+// under the matching resource and instrumentation library, that the data point belongs to
+func (gap *groupByAttrsProcessor) groupedMetric(ctx context.Context, extractedGroups *metricsGroupedByAttrs, resource pdata.Resource,
+	library pdata.InstrumentationLibrary, metric pdata.Metric, labels pdata.StringMap) pdata.Metric {
+	groupedAnything, groupedAttrMap := gap.splitLabels(labels)
+	if groupedAnything {
+		stats.Record(ctx, mNumGroupedMetrics.M(1))
+		// Some labels are going to be moved from data point to resource level,
+		// so we can delete those on the record level
+		groupedAttrMap.Range(func(key string, _ pdata.AttributeValue) bool {
+			labels.Delete(key)
+			return true
+		})
+	} else {
+		stats.Record(ctx, mNumNonGroupedMetrics.M(1))
+	}
+
+	// Lets combine the base resource attributes + the extracted (grouped) labels
+	// and keep them in the grouping entry
+	groupedMetrics := extractedGroups.attributeGroup(resource, groupedAttrMap)
+	return matchingMetric(matchingInstrumentationLibraryMetrics(groupedMetrics, library), metric)
+}
+
 func deleteAttributes(attrsForRemoval, targetAttrs pdata.AttributeMap) {
 	attrsForRemoval.Range(func(key string, _ pdata.AttributeValue) bool {
 		targetAttrs.Delete(key)
@@ -133,3 +212,20 @@ func (gap *groupByAttrsProcessor) splitAttrMap(attrMap pdata.AttributeMap) (bool
 
 	return groupedAnything, groupedAttrMap
 }
+
+// splitLabels splits the data point labels by groupByKeys, similarly to splitAttrMap, returning the
+// matching labels as string attributes
+func (gap *groupByAttrsProcessor) splitLabels(labels pdata.StringMap) (bool, pdata.AttributeMap) {
+	groupedAttrMap := pdata.NewAttributeMap()
+	groupedAnything := false
+
+	for _, labelKey := range gap.groupByKeys {
+		labelVal, found := labels.Get(labelKey)
+		if found {
+			groupedAttrMap.InsertString(labelKey, labelVal)
+			groupedAnything = true
+		}
+	}
+
+	return groupedAnything, groupedAttrMap
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -109,16 +110,42 @@ func someComplexTraces(withResourceAttrIndex bool, rsCount int, ilsCount int) pd
 	return traces
 }
 
+func someComplexMetrics(withResourceAttrIndex bool, rmCount int, ilmCount int, dpCount int) pdata.Metrics {
+	metrics := pdata.NewMetrics()
+
+	for i := 0; i < rmCount; i++ {
+		rm := metrics.ResourceMetrics().AppendEmpty()
+		if withResourceAttrIndex {
+			rm.Resource().Attributes().InsertInt("resourceAttrIndex", int64(i))
+		}
+
+		for j := 0; j < ilmCount; j++ {
+			metric := rm.InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+			metric.SetName(fmt.Sprintf("foo-%d-%d", i, j))
+			metric.SetDataType(pdata.MetricDataTypeGauge)
+			for k := 0; k < dpCount; k++ {
+				dp := metric.Gauge().DataPoints().AppendEmpty()
+				dp.SetTimestamp(pdata.TimestampFromTime(time.Now()))
+				dp.SetIntVal(int64(k))
+				dp.LabelsMap().Insert("commonGroupedAttr", "abc")
+				dp.LabelsMap().Insert("commonNonGroupedAttr", "xyz")
+			}
+		}
+	}
+
+	return metrics
+}
+
 // The "complex" use case has following input data:
-//  * Resource[Spans|Logs] #1
+//  * Resource[Spans|Logs|Metrics] #1
 //    Attributes: resourceAttrIndex => <resource_no> (when `withResourceAttrIndex` set to true)
-//      * InstrumentationLibrary[Spans|Logs] #1
-//          * [Span|Log] foo-1-1
-//            Attributes: commonGroupedAttr => abc, commonNonGroupedAttr => xyz
-//      * InstrumentationLibrary[Spans|Logs] #M
+//      * InstrumentationLibrary[Spans|Logs|Metrics] #1
+//          * [Span|Log|Metric] foo-1-1
+//            Attributes (or data point labels): commonGroupedAttr => abc, commonNonGroupedAttr => xyz
+//      * InstrumentationLibrary[Spans|Logs|Metrics] #M
 //        ...
 //    ...
-//   * Resource[Spans|Logs] #N
+//   * Resource[Spans|Logs|Metrics] #N
 //      ...
 func TestComplexAttributeGrouping(t *testing.T) {
 	// Following are record-level attributes that should be preserved after processing
@@ -133,6 +160,7 @@ func TestComplexAttributeGrouping(t *testing.T) {
 		outputResourceCount               int
 		outputInstrumentationLibraryCount int // Per each Resource
 		outputTotalRecordsCount           int // Per each Instrumentation Library
+		outputTotalDataPointsCount        int // Per each Instrumentation Library, for metrics
 	}{
 		{
 			name:                             "With not unique Resource-level attributes",
@@ -143,6 +171,7 @@ func TestComplexAttributeGrouping(t *testing.T) {
 			outputResourceCount:               1,
 			outputInstrumentationLibraryCount: 1,
 			outputTotalRecordsCount:           16,
+			outputTotalDataPointsCount:        32,
 		},
 		{
 			name:                             "With unique Resource-level attributes",
@@ -153,6 +182,7 @@ func TestComplexAttributeGrouping(t *testing.T) {
 			outputResourceCount:               4,
 			outputInstrumentationLibraryCount: 1,
 			outputTotalRecordsCount:           16,
+			outputTotalDataPointsCount:        32,
 		},
 	}
 
@@ -160,9 +190,9 @@ func TestComplexAttributeGrouping(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			inputLogs := someComplexLogs(tt.withResourceAttrIndex, tt.inputResourceCount, tt.inputInstrumentationLibraryCount)
 			inputTraces := someComplexTraces(tt.withResourceAttrIndex, tt.inputResourceCount, tt.inputInstrumentationLibraryCount)
+			inputMetrics := someComplexMetrics(tt.withResourceAttrIndex, tt.inputResourceCount, tt.inputInstrumentationLibraryCount, 2)
 
-			gap, err := createGroupByAttrsProcessor(zap.NewNop(), []string{"commonGroupedAttr"})
-			require.NoError(t, err)
+			gap := createGroupByAttrsProcessor(zap.NewNop(), []string{"commonGroupedAttr"})
 
 			processedLogs, err := gap.processLogs(context.Background(), inputLogs)
 			assert.NoError(t, err)
@@ -170,6 +200,9 @@ func TestComplexAttributeGrouping(t *testing.T) {
 			processedSpans, err := gap.processTraces(context.Background(), inputTraces)
 			assert.NoError(t, err)
 
+			processedMetrics, err := gap.processMetrics(context.Background(), inputMetrics)
+			assert.NoError(t, err)
+
 			rls := processedLogs.ResourceLogs()
 			assert.Equal(t, tt.outputResourceCount, rls.Len())
 			assert.Equal(t, tt.outputTotalRecordsCount, processedLogs.LogRecordCount())
@@ -207,6 +240,29 @@ func TestComplexAttributeGrouping(t *testing.T) {
 					}
 				}
 			}
+
+			rms := processedMetrics.ResourceMetrics()
+			assert.Equal(t, tt.outputResourceCount, rms.Len())
+			assert.Equal(t, tt.outputTotalDataPointsCount, processedMetrics.DataPointCount())
+			for i := 0; i < rms.Len(); i++ {
+				rm := rms.At(i)
+				assert.Equal(t, tt.outputInstrumentationLibraryCount, rm.InstrumentationLibraryMetrics().Len())
+
+				// This was present at data point level and should be found on Resource level after the processor
+				commonAttrValue, _ := rm.Resource().Attributes().Get("commonGroupedAttr")
+				assert.Equal(t, pdata.NewAttributeValueString("abc"), commonAttrValue)
+
+				for j := 0; j < rm.InstrumentationLibraryMetrics().Len(); j++ {
+					metrics := rm.InstrumentationLibraryMetrics().At(j).Metrics()
+					for k := 0; k < metrics.Len(); k++ {
+						dps := metrics.At(k).Gauge().DataPoints()
+						assert.Equal(t, 2, dps.Len())
+						for l := 0; l < dps.Len(); l++ {
+							assert.EqualValues(t, map[string]string{"commonNonGroupedAttr": "xyz"}, labelsAsMap(dps.At(l).LabelsMap()))
+						}
+					}
+				}
+			}
 		})
 	}
 }
@@ -243,8 +299,7 @@ func TestAttributeGrouping(t *testing.T) {
 			logs := someLogs(attrMap, tt.count)
 			spans := someSpans(attrMap, tt.count)
 
-			gap, err := createGroupByAttrsProcessor(zap.NewNop(), tt.groupByKeys)
-			require.NoError(t, err)
+			gap := createGroupByAttrsProcessor(zap.NewNop(), tt.groupByKeys)
 
 			expectedResource := prepareResource(attrMap, tt.groupByKeys)
 			expectedAttributes := filterAttributeMap(attrMap, tt.nonGroupedKeys)
@@ -293,6 +348,91 @@ func TestAttributeGrouping(t *testing.T) {
 	}
 }
 
+func TestCompaction(t *testing.T) {
+	// With no keys, records of matching resources and instrumentation libraries are only merged together
+	gap := createGroupByAttrsProcessor(zap.NewNop(), []string{})
+
+	traces := pdata.NewTraces()
+	logs := pdata.NewLogs()
+	metrics := pdata.NewMetrics()
+	for i := 0; i < 4; i++ {
+		rs := traces.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().InsertString("host.name", fmt.Sprint("host-", i%2))
+		ils := rs.InstrumentationLibrarySpans().AppendEmpty()
+		ils.InstrumentationLibrary().SetName("library")
+		span := ils.Spans().AppendEmpty()
+		span.SetName(fmt.Sprint("foo-", i))
+		span.Attributes().InsertString("host.name", "overridden")
+
+		rl := logs.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().InsertString("host.name", fmt.Sprint("host-", i%2))
+		ill := rl.InstrumentationLibraryLogs().AppendEmpty()
+		ill.InstrumentationLibrary().SetName("library")
+		ill.Logs().AppendEmpty().SetName(fmt.Sprint("foo-", i))
+
+		rm := metrics.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().InsertString("host.name", fmt.Sprint("host-", i%2))
+		ilm := rm.InstrumentationLibraryMetrics().AppendEmpty()
+		ilm.InstrumentationLibrary().SetName("library")
+		for _, temporality := range []pdata.AggregationTemporality{pdata.AggregationTemporalityCumulative, pdata.AggregationTemporalityDelta} {
+			metric := ilm.Metrics().AppendEmpty()
+			metric.SetName("foo")
+			metric.SetDataType(pdata.MetricDataTypeSum)
+			metric.Sum().SetAggregationTemporality(temporality)
+			dp := metric.Sum().DataPoints().AppendEmpty()
+			dp.SetIntVal(int64(i))
+			dp.LabelsMap().Insert("host.name", "overridden")
+		}
+	}
+
+	processedTraces, err := gap.processTraces(context.Background(), traces)
+	require.NoError(t, err)
+	processedLogs, err := gap.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+	processedMetrics, err := gap.processMetrics(context.Background(), metrics)
+	require.NoError(t, err)
+
+	rss := processedTraces.ResourceSpans()
+	require.Equal(t, 2, rss.Len())
+	rls := processedLogs.ResourceLogs()
+	require.Equal(t, 2, rls.Len())
+	rms := processedMetrics.ResourceMetrics()
+	require.Equal(t, 2, rms.Len())
+
+	for i := 0; i < 2; i++ {
+		expectedResource := pdata.NewResource()
+		expectedResource.Attributes().InsertString("host.name", fmt.Sprint("host-", i))
+
+		assert.Equal(t, expectedResource, rss.At(i).Resource())
+		require.Equal(t, 1, rss.At(i).InstrumentationLibrarySpans().Len())
+		spans := rss.At(i).InstrumentationLibrarySpans().At(0).Spans()
+		require.Equal(t, 2, spans.Len())
+		assert.Equal(t, fmt.Sprint("foo-", i), spans.At(0).Name())
+		assert.Equal(t, fmt.Sprint("foo-", i+2), spans.At(1).Name())
+		// Non grouped attributes are left untouched
+		assert.Equal(t, 1, spans.At(0).Attributes().Len())
+
+		assert.Equal(t, expectedResource, rls.At(i).Resource())
+		require.Equal(t, 1, rls.At(i).InstrumentationLibraryLogs().Len())
+		assert.Equal(t, 2, rls.At(i).InstrumentationLibraryLogs().At(0).Logs().Len())
+
+		assert.Equal(t, expectedResource, rms.At(i).Resource())
+		require.Equal(t, 1, rms.At(i).InstrumentationLibraryMetrics().Len())
+		ms := rms.At(i).InstrumentationLibraryMetrics().At(0).Metrics()
+		// Metrics with different temporality cannot be merged
+		require.Equal(t, 2, ms.Len())
+		for j := 0; j < ms.Len(); j++ {
+			assert.Equal(t, "foo", ms.At(j).Name())
+			dps := ms.At(j).Sum().DataPoints()
+			require.Equal(t, 2, dps.Len())
+			assert.Equal(t, int64(i), dps.At(0).IntVal())
+			assert.Equal(t, int64(i+2), dps.At(1).IntVal())
+		}
+		assert.Equal(t, pdata.AggregationTemporalityCumulative, ms.At(0).Sum().AggregationTemporality())
+		assert.Equal(t, pdata.AggregationTemporalityDelta, ms.At(1).Sum().AggregationTemporality())
+	}
+}
+
 func someSpans(attrs pdata.AttributeMap, count int) pdata.Traces {
 	traces := pdata.NewTraces()
 	ils := traces.ResourceSpans().AppendEmpty().InstrumentationLibrarySpans().AppendEmpty()
@@ -318,3 +458,12 @@ func someLogs(attrs pdata.AttributeMap, count int) pdata.Logs {
 
 	return logs
 }
+
+func labelsAsMap(labels pdata.StringMap) map[string]string {
+	m := make(map[string]string, labels.Len())
+	labels.Range(func(k, v string) bool {
+		m[k] = v
+		return true
+	})
+	return m
+}
@@ -38,8 +38,7 @@ const (
 )
 
 var (
-	errDiskStorageNotSupported    = fmt.Errorf("option 'disk storage' not supported in this release")
-	errDiscardOrphansNotSupported = fmt.Errorf("option 'discard orphans' not supported in this release")
+	errStorageNotConfigured = fmt.Errorf("option 'storage' must be set when 'store_on_disk' is enabled")
 )
 
 // NewFactory returns a new factory for the Filter processor.
@@ -60,10 +59,8 @@ func createDefaultConfig() config.Processor {
 		NumTraces:         defaultNumTraces,
 		NumWorkers:        defaultNumWorkers,
 		WaitDuration:      defaultWaitDuration,
-
-		// not supported for now
-		DiscardOrphans: defaultDiscardOrphans,
-		StoreOnDisk:    defaultStoreOnDisk,
+		DiscardOrphans:    defaultDiscardOrphans,
+		StoreOnDisk:       defaultStoreOnDisk,
 	}
 }
 
@@ -78,14 +75,13 @@ func createTracesProcessor(
 
 	var st storage
 	if oCfg.StoreOnDisk {
-		return nil, errDiskStorageNotSupported
-	}
-	if oCfg.DiscardOrphans {
-		return nil, errDiscardOrphansNotSupported
+		if oCfg.Storage == "" {
+			return nil, errStorageNotConfigured
+		}
+		st = newDiskStorage(oCfg.Storage, oCfg.ID())
+	} else {
+		st = newMemoryStorage()
 	}
 
-	// the only supported storage for now
-	st = newMemoryStorage()
-
 	return newGroupByTraceProcessor(params.Logger, st, nextConsumer, *oCfg), nil
 }
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component/componenttest"
 )
 
@@ -47,33 +48,50 @@ func TestCreateTestProcessor(t *testing.T) {
 	assert.NotNil(t, p)
 }
 
-func TestCreateTestProcessorWithNotImplementedOptions(t *testing.T) {
+func TestCreateTestProcessorWithStoreOnDiskWithoutStorage(t *testing.T) {
+	// prepare
+	f := NewFactory()
+	next := &mockProcessor{}
+	c := &Config{
+		StoreOnDisk: true,
+	}
+
+	// test
+	p, err := f.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), c, next)
+
+	// verify
+	assert.Equal(t, errStorageNotConfigured, err)
+	assert.Nil(t, p)
+}
+
+func TestCreateTestProcessorWithOptions(t *testing.T) {
 	// prepare
 	f := NewFactory()
 	next := &mockProcessor{}
 
 	// test
 	for _, tt := range []struct {
-		config      *Config
-		expectedErr error
+		config          *Config
+		expectedStorage storage
 	}{
 		{
 			&Config{
 				DiscardOrphans: true,
 			},
-			errDiscardOrphansNotSupported,
+			&memoryStorage{},
 		},
 		{
 			&Config{
 				StoreOnDisk: true,
+				Storage:     "file_storage",
 			},
-			errDiskStorageNotSupported,
+			&diskStorage{},
 		},
 	} {
 		p, err := f.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), tt.config, next)
 
 		// verify
-		assert.Error(t, tt.expectedErr, err)
-		assert.Nil(t, p)
+		require.NoError(t, err)
+		assert.IsType(t, tt.expectedStorage, p.(*groupByTraceProcessor).st)
 	}
 }
@@ -94,14 +94,18 @@ func (sp *groupByTraceProcessor) Capabilities() consumer.Capabilities {
 }
 
 // Start is invoked during service startup.
-func (sp *groupByTraceProcessor) Start(context.Context, component.Host) error {
+func (sp *groupByTraceProcessor) Start(ctx context.Context, host component.Host) error {
 	// start these metrics, as it might take a while for them to receive their first event
 	stats.Record(context.Background(), mTracesEvicted.M(0))
 	stats.Record(context.Background(), mIncompleteReleases.M(0))
 	stats.Record(context.Background(), mNumTracesConf.M(int64(sp.config.NumTraces)))
+	stats.Record(context.Background(), mDiscardedOrphans.M(0))
 
+	if err := sp.st.start(ctx, host); err != nil {
+		return err
+	}
 	sp.eventMachine.startInBackground()
-	return sp.st.start()
+	return nil
 }
 
 // Shutdown is invoked during service shutdown.
@@ -211,6 +215,14 @@ func (sp *groupByTraceProcessor) markAsReleased(traceID pdata.TraceID, fire func
 }
 
 func (sp *groupByTraceProcessor) onTraceReleased(rss []pdata.ResourceSpans) error {
+	if sp.config.DiscardOrphans && !hasRootSpan(rss) {
+		// the trace is still removed from the storage, as the traceRemoved event is fired
+		// along with the traceReleased one
+		sp.logger.Debug("discarding trace without root span")
+		stats.Record(context.Background(), mDiscardedOrphans.M(1))
+		return nil
+	}
+
 	trace := pdata.NewTraces()
 	for _, rs := range rss {
 		trs := trace.ResourceSpans().AppendEmpty()
@@ -243,6 +255,22 @@ func (sp *groupByTraceProcessor) onTraceRemoved(traceID pdata.TraceID) error {
 	return nil
 }
 
+// hasRootSpan returns whether any of the given spans is a root span, i.e.: a span without parent.
+func hasRootSpan(rss []pdata.ResourceSpans) bool {
+	for _, rs := range rss {
+		ilss := rs.InstrumentationLibrarySpans()
+		for i := 0; i < ilss.Len(); i++ {
+			spans := ilss.At(i).Spans()
+			for j := 0; j < spans.Len(); j++ {
+				if spans.At(j).ParentSpanID().IsEmpty() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 func (sp *groupByTraceProcessor) addSpans(traceID pdata.TraceID, trace pdata.Traces) error {
 	sp.logger.Debug("creating trace at the storage", zap.String("traceID", traceID.HexString()))
 	return sp.st.createOrAppend(traceID, trace)
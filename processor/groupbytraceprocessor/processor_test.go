@@ -542,6 +542,42 @@ func TestAsyncOnRelease(t *testing.T) {
 	close(blockCh)
 }
 
+func TestDiscardOrphans(t *testing.T) {
+	// prepare
+	config := Config{
+		WaitDuration:   time.Nanosecond,
+		NumTraces:      10,
+		NumWorkers:     1,
+		DiscardOrphans: true,
+	}
+
+	wg := &sync.WaitGroup{}
+	var received []pdata.Traces
+	next := &mockProcessor{
+		onTraces: func(_ context.Context, td pdata.Traces) error {
+			received = append(received, td)
+			wg.Done()
+			return nil
+		},
+	}
+	p := newGroupByTraceProcessor(zap.NewNop(), newMemoryStorage(), next, config)
+
+	orphan := simpleTraces()
+	orphan.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).SetParentSpanID(pdata.NewSpanID([8]byte{1, 2, 3, 4}))
+	complete := simpleTraces()
+	complete.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().AppendEmpty().SetParentSpanID(pdata.NewSpanID([8]byte{1, 2, 3, 4}))
+
+	// test
+	wg.Add(1) // only the complete trace is released
+	assert.NoError(t, p.onTraceReleased([]pdata.ResourceSpans{orphan.ResourceSpans().At(0)}))
+	assert.NoError(t, p.onTraceReleased([]pdata.ResourceSpans{complete.ResourceSpans().At(0)}))
+
+	// verify
+	wg.Wait()
+	require.Len(t, received, 1)
+	assert.Equal(t, complete, received[0])
+}
+
 func BenchmarkConsumeTracesCompleteOnFirstBatch(b *testing.B) {
 	// prepare
 	config := Config{
@@ -625,7 +661,7 @@ func (st *mockStorage) delete(traceID pdata.TraceID) ([]pdata.ResourceSpans, err
 	}
 	return nil, nil
 }
-func (st *mockStorage) start() error {
+func (st *mockStorage) start(context.Context, component.Host) error {
 	if st.onStart != nil {
 		return st.onStart()
 	}
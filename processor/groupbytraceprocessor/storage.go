@@ -15,6 +15,9 @@
 package groupbytraceprocessor
 
 import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/model/pdata"
 )
 
@@ -35,7 +38,7 @@ type storage interface {
 	delete(pdata.TraceID) ([]pdata.ResourceSpans, error)
 
 	// start gives the storage the opportunity to initialize any resources or procedures
-	start() error
+	start(ctx context.Context, host component.Host) error
 
 	// shutdown signals the storage that the processor is shutting down
 	shutdown() error
@@ -0,0 +1,328 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupbytraceprocessor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	extstorage "go.opentelemetry.io/collector/extension/storage"
+	"go.opentelemetry.io/collector/model/otlp"
+	"go.opentelemetry.io/collector/model/pdata"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/storage/storagehelper"
+)
+
+const (
+	// slotsKey holds the number of slots, each slot holding the ID of a trace with chunks in the
+	// storage. They allow removing the chunks left behind when the collector stops abruptly.
+	slotsKey = "slots"
+)
+
+// diskStorage keeps the spans of the traces in a storage extension, only the trace IDs
+// and the number of batches stored for each of them are kept in memory.
+type diskStorage struct {
+	sync.RWMutex
+	// traces holds the number of batches stored for each trace, and the slot of the trace
+	traces map[pdata.TraceID]*storedTrace
+	// slots is the number of slots in the storage, freeSlots are the ones not used by a trace
+	slots     int
+	freeSlots []int
+
+	storageName string
+	processorID config.ComponentID
+	client      extstorage.Client
+	marshaler   pdata.TracesMarshaler
+	unmarshaler pdata.TracesUnmarshaler
+
+	stopped                   bool
+	stoppedLock               sync.RWMutex
+	metricsCollectionInterval time.Duration
+}
+
+type storedTrace struct {
+	chunks int
+	slot   int
+}
+
+var _ storage = (*diskStorage)(nil)
+
+func newDiskStorage(storageName string, processorID config.ComponentID) *diskStorage {
+	return &diskStorage{
+		traces:                    make(map[pdata.TraceID]*storedTrace),
+		storageName:               storageName,
+		processorID:               processorID,
+		marshaler:                 otlp.NewProtobufTracesMarshaler(),
+		unmarshaler:               otlp.NewProtobufTracesUnmarshaler(),
+		metricsCollectionInterval: time.Second,
+	}
+}
+
+func chunkKey(traceID pdata.TraceID, chunk int) string {
+	return fmt.Sprintf("%s/%d", traceID.HexString(), chunk)
+}
+
+func slotKey(slot int) string {
+	return fmt.Sprintf("%s/%d", slotsKey, slot)
+}
+
+// createOrAppend stores the batch as the next chunk of the trace. The lock is held while
+// writing, so that the chunk count is only incremented once the chunk is stored.
+func (st *diskStorage) createOrAppend(traceID pdata.TraceID, td pdata.Traces) error {
+	buf, err := st.marshaler.MarshalTraces(td)
+	if err != nil {
+		return err
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	trace, ok := st.traces[traceID]
+	if ok {
+		if err = st.client.Set(context.Background(), chunkKey(traceID, trace.chunks), buf); err != nil {
+			return err
+		}
+		trace.chunks++
+		return nil
+	}
+
+	// the first chunk of a trace is written along with the slot holding its ID
+	slot, slots := st.slots, st.slots+1
+	if n := len(st.freeSlots); n > 0 {
+		slot, slots = st.freeSlots[n-1], st.slots
+	}
+	ops := []extstorage.Operation{
+		extstorage.SetOperation(chunkKey(traceID, 0), buf),
+		extstorage.SetOperation(slotKey(slot), []byte(traceID.HexString())),
+	}
+	if slots != st.slots {
+		ops = append(ops, extstorage.SetOperation(slotsKey, []byte(strconv.Itoa(slots))))
+	}
+	if err = st.client.Batch(context.Background(), ops...); err != nil {
+		return err
+	}
+
+	if slots == st.slots {
+		st.freeSlots = st.freeSlots[:len(st.freeSlots)-1]
+	}
+	st.slots = slots
+	st.traces[traceID] = &storedTrace{chunks: 1, slot: slot}
+	return nil
+}
+
+func (st *diskStorage) get(traceID pdata.TraceID) ([]pdata.ResourceSpans, error) {
+	st.RLock()
+	trace, ok := st.traces[traceID]
+	var chunks int
+	if ok {
+		chunks = trace.chunks
+	}
+	st.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+
+	ops := make([]extstorage.Operation, chunks)
+	for i := range ops {
+		ops[i] = extstorage.GetOperation(chunkKey(traceID, i))
+	}
+	if err := st.client.Batch(context.Background(), ops...); err != nil {
+		return nil, err
+	}
+	return st.unmarshal(ops)
+}
+
+func (st *diskStorage) delete(traceID pdata.TraceID) ([]pdata.ResourceSpans, error) {
+	st.Lock()
+	defer st.Unlock()
+
+	trace, ok := st.traces[traceID]
+	if !ok {
+		return nil, nil
+	}
+
+	// the operations are executed in order, the chunks are retrieved before being deleted
+	getOps := make([]extstorage.Operation, trace.chunks)
+	ops := make([]extstorage.Operation, 0, 2*trace.chunks+1)
+	for i := range getOps {
+		getOps[i] = extstorage.GetOperation(chunkKey(traceID, i))
+		ops = append(ops, getOps[i])
+	}
+	for i := 0; i < trace.chunks; i++ {
+		ops = append(ops, extstorage.DeleteOperation(chunkKey(traceID, i)))
+	}
+	ops = append(ops, extstorage.DeleteOperation(slotKey(trace.slot)))
+	// the slots are reset when the last trace is removed
+	last := len(st.traces) == 1
+	if last {
+		ops = append(ops, extstorage.DeleteOperation(slotsKey))
+	}
+	if err := st.client.Batch(context.Background(), ops...); err != nil {
+		return nil, err
+	}
+
+	delete(st.traces, traceID)
+	if last {
+		st.slots = 0
+		st.freeSlots = nil
+	} else {
+		st.freeSlots = append(st.freeSlots, trace.slot)
+	}
+	return st.unmarshal(getOps)
+}
+
+// unmarshal returns the resource spans of the chunks retrieved by the given operations,
+// chunks that couldn't be found are skipped.
+func (st *diskStorage) unmarshal(ops []extstorage.Operation) ([]pdata.ResourceSpans, error) {
+	var result []pdata.ResourceSpans
+	for _, op := range ops {
+		if op.Value == nil {
+			continue
+		}
+		td, err := st.unmarshaler.UnmarshalTraces(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < td.ResourceSpans().Len(); i++ {
+			result = append(result, td.ResourceSpans().At(i))
+		}
+	}
+	return result, nil
+}
+
+func (st *diskStorage) start(ctx context.Context, host component.Host) error {
+	client, err := storagehelper.GetClient(ctx, host, st.storageName, component.KindProcessor, st.processorID)
+	if err != nil {
+		return err
+	}
+	st.client = client
+
+	if err = st.removeLeftovers(ctx); err != nil {
+		client.Close(ctx)
+		return fmt.Errorf("failed to remove the traces left in the storage: %w", err)
+	}
+
+	go st.periodicMetrics()
+	return nil
+}
+
+// removeLeftovers removes the traces left in the storage by a previous run that didn't
+// shut down cleanly, as they can't be released anymore.
+func (st *diskStorage) removeLeftovers(ctx context.Context) error {
+	buf, err := st.client.Get(ctx, slotsKey)
+	if err != nil || buf == nil {
+		return err
+	}
+	slots, err := strconv.Atoi(string(buf))
+	if err != nil {
+		return err
+	}
+
+	slotOps := make([]extstorage.Operation, slots)
+	for i := range slotOps {
+		slotOps[i] = extstorage.GetOperation(slotKey(i))
+	}
+	if err = st.client.Batch(ctx, slotOps...); err != nil {
+		return err
+	}
+
+	var ops []extstorage.Operation
+	for i, op := range slotOps {
+		if op.Value != nil {
+			if err = st.removeChunks(ctx, string(op.Value)); err != nil {
+				return err
+			}
+		}
+		ops = append(ops, extstorage.DeleteOperation(slotKey(i)))
+	}
+	ops = append(ops, extstorage.DeleteOperation(slotsKey))
+	return st.client.Batch(ctx, ops...)
+}
+
+// removeChunks removes the chunks of a trace, which are numbered from zero without gaps.
+func (st *diskStorage) removeChunks(ctx context.Context, hexTraceID string) error {
+	for chunk := 0; ; chunk++ {
+		key := fmt.Sprintf("%s/%d", hexTraceID, chunk)
+		value, err := st.client.Get(ctx, key)
+		if err != nil || value == nil {
+			return err
+		}
+		if err = st.client.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+}
+
+// shutdown removes the traces still held by the storage, as the trace IDs kept in memory
+// are lost once the processor is shut down, and closes the storage client.
+func (st *diskStorage) shutdown() error {
+	st.stoppedLock.Lock()
+	defer st.stoppedLock.Unlock()
+	st.stopped = true
+
+	if st.client == nil {
+		return nil
+	}
+
+	st.Lock()
+	var ops []extstorage.Operation
+	for traceID, trace := range st.traces {
+		for i := 0; i < trace.chunks; i++ {
+			ops = append(ops, extstorage.DeleteOperation(chunkKey(traceID, i)))
+		}
+	}
+	for i := 0; i < st.slots; i++ {
+		ops = append(ops, extstorage.DeleteOperation(slotKey(i)))
+	}
+	ops = append(ops, extstorage.DeleteOperation(slotsKey))
+	st.traces = make(map[pdata.TraceID]*storedTrace)
+	st.slots = 0
+	st.freeSlots = nil
+	st.Unlock()
+
+	ctx := context.Background()
+	if err := st.client.Batch(ctx, ops...); err != nil {
+		return err
+	}
+	return st.client.Close(ctx)
+}
+
+func (st *diskStorage) periodicMetrics() {
+	numTraces := st.count()
+	stats.Record(context.Background(), mNumTracesInMemory.M(int64(numTraces)))
+
+	st.stoppedLock.RLock()
+	stopped := st.stopped
+	st.stoppedLock.RUnlock()
+	if stopped {
+		return
+	}
+
+	time.AfterFunc(st.metricsCollectionInterval, func() {
+		st.periodicMetrics()
+	})
+}
+
+func (st *diskStorage) count() int {
+	st.RLock()
+	defer st.RUnlock()
+	return len(st.traces)
+}
@@ -0,0 +1,336 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupbytraceprocessor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+	extstorage "go.opentelemetry.io/collector/extension/storage"
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+func TestDiskCreateAndGetTrace(t *testing.T) {
+	// prepare
+	st, _ := newStartedDiskStorage(t)
+
+	traceIDs := []pdata.TraceID{
+		pdata.NewTraceID([16]byte{1, 2, 3, 4}),
+		pdata.NewTraceID([16]byte{2, 3, 4, 5}),
+	}
+
+	baseTrace := pdata.NewTraces()
+	rss := baseTrace.ResourceSpans()
+	rs := rss.AppendEmpty()
+	ils := rs.InstrumentationLibrarySpans().AppendEmpty()
+	span := ils.Spans().AppendEmpty()
+
+	// test
+	for _, traceID := range traceIDs {
+		span.SetTraceID(traceID)
+		require.NoError(t, st.createOrAppend(traceID, baseTrace))
+	}
+
+	// verify
+	assert.Equal(t, 2, st.count())
+	for _, traceID := range traceIDs {
+		expected := []pdata.ResourceSpans{baseTrace.ResourceSpans().At(0)}
+		expected[0].InstrumentationLibrarySpans().At(0).Spans().At(0).SetTraceID(traceID)
+
+		retrieved, err := st.get(traceID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, retrieved)
+	}
+}
+
+func TestDiskDeleteTrace(t *testing.T) {
+	// prepare
+	st, client := newStartedDiskStorage(t)
+
+	traceID := pdata.NewTraceID([16]byte{1, 2, 3, 4})
+
+	trace := pdata.NewTraces()
+	rss := trace.ResourceSpans()
+	rs := rss.AppendEmpty()
+	ils := rs.InstrumentationLibrarySpans().AppendEmpty()
+	span := ils.Spans().AppendEmpty()
+	span.SetTraceID(traceID)
+
+	require.NoError(t, st.createOrAppend(traceID, trace))
+
+	// test
+	deleted, err := st.delete(traceID)
+
+	// verify
+	require.NoError(t, err)
+	assert.Equal(t, []pdata.ResourceSpans{trace.ResourceSpans().At(0)}, deleted)
+	assert.Empty(t, client.data)
+
+	retrieved, err := st.get(traceID)
+	require.NoError(t, err)
+	assert.Nil(t, retrieved)
+}
+
+func TestDiskAppendSpans(t *testing.T) {
+	// prepare
+	st, client := newStartedDiskStorage(t)
+
+	traceID := pdata.NewTraceID([16]byte{1, 2, 3, 4})
+
+	trace := pdata.NewTraces()
+	rss := trace.ResourceSpans()
+	rs := rss.AppendEmpty()
+	ils := rs.InstrumentationLibrarySpans().AppendEmpty()
+	span := ils.Spans().AppendEmpty()
+	span.SetTraceID(traceID)
+	span.SetSpanID(pdata.NewSpanID([8]byte{1, 2, 3, 4}))
+
+	require.NoError(t, st.createOrAppend(traceID, trace))
+
+	secondTrace := pdata.NewTraces()
+	secondRss := secondTrace.ResourceSpans()
+	secondRs := secondRss.AppendEmpty()
+	secondIls := secondRs.InstrumentationLibrarySpans().AppendEmpty()
+	secondSpan := secondIls.Spans().AppendEmpty()
+	secondSpan.SetName("second-name")
+	secondSpan.SetTraceID(traceID)
+	secondSpan.SetSpanID(pdata.NewSpanID([8]byte{5, 6, 7, 8}))
+
+	expected := []pdata.ResourceSpans{
+		pdata.NewResourceSpans(),
+		pdata.NewResourceSpans(),
+	}
+	ils.CopyTo(expected[0].InstrumentationLibrarySpans().AppendEmpty())
+	secondIls.CopyTo(expected[1].InstrumentationLibrarySpans().AppendEmpty())
+
+	// test
+	err := st.createOrAppend(traceID, secondTrace)
+	require.NoError(t, err)
+
+	// override something in the second span, to make sure we are storing a copy
+	secondSpan.SetName("changed-second-name")
+
+	// verify
+	assert.Len(t, client.data, 4) // two chunks, the slot of the trace and the number of slots
+	retrieved, err := st.get(traceID)
+	require.NoError(t, err)
+	require.Len(t, retrieved, 2)
+	assert.Equal(t, "second-name", retrieved[1].InstrumentationLibrarySpans().At(0).Spans().At(0).Name())
+
+	// now that we checked that the secondSpan change here didn't have an effect, revert
+	// so that we can compare the that everything else has the same value
+	secondSpan.SetName("second-name")
+	assert.Equal(t, expected, retrieved)
+}
+
+func TestDiskShutdownRemovesTraces(t *testing.T) {
+	// prepare
+	st, client := newStartedDiskStorage(t)
+	traceID := pdata.NewTraceID([16]byte{1, 2, 3, 4})
+	require.NoError(t, st.createOrAppend(traceID, simpleTracesWithID(traceID)))
+	require.Len(t, client.data, 3)
+
+	// test
+	require.NoError(t, st.shutdown())
+
+	// verify
+	assert.Empty(t, client.data)
+	assert.True(t, client.closed)
+}
+
+func TestDiskFailedWriteIsNotCounted(t *testing.T) {
+	// prepare
+	st, client := newStartedDiskStorage(t)
+	traceID := pdata.NewTraceID([16]byte{1, 2, 3, 4})
+	require.NoError(t, st.createOrAppend(traceID, simpleTracesWithID(traceID)))
+
+	// test
+	client.err = errors.New("some error")
+	assert.Error(t, st.createOrAppend(traceID, simpleTracesWithID(traceID)))
+	assert.Error(t, st.createOrAppend(pdata.NewTraceID([16]byte{2, 3, 4, 5}), simpleTracesWithID(traceID)))
+	client.err = nil
+	require.NoError(t, st.createOrAppend(traceID, simpleTracesWithID(traceID)))
+
+	// verify
+	assert.Equal(t, 1, st.count())
+	assert.Contains(t, client.data, chunkKey(traceID, 1))
+	retrieved, err := st.get(traceID)
+	require.NoError(t, err)
+	assert.Len(t, retrieved, 2)
+}
+
+func TestDiskSlotsAreReused(t *testing.T) {
+	// prepare
+	st, client := newStartedDiskStorage(t)
+	traceIDs := []pdata.TraceID{
+		pdata.NewTraceID([16]byte{1, 2, 3, 4}),
+		pdata.NewTraceID([16]byte{2, 3, 4, 5}),
+		pdata.NewTraceID([16]byte{3, 4, 5, 6}),
+	}
+	require.NoError(t, st.createOrAppend(traceIDs[0], simpleTracesWithID(traceIDs[0])))
+	require.NoError(t, st.createOrAppend(traceIDs[1], simpleTracesWithID(traceIDs[1])))
+
+	// test
+	_, err := st.delete(traceIDs[0])
+	require.NoError(t, err)
+	require.NoError(t, st.createOrAppend(traceIDs[2], simpleTracesWithID(traceIDs[2])))
+
+	// verify
+	assert.Equal(t, []byte("2"), client.data[slotsKey])
+	assert.Equal(t, []byte(traceIDs[2].HexString()), client.data[slotKey(0)])
+	assert.Equal(t, []byte(traceIDs[1].HexString()), client.data[slotKey(1)])
+}
+
+func TestDiskStartRemovesLeftovers(t *testing.T) {
+	// prepare
+	client := &mockStorageClient{data: make(map[string][]byte)}
+	host := &mockStorageHost{
+		Host:   componenttest.NewNopHost(),
+		client: client,
+	}
+	previous := newDiskStorage("file_storage", config.NewID(typeStr))
+	require.NoError(t, previous.start(context.Background(), host))
+	previous.stoppedLock.Lock()
+	previous.stopped = true
+	previous.stoppedLock.Unlock()
+
+	traceIDs := []pdata.TraceID{
+		pdata.NewTraceID([16]byte{1, 2, 3, 4}),
+		pdata.NewTraceID([16]byte{2, 3, 4, 5}),
+	}
+	for _, traceID := range traceIDs {
+		require.NoError(t, previous.createOrAppend(traceID, simpleTracesWithID(traceID)))
+		require.NoError(t, previous.createOrAppend(traceID, simpleTracesWithID(traceID)))
+	}
+	require.NotEmpty(t, client.data)
+
+	// test
+	st := newDiskStorage("file_storage", config.NewID(typeStr))
+	require.NoError(t, st.start(context.Background(), host))
+	defer func() {
+		st.stoppedLock.Lock()
+		st.stopped = true
+		st.stoppedLock.Unlock()
+	}()
+
+	// verify
+	assert.Empty(t, client.data)
+	assert.Equal(t, 0, st.count())
+}
+
+func TestDiskStorageNotFound(t *testing.T) {
+	// prepare
+	st := newDiskStorage("file_storage", config.NewID(typeStr))
+
+	// test
+	err := st.start(context.Background(), componenttest.NewNopHost())
+
+	// verify
+	assert.EqualError(t, err, "failed to find storage extension: 'file_storage'; please configure storage from one of: []")
+}
+
+func newStartedDiskStorage(t *testing.T) (*diskStorage, *mockStorageClient) {
+	client := &mockStorageClient{data: make(map[string][]byte)}
+	host := &mockStorageHost{
+		Host:   componenttest.NewNopHost(),
+		client: client,
+	}
+
+	st := newDiskStorage("file_storage", config.NewID(typeStr))
+	require.NoError(t, st.start(context.Background(), host))
+	t.Cleanup(func() {
+		st.stoppedLock.Lock()
+		st.stopped = true
+		st.stoppedLock.Unlock()
+	})
+	return st, client
+}
+
+type mockStorageHost struct {
+	component.Host
+	client extstorage.Client
+}
+
+func (h *mockStorageHost) GetExtensions() map[config.ComponentID]component.Extension {
+	return map[config.ComponentID]component.Extension{
+		config.NewID("file_storage"): &mockStorageExtension{client: h.client},
+	}
+}
+
+type mockStorageExtension struct {
+	component.Extension
+	client extstorage.Client
+}
+
+func (m *mockStorageExtension) GetClient(context.Context, component.Kind, config.ComponentID, string) (extstorage.Client, error) {
+	return m.client, nil
+}
+
+type mockStorageClient struct {
+	sync.Mutex
+	data   map[string][]byte
+	closed bool
+	// err is returned by the write operations, when set
+	err error
+}
+
+var _ extstorage.Client = (*mockStorageClient)(nil)
+
+func (m *mockStorageClient) Get(ctx context.Context, key string) ([]byte, error) {
+	op := extstorage.GetOperation(key)
+	err := m.Batch(ctx, op)
+	return op.Value, err
+}
+
+func (m *mockStorageClient) Set(ctx context.Context, key string, value []byte) error {
+	return m.Batch(ctx, extstorage.SetOperation(key, value))
+}
+
+func (m *mockStorageClient) Delete(ctx context.Context, key string) error {
+	return m.Batch(ctx, extstorage.DeleteOperation(key))
+}
+
+func (m *mockStorageClient) Batch(_ context.Context, ops ...extstorage.Operation) error {
+	m.Lock()
+	defer m.Unlock()
+	for _, op := range ops {
+		if m.err != nil && op.Type != extstorage.Get {
+			return m.err
+		}
+	}
+	for _, op := range ops {
+		switch op.Type {
+		case extstorage.Get:
+			op.Value = m.data[op.Key]
+		case extstorage.Set:
+			m.data[op.Key] = op.Value
+		case extstorage.Delete:
+			delete(m.data, op.Key)
+		}
+	}
+	return nil
+}
+
+func (m *mockStorageClient) Close(context.Context) error {
+	m.closed = true
+	return nil
+}
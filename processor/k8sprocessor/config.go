@@ -63,8 +63,22 @@ type ExtractConfig struct {
 	//   k8s.pod.name, k8s.pod.uid, k8s.deployment.name, k8s.cluster.name,
 	//   k8s.node.name, k8s.namespace.name and k8s.pod.start_time
 	//
+	// The following workload fields, resolved from the owner references of the pod,
+	// are also supported and have to be explicitly listed,
+	//   k8s.deployment.uid, k8s.replicaset.name, k8s.replicaset.uid,
+	//   k8s.statefulset.name, k8s.statefulset.uid, k8s.daemonset.name,
+	//   k8s.daemonset.uid, k8s.job.name, k8s.job.uid, k8s.cronjob.name
+	//   and k8s.cronjob.uid
+	//
+	// The following container fields, added when the k8s.container.name or the container.id
+	// resource attribute identifies a container of the pod, are also supported and have
+	// to be explicitly listed,
+	//   container.image.name, container.image.tag, container.id and
+	//   k8s.container.restart_count
+	//
 	// Specifying anything other than these values will result in an error.
-	// By default all of the fields are extracted and added to spans and metrics.
+	// By default all of the fields but the workload and container ones are extracted
+	// and added to spans and metrics.
 	Metadata []string `mapstructure:"metadata"`
 
 	// Annotations allows extracting data from pod annotations and record it
@@ -119,7 +133,7 @@ type FieldExtractConfig struct {
 	Key     string `mapstructure:"key"`
 	Regex   string `mapstructure:"regex"`
 	// From represents the source of the labels/annotations.
-	// Allowed values are "pod" and "namespace". The default is pod.
+	// Allowed values are "pod", "namespace" and "node". The default is pod.
 	From string `mapstructure:"from"`
 }
 
@@ -40,13 +40,43 @@
 //
 // If Pod association rules are not configured resources are associated with metadata only by connection's IP Address.
 //
-//
-//The k8sprocessor can be used for automatic tagging of spans, metrics and logs with k8s labels and annotations from pods and namespaces.
+// The workloads managing a pod are resolved by walking its owner references. The statefulset, daemonset, replicaset
+// and job names and UIDs are read from the pod itself, while the deployment and the cronjob are respectively resolved
+// through the replicaset and the job owning the pod, which are watched only when one of their fields is extracted.
+// metadata:
+//  - k8s.deployment.name
+//  - k8s.deployment.uid
+//  - k8s.replicaset.name
+//  - k8s.replicaset.uid
+//  - k8s.statefulset.name
+//  - k8s.statefulset.uid
+//  - k8s.daemonset.name
+//  - k8s.daemonset.uid
+//  - k8s.job.name
+//  - k8s.job.uid
+//  - k8s.cronjob.name
+//  - k8s.cronjob.uid
+//
+// Container metadata is added when the resource carries the "k8s.container.name" attribute or the "container.id"
+// attribute identifying a container of the pod. Otherwise, the container is looked up from the "log.file.path"
+// attribute, when it's a path written by the kubelet: /var/log/pods/<namespace>_<pod>_<uid>/<container>/<restart>.log
+// or /var/log/containers/<pod>_<namespace>_<container>-<id>.log. The image name and tag are read from the pod spec,
+// while the container ID and restart count are read from the pod status.
+// metadata:
+//  - container.image.name
+//  - container.image.tag
+//  - container.id
+//  - k8s.container.restart_count
+//
+//
+//The k8sprocessor can be used for automatic tagging of spans, metrics and logs with k8s labels and annotations from pods, namespaces and nodes.
 //The config for associating the data passing through the processor (spans, metrics and logs) with specific Pod/Namespace annotations/labels is configured via "annotations"  and "labels" keys.
-//This config represents a list of annotations/labels that are extracted from pods/namespaces and added to spans, metrics and logs.
+//This config represents a list of annotations/labels that are extracted from pods/namespaces/nodes and added to spans, metrics and logs.
 //Each item is specified as a config of tag_name (representing the tag name to tag the spans with),
 //key (representing the key used to extract value) and from (representing the kubernetes object used to extract the value).
-//The "from" field has only two possible values "pod" and "namespace" and defaults to "pod" if none is specified.
+//The "from" field has three possible values "pod", "namespace" and "node" and defaults to "pod" if none is specified.
+//Node labels and annotations are taken from the node the pod runs on, or from the node named by the "k8s.node.name"
+//resource attribute when the pod is unknown. When "filter.node" is set, only that node is watched.
 //
 //A few examples to use this config are as follows:
 //annotations:
@@ -65,10 +95,19 @@
 //	  key: label2
 //	  regex: field=(?P<value>.+)
 //	  from: pod
+//  - tag_name: cloud.availability_zone # extracts value of label from nodes with key `topology.kubernetes.io/zone` and inserts it as a tag with key `cloud.availability_zone`
+//	  key: topology.kubernetes.io/zone
+//	  from: node
 
 // RBAC
 //
-// TODO: mention the required RBAC rules.
+// The processor requires the permissions to list and watch "pods", and "namespaces" when namespace labels or
+// annotations are extracted. k8s.deployment.name is derived from the pod name, unless k8s.deployment.uid is
+// extracted, which requires the permissions to list and watch "replicasets" in the "apps" API group. Extracting
+// k8s.cronjob.name or k8s.cronjob.uid requires the permissions to list and watch "jobs" in the "batch" API group.
+// Extracting labels or annotations from nodes requires the permissions to list and watch "nodes". When replicasets
+// or jobs can't be listed, the owners of pods are resolved on a best effort basis, and k8s.deployment.name is
+// derived from the pod name. See the README for an example ClusterRole.
 //
 // Config
 //
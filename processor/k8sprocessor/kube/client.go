@@ -15,14 +15,18 @@
 package kube
 
 import (
+	"context"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	conventions "go.opentelemetry.io/collector/translator/conventions/v1.5.0"
 	"go.uber.org/zap"
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
 	api_v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
@@ -34,17 +38,25 @@ import (
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/k8sprocessor/observability"
 )
 
+// deploymentRegex extracts the deployment name from the name of a pod created by it, in the format:
+// [deployment-name]-[Random-String-For-ReplicaSet]-[Random-String-For-Pod]
+var deploymentRegex = regexp.MustCompile(`^(.*)-[0-9a-zA-Z]*-[0-9a-zA-Z]*$`)
+
 // WatchClient is the main interface provided by this package to a kubernetes cluster.
 type WatchClient struct {
-	m                 sync.RWMutex
-	deleteMut         sync.Mutex
-	logger            *zap.Logger
-	kc                kubernetes.Interface
-	informer          cache.SharedInformer
-	namespaceInformer cache.SharedInformer
-	deploymentRegex   *regexp.Regexp
-	deleteQueue       []deleteRequest
-	stopCh            chan struct{}
+	m                  sync.RWMutex
+	deleteMut          sync.Mutex
+	logger             *zap.Logger
+	kc                 kubernetes.Interface
+	informer           cache.SharedInformer
+	namespaceInformer  cache.SharedInformer
+	replicasetInformer cache.SharedInformer
+	jobInformer        cache.SharedInformer
+	nodeInformer       cache.SharedInformer
+	deleteQueue        []deleteRequest
+	stopCh             chan struct{}
+	// ownerSyncTimeout bounds the wait for the replicaset and job caches to sync on start
+	ownerSyncTimeout time.Duration
 
 	// A map containing Pod related data, used to associate them with resources.
 	// Key can be either an IP address or Pod UID
@@ -57,27 +69,40 @@ type WatchClient struct {
 	// A map containing Namespace related data, used to associate them with resources.
 	// Key is namespace name
 	Namespaces map[string]*Namespace
-}
 
-// Extract deployment name from the pod name. Pod name is created using
-// format: [deployment-name]-[Random-String-For-ReplicaSet]-[Random-String-For-Pod]
-var dRegex = regexp.MustCompile(`^(.*)-[0-9a-zA-Z]*-[0-9a-zA-Z]*$`)
+	// A map containing Node related data, used to associate them with resources.
+	// Key is node name
+	Nodes map[string]*Node
+
+	// A map containing ReplicaSet related data, used to resolve the deployment owning a pod.
+	// Key is replicaset UID
+	ReplicaSets map[string]*ReplicaSet
+
+	// A map containing Job related data, used to resolve the cronjob owning a pod.
+	// Key is job UID
+	Jobs map[string]*Job
+}
 
 // New initializes a new k8s Client.
-func New(logger *zap.Logger, apiCfg k8sconfig.APIConfig, rules ExtractionRules, filters Filters, associations []Association, exclude Excludes, newClientSet APIClientsetProvider, newInformer InformerProvider, newNamespaceInformer InformerProviderNamespace) (Client, error) {
+func New(logger *zap.Logger, apiCfg k8sconfig.APIConfig, rules ExtractionRules, filters Filters, associations []Association, exclude Excludes, opts ClientOptions) (Client, error) {
 	c := &WatchClient{
-		logger:          logger,
-		Rules:           rules,
-		Filters:         filters,
-		Associations:    associations,
-		Exclude:         exclude,
-		deploymentRegex: dRegex,
-		stopCh:          make(chan struct{}),
+		logger:       logger,
+		Rules:        rules,
+		Filters:      filters,
+		Associations: associations,
+		Exclude:      exclude,
+		stopCh:       make(chan struct{}),
+
+		ownerSyncTimeout: defaultOwnerSyncTimeout,
 	}
 	go c.deleteLoop(time.Second*30, defaultPodDeleteGracePeriod)
 
 	c.Pods = map[PodIdentifier]*Pod{}
 	c.Namespaces = map[string]*Namespace{}
+	c.Nodes = map[string]*Node{}
+	c.ReplicaSets = map[string]*ReplicaSet{}
+	c.Jobs = map[string]*Job{}
+	newClientSet := opts.APIClientset
 	if newClientSet == nil {
 		newClientSet = k8sconfig.MakeClient
 	}
@@ -97,25 +122,75 @@ func New(logger *zap.Logger, apiCfg k8sconfig.APIConfig, rules ExtractionRules,
 		zap.String("labelSelector", labelSelector.String()),
 		zap.String("fieldSelector", fieldSelector.String()),
 	)
+	newInformer := opts.Informer
 	if newInformer == nil {
 		newInformer = newSharedInformer
 	}
 
+	newNamespaceInformer := opts.NamespaceInformer
 	if newNamespaceInformer == nil {
 		newNamespaceInformer = newNamespaceSharedInformer
 	}
 
+	newReplicaSetInformer := opts.ReplicaSetInformer
+	if newReplicaSetInformer == nil {
+		newReplicaSetInformer = newReplicaSetSharedInformer
+	}
+
+	newJobInformer := opts.JobInformer
+	if newJobInformer == nil {
+		newJobInformer = newJobSharedInformer
+	}
+
+	newNodeInformer := opts.NodeInformer
+	if newNodeInformer == nil {
+		newNodeInformer = newNodeSharedInformer
+	}
+
 	c.informer = newInformer(c.kc, c.Filters.Namespace, labelSelector, fieldSelector)
 	if c.extractNamespaceLabelsAnnotations() {
 		c.namespaceInformer = newNamespaceInformer(c.kc)
 	} else {
 		c.namespaceInformer = NewNoOpInformer(c.kc)
 	}
+	if c.extractReplicaSetOwners() {
+		c.replicasetInformer = newReplicaSetInformer(c.kc, c.Filters.Namespace)
+	} else {
+		c.replicasetInformer = NewNoOpInformer(c.kc)
+	}
+	if c.extractJobOwners() {
+		c.jobInformer = newJobInformer(c.kc, c.Filters.Namespace)
+	} else {
+		c.jobInformer = NewNoOpInformer(c.kc)
+	}
+	if c.extractNodeLabelsAnnotations() {
+		c.nodeInformer = newNodeInformer(c.kc, c.Filters.Node)
+	} else {
+		c.nodeInformer = NewNoOpInformer(c.kc)
+	}
 	return c, err
 }
 
 // Start registers pod event handlers and starts watching the kubernetes cluster for pod changes.
 func (c *WatchClient) Start() {
+	c.replicasetInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.handleReplicaSetAdd,
+		UpdateFunc: c.handleReplicaSetUpdate,
+		DeleteFunc: c.handleReplicaSetDelete,
+	})
+	go c.replicasetInformer.Run(c.stopCh)
+	c.jobInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.handleJobAdd,
+		UpdateFunc: c.handleJobUpdate,
+		DeleteFunc: c.handleJobDelete,
+	})
+	go c.jobInformer.Run(c.stopCh)
+	// Pods are resolved to their workloads when they are added, so wait for the
+	// replicasets and jobs to be known before watching pods. The wait is bounded,
+	// as the permissions to list them might be missing, in which case the owners
+	// of pods are resolved on a best effort basis.
+	c.waitForOwnerCaches()
+
 	c.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.handlePodAdd,
 		UpdateFunc: c.handlePodUpdate,
@@ -128,6 +203,36 @@ func (c *WatchClient) Start() {
 		DeleteFunc: c.handleNamespaceDelete,
 	})
 	go c.namespaceInformer.Run(c.stopCh)
+	c.nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.handleNodeAdd,
+		UpdateFunc: c.handleNodeUpdate,
+		DeleteFunc: c.handleNodeDelete,
+	})
+	go c.nodeInformer.Run(c.stopCh)
+}
+
+// waitForOwnerCaches waits for the replicaset and job caches to sync, up to ownerSyncTimeout.
+func (c *WatchClient) waitForOwnerCaches() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.ownerSyncTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if cache.WaitForCacheSync(ctx.Done(), c.replicasetInformer.HasSynced, c.jobInformer.HasSynced) {
+		return
+	}
+	select {
+	case <-c.stopCh:
+	default:
+		c.logger.Warn("timed out waiting for the replicaset and job caches to sync, "+
+			"check that the collector is allowed to list and watch replicasets and jobs",
+			zap.Duration("timeout", c.ownerSyncTimeout))
+	}
 }
 
 // Stop signals the the k8s watcher/informer to stop watching for new events.
@@ -203,6 +308,86 @@ func (c *WatchClient) handleNamespaceDelete(obj interface{}) {
 	}
 }
 
+func (c *WatchClient) handleNodeAdd(obj interface{}) {
+	if node, ok := obj.(*api_v1.Node); ok {
+		c.addOrUpdateNode(node)
+	} else {
+		c.logger.Error("object received was not of type api_v1.Node", zap.Any("received", obj))
+	}
+}
+
+func (c *WatchClient) handleNodeUpdate(old, new interface{}) {
+	if node, ok := new.(*api_v1.Node); ok {
+		c.addOrUpdateNode(node)
+	} else {
+		c.logger.Error("object received was not of type api_v1.Node", zap.Any("received", new))
+	}
+}
+
+func (c *WatchClient) handleNodeDelete(obj interface{}) {
+	if node, ok := obj.(*api_v1.Node); ok {
+		c.m.Lock()
+		delete(c.Nodes, node.Name)
+		c.m.Unlock()
+	} else {
+		c.logger.Error("object received was not of type api_v1.Node", zap.Any("received", obj))
+	}
+}
+
+func (c *WatchClient) handleReplicaSetAdd(obj interface{}) {
+	if replicaset, ok := obj.(*apps_v1.ReplicaSet); ok {
+		c.addOrUpdateReplicaSet(replicaset)
+	} else {
+		c.logger.Error("object received was not of type apps_v1.ReplicaSet", zap.Any("received", obj))
+	}
+}
+
+func (c *WatchClient) handleReplicaSetUpdate(old, new interface{}) {
+	if replicaset, ok := new.(*apps_v1.ReplicaSet); ok {
+		c.addOrUpdateReplicaSet(replicaset)
+	} else {
+		c.logger.Error("object received was not of type apps_v1.ReplicaSet", zap.Any("received", new))
+	}
+}
+
+func (c *WatchClient) handleReplicaSetDelete(obj interface{}) {
+	if replicaset, ok := obj.(*apps_v1.ReplicaSet); ok {
+		// Pods are resolved to their deployment when they are added, so the
+		// attributes of the remaining pods are not affected by the deletion.
+		c.m.Lock()
+		delete(c.ReplicaSets, string(replicaset.UID))
+		c.m.Unlock()
+	} else {
+		c.logger.Error("object received was not of type apps_v1.ReplicaSet", zap.Any("received", obj))
+	}
+}
+
+func (c *WatchClient) handleJobAdd(obj interface{}) {
+	if job, ok := obj.(*batch_v1.Job); ok {
+		c.addOrUpdateJob(job)
+	} else {
+		c.logger.Error("object received was not of type batch_v1.Job", zap.Any("received", obj))
+	}
+}
+
+func (c *WatchClient) handleJobUpdate(old, new interface{}) {
+	if job, ok := new.(*batch_v1.Job); ok {
+		c.addOrUpdateJob(job)
+	} else {
+		c.logger.Error("object received was not of type batch_v1.Job", zap.Any("received", new))
+	}
+}
+
+func (c *WatchClient) handleJobDelete(obj interface{}) {
+	if job, ok := obj.(*batch_v1.Job); ok {
+		c.m.Lock()
+		delete(c.Jobs, string(job.UID))
+		c.m.Unlock()
+	} else {
+		c.logger.Error("object received was not of type batch_v1.Job", zap.Any("received", obj))
+	}
+}
+
 func (c *WatchClient) deleteLoop(interval time.Duration, gracePeriod time.Duration) {
 	// This loop runs after N seconds and deletes pods from cache.
 	// It iterates over the delete queue and deletes all that aren't
@@ -269,6 +454,17 @@ func (c *WatchClient) GetNamespace(namespace string) (*Namespace, bool) {
 	return nil, false
 }
 
+// GetNode takes a node name and returns the node object the name is associated with.
+func (c *WatchClient) GetNode(nodeName string) (*Node, bool) {
+	c.m.RLock()
+	node, ok := c.Nodes[nodeName]
+	c.m.RUnlock()
+	if ok {
+		return node, ok
+	}
+	return nil, false
+}
+
 func (c *WatchClient) extractPodAttributes(pod *api_v1.Pod) map[string]string {
 	tags := map[string]string{}
 	if c.Rules.PodName {
@@ -291,13 +487,7 @@ func (c *WatchClient) extractPodAttributes(pod *api_v1.Pod) map[string]string {
 		tags[conventions.AttributeK8SPodUID] = string(uid)
 	}
 
-	if c.Rules.Deployment {
-		// format: [deployment-name]-[Random-String-For-ReplicaSet]-[Random-String-For-Pod]
-		parts := c.deploymentRegex.FindStringSubmatch(pod.Name)
-		if len(parts) == 2 {
-			tags[conventions.AttributeK8SDeploymentName] = parts[1]
-		}
-	}
+	c.extractOwnerAttributes(pod, tags)
 
 	if c.Rules.Node {
 		tags[tagNodeName] = pod.Spec.NodeName
@@ -330,6 +520,146 @@ func (c *WatchClient) extractPodAttributes(pod *api_v1.Pod) map[string]string {
 	return tags
 }
 
+// extractOwnerAttributes walks the owner references of the pod to add the names and UIDs of the
+// workloads managing it. Deployments and cronjobs are resolved through the replicaset and the job
+// owning the pod.
+func (c *WatchClient) extractOwnerAttributes(pod *api_v1.Pod, tags map[string]string) {
+	for _, ref := range pod.OwnerReferences {
+		switch ref.Kind {
+		case "ReplicaSet":
+			if c.Rules.ReplicaSet {
+				tags[conventions.AttributeK8SReplicasetName] = ref.Name
+			}
+			if c.Rules.ReplicaSetUID {
+				tags[conventions.AttributeK8SReplicasetUID] = string(ref.UID)
+			}
+			replicaset, ok := c.getReplicaSet(string(ref.UID))
+			if !ok {
+				// The replicaset isn't known, fall back to the deployment name found in the pod name
+				if c.Rules.Deployment {
+					if parts := deploymentRegex.FindStringSubmatch(pod.Name); len(parts) == 2 {
+						tags[conventions.AttributeK8SDeploymentName] = parts[1]
+					}
+				}
+			} else if replicaset.Deployment.Name != "" {
+				if c.Rules.Deployment {
+					tags[conventions.AttributeK8SDeploymentName] = replicaset.Deployment.Name
+				}
+				if c.Rules.DeploymentUID {
+					tags[conventions.AttributeK8SDeploymentUID] = replicaset.Deployment.UID
+				}
+			}
+		case "StatefulSet":
+			if c.Rules.StatefulSet {
+				tags[conventions.AttributeK8SStatefulsetName] = ref.Name
+			}
+			if c.Rules.StatefulSetUID {
+				tags[conventions.AttributeK8SStatefulsetUID] = string(ref.UID)
+			}
+		case "DaemonSet":
+			if c.Rules.DaemonSet {
+				tags[conventions.AttributeK8SDaemonsetName] = ref.Name
+			}
+			if c.Rules.DaemonSetUID {
+				tags[conventions.AttributeK8SDaemonsetUID] = string(ref.UID)
+			}
+		case "Job":
+			if c.Rules.Job {
+				tags[conventions.AttributeK8SJobName] = ref.Name
+			}
+			if c.Rules.JobUID {
+				tags[conventions.AttributeK8SJobUID] = string(ref.UID)
+			}
+			if job, ok := c.getJob(string(ref.UID)); ok && job.CronJob.Name != "" {
+				if c.Rules.CronJob {
+					tags[conventions.AttributeK8SCronJobName] = job.CronJob.Name
+				}
+				if c.Rules.CronJobUID {
+					tags[conventions.AttributeK8SCronJobUID] = job.CronJob.UID
+				}
+			}
+		}
+	}
+}
+
+func (c *WatchClient) getReplicaSet(uid string) (*ReplicaSet, bool) {
+	c.m.RLock()
+	replicaset, ok := c.ReplicaSets[uid]
+	c.m.RUnlock()
+	return replicaset, ok
+}
+
+func (c *WatchClient) getJob(uid string) (*Job, bool) {
+	c.m.RLock()
+	job, ok := c.Jobs[uid]
+	c.m.RUnlock()
+	return job, ok
+}
+
+// extractPodContainers returns the containers of the pod, with their attributes extracted from the pod spec and status.
+func (c *WatchClient) extractPodContainers(pod *api_v1.Pod) map[string]*Container {
+	containers := map[string]*Container{}
+	for _, specs := range [][]api_v1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, spec := range specs {
+			container := &Container{
+				Name:       spec.Name,
+				Attributes: map[string]string{},
+			}
+			imageName, imageTag := parseImage(spec.Image)
+			if c.Rules.ContainerImageName && imageName != "" {
+				container.Attributes[conventions.AttributeContainerImageName] = imageName
+			}
+			if c.Rules.ContainerImageTag && imageTag != "" {
+				container.Attributes[conventions.AttributeContainerImageTag] = imageTag
+			}
+			containers[spec.Name] = container
+		}
+	}
+
+	for _, statuses := range [][]api_v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			container, ok := containers[status.Name]
+			if !ok {
+				continue
+			}
+			container.ID = trimContainerRuntime(status.ContainerID)
+			if c.Rules.ContainerID && container.ID != "" {
+				container.Attributes[conventions.AttributeContainerID] = container.ID
+			}
+			if c.Rules.ContainerRestartCount {
+				container.Attributes[TagContainerRestartCount] = strconv.Itoa(int(status.RestartCount))
+			}
+		}
+	}
+	return containers
+}
+
+// parseImage splits a container image reference, such as registry:5000/org/app:1.0, into its name and tag.
+// The tag defaults to latest when neither a tag nor a digest is set.
+func parseImage(image string) (name, tag string) {
+	name = image
+	hasDigest := false
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+		hasDigest = true
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		return name[:i], name[i+1:]
+	}
+	if hasDigest || name == "" {
+		return name, ""
+	}
+	return name, "latest"
+}
+
+// trimContainerRuntime removes the container runtime prefix, such as docker://, from a container ID.
+func trimContainerRuntime(containerID string) string {
+	if i := strings.Index(containerID, "://"); i >= 0 {
+		return containerID[i+len("://"):]
+	}
+	return containerID
+}
+
 func (c *WatchClient) extractNamespaceAttributes(namespace *api_v1.Namespace) map[string]string {
 	tags := map[string]string{}
 
@@ -351,6 +681,27 @@ func (c *WatchClient) extractNamespaceAttributes(namespace *api_v1.Namespace) ma
 	return tags
 }
 
+func (c *WatchClient) extractNodeAttributes(node *api_v1.Node) map[string]string {
+	tags := map[string]string{}
+
+	for _, r := range c.Rules.Labels {
+		if r.From == MetadataFromNode {
+			if v, ok := node.Labels[r.Key]; ok {
+				tags[r.Name] = c.extractField(v, r)
+			}
+		}
+	}
+
+	for _, r := range c.Rules.Annotations {
+		if r.From == MetadataFromNode {
+			if v, ok := node.Annotations[r.Key]; ok {
+				tags[r.Name] = c.extractField(v, r)
+			}
+		}
+	}
+	return tags
+}
+
 func (c *WatchClient) extractField(v string, r FieldExtractionRule) string {
 	// Check if a subset of the field should be extracted with a regular expression
 	// instead of the whole field.
@@ -372,12 +723,16 @@ func (c *WatchClient) addOrUpdatePod(pod *api_v1.Pod) {
 		Address:   pod.Status.PodIP,
 		PodUID:    string(pod.UID),
 		StartTime: pod.Status.StartTime,
+		NodeName:  pod.Spec.NodeName,
 	}
 
 	if c.shouldIgnorePod(pod) {
 		newPod.Ignore = true
 	} else {
 		newPod.Attributes = c.extractPodAttributes(pod)
+		if c.extractContainerAttributes() {
+			newPod.Containers = c.extractPodContainers(pod)
+		}
 	}
 
 	c.m.Lock()
@@ -513,3 +868,96 @@ func (c *WatchClient) extractNamespaceLabelsAnnotations() bool {
 
 	return false
 }
+
+func (c *WatchClient) addOrUpdateReplicaSet(replicaset *apps_v1.ReplicaSet) {
+	newReplicaSet := &ReplicaSet{
+		Name:      replicaset.Name,
+		Namespace: replicaset.Namespace,
+		UID:       string(replicaset.UID),
+	}
+	for _, ref := range replicaset.OwnerReferences {
+		if ref.Kind == "Deployment" {
+			newReplicaSet.Deployment = Deployment{
+				Name: ref.Name,
+				UID:  string(ref.UID),
+			}
+			break
+		}
+	}
+
+	c.m.Lock()
+	if replicaset.UID != "" {
+		c.ReplicaSets[string(replicaset.UID)] = newReplicaSet
+	}
+	c.m.Unlock()
+}
+
+func (c *WatchClient) addOrUpdateJob(job *batch_v1.Job) {
+	newJob := &Job{
+		Name:      job.Name,
+		Namespace: job.Namespace,
+		UID:       string(job.UID),
+	}
+	for _, ref := range job.OwnerReferences {
+		if ref.Kind == "CronJob" {
+			newJob.CronJob = CronJob{
+				Name: ref.Name,
+				UID:  string(ref.UID),
+			}
+			break
+		}
+	}
+
+	c.m.Lock()
+	if job.UID != "" {
+		c.Jobs[string(job.UID)] = newJob
+	}
+	c.m.Unlock()
+}
+
+func (c *WatchClient) addOrUpdateNode(node *api_v1.Node) {
+	newNode := &Node{
+		Name:    node.Name,
+		NodeUID: string(node.UID),
+	}
+	newNode.Attributes = c.extractNodeAttributes(node)
+
+	c.m.Lock()
+	if node.Name != "" {
+		c.Nodes[node.Name] = newNode
+	}
+	c.m.Unlock()
+}
+
+func (c *WatchClient) extractNodeLabelsAnnotations() bool {
+	for _, r := range c.Rules.Labels {
+		if r.From == MetadataFromNode {
+			return true
+		}
+	}
+
+	for _, r := range c.Rules.Annotations {
+		if r.From == MetadataFromNode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// extractContainerAttributes returns whether the containers of pods need to be recorded to extract their attributes.
+func (c *WatchClient) extractContainerAttributes() bool {
+	return c.Rules.ContainerImageName || c.Rules.ContainerImageTag || c.Rules.ContainerID || c.Rules.ContainerRestartCount
+}
+
+// extractReplicaSetOwners returns whether replicasets need to be watched to resolve the deployment owning pods.
+// They are only watched when the deployment UID is requested, the deployment name being otherwise derived
+// from the pod name, so that the default metadata doesn't require the permissions to watch replicasets.
+func (c *WatchClient) extractReplicaSetOwners() bool {
+	return c.Rules.DeploymentUID
+}
+
+// extractJobOwners returns whether jobs need to be watched to resolve the cronjob owning pods.
+func (c *WatchClient) extractJobOwners() bool {
+	return c.Rules.CronJob || c.Rules.CronJobUID
+}
@@ -26,6 +26,8 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
 	api_v1 "k8s.io/api/core/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -33,6 +35,7 @@ import (
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/k8sconfig"
 )
@@ -112,12 +115,12 @@ func namespaceAddAndUpdateTest(t *testing.T, c *WatchClient, handler func(obj in
 }
 
 func TestDefaultClientset(t *testing.T) {
-	c, err := New(zap.NewNop(), k8sconfig.APIConfig{}, ExtractionRules{}, Filters{}, []Association{}, Excludes{}, nil, nil, nil)
+	c, err := New(zap.NewNop(), k8sconfig.APIConfig{}, ExtractionRules{}, Filters{}, []Association{}, Excludes{}, ClientOptions{})
 	assert.Error(t, err)
 	assert.Equal(t, "invalid authType for kubernetes: ", err.Error())
 	assert.Nil(t, c)
 
-	c, err = New(zap.NewNop(), k8sconfig.APIConfig{}, ExtractionRules{}, Filters{}, []Association{}, Excludes{}, newFakeAPIClientset, nil, nil)
+	c, err = New(zap.NewNop(), k8sconfig.APIConfig{}, ExtractionRules{}, Filters{}, []Association{}, Excludes{}, ClientOptions{APIClientset: newFakeAPIClientset})
 	assert.NoError(t, err)
 	assert.NotNil(t, c)
 }
@@ -130,9 +133,7 @@ func TestBadFilters(t *testing.T) {
 		Filters{Fields: []FieldFilter{{Op: selection.Exists}}},
 		[]Association{},
 		Excludes{},
-		newFakeAPIClientset,
-		NewFakeInformer,
-		NewFakeNamespaceInformer,
+		fakeClientOptions(),
 	)
 	assert.Error(t, err)
 	assert.Nil(t, c)
@@ -157,6 +158,56 @@ func TestClientStartStop(t *testing.T) {
 	assert.True(t, fctr.HasStopped())
 }
 
+// unsyncedInformer never syncs, like the informer of a resource the collector isn't allowed to list.
+type unsyncedInformer struct {
+	*FakeInformer
+}
+
+func (f *unsyncedInformer) HasSynced() bool {
+	return false
+}
+
+func TestClientStartWithUnsyncedOwnerCaches(t *testing.T) {
+	observedLogger, logs := observer.New(zapcore.WarnLevel)
+	newUnsyncedInformer := func(kc kubernetes.Interface, namespace string) cache.SharedInformer {
+		return &unsyncedInformer{FakeInformer: NewFakeWorkloadInformer(kc, namespace).(*FakeInformer)}
+	}
+	opts := fakeClientOptions()
+	opts.ReplicaSetInformer = newUnsyncedInformer
+	client, err := New(zap.New(observedLogger), k8sconfig.APIConfig{}, ExtractionRules{DeploymentUID: true}, Filters{}, []Association{}, Excludes{}, opts)
+	require.NoError(t, err)
+	c := client.(*WatchClient)
+	c.ownerSyncTimeout = 50 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		c.Start()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return once the owner caches timed out")
+	}
+	assert.Equal(t, 1, logs.FilterMessageSnippet("timed out waiting for the replicaset and job caches to sync").Len())
+
+	// The pod informer runs regardless
+	c.Stop()
+	fctr := c.informer.GetController().(*FakeController)
+	assert.Eventually(t, fctr.HasStopped, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestOwnerInformersAreOptIn(t *testing.T) {
+	// The deployment name is derived from the pod name, without watching replicasets
+	c, _ := newTestClientWithRulesAndFilters(t, ExtractionRules{Deployment: true}, Filters{})
+	assert.IsType(t, &NoOpInformer{}, c.replicasetInformer)
+	assert.IsType(t, &NoOpInformer{}, c.jobInformer)
+
+	c, _ = newTestClientWithRulesAndFilters(t, ExtractionRules{DeploymentUID: true, CronJob: true}, Filters{})
+	assert.IsType(t, &FakeInformer{}, c.replicasetInformer)
+	assert.IsType(t, &FakeInformer{}, c.jobInformer)
+}
+
 func TestConstructorErrors(t *testing.T) {
 	er := ExtractionRules{}
 	ff := Filters{}
@@ -169,7 +220,9 @@ func TestConstructorErrors(t *testing.T) {
 			gotAPIConfig = c
 			return nil, fmt.Errorf("error creating k8s client")
 		}
-		c, err := New(zap.NewNop(), apiCfg, er, ff, []Association{}, Excludes{}, clientProvider, NewFakeInformer, NewFakeNamespaceInformer)
+		opts := fakeClientOptions()
+		opts.APIClientset = clientProvider
+		c, err := New(zap.NewNop(), apiCfg, er, ff, []Association{}, Excludes{}, opts)
 		assert.Nil(t, c)
 		assert.Error(t, err)
 		assert.Equal(t, err.Error(), "error creating k8s client")
@@ -403,6 +456,19 @@ func TestHandlerWrongType(t *testing.T) {
 func TestExtractionRules(t *testing.T) {
 	c, _ := newTestClientWithRulesAndFilters(t, ExtractionRules{}, Filters{})
 
+	c.handleReplicaSetAdd(&apps_v1.ReplicaSet{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "auth-service-abc12",
+			UID:       "11111111-2222-3333-4444-555555555555",
+			Namespace: "ns1",
+			OwnerReferences: []meta_v1.OwnerReference{{
+				Kind: "Deployment",
+				Name: "auth-service",
+				UID:  "66666666-7777-8888-9999-000000000000",
+			}},
+		},
+	})
+
 	pod := &api_v1.Pod{
 		ObjectMeta: meta_v1.ObjectMeta{
 			Name:              "auth-service-abc12-xyz3",
@@ -410,6 +476,11 @@ func TestExtractionRules(t *testing.T) {
 			Namespace:         "ns1",
 			CreationTimestamp: meta_v1.Now(),
 			ClusterName:       "cluster1",
+			OwnerReferences: []meta_v1.OwnerReference{{
+				Kind: "ReplicaSet",
+				Name: "auth-service-abc12",
+				UID:  "11111111-2222-3333-4444-555555555555",
+			}},
 			Labels: map[string]string{
 				"label1": "lv1",
 				"label2": "k1=v1 k5=v5 extra!",
@@ -442,6 +513,20 @@ func TestExtractionRules(t *testing.T) {
 		attributes: map[string]string{
 			"k8s.deployment.name": "auth-service",
 		},
+	}, {
+		name: "workload",
+		rules: ExtractionRules{
+			Deployment:    true,
+			DeploymentUID: true,
+			ReplicaSet:    true,
+			ReplicaSetUID: true,
+		},
+		attributes: map[string]string{
+			"k8s.deployment.name": "auth-service",
+			"k8s.deployment.uid":  "66666666-7777-8888-9999-000000000000",
+			"k8s.replicaset.name": "auth-service-abc12",
+			"k8s.replicaset.uid":  "11111111-2222-3333-4444-555555555555",
+		},
 	}, {
 		name: "metadata",
 		rules: ExtractionRules{
@@ -531,6 +616,270 @@ func TestExtractionRules(t *testing.T) {
 	}
 }
 
+func TestOwnerReferencesExtractionRules(t *testing.T) {
+	rules := ExtractionRules{
+		Deployment:     true,
+		DeploymentUID:  true,
+		ReplicaSet:     true,
+		ReplicaSetUID:  true,
+		StatefulSet:    true,
+		StatefulSetUID: true,
+		DaemonSet:      true,
+		DaemonSetUID:   true,
+		Job:            true,
+		JobUID:         true,
+		CronJob:        true,
+		CronJobUID:     true,
+	}
+	c, _ := newTestClientWithRulesAndFilters(t, rules, Filters{})
+
+	c.handleJobAdd(&batch_v1.Job{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: "backup-1628500000",
+			UID:  "job-uid",
+			OwnerReferences: []meta_v1.OwnerReference{{
+				Kind: "CronJob",
+				Name: "backup",
+				UID:  "cronjob-uid",
+			}},
+		},
+	})
+	c.handleReplicaSetAdd(&apps_v1.ReplicaSet{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: "web-abc12",
+			UID:  "replicaset-uid",
+		},
+	})
+
+	testCases := []struct {
+		name       string
+		owner      meta_v1.OwnerReference
+		attributes map[string]string
+	}{{
+		name:  "statefulset",
+		owner: meta_v1.OwnerReference{Kind: "StatefulSet", Name: "db", UID: "statefulset-uid"},
+		attributes: map[string]string{
+			"k8s.statefulset.name": "db",
+			"k8s.statefulset.uid":  "statefulset-uid",
+		},
+	}, {
+		name:  "daemonset",
+		owner: meta_v1.OwnerReference{Kind: "DaemonSet", Name: "agent", UID: "daemonset-uid"},
+		attributes: map[string]string{
+			"k8s.daemonset.name": "agent",
+			"k8s.daemonset.uid":  "daemonset-uid",
+		},
+	}, {
+		name:  "cronjob",
+		owner: meta_v1.OwnerReference{Kind: "Job", Name: "backup-1628500000", UID: "job-uid"},
+		attributes: map[string]string{
+			"k8s.job.name":     "backup-1628500000",
+			"k8s.job.uid":      "job-uid",
+			"k8s.cronjob.name": "backup",
+			"k8s.cronjob.uid":  "cronjob-uid",
+		},
+	}, {
+		name:  "job",
+		owner: meta_v1.OwnerReference{Kind: "Job", Name: "migration", UID: "other-job-uid"},
+		attributes: map[string]string{
+			"k8s.job.name": "migration",
+			"k8s.job.uid":  "other-job-uid",
+		},
+	}, {
+		// The replicaset is known and has no deployment.
+		name:  "bare-replicaset",
+		owner: meta_v1.OwnerReference{Kind: "ReplicaSet", Name: "web-abc12", UID: "replicaset-uid"},
+		attributes: map[string]string{
+			"k8s.replicaset.name": "web-abc12",
+			"k8s.replicaset.uid":  "replicaset-uid",
+		},
+	}, {
+		// The replicaset is unknown, so the deployment name is found in the pod name.
+		name:  "unknown-replicaset",
+		owner: meta_v1.OwnerReference{Kind: "ReplicaSet", Name: "looks-like-deployment-abc12", UID: "unknown-replicaset-uid"},
+		attributes: map[string]string{
+			"k8s.deployment.name": "looks-like-deployment",
+			"k8s.replicaset.name": "looks-like-deployment-abc12",
+			"k8s.replicaset.uid":  "unknown-replicaset-uid",
+		},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &api_v1.Pod{
+				ObjectMeta: meta_v1.ObjectMeta{
+					// The name looks like a pod of a deployment, it must only be used when the replicaset is unknown.
+					Name:            "looks-like-deployment-abc12-xyz3",
+					OwnerReferences: []meta_v1.OwnerReference{tc.owner},
+				},
+				Status: api_v1.PodStatus{
+					PodIP: "1.1.1.1",
+				},
+			}
+			c.handlePodAdd(pod)
+			p, ok := c.GetPod(PodIdentifier(pod.Status.PodIP))
+			require.True(t, ok)
+			assert.Equal(t, tc.attributes, p.Attributes)
+		})
+	}
+}
+
+func TestContainerExtractionRules(t *testing.T) {
+	pod := &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: "auth-service-abc12-xyz3",
+		},
+		Spec: api_v1.PodSpec{
+			InitContainers: []api_v1.Container{{
+				Name:  "init",
+				Image: "busybox",
+			}},
+			Containers: []api_v1.Container{{
+				Name:  "app",
+				Image: "example.com:5000/org/auth-service:1.2.3",
+			}, {
+				Name:  "sidecar",
+				Image: "example.com/sidecar@sha256:4a5573037f358b6cdfa2f3e8a9c33a5cf11bcd1675ca3b4e5fb9e2e9a6a8b6a1",
+			}},
+		},
+		Status: api_v1.PodStatus{
+			PodIP: "1.1.1.1",
+			InitContainerStatuses: []api_v1.ContainerStatus{{
+				Name:        "init",
+				ContainerID: "containerd://init-id",
+			}},
+			ContainerStatuses: []api_v1.ContainerStatus{{
+				Name:         "app",
+				ContainerID:  "docker://app-id",
+				RestartCount: 3,
+			}, {
+				Name: "sidecar",
+			}},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		rules      ExtractionRules
+		containers map[string]*Container
+	}{{
+		name:  "no-rules",
+		rules: ExtractionRules{},
+	}, {
+		name: "all",
+		rules: ExtractionRules{
+			ContainerImageName:    true,
+			ContainerImageTag:     true,
+			ContainerID:           true,
+			ContainerRestartCount: true,
+		},
+		containers: map[string]*Container{
+			"init": {
+				Name: "init",
+				ID:   "init-id",
+				Attributes: map[string]string{
+					"container.image.name":        "busybox",
+					"container.image.tag":         "latest",
+					"container.id":                "init-id",
+					"k8s.container.restart_count": "0",
+				},
+			},
+			"app": {
+				Name: "app",
+				ID:   "app-id",
+				Attributes: map[string]string{
+					"container.image.name":        "example.com:5000/org/auth-service",
+					"container.image.tag":         "1.2.3",
+					"container.id":                "app-id",
+					"k8s.container.restart_count": "3",
+				},
+			},
+			"sidecar": {
+				Name: "sidecar",
+				Attributes: map[string]string{
+					"container.image.name":        "example.com/sidecar",
+					"k8s.container.restart_count": "0",
+				},
+			},
+		},
+	}, {
+		name: "image",
+		rules: ExtractionRules{
+			ContainerImageName: true,
+		},
+		containers: map[string]*Container{
+			"init": {
+				Name:       "init",
+				ID:         "init-id",
+				Attributes: map[string]string{"container.image.name": "busybox"},
+			},
+			"app": {
+				Name:       "app",
+				ID:         "app-id",
+				Attributes: map[string]string{"container.image.name": "example.com:5000/org/auth-service"},
+			},
+			"sidecar": {
+				Name:       "sidecar",
+				Attributes: map[string]string{"container.image.name": "example.com/sidecar"},
+			},
+		},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := newTestClientWithRulesAndFilters(t, tc.rules, Filters{})
+			c.handlePodAdd(pod)
+			p, ok := c.GetPod(PodIdentifier(pod.Status.PodIP))
+			require.True(t, ok)
+			assert.Equal(t, tc.containers, p.Containers)
+		})
+	}
+}
+
+func TestReplicaSetAddUpdateDelete(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	replicaset := &apps_v1.ReplicaSet{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: "web-abc12",
+			UID:  "replicaset-uid",
+		},
+	}
+	c.handleReplicaSetAdd(replicaset)
+	require.Len(t, c.ReplicaSets, 1)
+	assert.Equal(t, Deployment{}, c.ReplicaSets["replicaset-uid"].Deployment)
+
+	updated := replicaset.DeepCopy()
+	updated.OwnerReferences = []meta_v1.OwnerReference{{Kind: "Deployment", Name: "web", UID: "deployment-uid"}}
+	c.handleReplicaSetUpdate(replicaset, updated)
+	require.Len(t, c.ReplicaSets, 1)
+	assert.Equal(t, Deployment{Name: "web", UID: "deployment-uid"}, c.ReplicaSets["replicaset-uid"].Deployment)
+
+	c.handleReplicaSetDelete(updated)
+	assert.Len(t, c.ReplicaSets, 0)
+}
+
+func TestJobAddUpdateDelete(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	job := &batch_v1.Job{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: "backup-1628500000",
+			UID:  "job-uid",
+		},
+	}
+	c.handleJobAdd(job)
+	require.Len(t, c.Jobs, 1)
+	assert.Equal(t, CronJob{}, c.Jobs["job-uid"].CronJob)
+
+	updated := job.DeepCopy()
+	updated.OwnerReferences = []meta_v1.OwnerReference{{Kind: "CronJob", Name: "backup", UID: "cronjob-uid"}}
+	c.handleJobUpdate(job, updated)
+	require.Len(t, c.Jobs, 1)
+	assert.Equal(t, CronJob{Name: "backup", UID: "cronjob-uid"}, c.Jobs["job-uid"].CronJob)
+
+	c.handleJobDelete(updated)
+	assert.Len(t, c.Jobs, 0)
+}
+
 func TestNamespaceExtractionRules(t *testing.T) {
 	c, _ := newTestClientWithRulesAndFilters(t, ExtractionRules{}, Filters{})
 
@@ -595,6 +944,62 @@ func TestNamespaceExtractionRules(t *testing.T) {
 	}
 }
 
+func TestNodeExtractionRules(t *testing.T) {
+	rules := ExtractionRules{
+		Annotations: []FieldExtractionRule{{
+			Name: "a1",
+			Key:  "annotation1",
+			From: MetadataFromNode,
+		}},
+		Labels: []FieldExtractionRule{{
+			Name: "zone",
+			Key:  "topology.kubernetes.io/zone",
+			From: MetadataFromNode,
+		}, {
+			Name: "pod-label",
+			Key:  "topology.kubernetes.io/zone",
+			From: MetadataFromPod,
+		}},
+	}
+	c, _ := newTestClientWithRulesAndFilters(t, rules, Filters{})
+	assert.IsType(t, &FakeInformer{}, c.nodeInformer)
+
+	node := &api_v1.Node{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: "node1",
+			UID:  "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee",
+			Labels: map[string]string{
+				"topology.kubernetes.io/zone":      "us-east-1a",
+				"node.kubernetes.io/instance-type": "m5.large",
+			},
+			Annotations: map[string]string{
+				"annotation1": "av1",
+			},
+		},
+	}
+	c.handleNodeAdd(node)
+	got, ok := c.GetNode("node1")
+	require.True(t, ok)
+	assert.Equal(t, "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee", got.NodeUID)
+	assert.Equal(t, map[string]string{"zone": "us-east-1a", "a1": "av1"}, got.Attributes)
+
+	updated := node.DeepCopy()
+	updated.Labels["topology.kubernetes.io/zone"] = "us-east-1b"
+	c.handleNodeUpdate(node, updated)
+	got, ok = c.GetNode("node1")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"zone": "us-east-1b", "a1": "av1"}, got.Attributes)
+
+	c.handleNodeDelete(updated)
+	_, ok = c.GetNode("node1")
+	assert.False(t, ok)
+}
+
+func TestNodeInformerNotNeeded(t *testing.T) {
+	c, _ := newTestClient(t)
+	assert.IsType(t, &NoOpInformer{}, c.nodeInformer)
+}
+
 func TestFilters(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -904,11 +1309,23 @@ func newTestClientWithRulesAndFilters(t *testing.T, e ExtractionRules, f Filters
 			{Name: regexp.MustCompile(`jaeger-collector`)},
 		},
 	}
-	c, err := New(logger, k8sconfig.APIConfig{}, e, f, []Association{}, exclude, newFakeAPIClientset, NewFakeInformer, NewFakeNamespaceInformer)
+	c, err := New(logger, k8sconfig.APIConfig{}, e, f, []Association{}, exclude, fakeClientOptions())
 	require.NoError(t, err)
 	return c.(*WatchClient), logs
 }
 
+// fakeClientOptions returns the options of a client connected to a fake cluster.
+func fakeClientOptions() ClientOptions {
+	return ClientOptions{
+		APIClientset:       newFakeAPIClientset,
+		Informer:           NewFakeInformer,
+		NamespaceInformer:  NewFakeNamespaceInformer,
+		ReplicaSetInformer: NewFakeWorkloadInformer,
+		JobInformer:        NewFakeWorkloadInformer,
+		NodeInformer:       NewFakeNodeInformer,
+	}
+}
+
 func newTestClient(t *testing.T) (*WatchClient, *observer.ObservedLogs) {
 	return newTestClientWithRulesAndFilters(t, ExtractionRules{}, Filters{})
 }
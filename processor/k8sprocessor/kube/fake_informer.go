@@ -84,6 +84,25 @@ func (f *FakeNamespaceInformer) GetController() cache.Controller {
 	return f.FakeController
 }
 
+func NewFakeWorkloadInformer(
+	_ kubernetes.Interface,
+	namespace string,
+) cache.SharedInformer {
+	return &FakeInformer{
+		FakeController: &FakeController{},
+		namespace:      namespace,
+	}
+}
+
+func NewFakeNodeInformer(
+	_ kubernetes.Interface,
+	_ string,
+) cache.SharedInformer {
+	return &FakeInformer{
+		FakeController: &FakeController{},
+	}
+}
+
 type FakeController struct {
 	sync.Mutex
 	stopped bool
@@ -28,13 +28,18 @@ import (
 
 const (
 	podNodeField            = "spec.nodeName"
+	nodeNameField           = "metadata.name"
 	ignoreAnnotation string = "opentelemetry.io/k8s-processor/ignore"
 	tagNodeName             = "k8s.node.name"
 	tagStartTime            = "k8s.pod.start_time"
+	// TagContainerRestartCount is the attribute holding the number of times a container has been restarted.
+	TagContainerRestartCount = "k8s.container.restart_count"
 	// MetadataFromPod is used to specify to extract metadata/labels/annotations from pod
 	MetadataFromPod = "pod"
 	// MetadataFromNamespace is used to specify to extract metadata/labels/annotations from namespace
 	MetadataFromNamespace = "namespace"
+	// MetadataFromNode is used to specify to extract metadata/labels/annotations from the node the pod runs on
+	MetadataFromNode = "node"
 )
 
 // PodIdentifier is a custom type to represent IP Address or Pod UID
@@ -44,18 +49,31 @@ var (
 	// TODO: move these to config with default values
 	defaultPodDeleteGracePeriod = time.Second * 120
 	watchSyncPeriod             = time.Minute * 5
+	defaultOwnerSyncTimeout     = time.Second * 10
 )
 
 // Client defines the main interface that allows querying pods by metadata.
 type Client interface {
 	GetPod(PodIdentifier) (*Pod, bool)
 	GetNamespace(string) (*Namespace, bool)
+	GetNode(string) (*Node, bool)
 	Start()
 	Stop()
 }
 
 // ClientProvider defines a func type that returns a new Client.
-type ClientProvider func(*zap.Logger, k8sconfig.APIConfig, ExtractionRules, Filters, []Association, Excludes, APIClientsetProvider, InformerProvider, InformerProviderNamespace) (Client, error)
+type ClientProvider func(*zap.Logger, k8sconfig.APIConfig, ExtractionRules, Filters, []Association, Excludes, ClientOptions) (Client, error)
+
+// ClientOptions holds the providers used by a Client to connect to the cluster and watch its resources.
+// The default providers are used for the ones left nil.
+type ClientOptions struct {
+	APIClientset       APIClientsetProvider
+	Informer           InformerProvider
+	NamespaceInformer  InformerProviderNamespace
+	ReplicaSetInformer InformerProviderWorkload
+	JobInformer        InformerProviderWorkload
+	NodeInformer       InformerProviderNode
+}
 
 // APIClientsetProvider defines a func type that initializes and return a new kubernetes
 // Clientset object.
@@ -70,10 +88,22 @@ type Pod struct {
 	StartTime  *metav1.Time
 	Ignore     bool
 	Namespace  string
+	NodeName   string
+
+	// Containers holds the metadata of the containers of the pod, by container name.
+	Containers map[string]*Container
 
 	DeletedAt time.Time
 }
 
+// Container represents a container of a kubernetes pod.
+type Container struct {
+	Name string
+	// ID is the ID of the current instance of the container, without the container runtime prefix.
+	ID         string
+	Attributes map[string]string
+}
+
 // Namespace represents a kubernetes namespace.
 type Namespace struct {
 	Name         string
@@ -83,6 +113,41 @@ type Namespace struct {
 	DeletedAt    time.Time
 }
 
+// Node represents a kubernetes node.
+type Node struct {
+	Name       string
+	NodeUID    string
+	Attributes map[string]string
+}
+
+// ReplicaSet represents a kubernetes replicaset.
+type ReplicaSet struct {
+	Name       string
+	Namespace  string
+	UID        string
+	Deployment Deployment
+}
+
+// Deployment represents a kubernetes deployment owning a replicaset.
+type Deployment struct {
+	Name string
+	UID  string
+}
+
+// Job represents a kubernetes job.
+type Job struct {
+	Name      string
+	Namespace string
+	UID       string
+	CronJob   CronJob
+}
+
+// CronJob represents a kubernetes cronjob owning a job.
+type CronJob struct {
+	Name string
+	UID  string
+}
+
 type deleteRequest struct {
 	// id is identifier (IP address or Pod UID) of pod to remove from pods map
 	id PodIdentifier
@@ -118,13 +183,29 @@ type FieldFilter struct {
 // ExtractionRules is used to specify the information that needs to be extracted
 // from pods and added to the spans as tags.
 type ExtractionRules struct {
-	Deployment bool
-	Namespace  bool
-	PodName    bool
-	PodUID     bool
-	Node       bool
-	Cluster    bool
-	StartTime  bool
+	Deployment     bool
+	DeploymentUID  bool
+	ReplicaSet     bool
+	ReplicaSetUID  bool
+	StatefulSet    bool
+	StatefulSetUID bool
+	DaemonSet      bool
+	DaemonSetUID   bool
+	Job            bool
+	JobUID         bool
+	CronJob        bool
+	CronJobUID     bool
+	Namespace      bool
+	PodName        bool
+	PodUID         bool
+	Node           bool
+	Cluster        bool
+	StartTime      bool
+
+	ContainerImageName    bool
+	ContainerImageTag     bool
+	ContainerID           bool
+	ContainerRestartCount bool
 
 	Annotations []FieldExtractionRule
 	Labels      []FieldExtractionRule
@@ -141,9 +222,10 @@ type FieldExtractionRule struct {
 	// Full value is extracted when no regexp is provided.
 	Regex *regexp.Regexp
 	// From determines the kubernetes object the field should be retrieved from.
-	// Currently only two values are supported,
+	// Currently only three values are supported,
 	//  - pod
 	//  - namespace
+	//  - node
 	From string
 }
 
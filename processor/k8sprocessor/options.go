@@ -95,6 +95,36 @@ func WithExtractMetadata(fields ...string) Option {
 				p.rules.StartTime = true
 			case metadataDeployment, conventions.AttributeK8SDeploymentName:
 				p.rules.Deployment = true
+			case conventions.AttributeK8SDeploymentUID:
+				p.rules.DeploymentUID = true
+			case conventions.AttributeK8SReplicasetName:
+				p.rules.ReplicaSet = true
+			case conventions.AttributeK8SReplicasetUID:
+				p.rules.ReplicaSetUID = true
+			case conventions.AttributeK8SStatefulsetName:
+				p.rules.StatefulSet = true
+			case conventions.AttributeK8SStatefulsetUID:
+				p.rules.StatefulSetUID = true
+			case conventions.AttributeK8SDaemonsetName:
+				p.rules.DaemonSet = true
+			case conventions.AttributeK8SDaemonsetUID:
+				p.rules.DaemonSetUID = true
+			case conventions.AttributeK8SJobName:
+				p.rules.Job = true
+			case conventions.AttributeK8SJobUID:
+				p.rules.JobUID = true
+			case conventions.AttributeK8SCronJobName:
+				p.rules.CronJob = true
+			case conventions.AttributeK8SCronJobUID:
+				p.rules.CronJobUID = true
+			case conventions.AttributeContainerImageName:
+				p.rules.ContainerImageName = true
+			case conventions.AttributeContainerImageTag:
+				p.rules.ContainerImageTag = true
+			case conventions.AttributeContainerID:
+				p.rules.ContainerID = true
+			case kube.TagContainerRestartCount:
+				p.rules.ContainerRestartCount = true
 			case metadataCluster, conventions.AttributeK8SClusterName:
 				p.rules.Cluster = true
 			case metadataNode, conventions.AttributeK8SNodeName:
@@ -142,8 +172,10 @@ func extractFieldRules(fieldType string, fields ...FieldExtractConfig) ([]kube.F
 			a.From = kube.MetadataFromPod
 		case kube.MetadataFromNamespace:
 			a.From = kube.MetadataFromNamespace
+		case kube.MetadataFromNode:
+			a.From = kube.MetadataFromNode
 		default:
-			return rules, fmt.Errorf("%s is not a valid choice for From. Must be one of: pod, namespace", a.From)
+			return rules, fmt.Errorf("%s is not a valid choice for From. Must be one of: pod, namespace, node", a.From)
 		}
 
 		if name == "" {
@@ -151,6 +183,8 @@ func extractFieldRules(fieldType string, fields ...FieldExtractConfig) ([]kube.F
 				name = fmt.Sprintf("k8s.pod.%s.%s", fieldType, a.Key)
 			} else if a.From == kube.MetadataFromNamespace {
 				name = fmt.Sprintf("k8s.namespace.%s.%s", fieldType, a.Key)
+			} else if a.From == kube.MetadataFromNode {
+				name = fmt.Sprintf("k8s.node.%s.%s", fieldType, a.Key)
 			}
 		}
 
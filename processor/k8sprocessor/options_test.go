@@ -217,6 +217,34 @@ func TestWithExtractLabels(t *testing.T) {
 			},
 			"",
 		},
+		{
+			"default-tag-name-node",
+			[]FieldExtractConfig{
+				{
+					Key:  "topology.kubernetes.io/zone",
+					From: kube.MetadataFromNode,
+				},
+			},
+			[]kube.FieldExtractionRule{
+				{
+					Name: "k8s.node.labels.topology.kubernetes.io/zone",
+					Key:  "topology.kubernetes.io/zone",
+					From: kube.MetadataFromNode,
+				},
+			},
+			"",
+		},
+		{
+			"bad-from",
+			[]FieldExtractConfig{
+				{
+					Key:  "key1",
+					From: "cluster",
+				},
+			},
+			[]kube.FieldExtractionRule{},
+			"cluster is not a valid choice for From. Must be one of: pod, namespace, node",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -263,6 +291,49 @@ func TestWithExtractMetadata(t *testing.T) {
 	assert.False(t, p.rules.StartTime)
 	assert.False(t, p.rules.Deployment)
 	assert.False(t, p.rules.Node)
+	assert.False(t, p.rules.ReplicaSet)
+
+	p = &kubernetesprocessor{}
+	assert.NoError(t, WithExtractMetadata(
+		conventions.AttributeK8SDeploymentUID,
+		conventions.AttributeK8SReplicasetName,
+		conventions.AttributeK8SReplicasetUID,
+		conventions.AttributeK8SStatefulsetName,
+		conventions.AttributeK8SStatefulsetUID,
+		conventions.AttributeK8SDaemonsetName,
+		conventions.AttributeK8SDaemonsetUID,
+		conventions.AttributeK8SJobName,
+		conventions.AttributeK8SJobUID,
+		conventions.AttributeK8SCronJobName,
+		conventions.AttributeK8SCronJobUID,
+	)(p))
+	assert.Equal(t, kube.ExtractionRules{
+		DeploymentUID:  true,
+		ReplicaSet:     true,
+		ReplicaSetUID:  true,
+		StatefulSet:    true,
+		StatefulSetUID: true,
+		DaemonSet:      true,
+		DaemonSetUID:   true,
+		Job:            true,
+		JobUID:         true,
+		CronJob:        true,
+		CronJobUID:     true,
+	}, p.rules)
+
+	p = &kubernetesprocessor{}
+	assert.NoError(t, WithExtractMetadata(
+		conventions.AttributeContainerImageName,
+		conventions.AttributeContainerImageTag,
+		conventions.AttributeContainerID,
+		kube.TagContainerRestartCount,
+	)(p))
+	assert.Equal(t, kube.ExtractionRules{
+		ContainerImageName:    true,
+		ContainerImageTag:     true,
+		ContainerID:           true,
+		ContainerRestartCount: true,
+	}, p.rules)
 }
 
 func TestWithFilterLabels(t *testing.T) {
@@ -16,6 +16,7 @@ package k8sprocessor
 
 import (
 	"context"
+	"regexp"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/model/pdata"
@@ -29,6 +30,16 @@ import (
 const (
 	k8sIPLabelName    string = "k8s.pod.ip"
 	clientIPLabelName string = "ip"
+
+	// logFilePathAttribute is the attribute holding the path of the file a log was read from.
+	logFilePathAttribute string = "log.file.path"
+)
+
+var (
+	// podsLogPathRegex matches /var/log/pods/<namespace>_<pod>_<uid>/<container>/<restart>.log
+	podsLogPathRegex = regexp.MustCompile(`/pods/[^_/]+_[^_/]+_[^_/]+/([^/]+)/[0-9]+\.log$`)
+	// containersLogPathRegex matches /var/log/containers/<pod>_<namespace>_<container>-<id>.log
+	containersLogPathRegex = regexp.MustCompile(`/containers/[^_/]+_[^_/]+_([^/]+)-([0-9a-f]{64})\.log$`)
 )
 
 type kubernetesprocessor struct {
@@ -47,7 +58,7 @@ func (kp *kubernetesprocessor) initKubeClient(logger *zap.Logger, kubeClient kub
 		kubeClient = kube.New
 	}
 	if !kp.passthroughMode {
-		kc, err := kubeClient(logger, kp.apiConfig, kp.rules, kp.filters, kp.podAssociations, kp.podIgnore, nil, nil, nil)
+		kc, err := kubeClient(logger, kp.apiConfig, kp.rules, kp.filters, kp.podAssociations, kp.podIgnore, kube.ClientOptions{})
 		if err != nil {
 			return err
 		}
@@ -116,8 +127,23 @@ func (kp *kubernetesprocessor) processResource(ctx context.Context, resource pda
 		return
 	}
 
+	nodeName := stringAttributeFromMap(resource.Attributes(), conventions.AttributeK8SNodeName)
 	if podIdentifierKey != "" {
-		attrsToAdd := kp.getAttributesForPod(podIdentifierValue)
+		if pod, ok := kp.kc.GetPod(podIdentifierValue); ok {
+			for key, val := range pod.Attributes {
+				resource.Attributes().InsertString(key, val)
+			}
+			for key, val := range getAttributesForContainer(pod, resource.Attributes()) {
+				resource.Attributes().InsertString(key, val)
+			}
+			if pod.NodeName != "" {
+				nodeName = pod.NodeName
+			}
+		}
+	}
+
+	if nodeName != "" {
+		attrsToAdd := kp.getAttributesForNode(nodeName)
 		for key, val := range attrsToAdd {
 			resource.Attributes().InsertString(key, val)
 		}
@@ -131,12 +157,51 @@ func (kp *kubernetesprocessor) processResource(ctx context.Context, resource pda
 	}
 }
 
-func (kp *kubernetesprocessor) getAttributesForPod(identifier kube.PodIdentifier) map[string]string {
-	pod, ok := kp.kc.GetPod(identifier)
+// getAttributesForContainer returns the attributes of the pod container identified by the
+// k8s.container.name or the container.id resource attribute, or else by the path of the log file.
+func getAttributesForContainer(pod *kube.Pod, attrs pdata.AttributeMap) map[string]string {
+	if len(pod.Containers) == 0 {
+		return nil
+	}
+	name := stringAttributeFromMap(attrs, conventions.AttributeK8SContainerName)
+	id := stringAttributeFromMap(attrs, conventions.AttributeContainerID)
+	if name == "" && id == "" {
+		name, id = containerFromLogFilePath(stringAttributeFromMap(attrs, logFilePathAttribute))
+	}
+	if name != "" {
+		if container, ok := pod.Containers[name]; ok {
+			return container.Attributes
+		}
+		return nil
+	}
+	if id != "" {
+		for _, container := range pod.Containers {
+			if container.ID == id {
+				return container.Attributes
+			}
+		}
+	}
+	return nil
+}
+
+// containerFromLogFilePath returns the container name, and the container ID when available, found in the path of a
+// log file written by the kubelet.
+func containerFromLogFilePath(path string) (name, id string) {
+	if parts := podsLogPathRegex.FindStringSubmatch(path); parts != nil {
+		return parts[1], ""
+	}
+	if parts := containersLogPathRegex.FindStringSubmatch(path); parts != nil {
+		return parts[1], parts[2]
+	}
+	return "", ""
+}
+
+func (kp *kubernetesprocessor) getAttributesForNode(nodeName string) map[string]string {
+	node, ok := kp.kc.GetNode(nodeName)
 	if !ok {
 		return nil
 	}
-	return pod.Attributes
+	return node.Attributes
 }
 
 func (kp *kubernetesprocessor) getAttributesForPodsNamespace(namespace string) map[string]string {
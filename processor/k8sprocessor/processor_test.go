@@ -217,7 +217,7 @@ func TestProcessorBadConfig(t *testing.T) {
 }
 
 func TestProcessorBadClientProvider(t *testing.T) {
-	clientProvider := func(_ *zap.Logger, _ k8sconfig.APIConfig, _ kube.ExtractionRules, _ kube.Filters, _ []kube.Association, _ kube.Excludes, _ kube.APIClientsetProvider, _ kube.InformerProvider, _ kube.InformerProviderNamespace) (kube.Client, error) {
+	clientProvider := func(_ *zap.Logger, _ k8sconfig.APIConfig, _ kube.ExtractionRules, _ kube.Filters, _ []kube.Association, _ kube.Excludes, _ kube.ClientOptions) (kube.Client, error) {
 		return nil, fmt.Errorf("bad client error")
 	}
 
@@ -283,6 +283,82 @@ func withPodUID(uid string) generateResourceFunc {
 	}
 }
 
+func withNodeName(name string) generateResourceFunc {
+	return func(res pdata.Resource) {
+		res.Attributes().InsertString(conventions.AttributeK8SNodeName, name)
+	}
+}
+
+func withContainerName(name string) generateResourceFunc {
+	return func(res pdata.Resource) {
+		res.Attributes().InsertString(conventions.AttributeK8SContainerName, name)
+	}
+}
+
+func withContainerID(id string) generateResourceFunc {
+	return func(res pdata.Resource) {
+		res.Attributes().InsertString(conventions.AttributeContainerID, id)
+	}
+}
+
+func withLogFilePath(path string) generateResourceFunc {
+	return func(res pdata.Resource) {
+		res.Attributes().InsertString("log.file.path", path)
+	}
+}
+
+func TestContainerFromLogFilePath(t *testing.T) {
+	containerID := "4c1a1e7e6a3e4c1d6b2f0e8a9d7c5b3a1f2e4d6c8b0a9e7f5d3c1b2a4e6f8d0c"
+	tests := []struct {
+		name     string
+		path     string
+		wantName string
+		wantID   string
+	}{
+		{
+			name:     "pods-layout",
+			path:     "/var/log/pods/default_web-7d4b9c8f6d-x2x9z_ef10d10b-2da5-4030-812e-5f45c1531227/nginx/0.log",
+			wantName: "nginx",
+		},
+		{
+			name:     "pods-layout-restarted",
+			path:     "/var/log/pods/kube-system_coredns-558bd4d5db-7kqxb_0b6b3b8e-1f0a-4f7e-9c5a-2a3d4e5f6a7b/coredns/12.log",
+			wantName: "coredns",
+		},
+		{
+			name:     "containers-layout",
+			path:     "/var/log/containers/web-7d4b9c8f6d-x2x9z_default_nginx-" + containerID + ".log",
+			wantName: "nginx",
+			wantID:   containerID,
+		},
+		{
+			name:     "containers-layout-dashed-container-name",
+			path:     "/var/log/containers/web-7d4b9c8f6d-x2x9z_default_istio-proxy-" + containerID + ".log",
+			wantName: "istio-proxy",
+			wantID:   containerID,
+		},
+		{
+			name: "containers-layout-without-id",
+			path: "/var/log/containers/web-7d4b9c8f6d-x2x9z_default_nginx.log",
+		},
+		{
+			name: "other-file",
+			path: "/var/log/syslog",
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, id := containerFromLogFilePath(tt.path)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantID, id)
+		})
+	}
+}
+
 func TestIPDetectionFromContext(t *testing.T) {
 	m := newMultiTest(t, NewFactory().CreateDefaultConfig(), nil)
 
@@ -594,6 +670,174 @@ func TestPodUID(t *testing.T) {
 	})
 }
 
+func TestProcessorAddContainerAttributes(t *testing.T) {
+	tests := []struct {
+		name      string
+		container generateResourceFunc
+		want      map[string]string
+	}{
+		{
+			name:      "by-container-name",
+			container: withContainerName("app"),
+			want: map[string]string{
+				"container.image.name": "example.com/app",
+				"container.image.tag":  "1.0",
+			},
+		},
+		{
+			name:      "by-container-id",
+			container: withContainerID("sidecar-id"),
+			want: map[string]string{
+				"container.image.name": "example.com/sidecar",
+				"container.image.tag":  "latest",
+			},
+		},
+		{
+			name:      "by-pods-log-file-path",
+			container: withLogFilePath("/var/log/pods/default_PodA_ef10d10b-2da5-4030-812e-5f45c1531227/app/0.log"),
+			want: map[string]string{
+				"container.image.name": "example.com/app",
+				"container.image.tag":  "1.0",
+			},
+		},
+		{
+			name:      "by-containers-log-file-path",
+			container: withLogFilePath("/var/log/containers/PodA_default_sidecar-4c1a1e7e6a3e4c1d6b2f0e8a9d7c5b3a1f2e4d6c8b0a9e7f5d3c1b2a4e6f8d0c.log"),
+			want: map[string]string{
+				"container.image.name": "example.com/sidecar",
+				"container.image.tag":  "latest",
+			},
+		},
+		{
+			name:      "unknown-container",
+			container: withContainerName("unknown"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMultiTest(
+				t,
+				NewFactory().CreateDefaultConfig(),
+				nil,
+			)
+			m.kubernetesProcessorOperation(func(kp *kubernetesprocessor) {
+				kp.podAssociations = []kube.Association{
+					{
+						From: "resource_attribute",
+						Name: "k8s.pod.uid",
+					},
+				}
+				kp.kc.(*fakeClient).Pods["ef10d10b-2da5-4030-812e-5f45c1531227"] = &kube.Pod{
+					Name:       "PodA",
+					Attributes: map[string]string{"k8s.pod.name": "PodA"},
+					Containers: map[string]*kube.Container{
+						"app": {
+							Name: "app",
+							ID:   "app-id",
+							Attributes: map[string]string{
+								"container.image.name": "example.com/app",
+								"container.image.tag":  "1.0",
+							},
+						},
+						"sidecar": {
+							Name: "sidecar",
+							ID:   "sidecar-id",
+							Attributes: map[string]string{
+								"container.image.name": "example.com/sidecar",
+								"container.image.tag":  "latest",
+							},
+						},
+					},
+				}
+			})
+
+			m.testConsume(context.Background(),
+				generateTraces(withPodUID("ef10d10b-2da5-4030-812e-5f45c1531227"), tt.container),
+				generateMetrics(withPodUID("ef10d10b-2da5-4030-812e-5f45c1531227"), tt.container),
+				generateLogs(withPodUID("ef10d10b-2da5-4030-812e-5f45c1531227"), tt.container),
+				nil)
+
+			m.assertBatchesLen(1)
+			m.assertResourceObjectLen(0)
+			// The pod UID, the pod name and the container identifier are always present.
+			m.assertResourceAttributesLen(0, 3+len(tt.want))
+			m.assertResource(0, func(r pdata.Resource) {
+				assertResourceHasStringAttribute(t, r, "k8s.pod.name", "PodA")
+				for k, v := range tt.want {
+					assertResourceHasStringAttribute(t, r, k, v)
+				}
+			})
+		})
+	}
+}
+
+func TestProcessorAddNodeAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource []generateResourceFunc
+		want     map[string]string
+	}{
+		{
+			name:     "node-of-pod",
+			resource: []generateResourceFunc{withPodUID("ef10d10b-2da5-4030-812e-5f45c1531227")},
+			want:     map[string]string{"zone": "us-east-1a"},
+		},
+		{
+			name:     "node-name-attribute",
+			resource: []generateResourceFunc{withNodeName("node2")},
+			want:     map[string]string{"zone": "us-east-1b"},
+		},
+		{
+			name:     "unknown-node",
+			resource: []generateResourceFunc{withNodeName("node3")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMultiTest(
+				t,
+				NewFactory().CreateDefaultConfig(),
+				nil,
+			)
+			m.kubernetesProcessorOperation(func(kp *kubernetesprocessor) {
+				kp.podAssociations = []kube.Association{
+					{
+						From: "resource_attribute",
+						Name: "k8s.pod.uid",
+					},
+				}
+				kp.kc.(*fakeClient).Pods["ef10d10b-2da5-4030-812e-5f45c1531227"] = &kube.Pod{
+					Name:       "PodA",
+					NodeName:   "node1",
+					Attributes: map[string]string{"k8s.node.name": "node1"},
+				}
+				kp.kc.(*fakeClient).Nodes = map[string]*kube.Node{
+					"node1": {Name: "node1", Attributes: map[string]string{"zone": "us-east-1a"}},
+					"node2": {Name: "node2", Attributes: map[string]string{"zone": "us-east-1b"}},
+				}
+			})
+
+			m.testConsume(context.Background(),
+				generateTraces(tt.resource...),
+				generateMetrics(tt.resource...),
+				generateLogs(tt.resource...),
+				nil)
+
+			m.assertBatchesLen(1)
+			m.assertResourceObjectLen(0)
+			m.assertResource(0, func(r pdata.Resource) {
+				for k, v := range tt.want {
+					assertResourceHasStringAttribute(t, r, k, v)
+				}
+				_, ok := r.Attributes().Get("zone")
+				assert.Equal(t, len(tt.want) > 0, ok)
+			})
+		})
+	}
+}
+
 func TestProcessorAddLabels(t *testing.T) {
 	m := newMultiTest(
 		t,
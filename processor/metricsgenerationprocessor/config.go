@@ -39,6 +39,12 @@ const (
 
 	// operationFieldName is the mapstructure field name for Operation field
 	operationFieldName = "operation"
+
+	// expressionFieldName is the mapstructure field name for Expression field
+	expressionFieldName = "expression"
+
+	// metricsFieldName is the mapstructure field name for Metrics field
+	metricsFieldName = "metrics"
 )
 
 // Config defines the configuration for the processor.
@@ -59,7 +65,7 @@ type Rule struct {
 	// The rule type following which the new metric will be generated. This is a required field.
 	Type GenerationType `mapstructure:"type"`
 
-	// First operand metric to use in the calculation. This is a required field.
+	// First operand metric to use in the calculation. A required field if the type is calculate or scale.
 	Metric1 string `mapstructure:"metric1"`
 
 	// Second operand metric to use in the calculation. A required field if the type is calculate.
@@ -70,6 +76,13 @@ type Rule struct {
 
 	// A constant number by which the first operand will be scaled. A required field if the type is scale.
 	ScaleBy float64 `mapstructure:"scale_by"`
+
+	// The arithmetic expression to evaluate, e.g. (a - b) / c * 100. A required field if the type is expression.
+	Expression string `mapstructure:"expression"`
+
+	// Metrics maps the variables of the expression to the names of the metrics providing their values.
+	// A required field if the type is expression.
+	Metrics map[string]string `mapstructure:"metrics"`
 }
 
 type GenerationType string
@@ -81,9 +94,12 @@ const (
 
 	// Generates a new metric scaling the value of s given metric with a provided constant
 	scale GenerationType = "scale"
+
+	// Generates a new metric evaluating an arithmetic expression over any number of metrics
+	expression GenerationType = "expression"
 )
 
-var generationTypes = map[GenerationType]struct{}{calculate: {}, scale: {}}
+var generationTypes = map[GenerationType]struct{}{calculate: {}, scale: {}, expression: {}}
 
 func (gt GenerationType) isValid() bool {
 	_, ok := generationTypes[gt]
@@ -161,6 +177,21 @@ func (config *Config) Validate() error {
 			return fmt.Errorf("%q must be in %q", typeFieldName, generationTypeKeys())
 		}
 
+		if rule.Type == expression {
+			if rule.Expression == "" {
+				return fmt.Errorf("missing required field %q for generation type %q", expressionFieldName, expression)
+			}
+
+			if len(rule.Metrics) == 0 {
+				return fmt.Errorf("missing required field %q for generation type %q", metricsFieldName, expression)
+			}
+
+			if _, err := compileExpression(rule.Expression, rule.Metrics); err != nil {
+				return fmt.Errorf("invalid %q: %w", expressionFieldName, err)
+			}
+			continue
+		}
+
 		if rule.Metric1 == "" {
 			return fmt.Errorf("missing required field %q", metric1FieldName)
 		}
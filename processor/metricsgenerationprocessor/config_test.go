@@ -55,10 +55,28 @@ func TestLoadingFullConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			configFile: "config_expression.yaml",
+			expCfg: &Config{
+				ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+				Rules: []Rule{
+					{
+						Name:       "cache.hit_rate",
+						Unit:       "percent",
+						Type:       "expression",
+						Expression: "hits / (hits + misses) * 100",
+						Metrics: map[string]string{
+							"hits":   "cache.hits",
+							"misses": "cache.misses",
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
-		t.Run(test.expCfg.ID().String(), func(t *testing.T) {
+		t.Run(test.configFile, func(t *testing.T) {
 			factories, err := componenttest.NopFactories()
 			assert.NoError(t, err)
 
@@ -119,6 +137,25 @@ func TestValidateConfig(t *testing.T) {
 			succeed:      false,
 			errorMessage: fmt.Sprintf("%q must be in %q", operationFieldName, operationTypeKeys()),
 		},
+		{
+			configName: "config_expression.yaml",
+			succeed:    true,
+		},
+		{
+			configName:   "config_missing_expression.yaml",
+			succeed:      false,
+			errorMessage: fmt.Sprintf("missing required field %q for generation type %q", expressionFieldName, expression),
+		},
+		{
+			configName:   "config_missing_metrics.yaml",
+			succeed:      false,
+			errorMessage: fmt.Sprintf("missing required field %q for generation type %q", metricsFieldName, expression),
+		},
+		{
+			configName:   "config_invalid_expression.yaml",
+			succeed:      false,
+			errorMessage: fmt.Sprintf("invalid %q: unknown name b (1:5)\n | a / b\n | ....^", expressionFieldName),
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,171 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsgenerationprocessor
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+)
+
+// compileExpression compiles the arithmetic expression of a rule, checking that it only uses the
+// variables defined by the given metrics and that it evaluates to a number.
+func compileExpression(expression string, metrics map[string]string) (*vm.Program, error) {
+	env := make(map[string]interface{}, len(metrics))
+	for variable := range metrics {
+		env[variable] = float64(0)
+	}
+	return expr.Compile(expression, expr.Env(env), expr.AsFloat64())
+}
+
+// series holds the data points of a metric, by the key of their labels.
+type series struct {
+	points map[string]pdata.NumberDataPoint
+}
+
+// isScalar returns whether the metric has a single data point without labels, in which case
+// its value applies to the data points of all the other metrics.
+func (s *series) isScalar() bool {
+	if len(s.points) != 1 {
+		return false
+	}
+	_, ok := s.points[""]
+	return ok
+}
+
+// generateExpressionMetric creates a new gauge metric evaluating the expression of the given rule, and adds it
+// to the first instrumentation library of the Resource Metric, unless no data point could be generated. Data points of the different metrics are joined
+// by their labels: a data point is generated for each set of labels found in all the metrics, metrics with a
+// single data point without labels being used for all of them.
+func generateExpressionMetric(rm pdata.ResourceMetrics, nameToMetricMap map[string]pdata.Metric, rule internalRule, logger *zap.Logger) {
+	if rule.program == nil {
+		return
+	}
+
+	variables := make([]string, 0, len(rule.metrics))
+	for variable := range rule.metrics {
+		variables = append(variables, variable)
+	}
+	sort.Strings(variables)
+
+	allSeries := make(map[string]*series, len(variables))
+	var joined *series
+	for _, variable := range variables {
+		metricName := rule.metrics[variable]
+		metric, ok := nameToMetricMap[metricName]
+		if !ok {
+			logger.Debug("Missing metric", zap.String("metric_name", metricName))
+			return
+		}
+		s := newSeries(metric)
+		if s == nil {
+			logger.Debug("Unsupported metric data type", zap.String("metric_name", metricName))
+			return
+		}
+		allSeries[variable] = s
+		if !s.isScalar() && joined == nil {
+			joined = s
+		}
+	}
+	if joined == nil {
+		joined = allSeries[variables[0]]
+	}
+
+	keys := make([]string, 0, len(joined.points))
+	for key := range joined.points {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// the metric is only added along with its first data point, so that no empty metric is generated
+	var dataPoints pdata.NumberDataPointSlice
+	hasMetric := false
+	env := make(map[string]interface{}, len(variables))
+	for _, key := range keys {
+		timestamp := pdata.Timestamp(0)
+		complete := true
+		for _, variable := range variables {
+			s := allSeries[variable]
+			point, ok := s.points[key]
+			if !ok && s.isScalar() {
+				point, ok = s.points[""]
+			}
+			if !ok {
+				complete = false
+				break
+			}
+			env[variable] = getDataPointValue(point)
+			if point.Timestamp() > timestamp {
+				timestamp = point.Timestamp()
+			}
+		}
+		if !complete {
+			continue
+		}
+
+		result, err := expr.Run(rule.program, env)
+		if err != nil {
+			logger.Debug("Failed to evaluate expression", zap.String("metric_name", rule.name), zap.Error(err))
+			continue
+		}
+		value := result.(float64)
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			logger.Debug("The expression produced a non-finite value", zap.String("metric_name", rule.name), zap.Float64("value", value))
+			continue
+		}
+
+		if !hasMetric {
+			newMetric := appendMetric(rm.InstrumentationLibraryMetrics().At(0), rule.name, rule.unit)
+			newMetric.SetDataType(pdata.MetricDataTypeGauge)
+			dataPoints = newMetric.Gauge().DataPoints()
+			hasMetric = true
+		}
+		dataPoint := dataPoints.AppendEmpty()
+		joined.points[key].LabelsMap().CopyTo(dataPoint.LabelsMap())
+		dataPoint.SetTimestamp(timestamp)
+		dataPoint.SetDoubleVal(value)
+	}
+}
+
+// newSeries indexes the data points of a gauge or sum metric by their labels, or returns nil for other metrics.
+func newSeries(metric pdata.Metric) *series {
+	dataPoints, ok := getNumberDataPoints(metric)
+	if !ok {
+		return nil
+	}
+	s := &series{
+		points: make(map[string]pdata.NumberDataPoint, dataPoints.Len()),
+	}
+	for i := 0; i < dataPoints.Len(); i++ {
+		s.points[labelsKey(dataPoints.At(i).LabelsMap())] = dataPoints.At(i)
+	}
+	return s
+}
+
+// labelsKey returns a key identifying the given set of labels, regardless of their order.
+func labelsKey(labels pdata.StringMap) string {
+	pairs := make([]string, 0, labels.Len())
+	labels.Range(func(k, v string) bool {
+		pairs = append(pairs, k+"\x00"+v)
+		return true
+	})
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x01")
+}
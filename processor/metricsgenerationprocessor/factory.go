@@ -80,6 +80,11 @@ func buildInternalConfig(config *Config) []internalRule {
 			operation: string(rule.Operation),
 			scaleBy:   rule.ScaleBy,
 		}
+		if rule.Type == expression {
+			// Invalid expressions are reported by Validate, the rule is then ignored.
+			customRule.program, _ = compileExpression(rule.Expression, rule.Metrics)
+			customRule.metrics = rule.Metrics
+		}
 		internalRules[i] = customRule
 	}
 	return internalRules
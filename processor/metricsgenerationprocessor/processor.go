@@ -17,6 +17,7 @@ package metricsgenerationprocessor
 import (
 	"context"
 
+	"github.com/antonmedv/expr/vm"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/model/pdata"
 	"go.uber.org/zap"
@@ -35,6 +36,8 @@ type internalRule struct {
 	metric2   string
 	operation string
 	scaleBy   float64
+	program   *vm.Program
+	metrics   map[string]string
 }
 
 func newMetricsGenerationProcessor(rules []internalRule, logger *zap.Logger) *metricsGenerationProcessor {
@@ -58,6 +61,11 @@ func (mgp *metricsGenerationProcessor) processMetrics(_ context.Context, md pdat
 		nameToMetricMap := getNameToMetricMap(rm)
 
 		for _, rule := range mgp.rules {
+			if rule.ruleType == string(expression) {
+				generateExpressionMetric(rm, nameToMetricMap, rule, mgp.logger)
+				continue
+			}
+
 			operand2 := float64(0)
 			_, ok := nameToMetricMap[rule.metric1]
 			if !ok {
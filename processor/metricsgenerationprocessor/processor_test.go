@@ -395,3 +395,221 @@ func getOutputForIntGaugeTest() pdata.Metrics {
 
 	return intGaugeOutputMetrics
 }
+
+type labelledPoint struct {
+	labels map[string]string
+	value  float64
+}
+
+type labelledMetric struct {
+	name     string
+	dataType pdata.MetricDataType
+	points   []labelledPoint
+}
+
+func generateLabelledMetrics(metrics ...labelledMetric) pdata.Metrics {
+	md := pdata.NewMetrics()
+	now := time.Now()
+
+	ms := md.ResourceMetrics().AppendEmpty().InstrumentationLibraryMetrics().AppendEmpty().Metrics()
+	for _, lm := range metrics {
+		m := ms.AppendEmpty()
+		m.SetName(lm.name)
+		m.SetDataType(lm.dataType)
+		var dps pdata.NumberDataPointSlice
+		if lm.dataType == pdata.MetricDataTypeSum {
+			m.Sum().SetIsMonotonic(true)
+			m.Sum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+			dps = m.Sum().DataPoints()
+		} else {
+			dps = m.Gauge().DataPoints()
+		}
+		for _, p := range lm.points {
+			dp := dps.AppendEmpty()
+			dp.SetTimestamp(pdata.TimestampFromTime(now))
+			dp.SetDoubleVal(p.value)
+			for k, v := range p.labels {
+				dp.LabelsMap().Insert(k, v)
+			}
+		}
+	}
+	return md
+}
+
+func TestMetricsGenerationProcessorExpression(t *testing.T) {
+	cacheMetrics := generateLabelledMetrics(
+		labelledMetric{
+			name:     "cache.hits",
+			dataType: pdata.MetricDataTypeSum,
+			points: []labelledPoint{
+				{labels: map[string]string{"cache": "a", "region": "eu"}, value: 30},
+				{labels: map[string]string{"cache": "b", "region": "eu"}, value: 10},
+				{labels: map[string]string{"cache": "c", "region": "eu"}, value: 5},
+			},
+		},
+		labelledMetric{
+			name:     "cache.misses",
+			dataType: pdata.MetricDataTypeSum,
+			points: []labelledPoint{
+				// Labels in a different order must still match.
+				{labels: map[string]string{"region": "eu", "cache": "a"}, value: 10},
+				{labels: map[string]string{"cache": "b", "region": "eu"}, value: 0},
+				{labels: map[string]string{"cache": "d", "region": "eu"}, value: 5},
+			},
+		},
+		labelledMetric{
+			name:     "scale",
+			dataType: pdata.MetricDataTypeGauge,
+			points:   []labelledPoint{{value: 100}},
+		},
+	)
+
+	tests := []struct {
+		name   string
+		rule   Rule
+		want   []labelledPoint
+		absent bool
+	}{
+		{
+			name: "hit_rate_joined_by_labels",
+			rule: Rule{
+				Name:       "cache.hit_rate",
+				Unit:       "%",
+				Type:       "expression",
+				Expression: "hits / (hits + misses) * scale",
+				Metrics: map[string]string{
+					"hits":   "cache.hits",
+					"misses": "cache.misses",
+					"scale":  "scale",
+				},
+			},
+			want: []labelledPoint{
+				{labels: map[string]string{"cache": "a", "region": "eu"}, value: 75},
+				{labels: map[string]string{"cache": "b", "region": "eu"}, value: 100},
+			},
+		},
+		{
+			name: "divide_by_zero_skipped",
+			rule: Rule{
+				Name:       "cache.miss_ratio",
+				Type:       "expression",
+				Expression: "hits / misses",
+				Metrics: map[string]string{
+					"hits":   "cache.hits",
+					"misses": "cache.misses",
+				},
+			},
+			want: []labelledPoint{
+				{labels: map[string]string{"cache": "a", "region": "eu"}, value: 3},
+			},
+		},
+		{
+			name: "missing_metric",
+			rule: Rule{
+				Name:       "cache.total",
+				Type:       "expression",
+				Expression: "hits + unknown",
+				Metrics: map[string]string{
+					"hits":    "cache.hits",
+					"unknown": "cache.unknown",
+				},
+			},
+			absent: true,
+		},
+		{
+			name: "no_data_point",
+			rule: Rule{
+				Name:       "cache.invalid",
+				Type:       "expression",
+				Expression: "hits / (misses - misses)",
+				Metrics: map[string]string{
+					"hits":   "cache.hits",
+					"misses": "cache.misses",
+				},
+			},
+			// every data point divides by zero, no empty metric is generated
+			absent: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := new(consumertest.MetricsSink)
+			cfg := &Config{
+				ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+				Rules:             []Rule{test.rule},
+			}
+			require.NoError(t, cfg.Validate())
+			mgp, err := NewFactory().CreateMetricsProcessor(
+				context.Background(),
+				componenttest.NewNopProcessorCreateSettings(),
+				cfg,
+				next,
+			)
+			require.NoError(t, err)
+
+			require.NoError(t, mgp.ConsumeMetrics(context.Background(), cacheMetrics.Clone()))
+
+			metrics := next.AllMetrics()[0].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+			if test.absent {
+				require.Equal(t, 3, metrics.Len())
+				return
+			}
+			require.Equal(t, 4, metrics.Len())
+			generated := metrics.At(3)
+			assert.Equal(t, test.rule.Name, generated.Name())
+			assert.Equal(t, test.rule.Unit, generated.Unit())
+			require.Equal(t, pdata.MetricDataTypeGauge, generated.DataType())
+
+			dps := generated.Gauge().DataPoints()
+			require.Equal(t, len(test.want), dps.Len())
+			for i, want := range test.want {
+				assert.Equal(t, want.value, dps.At(i).DoubleVal())
+				assert.NotZero(t, dps.At(i).Timestamp())
+				labels := map[string]string{}
+				dps.At(i).LabelsMap().Range(func(k, v string) bool {
+					labels[k] = v
+					return true
+				})
+				assert.Equal(t, want.labels, labels)
+			}
+		})
+	}
+}
+
+func TestMetricsGenerationProcessorCalculateSum(t *testing.T) {
+	next := new(consumertest.MetricsSink)
+	cfg := &Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+		Rules: []Rule{
+			{
+				Name:      "requests.percent",
+				Type:      "calculate",
+				Metric1:   "requests.failed",
+				Metric2:   "requests.total",
+				Operation: "percent",
+			},
+		},
+	}
+	mgp, err := NewFactory().CreateMetricsProcessor(
+		context.Background(),
+		componenttest.NewNopProcessorCreateSettings(),
+		cfg,
+		next,
+	)
+	require.NoError(t, err)
+
+	md := generateLabelledMetrics(
+		labelledMetric{name: "requests.failed", dataType: pdata.MetricDataTypeSum, points: []labelledPoint{{value: 5}}},
+		labelledMetric{name: "requests.total", dataType: pdata.MetricDataTypeSum, points: []labelledPoint{{value: 50}}},
+	)
+	require.NoError(t, mgp.ConsumeMetrics(context.Background(), md))
+
+	metrics := next.AllMetrics()[0].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+	require.Equal(t, 3, metrics.Len())
+	generated := metrics.At(2)
+	assert.Equal(t, "requests.percent", generated.Name())
+	require.Equal(t, pdata.MetricDataTypeGauge, generated.DataType())
+	require.Equal(t, 1, generated.Gauge().DataPoints().Len())
+	assert.Equal(t, float64(10), generated.Gauge().DataPoints().At(0).DoubleVal())
+}
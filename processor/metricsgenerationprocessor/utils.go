@@ -34,21 +34,35 @@ func getNameToMetricMap(rm pdata.ResourceMetrics) map[string]pdata.Metric {
 	return metricMap
 }
 
+// getNumberDataPoints returns the data points of the given gauge or sum metric.
+func getNumberDataPoints(metric pdata.Metric) (pdata.NumberDataPointSlice, bool) {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeGauge:
+		return metric.Gauge().DataPoints(), true
+	case pdata.MetricDataTypeSum:
+		return metric.Sum().DataPoints(), true
+	}
+	return pdata.NumberDataPointSlice{}, false
+}
+
+// getDataPointValue returns the value of the given data point as a floating point number.
+func getDataPointValue(dataPoint pdata.NumberDataPoint) float64 {
+	switch dataPoint.Type() {
+	case pdata.MetricValueTypeDouble:
+		return dataPoint.DoubleVal()
+	case pdata.MetricValueTypeInt:
+		return float64(dataPoint.IntVal())
+	}
+	return 0
+}
+
 // getMetricValue returns the value of the first data point from the given metric.
 func getMetricValue(metric pdata.Metric) float64 {
-	if metric.DataType() == pdata.MetricDataTypeGauge {
-		dataPoints := metric.Gauge().DataPoints()
-		if dataPoints.Len() > 0 {
-			switch dataPoints.At(0).Type() {
-			case pdata.MetricValueTypeDouble:
-				return dataPoints.At(0).DoubleVal()
-			case pdata.MetricValueTypeInt:
-				return float64(dataPoints.At(0).IntVal())
-			}
-		}
+	dataPoints, ok := getNumberDataPoints(metric)
+	if !ok || dataPoints.Len() == 0 {
 		return 0
 	}
-	return 0
+	return getDataPointValue(dataPoints.At(0))
 }
 
 // generateMetrics creates a new metric based on the given rule and add it to the Resource Metric.
@@ -71,16 +85,13 @@ func generateMetrics(rm pdata.ResourceMetrics, operand2 float64, rule internalRu
 }
 
 func addDoubleGaugeDataPoints(from pdata.Metric, to pdata.Metric, operand2 float64, operation string, logger *zap.Logger) {
-	dataPoints := from.Gauge().DataPoints()
+	dataPoints, ok := getNumberDataPoints(from)
+	if !ok {
+		return
+	}
 	for i := 0; i < dataPoints.Len(); i++ {
 		fromDataPoint := dataPoints.At(i)
-		var operand1 float64
-		switch fromDataPoint.Type() {
-		case pdata.MetricValueTypeDouble:
-			operand1 = fromDataPoint.DoubleVal()
-		case pdata.MetricValueTypeInt:
-			operand1 = float64(fromDataPoint.IntVal())
-		}
+		operand1 := getDataPointValue(fromDataPoint)
 
 		neweDoubleDataPoint := to.Gauge().DataPoints().AppendEmpty()
 		fromDataPoint.CopyTo(neweDoubleDataPoint)
@@ -54,6 +54,9 @@ const (
 
 	// SubmatchCaseFieldName is the mapstructure field name for SubmatchCase field
 	SubmatchCaseFieldName = "submatch_case"
+
+	// ResourceAttributeFieldName is the mapstructure field name for ResourceAttribute field
+	ResourceAttributeFieldName = "resource_attribute"
 )
 
 // Config defines configuration for Resource processor.
@@ -151,6 +154,11 @@ type Operation struct {
 
 	// LabelValue identifies the exact label value to operate on
 	LabelValue string `mapstructure:"label_value"`
+
+	// ResourceAttribute identifies the resource attribute to copy to a label when the operation is
+	// `CopyResourceAttributeToLabel`, or names the resource attribute a label is promoted to when the operation
+	// is `PromoteLabelToResource`.
+	ResourceAttribute string `mapstructure:"resource_attribute"`
 }
 
 // ValueAction renames label values.
@@ -217,9 +225,18 @@ const (
 	// AggregateLabelValues aggregates away the values in Operation.AggregatedValues
 	// by the method indicated by Operation.AggregationType.
 	AggregateLabelValues OperationAction = "aggregate_label_values"
+
+	// CopyResourceAttributeToLabel adds the value of the resource attribute Operation.ResourceAttribute
+	// as a label named Operation.NewLabel, or the attribute name if empty.
+	CopyResourceAttributeToLabel OperationAction = "copy_resource_attribute_to_label"
+
+	// PromoteLabelToResource moves the label Operation.Label to the resource attribute named
+	// Operation.ResourceAttribute, or the label name if empty, splitting the metric by label value.
+	PromoteLabelToResource OperationAction = "promote_label_to_resource"
 )
 
-var operationActions = []OperationAction{AddLabel, UpdateLabel, DeleteLabelValue, ToggleScalarDataType, ScaleValue, AggregateLabels, AggregateLabelValues}
+var operationActions = []OperationAction{AddLabel, UpdateLabel, DeleteLabelValue, ToggleScalarDataType, ScaleValue, AggregateLabels, AggregateLabelValues,
+	CopyResourceAttributeToLabel, PromoteLabelToResource}
 
 func (oa OperationAction) isValid() bool {
 	for _, operationAction := range operationActions {
@@ -15,209 +15,336 @@
 package metricstransformprocessor
 
 import (
+	"fmt"
 	"math"
-	"math/rand"
 	"sort"
+	"strconv"
+	"strings"
 
-	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"go.opentelemetry.io/collector/model/pdata"
 )
 
-// timeseriesAndLabelValues is a data structure for grouping timeseries that will be aggregated
-type timeseriesAndLabelValues struct {
-	timeseries  []*metricspb.TimeSeries
-	labelValues []*metricspb.LabelValue
+// relabelFunc returns the labels a data point will have once aggregated, or false if the data point is not
+// aggregated and must be left unchanged
+type relabelFunc func(labels pdata.StringMap) (pdata.StringMap, bool)
+
+// dataPointGroup is a data structure for grouping data points that will be aggregated
+type dataPointGroup struct {
+	labels pdata.StringMap
+	// points holds the indices of the grouped data points, grouped by timestamp and ordered by timestamp
+	points       [][]int
+	timestampIdx map[int64]int
+}
+
+// aggregateDataPoints merges the data points of the metric that share the same labels, as returned by relabel,
+// and the same start timestamp using the specified aggregation. Histogram and summary data points can only be
+// merged by taking the sum, and histogram data points only if they have the same bucket bounds; otherwise an
+// error is returned and the metric is left unchanged
+func (mtp *metricsTransformProcessor) aggregateDataPoints(metric pdata.Metric, relabel relabelFunc, aggrType AggregationType) error {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeGauge:
+		mtp.aggregateNumberDataPoints(metric.Gauge().DataPoints(), relabel, aggrType)
+	case pdata.MetricDataTypeSum:
+		mtp.aggregateNumberDataPoints(metric.Sum().DataPoints(), relabel, aggrType)
+	case pdata.MetricDataTypeHistogram:
+		return mtp.aggregateHistogramDataPoints(metric, relabel, aggrType)
+	case pdata.MetricDataTypeSummary:
+		return mtp.aggregateSummaryDataPoints(metric, relabel, aggrType)
+	}
+	return nil
 }
 
-// mergeTimeseries merges each group of timeseries using the specified aggregation
-// Returns the merged timeseries
-func (mtp *metricsTransformProcessor) mergeTimeseries(groupedTimeseries map[string]*timeseriesAndLabelValues, aggrType AggregationType, metricType metricspb.MetricDescriptor_Type) []*metricspb.TimeSeries {
-	newTimeSeriesList := make([]*metricspb.TimeSeries, len(groupedTimeseries))
-	idxCounter := 0
-	for _, tlv := range groupedTimeseries {
-		timestampToPoints, startTimestamp := mtp.groupPointsByTimestamp(tlv.timeseries)
-		newPoints := mtp.mergePoints(timestampToPoints, aggrType, metricType)
-		newSingleTimeSeries := &metricspb.TimeSeries{
-			StartTimestamp: startTimestamp,
-			LabelValues:    tlv.labelValues,
-			Points:         newPoints,
+// aggregateNumberDataPoints replaces the data points in dps by the aggregated data points, followed by the
+// unchanged ones, ordered by start timestamp
+func (mtp *metricsTransformProcessor) aggregateNumberDataPoints(dps pdata.NumberDataPointSlice, relabel relabelFunc, aggrType AggregationType) {
+	groups, unchanged := mtp.groupDataPoints(dps.Len(), func(i int) (pdata.StringMap, pdata.Timestamp, pdata.Timestamp) {
+		dp := dps.At(i)
+		return dp.LabelsMap(), dp.StartTimestamp(), dp.Timestamp()
+	}, relabel)
+
+	newDps := pdata.NewNumberDataPointSlice()
+	newDps.EnsureCapacity(len(groups) + len(unchanged))
+	for _, group := range groups {
+		for _, idxs := range group.points {
+			newDp := newDps.AppendEmpty()
+			dps.At(idxs[0]).CopyTo(newDp)
+			group.labels.CopyTo(newDp.LabelsMap())
+			mtp.mergeNumberDataPoints(dps, idxs[1:], aggrType, newDp)
 		}
-		newTimeSeriesList[idxCounter] = newSingleTimeSeries
-		idxCounter++
+	}
+	for _, idx := range unchanged {
+		dps.At(idx).CopyTo(newDps.AppendEmpty())
 	}
 
-	return newTimeSeriesList
+	newDps.Sort(func(a, b pdata.NumberDataPoint) bool {
+		return mtp.compareTimestamps(a.StartTimestamp(), b.StartTimestamp())
+	})
+	dps.RemoveIf(func(pdata.NumberDataPoint) bool { return true })
+	newDps.MoveAndAppendTo(dps)
 }
 
-// sortTimeseries performs an in place sort of a list of timeseries by start timestamp
-// Returns the sorted timeseries
-func (mtp *metricsTransformProcessor) sortTimeseries(timeseries []*metricspb.TimeSeries) {
-	sort.Slice(timeseries, func(i, j int) bool {
-		return mtp.compareTimestamps(timeseries[i].StartTimestamp, timeseries[j].StartTimestamp)
+// aggregateHistogramDataPoints replaces the data points of the histogram metric by the aggregated data points,
+// followed by the unchanged ones, ordered by start timestamp
+func (mtp *metricsTransformProcessor) aggregateHistogramDataPoints(metric pdata.Metric, relabel relabelFunc, aggrType AggregationType) error {
+	dps := metric.Histogram().DataPoints()
+	groups, unchanged := mtp.groupDataPoints(dps.Len(), func(i int) (pdata.StringMap, pdata.Timestamp, pdata.Timestamp) {
+		dp := dps.At(i)
+		return dp.LabelsMap(), dp.StartTimestamp(), dp.Timestamp()
+	}, relabel)
+
+	// make sure all the groups can be merged before changing anything
+	for _, group := range groups {
+		for _, idxs := range group.points {
+			if len(idxs) == 1 {
+				continue
+			}
+			if aggrType != Sum {
+				return fmt.Errorf("histogram metric %q can only be aggregated using the %q aggregation type, got %q", metric.Name(), Sum, aggrType)
+			}
+			bounds := dps.At(idxs[0]).ExplicitBounds()
+			for _, idx := range idxs[1:] {
+				if otherBounds := dps.At(idx).ExplicitBounds(); !equalBounds(bounds, otherBounds) {
+					return fmt.Errorf("histogram metric %q cannot be aggregated as its data points have different bucket bounds: %v and %v", metric.Name(), bounds, otherBounds)
+				}
+			}
+		}
+	}
+
+	newDps := pdata.NewHistogramDataPointSlice()
+	newDps.EnsureCapacity(len(groups) + len(unchanged))
+	for _, group := range groups {
+		for _, idxs := range group.points {
+			newDp := newDps.AppendEmpty()
+			dps.At(idxs[0]).CopyTo(newDp)
+			group.labels.CopyTo(newDp.LabelsMap())
+			mtp.mergeHistogramDataPoints(dps, idxs[1:], newDp)
+		}
+	}
+	for _, idx := range unchanged {
+		dps.At(idx).CopyTo(newDps.AppendEmpty())
+	}
+
+	newDps.Sort(func(a, b pdata.HistogramDataPoint) bool {
+		return mtp.compareTimestamps(a.StartTimestamp(), b.StartTimestamp())
 	})
+	dps.RemoveIf(func(pdata.HistogramDataPoint) bool { return true })
+	newDps.MoveAndAppendTo(dps)
+	return nil
 }
 
-// groupPointsByTimestamp groups points by timestamp
-// Returns a map of grouped points and the minimum start timestamp
-func (mtp *metricsTransformProcessor) groupPointsByTimestamp(timeseries []*metricspb.TimeSeries) (map[int64][]*metricspb.Point, *timestamppb.Timestamp) {
-	var startTimestamp *timestamppb.Timestamp
-	timestampToPoints := make(map[int64][]*metricspb.Point)
-	for _, ts := range timeseries {
-		if startTimestamp == nil {
-			startTimestamp = ts.StartTimestamp
-		}
-		for _, p := range ts.Points {
-			if points, ok := timestampToPoints[p.Timestamp.Seconds]; ok {
-				timestampToPoints[p.Timestamp.Seconds] = append(points, p)
-			} else {
-				timestampToPoints[p.Timestamp.Seconds] = []*metricspb.Point{p}
+// aggregateSummaryDataPoints replaces the data points of the summary metric by the aggregated data points,
+// followed by the unchanged ones, ordered by start timestamp
+func (mtp *metricsTransformProcessor) aggregateSummaryDataPoints(metric pdata.Metric, relabel relabelFunc, aggrType AggregationType) error {
+	dps := metric.Summary().DataPoints()
+	groups, unchanged := mtp.groupDataPoints(dps.Len(), func(i int) (pdata.StringMap, pdata.Timestamp, pdata.Timestamp) {
+		dp := dps.At(i)
+		return dp.LabelsMap(), dp.StartTimestamp(), dp.Timestamp()
+	}, relabel)
+
+	// make sure all the groups can be merged before changing anything
+	for _, group := range groups {
+		for _, idxs := range group.points {
+			if len(idxs) > 1 && aggrType != Sum {
+				return fmt.Errorf("summary metric %q can only be aggregated using the %q aggregation type, got %q", metric.Name(), Sum, aggrType)
 			}
 		}
 	}
-	return timestampToPoints, startTimestamp
+
+	newDps := pdata.NewSummaryDataPointSlice()
+	newDps.EnsureCapacity(len(groups) + len(unchanged))
+	for _, group := range groups {
+		for _, idxs := range group.points {
+			newDp := newDps.AppendEmpty()
+			dps.At(idxs[0]).CopyTo(newDp)
+			group.labels.CopyTo(newDp.LabelsMap())
+			mtp.mergeSummaryDataPoints(dps, idxs[1:], newDp)
+		}
+	}
+	for _, idx := range unchanged {
+		dps.At(idx).CopyTo(newDps.AppendEmpty())
+	}
+
+	newDps.Sort(func(a, b pdata.SummaryDataPoint) bool {
+		return mtp.compareTimestamps(a.StartTimestamp(), b.StartTimestamp())
+	})
+	dps.RemoveIf(func(pdata.SummaryDataPoint) bool { return true })
+	newDps.MoveAndAppendTo(dps)
+	return nil
 }
 
-// mergePoints aggregates points in the groups provided in timestampToPoints by the specific caluculation indicated by aggrType and dataType
-// Returns a group of aggregated points
-func (mtp *metricsTransformProcessor) mergePoints(timestampToPoints map[int64][]*metricspb.Point, aggrType AggregationType, metricType metricspb.MetricDescriptor_Type) []*metricspb.Point {
-	newPoints := make([]*metricspb.Point, 0, len(timestampToPoints))
-	for _, points := range timestampToPoints {
-		timestamp := points[0].Timestamp
-		switch metricType {
-		case metricspb.MetricDescriptor_CUMULATIVE_INT64, metricspb.MetricDescriptor_GAUGE_INT64:
-			intPoint := mtp.mergeInt64(points, aggrType)
-			newPoints = append(newPoints, &metricspb.Point{
-				Timestamp: timestamp,
-				Value:     intPoint,
-			})
-		case metricspb.MetricDescriptor_CUMULATIVE_DOUBLE, metricspb.MetricDescriptor_GAUGE_DOUBLE:
-			doublePoint := mtp.mergeDouble(points, aggrType)
-			newPoints = append(newPoints, &metricspb.Point{
-				Timestamp: timestamp,
-				Value:     doublePoint,
-			})
-		case metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION, metricspb.MetricDescriptor_GAUGE_DISTRIBUTION:
-			if aggrType != Sum {
-				mtp.logger.Warn("Distribution data can only be aggregated by taking the sum")
-				newPoints = append(newPoints, points...)
-				break
+// groupDataPoints groups the data points that will be aggregated together based on their labels, as returned
+// by relabel, and their start timestamp. Within a group, data points are further grouped by timestamp.
+// Returns the groups in order of appearance, and the indices of the data points that are left unchanged
+func (mtp *metricsTransformProcessor) groupDataPoints(count int, dataPointAt func(i int) (pdata.StringMap, pdata.Timestamp, pdata.Timestamp),
+	relabel relabelFunc) ([]*dataPointGroup, []int) {
+	var groups []*dataPointGroup
+	var unchanged []int
+	timestamps := make([]pdata.Timestamp, count)
+
+	// key is a composite of the label values and the start timestamp as a single string
+	groupsByKey := make(map[string]*dataPointGroup)
+	for i := 0; i < count; i++ {
+		labels, startTimestamp, timestamp := dataPointAt(i)
+		timestamps[i] = timestamp
+
+		newLabels, ok := relabel(labels)
+		if !ok {
+			unchanged = append(unchanged, i)
+			continue
+		}
+
+		key := labelsAsKey(newLabels) + strconv.FormatInt(startTimestamp.AsTime().Unix(), 10)
+		group, ok := groupsByKey[key]
+		if !ok {
+			group = &dataPointGroup{
+				labels:       newLabels,
+				timestampIdx: make(map[int64]int),
 			}
-			distPoint := mtp.mergeDistribution(points)
-			newPoints = append(newPoints, &metricspb.Point{
-				Timestamp: timestamp,
-				Value:     distPoint,
-			})
+			groupsByKey[key] = group
+			groups = append(groups, group)
+		}
 
+		seconds := timestamp.AsTime().Unix()
+		if idx, ok := group.timestampIdx[seconds]; ok {
+			group.points[idx] = append(group.points[idx], i)
+		} else {
+			group.timestampIdx[seconds] = len(group.points)
+			group.points = append(group.points, []int{i})
 		}
 	}
-	sort.Slice(newPoints, func(i, j int) bool {
-		return mtp.compareTimestamps(newPoints[i].Timestamp, newPoints[j].Timestamp)
+
+	for _, group := range groups {
+		points := group.points
+		sort.SliceStable(points, func(i, j int) bool {
+			return mtp.compareTimestamps(timestamps[points[i][0]], timestamps[points[j][0]])
+		})
+	}
+	return groups, unchanged
+}
+
+// labelsAsKey composes a key identifying the set of labels, regardless of their order
+func labelsAsKey(labels pdata.StringMap) string {
+	pairs := make([]string, 0, labels.Len())
+	labels.Range(func(k string, v string) bool {
+		pairs = append(pairs, k+"\x00"+v)
+		return true
 	})
-	return newPoints
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x01") + "\x01"
 }
 
-// mergeInt64 merges int64 points into one point based on the provided aggrType
-// Returns the aggregated int64 value
-func (mtp *metricsTransformProcessor) mergeInt64(points []*metricspb.Point, aggrType AggregationType) *metricspb.Point_Int64Value {
-	intVal := int64(0)
-	if len(points) > 0 {
-		intVal = points[0].GetInt64Value()
-	}
-	for _, p := range points[1:] {
-		if aggrType == Sum || aggrType == Mean {
-			intVal += p.GetInt64Value()
-		} else if aggrType == Max {
-			intVal = mtp.maxInt64(intVal, p.GetInt64Value())
-		} else if aggrType == Min {
-			intVal = mtp.minInt64(intVal, p.GetInt64Value())
+// mergeNumberDataPoints merges the values of the data points at idxs into dest based on the provided aggrType
+func (mtp *metricsTransformProcessor) mergeNumberDataPoints(dps pdata.NumberDataPointSlice, idxs []int, aggrType AggregationType, dest pdata.NumberDataPoint) {
+	switch dest.Type() {
+	case pdata.MetricValueTypeInt:
+		intVal := dest.IntVal()
+		for _, idx := range idxs {
+			dp := dps.At(idx)
+			if aggrType == Sum || aggrType == Mean {
+				intVal += dp.IntVal()
+			} else if aggrType == Max {
+				intVal = mtp.maxInt64(intVal, dp.IntVal())
+			} else if aggrType == Min {
+				intVal = mtp.minInt64(intVal, dp.IntVal())
+			}
+			dp.Exemplars().MoveAndAppendTo(dest.Exemplars())
 		}
+		if aggrType == Mean {
+			intVal /= int64(len(idxs) + 1)
+		}
+		dest.SetIntVal(intVal)
+	case pdata.MetricValueTypeDouble:
+		doubleVal := dest.DoubleVal()
+		for _, idx := range idxs {
+			dp := dps.At(idx)
+			if aggrType == Sum || aggrType == Mean {
+				doubleVal += dp.DoubleVal()
+			} else if aggrType == Max {
+				doubleVal = math.Max(doubleVal, dp.DoubleVal())
+			} else if aggrType == Min {
+				doubleVal = math.Min(doubleVal, dp.DoubleVal())
+			}
+			dp.Exemplars().MoveAndAppendTo(dest.Exemplars())
+		}
+		if aggrType == Mean {
+			doubleVal /= float64(len(idxs) + 1)
+		}
+		dest.SetDoubleVal(doubleVal)
 	}
-	if aggrType == Mean {
-		intVal /= int64(len(points))
-	}
-	return &metricspb.Point_Int64Value{Int64Value: intVal}
 }
 
-// mergeDouble merges double points into one point based on the provided aggrType
-// Returns the aggregated double value
-func (mtp *metricsTransformProcessor) mergeDouble(points []*metricspb.Point, aggrType AggregationType) *metricspb.Point_DoubleValue {
-	doubleVal := float64(0)
-	if len(points) > 0 {
-		doubleVal = points[0].GetDoubleValue()
-	}
-	for _, p := range points[1:] {
-		if aggrType == Sum || aggrType == Mean {
-			doubleVal += p.GetDoubleValue()
-		} else if aggrType == Max {
-			doubleVal = math.Max(doubleVal, p.GetDoubleValue())
-		} else if aggrType == Min {
-			doubleVal = math.Min(doubleVal, p.GetDoubleValue())
+// mergeHistogramDataPoints adds the counts, sums and bucket counts of the data points at idxs, which have the
+// same bucket bounds as dest, to dest
+func (mtp *metricsTransformProcessor) mergeHistogramDataPoints(dps pdata.HistogramDataPointSlice, idxs []int, dest pdata.HistogramDataPoint) {
+	if len(idxs) == 0 {
+		return
+	}
+
+	bucketCounts := make([]uint64, len(dest.BucketCounts()))
+	copy(bucketCounts, dest.BucketCounts())
+	for _, idx := range idxs {
+		dp := dps.At(idx)
+		dest.SetCount(dest.Count() + dp.Count())
+		dest.SetSum(dest.Sum() + dp.Sum())
+		for i, count := range dp.BucketCounts() {
+			if i < len(bucketCounts) {
+				bucketCounts[i] += count
+			}
 		}
+		dp.Exemplars().MoveAndAppendTo(dest.Exemplars())
+	}
+	dest.SetBucketCounts(bucketCounts)
+}
+
+// mergeSummaryDataPoints adds the counts and sums of the data points at idxs to dest. Quantiles cannot be
+// computed from the quantiles of the merged data points, so they are dropped
+func (mtp *metricsTransformProcessor) mergeSummaryDataPoints(dps pdata.SummaryDataPointSlice, idxs []int, dest pdata.SummaryDataPoint) {
+	if len(idxs) == 0 {
+		return
 	}
-	if aggrType == Mean {
-		doubleVal /= float64(len(points))
+
+	for _, idx := range idxs {
+		dp := dps.At(idx)
+		dest.SetCount(dest.Count() + dp.Count())
+		dest.SetSum(dest.Sum() + dp.Sum())
 	}
-	return &metricspb.Point_DoubleValue{DoubleValue: doubleVal}
+	dest.QuantileValues().RemoveIf(func(pdata.ValueAtQuantile) bool { return true })
 }
 
-// mergeDistribution merges distribution points into one point
-// Returns the aggregated distribution value
-func (mtp *metricsTransformProcessor) mergeDistribution(points []*metricspb.Point) *metricspb.Point_DistributionValue {
-	var distVal *metricspb.DistributionValue
-	for _, p := range points {
-		if distVal == nil {
-			distVal = p.GetDistributionValue()
-			continue
+// equalBounds returns true if both histogram bucket bounds are the same
+func equalBounds(bounds1 []float64, bounds2 []float64) bool {
+	if len(bounds1) != len(bounds2) {
+		return false
+	}
+	for i := range bounds1 {
+		if bounds1[i] != bounds2[i] {
+			return false
 		}
-		distVal = mtp.computeDistVals(distVal, p.GetDistributionValue())
 	}
-	return &metricspb.Point_DistributionValue{DistributionValue: distVal}
+	return true
 }
 
-// computeDistVals does the necessary computations and aggregation to combine two distribution value into one
-// returns the combined distribution value
-func (mtp *metricsTransformProcessor) computeDistVals(val1 *metricspb.DistributionValue, val2 *metricspb.DistributionValue) *metricspb.DistributionValue {
-	buckets := make([]*metricspb.DistributionValue_Bucket, len(val1.Buckets))
-	for i := range buckets {
-		buckets[i] = &metricspb.DistributionValue_Bucket{
-			Count:    val1.Buckets[i].Count + val2.Buckets[i].Count,
-			Exemplar: mtp.pickExemplar(val1.Buckets[i].Exemplar, val2.Buckets[i].Exemplar),
-		}
+// maxInt64 returns the max between num1 and num2
+func (mtp *metricsTransformProcessor) maxInt64(num1, num2 int64) int64 {
+	if num1 > num2 {
+		return num1
 	}
-	newDistVal := &metricspb.DistributionValue{
-		BucketOptions: &metricspb.DistributionValue_BucketOptions{
-			Type: &metricspb.DistributionValue_BucketOptions_Explicit_{
-				Explicit: &metricspb.DistributionValue_BucketOptions_Explicit{
-					Bounds: val1.BucketOptions.GetExplicit().Bounds,
-				},
-			},
-		},
-		Count:                 val1.Count + val2.Count,
-		Sum:                   val1.Sum + val2.Sum,
-		Buckets:               buckets,
-		SumOfSquaredDeviation: mtp.computeSumOfSquaredDeviation(val1, val2),
-	}
-	return newDistVal
+	return num2
 }
 
-// computeSumOfSquaredDeviation computes the combined SumOfSquaredDeviation from the two points
-// Formula derived from https://math.stackexchange.com/questions/2971315/how-do-i-combine-standard-deviations-of-two-groups
-// SSDcomb = SSD1 + n(ave(x) - ave(z))^2 + SSD2 +n(ave(y) - ave(z))^2
-func (mtp *metricsTransformProcessor) computeSumOfSquaredDeviation(val1 *metricspb.DistributionValue, val2 *metricspb.DistributionValue) float64 {
-	mean1 := val1.Sum / float64(val1.Count)
-	mean2 := val2.Sum / float64(val2.Count)
-	meanCombined := (val1.Sum + val2.Sum) / float64(val1.Count+val2.Count)
-	squaredMeanDiff1 := math.Pow(mean1-meanCombined, 2)
-	squaredMeanDiff2 := math.Pow(mean2-meanCombined, 2)
-	return val1.SumOfSquaredDeviation + (float64(val1.Count) * squaredMeanDiff1) + val2.SumOfSquaredDeviation + (float64(val2.Count) * squaredMeanDiff2)
+// minInt64 returns the min between num1 and num2
+func (mtp *metricsTransformProcessor) minInt64(num1, num2 int64) int64 {
+	if num1 < num2 {
+		return num1
+	}
+	return num2
 }
 
-// pickExemplar picks an exemplar from 2 randomly with each haing a 50% chance of getting picked
-func (mtp *metricsTransformProcessor) pickExemplar(e1 *metricspb.DistributionValue_Exemplar, e2 *metricspb.DistributionValue_Exemplar) *metricspb.DistributionValue_Exemplar {
-	r := rand.Intn(2)
-	pickedExemplar := e1
-	if r == 1 {
-		pickedExemplar = e2
+// compareTimestamps returns if t1 is a smaller timestamp than t2, unset timestamps being the largest
+func (mtp *metricsTransformProcessor) compareTimestamps(t1 pdata.Timestamp, t2 pdata.Timestamp) bool {
+	if t1 == 0 || t2 == 0 {
+		return t1 != 0
 	}
-	return pickedExemplar
+
+	return t1 < t2
 }
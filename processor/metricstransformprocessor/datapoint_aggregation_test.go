@@ -0,0 +1,140 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricstransformprocessor
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+)
+
+func aggregateAllLabels(pdata.StringMap) (pdata.StringMap, bool) {
+	return pdata.NewStringMap(), true
+}
+
+func TestAggregateHistogramDataPoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		aggrType AggregationType
+		bounds   [][]float64
+		wantErr  string
+	}{
+		{
+			name:     "same_bounds",
+			aggrType: Sum,
+			bounds:   [][]float64{{1, 2}, {1, 2}},
+		},
+		{
+			name:     "different_bounds",
+			aggrType: Sum,
+			bounds:   [][]float64{{1, 2}, {1, 3}},
+			wantErr:  `histogram metric "metric1" cannot be aggregated as its data points have different bucket bounds: [1 2] and [1 3]`,
+		},
+		{
+			name:     "different_bucket_count",
+			aggrType: Sum,
+			bounds:   [][]float64{{1, 2}, {1}},
+			wantErr:  `histogram metric "metric1" cannot be aggregated as its data points have different bucket bounds: [1 2] and [1]`,
+		},
+		{
+			name:     "not_sum",
+			aggrType: Mean,
+			bounds:   [][]float64{{1, 2}, {1, 2}},
+			wantErr:  `histogram metric "metric1" can only be aggregated using the "sum" aggregation type, got "mean"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metric := pdata.NewMetric()
+			metric.SetName("metric1")
+			metric.SetDataType(pdata.MetricDataTypeHistogram)
+			for i, bounds := range test.bounds {
+				dp := metric.Histogram().DataPoints().AppendEmpty()
+				dp.LabelsMap().Insert("label1", "value"+strconv.Itoa(i+1))
+				dp.SetTimestamp(pdata.Timestamp(1e9))
+				dp.SetCount(uint64(len(bounds) + 1))
+				dp.SetSum(float64(i + 1))
+				dp.SetExplicitBounds(bounds)
+				bucketCounts := make([]uint64, len(bounds)+1)
+				for j := range bucketCounts {
+					bucketCounts[j] = 1
+				}
+				dp.SetBucketCounts(bucketCounts)
+			}
+
+			mtp := newMetricsTransformProcessor(zap.NewNop(), nil)
+			err := mtp.aggregateDataPoints(metric, aggregateAllLabels, test.aggrType)
+
+			dps := metric.Histogram().DataPoints()
+			if test.wantErr != "" {
+				assert.EqualError(t, err, test.wantErr)
+				// the metric is left unchanged
+				require.Equal(t, len(test.bounds), dps.Len())
+				for i := 0; i < dps.Len(); i++ {
+					assert.Equal(t, 1, dps.At(i).LabelsMap().Len())
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, 1, dps.Len())
+			dp := dps.At(0)
+			assert.Equal(t, 0, dp.LabelsMap().Len())
+			assert.Equal(t, uint64(6), dp.Count())
+			assert.Equal(t, float64(3), dp.Sum())
+			assert.Equal(t, []float64{1, 2}, dp.ExplicitBounds())
+			assert.Equal(t, []uint64{2, 2, 2}, dp.BucketCounts())
+		})
+	}
+}
+
+func TestAggregateSummaryDataPoints(t *testing.T) {
+	newSummary := func() pdata.Metric {
+		metric := pdata.NewMetric()
+		metric.SetName("metric1")
+		metric.SetDataType(pdata.MetricDataTypeSummary)
+		for i := 0; i < 2; i++ {
+			dp := metric.Summary().DataPoints().AppendEmpty()
+			dp.LabelsMap().Insert("label1", "value"+strconv.Itoa(i+1))
+			dp.SetTimestamp(pdata.Timestamp(1e9))
+			dp.SetCount(uint64(i + 1))
+			dp.SetSum(float64(10 * (i + 1)))
+			quantile := dp.QuantileValues().AppendEmpty()
+			quantile.SetQuantile(0.5)
+			quantile.SetValue(float64(i))
+		}
+		return metric
+	}
+
+	mtp := newMetricsTransformProcessor(zap.NewNop(), nil)
+
+	metric := newSummary()
+	require.NoError(t, mtp.aggregateDataPoints(metric, aggregateAllLabels, Sum))
+	dps := metric.Summary().DataPoints()
+	require.Equal(t, 1, dps.Len())
+	assert.Equal(t, uint64(3), dps.At(0).Count())
+	assert.Equal(t, float64(30), dps.At(0).Sum())
+	assert.Equal(t, 0, dps.At(0).QuantileValues().Len())
+
+	metric = newSummary()
+	assert.EqualError(t, mtp.aggregateDataPoints(metric, aggregateAllLabels, Max),
+		`summary metric "metric1" can only be aggregated using the "sum" aggregation type, got "max"`)
+	assert.Equal(t, 2, metric.Summary().DataPoints().Len())
+}
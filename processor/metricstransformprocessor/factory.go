@@ -131,6 +131,12 @@ func validateConfiguration(config *Config) error {
 			if op.Action == ScaleValue && op.Scale == 0 {
 				return fmt.Errorf("operation %v: missing required field %q while %q is %v", i+1, ScaleFieldName, ActionFieldName, ScaleValue)
 			}
+			if op.Action == CopyResourceAttributeToLabel && op.ResourceAttribute == "" {
+				return fmt.Errorf("operation %v: missing required field %q while %q is %v", i+1, ResourceAttributeFieldName, ActionFieldName, CopyResourceAttributeToLabel)
+			}
+			if op.Action == PromoteLabelToResource && op.Label == "" {
+				return fmt.Errorf("operation %v: missing required field %q while %q is %v", i+1, LabelFieldName, ActionFieldName, PromoteLabelToResource)
+			}
 
 			if op.AggregationType != "" && !op.AggregationType.isValid() {
 				return fmt.Errorf("operation %v: %q must be in %q", i+1, AggregationTypeFieldName, aggregationTypes)
@@ -94,6 +94,16 @@ func TestCreateProcessors(t *testing.T) {
 			succeed:      false,
 			errorMessage: fmt.Sprintf("operation %v: missing required field %q while %q is %v", 1, ScaleFieldName, ActionFieldName, ScaleValue),
 		},
+		{
+			configName:   "config_invalid_resource_attribute.yaml",
+			succeed:      false,
+			errorMessage: fmt.Sprintf("operation %v: missing required field %q while %q is %v", 1, ResourceAttributeFieldName, ActionFieldName, CopyResourceAttributeToLabel),
+		},
+		{
+			configName:   "config_invalid_promoted_label.yaml",
+			succeed:      false,
+			errorMessage: fmt.Sprintf("operation %v: missing required field %q while %q is %v", 1, LabelFieldName, ActionFieldName, PromoteLabelToResource),
+		},
 		{
 			configName:   "config_invalid_regexp.yaml",
 			succeed:      false,
@@ -18,18 +18,12 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
-	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
-	agentmetricspb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/metrics/v1"
-	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
-	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
 	"go.opentelemetry.io/collector/model/pdata"
-	"go.opentelemetry.io/collector/translator/internaldata"
 	"go.uber.org/zap"
-	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type metricsTransformProcessor struct {
@@ -60,9 +54,14 @@ type internalFilter interface {
 }
 
 type match struct {
-	metric     *metricspb.Metric
+	metricRef
 	pattern    *regexp.Regexp
 	submatches []int
+
+	// source and matchLabels are set when only some of the data points of source matched the label
+	// matchers, in which case metric is a detached copy of source holding only the matched data points.
+	source      pdata.Metric
+	matchLabels map[string]StringMatcher
 }
 
 type StringMatcher interface {
@@ -85,9 +84,8 @@ func (f internalFilterStrict) getMatches(toMatch metricNameMapping) []*match {
 	if metrics, ok := toMatch[f.include]; ok {
 		matches := make([]*match, 0)
 		for _, metric := range metrics {
-			matchedMetric := labelMatched(f.matchLabels, metric)
-			if matchedMetric != nil {
-				matches = append(matches, &match{metric: matchedMetric})
+			if matchedMetric := labelMatched(f.matchLabels, metric); matchedMetric != nil {
+				matches = append(matches, matchedMetric)
 			}
 		}
 		return matches
@@ -110,9 +108,10 @@ func (f internalFilterRegexp) getMatches(toMatch metricNameMapping) []*match {
 	for name, metrics := range toMatch {
 		if submatches := f.include.FindStringSubmatchIndex(name); submatches != nil {
 			for _, metric := range metrics {
-				matchedMetric := labelMatched(f.matchLabels, metric)
-				if matchedMetric != nil {
-					matches = append(matches, &match{metric: matchedMetric, pattern: f.include, submatches: submatches})
+				if matchedMetric := labelMatched(f.matchLabels, metric); matchedMetric != nil {
+					matchedMetric.pattern = f.include
+					matchedMetric.submatches = submatches
+					matches = append(matches, matchedMetric)
 				}
 			}
 		}
@@ -124,76 +123,76 @@ func (f internalFilterRegexp) getSubexpNames() []string {
 	return f.include.SubexpNames()
 }
 
-func labelMatched(matchLabels map[string]StringMatcher, metric *metricspb.Metric) *metricspb.Metric {
+// labelMatched returns a match for the metric if any of its data points have the label values given in
+// matchLabels, or nil otherwise. If only some of the data points match, the returned match holds a copy
+// of the metric with just those data points.
+func labelMatched(matchLabels map[string]StringMatcher, ref metricRef) *match {
+	matched := &match{metricRef: ref}
 	if len(matchLabels) == 0 {
-		return metric
+		return matched
 	}
 
-	metricWithMatchedLabel := &metricspb.Metric{}
-	metricWithMatchedLabel.MetricDescriptor = proto.Clone(metric.MetricDescriptor).(*metricspb.MetricDescriptor)
-	metricWithMatchedLabel.Resource = proto.Clone(metric.Resource).(*resourcepb.Resource)
-
-	var timeSeriesWithMatchedLabel []*metricspb.TimeSeries
-	labelIndexValueMap := make(map[int]StringMatcher)
-
-	for key, value := range matchLabels {
-		keyFound := false
-
-		for idx, label := range metric.MetricDescriptor.LabelKeys {
-			if label.Key != key {
-				continue
-			}
+	metricWithMatchedLabel := pdata.NewMetric()
+	ref.metric.CopyTo(metricWithMatchedLabel)
+	removeDataPoints(metricWithMatchedLabel, func(labels pdata.StringMap) bool {
+		return !labelsMatched(matchLabels, labels)
+	})
 
-			keyFound = true
-			labelIndexValueMap[idx] = value
-		}
-
-		// if a label-key is not found then return nil only if the given label-value is non-empty. If a given label-value is empty
-		// and the key is not found then move forward. In this approach we can make sure certain key is not present which is a valid use case.
-		if !keyFound && !value.MatchString("") {
-			return nil
-		}
+	switch count := dataPointCount(metricWithMatchedLabel); count {
+	case 0:
+		return nil
+	case dataPointCount(ref.metric):
+		return matched
 	}
 
-	for _, timeseries := range metric.Timeseries {
-		allValuesMatched := true
-		for index, value := range labelIndexValueMap {
-			if !value.MatchString(timeseries.LabelValues[index].Value) {
-				allValuesMatched = false
-				break
-			}
-		}
-		if allValuesMatched {
-			timeSeriesWithMatchedLabel = append(timeSeriesWithMatchedLabel, timeseries)
-		}
-	}
+	matched.metric = metricWithMatchedLabel
+	matched.source = ref.metric
+	matched.matchLabels = matchLabels
+	return matched
+}
 
-	if len(timeSeriesWithMatchedLabel) == 0 {
-		return nil
+// labelsMatched returns true if the labels have values matching all of matchLabels. A missing label is
+// treated as having an empty value, so that it is possible to make sure a certain label is not present.
+func labelsMatched(matchLabels map[string]StringMatcher, labels pdata.StringMap) bool {
+	for key, matcher := range matchLabels {
+		value, _ := labels.Get(key)
+		if !matcher.MatchString(value) {
+			return false
+		}
 	}
+	return true
+}
 
-	metricWithMatchedLabel.Timeseries = timeSeriesWithMatchedLabel
-	return metricWithMatchedLabel
+// metricRef references a metric along with the instrumentation library and the resource it belongs to.
+type metricRef struct {
+	metric   pdata.Metric
+	ilm      pdata.InstrumentationLibraryMetrics
+	resource pdata.Resource
 }
 
-type metricNameMapping map[string][]*metricspb.Metric
+type metricNameMapping map[string][]metricRef
 
-func newMetricNameMapping(metrics []*metricspb.Metric) metricNameMapping {
-	mnm := metricNameMapping(make(map[string][]*metricspb.Metric, len(metrics)))
-	for _, m := range metrics {
-		mnm.add(m.MetricDescriptor.Name, m)
+func newMetricNameMapping(rm pdata.ResourceMetrics) metricNameMapping {
+	mnm := metricNameMapping(make(map[string][]metricRef))
+	ilms := rm.InstrumentationLibraryMetrics()
+	for i := 0; i < ilms.Len(); i++ {
+		ilm := ilms.At(i)
+		metrics := ilm.Metrics()
+		for j := 0; j < metrics.Len(); j++ {
+			mnm.add(metrics.At(j).Name(), metricRef{metric: metrics.At(j), ilm: ilm, resource: rm.Resource()})
+		}
 	}
 	return mnm
 }
 
-func (mnm metricNameMapping) add(name string, metrics ...*metricspb.Metric) {
-	mnm[name] = append(mnm[name], metrics...)
+func (mnm metricNameMapping) add(name string, refs ...metricRef) {
+	mnm[name] = append(mnm[name], refs...)
 }
 
-func (mnm metricNameMapping) remove(name string, metrics ...*metricspb.Metric) {
+func (mnm metricNameMapping) remove(name string, metrics ...pdata.Metric) {
 	for _, metric := range metrics {
-		for j, m := range mnm[name] {
-			if metric == m {
+		for j, ref := range mnm[name] {
+			if metric == ref.metric {
 				mnm[name] = append(mnm[name][:j], mnm[name][j+1:]...)
 				break
 			}
@@ -211,21 +210,18 @@ func newMetricsTransformProcessor(logger *zap.Logger, internalTransforms []inter
 // processMetrics implements the ProcessMetricsFunc type.
 func (mtp *metricsTransformProcessor) processMetrics(_ context.Context, md pdata.Metrics) (pdata.Metrics, error) {
 	rms := md.ResourceMetrics()
-	groupedMds := make([]*agentmetricspb.ExportMetricsServiceRequest, 0)
-
-	out := pdata.NewMetrics()
+	moved := newMovedResourceMetrics()
 
 	for i := 0; i < rms.Len(); i++ {
-		node, resource, metrics := internaldata.ResourceMetricsToOC(rms.At(i))
+		rm := rms.At(i)
 
-		nameToMetricMapping := newMetricNameMapping(metrics)
+		nameToMetricMapping := newMetricNameMapping(rm)
 		for _, transform := range mtp.transforms {
 			matchedMetrics := transform.MetricIncludeFilter.getMatches(nameToMetricMapping)
 
 			if transform.Action == Group && len(matchedMetrics) > 0 {
-				nData := mtp.groupMatchedMetrics(node, resource, matchedMetrics, transform)
-				groupedMds = append(groupedMds, nData)
-				metrics = mtp.removeMatchedMetrics(metrics, matchedMetrics)
+				mtp.detachMatchedDataPoints(matchedMetrics)
+				mtp.groupMatchedMetrics(moved.group(rm.Resource()), matchedMetrics, transform, nameToMetricMapping)
 			}
 
 			if transform.Action == Combine && len(matchedMetrics) > 0 {
@@ -235,70 +231,175 @@ func (mtp *metricsTransformProcessor) processMetrics(_ context.Context, md pdata
 					continue
 				}
 
-				combined := mtp.combine(matchedMetrics, transform)
-				metrics = mtp.removeMatchedMetricsAndAppendCombined(metrics, matchedMetrics, combined)
+				combined, err := mtp.combine(matchedMetrics, transform)
+				if err != nil {
+					// TODO: report via trace / metric instead
+					mtp.logger.Warn(err.Error())
+					continue
+				}
+				mtp.detachMatchedDataPoints(matchedMetrics)
+				mtp.removeMatchedMetrics(matchedMetrics, nameToMetricMapping)
 
 				// set matchedMetrics to the combined metric so that any additional operations are performed on
 				// the combined metric
-				matchedMetrics = []*match{{metric: combined}}
+				matchedMetrics = []*match{combined}
 			}
 
 			for _, match := range matchedMetrics {
-				metricName := match.metric.MetricDescriptor.Name
-
-				if transform.Action == Insert {
-					match.metric = proto.Clone(match.metric).(*metricspb.Metric)
-					metrics = append(metrics, match.metric)
+				metricName := match.metric.Name()
+
+				switch {
+				case transform.Action == Insert:
+					match.metric = appendMetric(match.ilm, match.metric)
+				case transform.Action == Update && match.matchLabels != nil:
+					match.detachDataPoints()
+					match.metric = appendMetric(match.ilm, match.metric)
+					nameToMetricMapping.add(metricName, match.metricRef)
 				}
 
-				mtp.update(match, transform)
-
 				if transform.NewName != "" {
-					if transform.Action == Update {
+					mtp.rename(match, transform)
+					if transform.Action == Update || transform.Action == Group {
 						nameToMetricMapping.remove(metricName, match.metric)
 					}
-					nameToMetricMapping.add(match.metric.MetricDescriptor.Name, match.metric)
+					nameToMetricMapping.add(match.metric.Name(), match.metricRef)
 				}
+
+				mtp.update(match.metricRef, transform, moved, nameToMetricMapping)
 			}
 		}
+	}
+
+	moved.moveAndAppendTo(rms)
+	return md, nil
+}
+
+// movedResourceMetrics keeps track of the ResourceMetrics created for the metrics moved to a different resource,
+// either by grouping them or by promoting their labels to resource attributes, and of the resources the metrics
+// were moved from.
+type movedResourceMetrics struct {
+	rms       pdata.ResourceMetricsSlice
+	promoted  map[pdata.Resource]map[string]pdata.ResourceMetrics
+	movedFrom map[pdata.Resource]bool
+}
 
-		internaldata.OCToMetrics(node, resource, metrics).ResourceMetrics().MoveAndAppendTo(out.ResourceMetrics())
+func newMovedResourceMetrics() *movedResourceMetrics {
+	return &movedResourceMetrics{
+		rms:       pdata.NewResourceMetricsSlice(),
+		promoted:  make(map[pdata.Resource]map[string]pdata.ResourceMetrics),
+		movedFrom: make(map[pdata.Resource]bool),
 	}
+}
+
+// group returns a new ResourceMetrics with a copy of the resource, for metrics grouped from it.
+func (m *movedResourceMetrics) group(from pdata.Resource) pdata.ResourceMetrics {
+	m.movedFrom[from] = true
+	rm := m.rms.AppendEmpty()
+	from.CopyTo(rm.Resource())
+	return rm
+}
 
-	for i := range groupedMds {
-		internaldata.OCToMetrics(groupedMds[i].Node, groupedMds[i].Resource, groupedMds[i].Metrics).ResourceMetrics().MoveAndAppendTo(out.ResourceMetrics())
+// promote returns the ResourceMetrics with a copy of the resource, updated with the resource attribute, for
+// metrics whose data points have a label promoted from it. Metrics promoted with the same attribute and value
+// share the same ResourceMetrics.
+func (m *movedResourceMetrics) promote(from pdata.Resource, key string, value string) pdata.ResourceMetrics {
+	m.movedFrom[from] = true
+	if _, ok := m.promoted[from]; !ok {
+		m.promoted[from] = make(map[string]pdata.ResourceMetrics)
 	}
 
-	return out, nil
+	attribute := key + "\x00" + value
+	if rm, ok := m.promoted[from][attribute]; ok {
+		return rm
+	}
+	rm := m.rms.AppendEmpty()
+	from.CopyTo(rm.Resource())
+	rm.Resource().Attributes().UpsertString(key, value)
+	m.promoted[from][attribute] = rm
+	return rm
 }
 
-// groupMatchedMetrics groups matched metrics into a new MetricsData with a new Resource and returns it.
-func (mtp *metricsTransformProcessor) groupMatchedMetrics(node *commonpb.Node, resource *resourcepb.Resource, matchedMetrics []*match,
-	transform internalTransform) (nData *agentmetricspb.ExportMetricsServiceRequest) {
-	// create new ResouceMetrics bucket
-	nData = &agentmetricspb.ExportMetricsServiceRequest{
-		Node:     proto.Clone(node).(*commonpb.Node),
-		Resource: proto.Clone(resource).(*resourcepb.Resource),
-		Metrics:  make([]*metricspb.Metric, 0),
+// moveAndAppendTo drops the instrumentation libraries and resources left without metrics once their metrics were
+// moved, then moves the new ResourceMetrics to the end of rms.
+func (m *movedResourceMetrics) moveAndAppendTo(rms pdata.ResourceMetricsSlice) {
+	removeEmpty := func(rm pdata.ResourceMetrics) bool {
+		if !m.movedFrom[rm.Resource()] {
+			return false
+		}
+		rm.InstrumentationLibraryMetrics().RemoveIf(func(ilm pdata.InstrumentationLibraryMetrics) bool {
+			return ilm.Metrics().Len() == 0
+		})
+		return rm.InstrumentationLibraryMetrics().Len() == 0
 	}
+	rms.RemoveIf(removeEmpty)
+	m.rms.RemoveIf(removeEmpty)
+	m.rms.MoveAndAppendTo(rms)
+}
 
-	// update new resource labels to the new ResouceMetrics bucket
-	if nData.Resource == nil || nData.Resource.GetLabels() == nil {
-		nData.Resource = &resourcepb.Resource{
-			Labels: make(map[string]string),
+// instrumentationLibraryMetrics returns the instrumentation library metrics of rm for the instrumentation library,
+// appending it if rm doesn't have it yet.
+func instrumentationLibraryMetrics(rm pdata.ResourceMetrics, library pdata.InstrumentationLibrary) pdata.InstrumentationLibraryMetrics {
+	ilms := rm.InstrumentationLibraryMetrics()
+	for i := 0; i < ilms.Len(); i++ {
+		ilm := ilms.At(i)
+		if ilm.InstrumentationLibrary().Name() == library.Name() && ilm.InstrumentationLibrary().Version() == library.Version() {
+			return ilm
 		}
 	}
+	ilm := ilms.AppendEmpty()
+	library.CopyTo(ilm.InstrumentationLibrary())
+	return ilm
+}
+
+// appendMetric appends a copy of the metric to the instrumentation library metrics and returns the copy.
+func appendMetric(ilm pdata.InstrumentationLibraryMetrics, metric pdata.Metric) pdata.Metric {
+	newMetric := ilm.Metrics().AppendEmpty()
+	metric.CopyTo(newMetric)
+	return newMetric
+}
+
+// detachMatchedDataPoints detaches the matched data points of every match from their source metric.
+func (mtp *metricsTransformProcessor) detachMatchedDataPoints(matchedMetrics []*match) {
+	for _, match := range matchedMetrics {
+		match.detachDataPoints()
+	}
+}
+
+// detachDataPoints removes the matched data points from the source metric when only some of its data points
+// matched, so that the transform leaves the unmatched data points untouched.
+func (m *match) detachDataPoints() {
+	if m.matchLabels == nil {
+		return
+	}
+	removeDataPoints(m.source, func(labels pdata.StringMap) bool {
+		return labelsMatched(m.matchLabels, labels)
+	})
+}
 
-	rlabels := nData.Resource.GetLabels()
+// groupMatchedMetrics moves the matched metrics into the grouped ResourceMetrics, updating its resource with the
+// transform's resource labels.
+func (mtp *metricsTransformProcessor) groupMatchedMetrics(grouped pdata.ResourceMetrics, matchedMetrics []*match,
+	transform internalTransform, nameToMetricMapping metricNameMapping) {
+	// update new resource labels to the new ResourceMetrics bucket
+	rattrs := grouped.Resource().Attributes()
 	for k, v := range transform.GroupResourceLabels {
-		rlabels[k] = v
+		rattrs.UpsertString(k, v)
 	}
 
-	// reassign matched metrics to the new ResouceMetrics bucket
-	for _, match := range matchedMetrics {
-		nData.Metrics = append(nData.Metrics, match.metric)
+	// copy matched metrics to the new ResourceMetrics bucket, keeping their instrumentation libraries
+	groupedMetrics := make([]metricRef, len(matchedMetrics))
+	for i, match := range matchedMetrics {
+		ilm := instrumentationLibraryMetrics(grouped, match.ilm.InstrumentationLibrary())
+		groupedMetrics[i] = metricRef{metric: appendMetric(ilm, match.metric), ilm: ilm, resource: grouped.Resource()}
+	}
+
+	mtp.removeMatchedMetrics(matchedMetrics, nameToMetricMapping)
+
+	// point the matches to the grouped metrics, so that any additional operations are performed on them
+	for i, match := range matchedMetrics {
+		match.metricRef = groupedMetrics[i]
+		nameToMetricMapping.add(match.metric.Name(), match.metricRef)
 	}
-	return nData
 }
 
 // canBeCombined returns true if all the provided metrics share the same type, unit, and labels
@@ -307,28 +408,31 @@ func (mtp *metricsTransformProcessor) canBeCombined(matchedMetrics []*match) err
 		return nil
 	}
 
-	firstMetricDescriptor := matchedMetrics[0].metric.MetricDescriptor
-	firstMetricLabelKeys := make(map[string]struct{}, len(firstMetricDescriptor.LabelKeys))
-	for _, label := range firstMetricDescriptor.LabelKeys {
-		firstMetricLabelKeys[label.Key] = struct{}{}
+	firstMetric := matchedMetrics[0].metric
+	firstMetricType := metricTypeOf(firstMetric)
+	firstMetricLabelKeys := labelKeys(firstMetric)
+	firstMetricLabelKeySet := make(map[string]struct{}, len(firstMetricLabelKeys))
+	for _, key := range firstMetricLabelKeys {
+		firstMetricLabelKeySet[key] = struct{}{}
 	}
 
 	for i := 1; i < len(matchedMetrics); i++ {
 		metric := matchedMetrics[i].metric
-		if metric.MetricDescriptor.Type != firstMetricDescriptor.Type {
-			return fmt.Errorf("metrics cannot be combined as they are of different types: %v (%v) and %v (%v)", firstMetricDescriptor.Name, firstMetricDescriptor.Type, metric.MetricDescriptor.Name, metric.MetricDescriptor.Type)
+		if metricType := metricTypeOf(metric); metricType != firstMetricType {
+			return fmt.Errorf("metrics cannot be combined as they are of different types: %v (%v) and %v (%v)", firstMetric.Name(), firstMetricType, metric.Name(), metricType)
 		}
-		if metric.MetricDescriptor.Unit != firstMetricDescriptor.Unit {
-			return fmt.Errorf("metrics cannot be combined as they have different units: %v (%v) and %v (%v)", firstMetricDescriptor.Name, firstMetricDescriptor.Unit, metric.MetricDescriptor.Name, metric.MetricDescriptor.Unit)
+		if metric.Unit() != firstMetric.Unit() {
+			return fmt.Errorf("metrics cannot be combined as they have different units: %v (%v) and %v (%v)", firstMetric.Name(), firstMetric.Unit(), metric.Name(), metric.Unit())
 		}
 
-		if len(metric.MetricDescriptor.LabelKeys) != len(firstMetricLabelKeys) {
-			return fmt.Errorf("metrics cannot be combined as they have different labels: %v (%v) and %v (%v)", firstMetricDescriptor.Name, firstMetricDescriptor.LabelKeys, metric.MetricDescriptor.Name, metric.MetricDescriptor.LabelKeys)
+		metricLabelKeys := labelKeys(metric)
+		if len(metricLabelKeys) != len(firstMetricLabelKeys) {
+			return fmt.Errorf("metrics cannot be combined as they have different labels: %v (%v) and %v (%v)", firstMetric.Name(), firstMetricLabelKeys, metric.Name(), metricLabelKeys)
 		}
 
-		for _, label := range metric.MetricDescriptor.LabelKeys {
-			if _, ok := firstMetricLabelKeys[label.Key]; !ok {
-				return fmt.Errorf("metrics cannot be combined as they have different labels: %v (%v) and %v (%v)", firstMetricDescriptor.Name, firstMetricDescriptor.LabelKeys, metric.MetricDescriptor.Name, metric.MetricDescriptor.LabelKeys)
+		for _, key := range metricLabelKeys {
+			if _, ok := firstMetricLabelKeySet[key]; !ok {
+				return fmt.Errorf("metrics cannot be combined as they have different labels: %v (%v) and %v (%v)", firstMetric.Name(), firstMetricLabelKeys, metric.Name(), metricLabelKeys)
 			}
 		}
 	}
@@ -336,48 +440,96 @@ func (mtp *metricsTransformProcessor) canBeCombined(matchedMetrics []*match) err
 	return nil
 }
 
-// combine combines the metrics based on the supplied filter.
-func (mtp *metricsTransformProcessor) combine(matchedMetrics []*match, transform internalTransform) *metricspb.Metric {
-	// create combined metric with relevant name & descriptor
-	combinedMetric := &metricspb.Metric{}
-	combinedMetric.MetricDescriptor = proto.Clone(matchedMetrics[0].metric.MetricDescriptor).(*metricspb.MetricDescriptor)
-	combinedMetric.MetricDescriptor.Name = transform.NewName
-	combinedMetric.MetricDescriptor.Description = ""
+// metricTypeOf describes the type of the metric, including the value type of its number data points and, for
+// sums and histograms, their aggregation temporality.
+func metricTypeOf(metric pdata.Metric) string {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeGauge:
+		return fmt.Sprintf("%v(%v)", metric.DataType(), numberValueType(metric.Gauge().DataPoints()))
+	case pdata.MetricDataTypeSum:
+		sum := metric.Sum()
+		return fmt.Sprintf("%v(%v, %v, monotonic=%v)", metric.DataType(), numberValueType(sum.DataPoints()), sum.AggregationTemporality(), sum.IsMonotonic())
+	case pdata.MetricDataTypeHistogram:
+		return fmt.Sprintf("%v(%v)", metric.DataType(), metric.Histogram().AggregationTemporality())
+	}
+	return metric.DataType().String()
+}
+
+// numberValueType returns "int" if all the data points have integer values and "double" otherwise.
+func numberValueType(dps pdata.NumberDataPointSlice) string {
+	for i := 0; i < dps.Len(); i++ {
+		if dps.At(i).Type() != pdata.MetricValueTypeInt {
+			return "double"
+		}
+	}
+	return "int"
+}
 
-	// append label keys based on the transform filter's named capturing groups
+// labelKeys returns the sorted set of label keys used across all the data points of the metric.
+func labelKeys(metric pdata.Metric) []string {
+	keySet := make(map[string]struct{})
+	rangeDataPointLabels(metric, func(labels pdata.StringMap) {
+		labels.Range(func(k string, _ string) bool {
+			keySet[k] = struct{}{}
+			return true
+		})
+	})
+
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// combine combines the metrics based on the supplied filter into a new metric, which is appended to the
+// instrumentation library of the first matched metric.
+func (mtp *metricsTransformProcessor) combine(matchedMetrics []*match, transform internalTransform) (*match, error) {
+	// create combined metric with relevant name & data type
+	combinedMetric := pdata.NewMetric()
+	matchedMetrics[0].metric.CopyTo(combinedMetric)
+	combinedMetric.SetName(transform.NewName)
+	combinedMetric.SetDescription("")
+	removeDataPoints(combinedMetric, func(pdata.StringMap) bool { return true })
+
+	// use the transform filter's named capturing groups as label keys, if the subexpression is not named,
+	// use regexp notation, e.g. $1
 	subexprNames := transform.MetricIncludeFilter.getSubexpNames()
+	labelKeys := make([]string, len(subexprNames))
 	for i := 1; i < len(subexprNames); i++ {
-		// if the subexpression is not named, use regexp notation, e.g. $1
-		name := subexprNames[i]
-		if name == "" {
-			name = "$" + strconv.Itoa(i)
+		labelKeys[i] = subexprNames[i]
+		if labelKeys[i] == "" {
+			labelKeys[i] = "$" + strconv.Itoa(i)
 		}
-
-		combinedMetric.MetricDescriptor.LabelKeys = append(combinedMetric.MetricDescriptor.LabelKeys, &metricspb.LabelKey{Key: name})
 	}
 
-	// combine timeseries from all metrics, using the specified AggregationType if data points need to be merged
-	var allTimeseries []*metricspb.TimeSeries
+	// combine data points from all metrics, adding label values based on regex submatches
 	for _, match := range matchedMetrics {
-		for _, ts := range match.metric.Timeseries {
-			// append label values based on regex submatches
+		metric := pdata.NewMetric()
+		match.metric.CopyTo(metric)
+		rangeDataPointLabels(metric, func(labels pdata.StringMap) {
 			for i := 1; i < len(match.submatches)/2; i++ {
-				submatch := match.metric.MetricDescriptor.Name[match.submatches[2*i]:match.submatches[2*i+1]]
+				submatch := match.metric.Name()[match.submatches[2*i]:match.submatches[2*i+1]]
 				submatch = replaceCaseOfSubmatch(transform.SubmatchCase, submatch)
-				ts.LabelValues = append(ts.LabelValues, &metricspb.LabelValue{Value: submatch, HasValue: (submatch != "")})
+				if submatch != "" {
+					labels.Upsert(labelKeys[i], submatch)
+				}
 			}
-
-			allTimeseries = append(allTimeseries, match.metric.Timeseries...)
-		}
+		})
+		moveDataPoints(metric, combinedMetric)
 	}
 
-	groupedTimeseries := mtp.groupTimeseries(allTimeseries, len(combinedMetric.MetricDescriptor.LabelKeys))
-	aggregatedTimeseries := mtp.mergeTimeseries(groupedTimeseries, transform.AggregationType, combinedMetric.MetricDescriptor.Type)
-
-	mtp.sortTimeseries(aggregatedTimeseries)
-	combinedMetric.Timeseries = aggregatedTimeseries
+	// merge data points using the specified AggregationType if they have the same labels
+	err := mtp.aggregateDataPoints(combinedMetric, func(labels pdata.StringMap) (pdata.StringMap, bool) {
+		return labels, true
+	}, transform.AggregationType)
+	if err != nil {
+		return nil, err
+	}
 
-	return combinedMetric
+	first := matchedMetrics[0]
+	return &match{metricRef: metricRef{metric: appendMetric(first.ilm, combinedMetric), ilm: first.ilm, resource: first.resource}}, nil
 }
 
 func replaceCaseOfSubmatch(replacement SubmatchCase, submatch string) string {
@@ -391,96 +543,150 @@ func replaceCaseOfSubmatch(replacement SubmatchCase, submatch string) string {
 	return submatch
 }
 
-// removeMatchedMetrics removes the set of matched metrics from metrics
-func (mtp *metricsTransformProcessor) removeMatchedMetrics(metrics []*metricspb.Metric, matchedMetrics []*match) []*metricspb.Metric {
-	filteredMetrics := make([]*metricspb.Metric, 0, len(metrics)-len(matchedMetrics))
-	for _, metric := range metrics {
-		var matched bool
-		for _, match := range matchedMetrics {
-			if match.metric == metric {
-				matched = true
-				break
-			}
-		}
-		if !matched {
-			filteredMetrics = append(filteredMetrics, metric)
+// removeMatchedMetrics removes the set of matched metrics from their instrumentation libraries and from the
+// name mapping
+func (mtp *metricsTransformProcessor) removeMatchedMetrics(matchedMetrics []*match, nameToMetricMapping metricNameMapping) {
+	matchedByILM := make(map[pdata.InstrumentationLibraryMetrics]map[pdata.Metric]struct{})
+	for _, match := range matchedMetrics {
+		if _, ok := matchedByILM[match.ilm]; !ok {
+			matchedByILM[match.ilm] = make(map[pdata.Metric]struct{})
 		}
+		matchedByILM[match.ilm][match.metric] = struct{}{}
+		nameToMetricMapping.remove(match.metric.Name(), match.metric)
+	}
+
+	for ilm, matched := range matchedByILM {
+		ilm.Metrics().RemoveIf(func(metric pdata.Metric) bool {
+			_, ok := matched[metric]
+			return ok
+		})
 	}
-	return filteredMetrics
 }
 
-// removeMatchedMetricsAndAppendCombined removes the set of matched metrics from metrics and appends the combined metric at the end.
-func (mtp *metricsTransformProcessor) removeMatchedMetricsAndAppendCombined(metrics []*metricspb.Metric, matchedMetrics []*match, combined *metricspb.Metric) []*metricspb.Metric {
-	filteredMetrics := mtp.removeMatchedMetrics(metrics, matchedMetrics)
-	return append(filteredMetrics, combined)
+// rename renames the matched metric to the transform's new name, expanding the regexp submatches if any.
+func (mtp *metricsTransformProcessor) rename(match *match, transform internalTransform) {
+	if match.pattern == nil {
+		match.metric.SetName(transform.NewName)
+	} else {
+		match.metric.SetName(string(match.pattern.ExpandString([]byte{}, transform.NewName, match.metric.Name(), match.submatches)))
+	}
 }
 
-// update updates the metric content based on operations indicated in transform.
-func (mtp *metricsTransformProcessor) update(match *match, transform internalTransform) {
-	if transform.NewName != "" {
-		if match.pattern == nil {
-			match.metric.MetricDescriptor.Name = transform.NewName
-		} else {
-			match.metric.MetricDescriptor.Name = string(match.pattern.ExpandString([]byte{}, transform.NewName, match.metric.MetricDescriptor.Name, match.submatches))
+// update updates the metric content based on operations indicated in transform. Operations following a label
+// promotion are performed on all the metrics the data points were moved to.
+func (mtp *metricsTransformProcessor) update(ref metricRef, transform internalTransform, moved *movedResourceMetrics, nameToMetricMapping metricNameMapping) {
+	refs := []metricRef{ref}
+	for _, op := range transform.Operations {
+		if op.configOperation.Action == PromoteLabelToResource {
+			var promotedRefs []metricRef
+			for _, ref := range refs {
+				promotedRefs = append(promotedRefs, mtp.promoteLabelToResourceOp(ref, op, moved, nameToMetricMapping)...)
+			}
+			refs = promotedRefs
+			continue
+		}
+
+		for _, ref := range refs {
+			mtp.updateMetric(ref, op)
 		}
 	}
+}
 
-	for _, op := range transform.Operations {
-		switch op.configOperation.Action {
-		case UpdateLabel:
-			mtp.updateLabelOp(match.metric, op)
-		case AggregateLabels:
-			mtp.aggregateLabelsOp(match.metric, op)
-		case AggregateLabelValues:
-			mtp.aggregateLabelValuesOp(match.metric, op)
-		case ToggleScalarDataType:
-			mtp.ToggleScalarDataType(match.metric)
-		case ScaleValue:
-			mtp.scaleValueOp(match.metric, op)
-		case AddLabel:
-			mtp.addLabelOp(match.metric, op)
-		case DeleteLabelValue:
-			mtp.deleteLabelValueOp(match.metric, op)
+// updateMetric performs the operation on the metric.
+func (mtp *metricsTransformProcessor) updateMetric(ref metricRef, op internalOperation) {
+	metric := ref.metric
+	switch op.configOperation.Action {
+	case UpdateLabel:
+		mtp.updateLabelOp(metric, op)
+	case AggregateLabels:
+		if err := mtp.aggregateLabelsOp(metric, op); err != nil {
+			// TODO: report via trace / metric instead
+			mtp.logger.Warn(err.Error())
+		}
+	case AggregateLabelValues:
+		if err := mtp.aggregateLabelValuesOp(metric, op); err != nil {
+			// TODO: report via trace / metric instead
+			mtp.logger.Warn(err.Error())
 		}
+	case ToggleScalarDataType:
+		mtp.ToggleScalarDataType(metric)
+	case ScaleValue:
+		mtp.scaleValueOp(metric, op)
+	case AddLabel:
+		mtp.addLabelOp(metric, op)
+	case DeleteLabelValue:
+		mtp.deleteLabelValueOp(metric, op)
+	case CopyResourceAttributeToLabel:
+		mtp.copyResourceAttributeToLabelOp(ref, op)
 	}
 }
 
-// getLabelIdxs gets the indices of the labelSet labels' indices in the metric's descriptor's labels field
-// Returns the indices slice and a slice of the actual labels selected by this slice of indices
-func (mtp *metricsTransformProcessor) getLabelIdxs(metric *metricspb.Metric, labelSet map[string]bool) ([]int, []*metricspb.LabelKey) {
-	labelIdxs := make([]int, 0)
-	labels := make([]*metricspb.LabelKey, 0)
-	for idx, label := range metric.MetricDescriptor.LabelKeys {
-		_, ok := labelSet[label.Key]
-		if ok {
-			labelIdxs = append(labelIdxs, idx)
-			labels = append(labels, label)
+// rangeDataPointLabels calls f with the labels of every data point of the metric.
+func rangeDataPointLabels(metric pdata.Metric, f func(labels pdata.StringMap)) {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeGauge:
+		rangeNumberDataPointLabels(metric.Gauge().DataPoints(), f)
+	case pdata.MetricDataTypeSum:
+		rangeNumberDataPointLabels(metric.Sum().DataPoints(), f)
+	case pdata.MetricDataTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			f(dps.At(i).LabelsMap())
+		}
+	case pdata.MetricDataTypeSummary:
+		dps := metric.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			f(dps.At(i).LabelsMap())
 		}
 	}
-	return labelIdxs, labels
 }
 
-// maxInt64 returns the max between num1 and num2
-func (mtp *metricsTransformProcessor) maxInt64(num1, num2 int64) int64 {
-	if num1 > num2 {
-		return num1
+func rangeNumberDataPointLabels(dps pdata.NumberDataPointSlice, f func(labels pdata.StringMap)) {
+	for i := 0; i < dps.Len(); i++ {
+		f(dps.At(i).LabelsMap())
 	}
-	return num2
 }
 
-// minInt64 returns the min between num1 and num2
-func (mtp *metricsTransformProcessor) minInt64(num1, num2 int64) int64 {
-	if num1 < num2 {
-		return num1
+// removeDataPoints removes the data points of the metric for which f returns true.
+func removeDataPoints(metric pdata.Metric, f func(labels pdata.StringMap) bool) {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeGauge:
+		metric.Gauge().DataPoints().RemoveIf(func(dp pdata.NumberDataPoint) bool { return f(dp.LabelsMap()) })
+	case pdata.MetricDataTypeSum:
+		metric.Sum().DataPoints().RemoveIf(func(dp pdata.NumberDataPoint) bool { return f(dp.LabelsMap()) })
+	case pdata.MetricDataTypeHistogram:
+		metric.Histogram().DataPoints().RemoveIf(func(dp pdata.HistogramDataPoint) bool { return f(dp.LabelsMap()) })
+	case pdata.MetricDataTypeSummary:
+		metric.Summary().DataPoints().RemoveIf(func(dp pdata.SummaryDataPoint) bool { return f(dp.LabelsMap()) })
 	}
-	return num2
 }
 
-// compareTimestamps returns if t1 is a smaller timestamp than t2
-func (mtp *metricsTransformProcessor) compareTimestamps(t1 *timestamppb.Timestamp, t2 *timestamppb.Timestamp) bool {
-	if t1 == nil || t2 == nil {
-		return t1 != nil
+// moveDataPoints moves all the data points of from to the end of the data points of to, which must be of the
+// same data type.
+func moveDataPoints(from pdata.Metric, to pdata.Metric) {
+	switch from.DataType() {
+	case pdata.MetricDataTypeGauge:
+		from.Gauge().DataPoints().MoveAndAppendTo(to.Gauge().DataPoints())
+	case pdata.MetricDataTypeSum:
+		from.Sum().DataPoints().MoveAndAppendTo(to.Sum().DataPoints())
+	case pdata.MetricDataTypeHistogram:
+		from.Histogram().DataPoints().MoveAndAppendTo(to.Histogram().DataPoints())
+	case pdata.MetricDataTypeSummary:
+		from.Summary().DataPoints().MoveAndAppendTo(to.Summary().DataPoints())
 	}
+}
 
-	return t1.Seconds < t2.Seconds || (t1.Seconds == t2.Seconds && t1.Nanos < t2.Nanos)
+// dataPointCount returns the number of data points of the metric.
+func dataPointCount(metric pdata.Metric) int {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeGauge:
+		return metric.Gauge().DataPoints().Len()
+	case pdata.MetricDataTypeSum:
+		return metric.Sum().DataPoints().Len()
+	case pdata.MetricDataTypeHistogram:
+		return metric.Histogram().DataPoints().Len()
+	case pdata.MetricDataTypeSummary:
+		return metric.Summary().DataPoints().Len()
+	}
+	return 0
 }
@@ -16,7 +16,9 @@ package metricstransformprocessor
 
 import (
 	"context"
-	"math"
+	"regexp"
+	"sort"
+	"strconv"
 	"testing"
 
 	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
@@ -25,6 +27,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/model/pdata"
 	"go.opentelemetry.io/collector/processor/processorhelper"
 	"go.opentelemetry.io/collector/translator/internaldata"
 	"go.uber.org/zap"
@@ -73,8 +76,190 @@ func TestMetricsTransformProcessor(t *testing.T) {
 	}
 }
 
+func TestMetricsTransformProcessorKeepsInstrumentationLibraries(t *testing.T) {
+	transforms := []internalTransform{
+		{
+			MetricIncludeFilter: internalFilterStrict{include: "metric1"},
+			Action:              Insert,
+			NewName:             "new/metric1",
+		},
+		{
+			MetricIncludeFilter: internalFilterStrict{include: "metric2"},
+			Action:              Update,
+			Operations: []internalOperation{
+				{
+					configOperation: Operation{
+						Action:   AddLabel,
+						NewLabel: "label1",
+						NewValue: "value1",
+					},
+				},
+			},
+		},
+	}
+
+	md := pdata.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().InsertString("resource", "value")
+	for i, name := range []string{"metric1", "metric2"} {
+		ilm := rm.InstrumentationLibraryMetrics().AppendEmpty()
+		ilm.InstrumentationLibrary().SetName("library" + strconv.Itoa(i+1))
+		metric := ilm.Metrics().AppendEmpty()
+		metric.SetName(name)
+		metric.SetDataType(pdata.MetricDataTypeSum)
+		metric.Sum().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+		dp := metric.Sum().DataPoints().AppendEmpty()
+		dp.SetTimestamp(pdata.Timestamp(2))
+		dp.SetIntVal(3)
+	}
+
+	p := newMetricsTransformProcessor(zap.NewExample(), transforms)
+	got, err := p.processMetrics(context.Background(), md)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, got.ResourceMetrics().Len())
+	rm = got.ResourceMetrics().At(0)
+	val, ok := rm.Resource().Attributes().Get("resource")
+	require.True(t, ok)
+	assert.Equal(t, "value", val.StringVal())
+
+	ilms := rm.InstrumentationLibraryMetrics()
+	require.Equal(t, 2, ilms.Len())
+
+	assert.Equal(t, "library1", ilms.At(0).InstrumentationLibrary().Name())
+	require.Equal(t, 2, ilms.At(0).Metrics().Len())
+	assert.Equal(t, "metric1", ilms.At(0).Metrics().At(0).Name())
+	inserted := ilms.At(0).Metrics().At(1)
+	assert.Equal(t, "new/metric1", inserted.Name())
+	assert.Equal(t, pdata.AggregationTemporalityDelta, inserted.Sum().AggregationTemporality())
+	assert.Equal(t, int64(3), inserted.Sum().DataPoints().At(0).IntVal())
+
+	assert.Equal(t, "library2", ilms.At(1).InstrumentationLibrary().Name())
+	require.Equal(t, 1, ilms.At(1).Metrics().Len())
+	updated := ilms.At(1).Metrics().At(0)
+	assert.Equal(t, pdata.AggregationTemporalityDelta, updated.Sum().AggregationTemporality())
+	label, ok := updated.Sum().DataPoints().At(0).LabelsMap().Get("label1")
+	require.True(t, ok)
+	assert.Equal(t, "value1", label)
+}
+
+func TestMetricsTransformProcessorCopyResourceAttributeToLabel(t *testing.T) {
+	transforms := []internalTransform{
+		{
+			MetricIncludeFilter: internalFilterStrict{include: "metric1"},
+			Action:              Update,
+			Operations: []internalOperation{
+				{
+					configOperation: Operation{
+						Action:            CopyResourceAttributeToLabel,
+						ResourceAttribute: "host.name",
+						NewLabel:          "host",
+					},
+				},
+				{
+					configOperation: Operation{
+						Action:            CopyResourceAttributeToLabel,
+						ResourceAttribute: "missing",
+					},
+				},
+			},
+		},
+	}
+
+	md := pdata.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().InsertString("host.name", "host1")
+	metric := rm.InstrumentationLibraryMetrics().AppendEmpty().Metrics().AppendEmpty()
+	metric.SetName("metric1")
+	metric.SetDataType(pdata.MetricDataTypeGauge)
+	metric.Gauge().DataPoints().AppendEmpty().SetIntVal(1)
+	dp := metric.Gauge().DataPoints().AppendEmpty()
+	dp.SetIntVal(2)
+	dp.LabelsMap().Insert("host", "host2")
+
+	p := newMetricsTransformProcessor(zap.NewExample(), transforms)
+	got, err := p.processMetrics(context.Background(), md)
+	require.NoError(t, err)
+
+	dps := got.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics().At(0).Gauge().DataPoints()
+	require.Equal(t, 2, dps.Len())
+	assert.Equal(t, map[string]string{"host": "host1"}, labelsAsMap(dps.At(0).LabelsMap()))
+	assert.Equal(t, map[string]string{"host": "host2"}, labelsAsMap(dps.At(1).LabelsMap()))
+}
+
+func TestMetricsTransformProcessorPromoteLabelToResource(t *testing.T) {
+	transforms := []internalTransform{
+		{
+			MetricIncludeFilter: internalFilterRegexp{include: regexp.MustCompile("^metric[12]$")},
+			Action:              Update,
+			Operations: []internalOperation{
+				{
+					configOperation: Operation{
+						Action:            PromoteLabelToResource,
+						Label:             "host",
+						ResourceAttribute: "host.name",
+					},
+				},
+			},
+		},
+	}
+
+	md := pdata.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().InsertString("service.name", "service1")
+	ilm := rm.InstrumentationLibraryMetrics().AppendEmpty()
+	ilm.InstrumentationLibrary().SetName("library1")
+	for _, name := range []string{"metric1", "metric2", "metric3"} {
+		metric := ilm.Metrics().AppendEmpty()
+		metric.SetName(name)
+		metric.SetDataType(pdata.MetricDataTypeGauge)
+		for i, host := range []string{"host1", "host2", ""} {
+			dp := metric.Gauge().DataPoints().AppendEmpty()
+			dp.SetIntVal(int64(i))
+			dp.LabelsMap().Insert("label", "value")
+			if host != "" && name != "metric2" {
+				dp.LabelsMap().Insert("host", host)
+			}
+		}
+	}
+
+	p := newMetricsTransformProcessor(zap.NewExample(), transforms)
+	got, err := p.processMetrics(context.Background(), md)
+	require.NoError(t, err)
+
+	rms := got.ResourceMetrics()
+	require.Equal(t, 3, rms.Len())
+
+	// data points without the label are kept in the original resource
+	assert.Equal(t, map[string]string{"service.name": "service1"}, attributesAsMap(rms.At(0).Resource().Attributes()))
+	metrics := rms.At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+	require.Equal(t, 3, metrics.Len())
+	assert.Equal(t, "metric1", metrics.At(0).Name())
+	assert.Equal(t, 1, metrics.At(0).Gauge().DataPoints().Len())
+	assert.Equal(t, "metric2", metrics.At(1).Name())
+	assert.Equal(t, 3, metrics.At(1).Gauge().DataPoints().Len())
+	assert.Equal(t, "metric3", metrics.At(2).Name())
+	assert.Equal(t, 3, metrics.At(2).Gauge().DataPoints().Len())
+
+	for i, host := range []string{"host1", "host2"} {
+		rm := rms.At(i + 1)
+		assert.Equal(t, map[string]string{"service.name": "service1", "host.name": host}, attributesAsMap(rm.Resource().Attributes()))
+		require.Equal(t, 1, rm.InstrumentationLibraryMetrics().Len())
+		ilm := rm.InstrumentationLibraryMetrics().At(0)
+		assert.Equal(t, "library1", ilm.InstrumentationLibrary().Name())
+		require.Equal(t, 1, ilm.Metrics().Len())
+		metric := ilm.Metrics().At(0)
+		assert.Equal(t, "metric1", metric.Name())
+		require.Equal(t, 1, metric.Gauge().DataPoints().Len())
+		dp := metric.Gauge().DataPoints().At(0)
+		assert.Equal(t, int64(i), dp.IntVal())
+		assert.Equal(t, map[string]string{"label": "value"}, labelsAsMap(dp.LabelsMap()))
+	}
+}
+
 func TestComputeDistVals(t *testing.T) {
-	ssdTests := []struct {
+	bounds := []float64{2, 5}
+	distTests := []struct {
 		name        string
 		pointGroup1 []float64
 		pointGroup2 []float64
@@ -96,55 +281,97 @@ func TestComputeDistVals(t *testing.T) {
 		},
 	}
 
-	for _, test := range ssdTests {
+	for _, test := range distTests {
 		t.Run(test.name, func(t *testing.T) {
 			p := newMetricsTransformProcessor(nil, nil)
 
-			pointGroup1 := test.pointGroup1
-			pointGroup2 := test.pointGroup2
-			sum1, sumOfSquaredDeviation1 := calculateSumOfSquaredDeviation(pointGroup1)
-			sum2, sumOfSquaredDeviation2 := calculateSumOfSquaredDeviation(pointGroup2)
-			_, sumOfSquaredDeviation := calculateSumOfSquaredDeviation(append(pointGroup1, pointGroup2...))
-
-			val1 := &metricspb.DistributionValue{
-				Count:                 int64(len(pointGroup1)),
-				Sum:                   sum1,
-				SumOfSquaredDeviation: sumOfSquaredDeviation1,
-			}
-
-			val2 := &metricspb.DistributionValue{
-				Count:                 int64(len(pointGroup2)),
-				Sum:                   sum2,
-				SumOfSquaredDeviation: sumOfSquaredDeviation2,
-			}
+			dps := pdata.NewHistogramDataPointSlice()
+			histogramOf(test.pointGroup1, bounds).CopyTo(dps.AppendEmpty())
+			histogramOf(test.pointGroup2, bounds).CopyTo(dps.AppendEmpty())
+			dest := pdata.NewHistogramDataPoint()
+			dps.At(0).CopyTo(dest)
 
-			outVal := p.computeSumOfSquaredDeviation(val1, val2)
+			p.mergeHistogramDataPoints(dps, []int{1}, dest)
 
-			assert.Equal(t, sumOfSquaredDeviation, outVal)
+			expected := histogramOf(append(test.pointGroup1, test.pointGroup2...), bounds)
+			assert.Equal(t, expected.Count(), dest.Count())
+			assert.Equal(t, expected.Sum(), dest.Sum())
+			assert.Equal(t, expected.BucketCounts(), dest.BucketCounts())
+			assert.Equal(t, bounds, dest.ExplicitBounds())
 		})
 	}
 }
 
-// calculateSumOfSquaredDeviation returns the sum and the sumOfSquaredDeviation for this slice
-func calculateSumOfSquaredDeviation(slice []float64) (sum float64, sumOfSquaredDeviation float64) {
-	sum = 0
-	for _, e := range slice {
-		sum += e
-	}
-	ave := sum / float64(len(slice))
-	sumOfSquaredDeviation = 0
-	for _, e := range slice {
-		sumOfSquaredDeviation += math.Pow((e - ave), 2)
+// histogramOf returns a histogram data point recording the given values in the buckets delimited by bounds
+func histogramOf(values []float64, bounds []float64) pdata.HistogramDataPoint {
+	dp := pdata.NewHistogramDataPoint()
+	bucketCounts := make([]uint64, len(bounds)+1)
+	sum := float64(0)
+	for _, v := range values {
+		sum += v
+		bucket := sort.SearchFloat64s(bounds, v)
+		bucketCounts[bucket]++
 	}
-	return
+	dp.SetCount(uint64(len(values)))
+	dp.SetSum(sum)
+	dp.SetExplicitBounds(bounds)
+	dp.SetBucketCounts(bucketCounts)
+	return dp
 }
 
 func TestExemplars(t *testing.T) {
 	p := newMetricsTransformProcessor(nil, nil)
-	exe1 := &metricspb.DistributionValue_Exemplar{Value: 1}
-	exe2 := &metricspb.DistributionValue_Exemplar{Value: 2}
-	picked := p.pickExemplar(exe1, exe2)
-	assert.True(t, picked == exe1 || picked == exe2)
+
+	dps := pdata.NewHistogramDataPointSlice()
+	for _, value := range []float64{1, 2} {
+		dp := dps.AppendEmpty()
+		dp.SetCount(1)
+		dp.SetExplicitBounds([]float64{5})
+		dp.SetBucketCounts([]uint64{1, 0})
+		dp.Exemplars().AppendEmpty().SetDoubleVal(value)
+	}
+	dest := pdata.NewHistogramDataPoint()
+	dps.At(0).CopyTo(dest)
+
+	p.mergeHistogramDataPoints(dps, []int{1}, dest)
+
+	require.Equal(t, 2, dest.Exemplars().Len())
+	assert.Equal(t, float64(1), dest.Exemplars().At(0).DoubleVal())
+	assert.Equal(t, float64(2), dest.Exemplars().At(1).DoubleVal())
+
+	numberDps := pdata.NewNumberDataPointSlice()
+	for _, value := range []float64{1, 2} {
+		dp := numberDps.AppendEmpty()
+		dp.SetDoubleVal(value)
+		dp.Exemplars().AppendEmpty().SetDoubleVal(value)
+	}
+	numberDest := pdata.NewNumberDataPoint()
+	numberDps.At(0).CopyTo(numberDest)
+
+	p.mergeNumberDataPoints(numberDps, []int{1}, Sum, numberDest)
+
+	assert.Equal(t, float64(3), numberDest.DoubleVal())
+	require.Equal(t, 2, numberDest.Exemplars().Len())
+	assert.Equal(t, float64(1), numberDest.Exemplars().At(0).DoubleVal())
+	assert.Equal(t, float64(2), numberDest.Exemplars().At(1).DoubleVal())
+}
+
+func labelsAsMap(labels pdata.StringMap) map[string]string {
+	m := make(map[string]string, labels.Len())
+	labels.Range(func(k, v string) bool {
+		m[k] = v
+		return true
+	})
+	return m
+}
+
+func attributesAsMap(attributes pdata.AttributeMap) map[string]string {
+	m := make(map[string]string, attributes.Len())
+	attributes.Range(func(k string, v pdata.AttributeValue) bool {
+		m[k] = v.StringVal()
+		return true
+	})
+	return m
 }
 
 func BenchmarkMetricsTransformProcessorRenameMetrics(b *testing.B) {
@@ -152,7 +379,7 @@ func BenchmarkMetricsTransformProcessorRenameMetrics(b *testing.B) {
 
 	transforms := []internalTransform{
 		{
-			MetricIncludeFilter: internalFilterStrict{include: "metric"},
+			MetricIncludeFilter: internalFilterStrict{include: "metric1"},
 			Action:              Insert,
 			NewName:             "new/metric1",
 		},
@@ -162,12 +389,104 @@ func BenchmarkMetricsTransformProcessorRenameMetrics(b *testing.B) {
 	for i := 0; i < metricCount; i++ {
 		in[i] = metricBuilder().setName("metric1").build()
 	}
-	p := newMetricsTransformProcessor(nil, transforms)
+
+	benchmarkMetricsTransformProcessor(b, transforms, in)
+}
+
+func BenchmarkMetricsTransformProcessorUpdateLabels(b *testing.B) {
+	const metricCount = 1000
+
+	transforms := []internalTransform{
+		{
+			MetricIncludeFilter: internalFilterRegexp{include: regexp.MustCompile("^metric")},
+			Action:              Update,
+			Operations: []internalOperation{
+				{
+					configOperation: Operation{
+						Action:   UpdateLabel,
+						Label:    "label1",
+						NewLabel: "new/label1",
+					},
+					valueActionsMapping: map[string]string{"value1": "new/value1"},
+				},
+				{
+					configOperation: Operation{
+						Action:   AddLabel,
+						NewLabel: "label3",
+						NewValue: "value3",
+					},
+				},
+				{
+					configOperation: Operation{
+						Action: ScaleValue,
+						Scale:  1000,
+					},
+				},
+			},
+		},
+	}
+
+	in := make([]*metricspb.Metric, metricCount)
+	for i := 0; i < metricCount; i++ {
+		in[i] = metricBuilder().setName("metric"+strconv.Itoa(i)).
+			setLabels([]string{"label1", "label2"}).
+			setDataType(metricspb.MetricDescriptor_GAUGE_DOUBLE).
+			addTimeseries(1, []string{"value1", "value2"}).
+			addTimeseries(1, []string{"value3", "value4"}).
+			addDoublePoint(0, 1, 2).
+			addDoublePoint(1, 3, 2).build()
+	}
+
+	benchmarkMetricsTransformProcessor(b, transforms, in)
+}
+
+func BenchmarkMetricsTransformProcessorAggregateLabels(b *testing.B) {
+	const metricCount = 1000
+
+	transforms := []internalTransform{
+		{
+			MetricIncludeFilter: internalFilterRegexp{include: regexp.MustCompile("^metric")},
+			Action:              Update,
+			Operations: []internalOperation{
+				{
+					configOperation: Operation{
+						Action:          AggregateLabels,
+						AggregationType: Sum,
+					},
+					labelSetMap: map[string]bool{"label1": true},
+				},
+			},
+		},
+	}
+
+	in := make([]*metricspb.Metric, metricCount)
+	for i := 0; i < metricCount; i++ {
+		in[i] = metricBuilder().setName("metric"+strconv.Itoa(i)).
+			setLabels([]string{"label1", "label2"}).
+			setDataType(metricspb.MetricDescriptor_CUMULATIVE_INT64).
+			addTimeseries(1, []string{"value1", "value2"}).
+			addTimeseries(1, []string{"value1", "value3"}).
+			addTimeseries(1, []string{"value2", "value2"}).
+			addInt64Point(0, 1, 2).
+			addInt64Point(1, 3, 2).
+			addInt64Point(2, 5, 2).build()
+	}
+
+	benchmarkMetricsTransformProcessor(b, transforms, in)
+}
+
+func benchmarkMetricsTransformProcessor(b *testing.B, transforms []internalTransform, in []*metricspb.Metric) {
+	p := newMetricsTransformProcessor(zap.NewNop(), transforms)
 	mtp, _ := processorhelper.NewMetricsProcessor(&Config{}, consumertest.NewNop(), p.processMetrics)
+	md := internaldata.OCToMetrics(nil, nil, in)
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		mtp.ConsumeMetrics(context.Background(), internaldata.OCToMetrics(nil, nil, in))
+		b.StopTimer()
+		data := md.Clone()
+		b.StartTimer()
+		mtp.ConsumeMetrics(context.Background(), data)
 	}
 }
@@ -532,7 +532,6 @@ var (
 					build(),
 			},
 		},
-		// this test case also tests the correctness of the SumOfSquaredDeviation merging
 		{
 			name: "metric_label_values_aggregation_sum_distribution_update",
 			transforms: []internalTransform{
@@ -557,16 +556,16 @@ var (
 					addTimeseries(1, []string{"label1-value1", "label2-value1"}).
 					addTimeseries(1, []string{"label1-value1", "label2-value2"}).
 					addTimeseries(1, []string{"label1-value1", "label2-value3"}).
-					addDistributionPoints(0, 3, 6, []float64{1, 2, 3}, []int64{0, 1, 1, 1}).  // pointGroup1: {1, 2, 3}, SumOfSquaredDeviation = 2
-					addDistributionPoints(1, 5, 10, []float64{1, 2, 3}, []int64{0, 2, 1, 2}). // pointGroup2: {1, 2, 3, 3, 1}, SumOfSquaredDeviation = 4
-					addDistributionPoints(2, 7, 14, []float64{1, 2, 3}, []int64{0, 3, 1, 3}). // pointGroup3: {1, 1, 2, 3, 3, 1, 3}, SumOfSquaredDeviation = 6
+					addDistributionPoints(0, 3, 6, []float64{1, 2, 3}, []int64{0, 1, 1, 1}).  // pointGroup1: {1, 2, 3}
+					addDistributionPoints(1, 5, 10, []float64{1, 2, 3}, []int64{0, 2, 1, 2}). // pointGroup2: {1, 2, 3, 3, 1}
+					addDistributionPoints(2, 7, 14, []float64{1, 2, 3}, []int64{0, 3, 1, 3}). // pointGroup3: {1, 1, 2, 3, 3, 1, 3}
 					build(),
 			},
 			out: []*metricspb.Metric{
 				metricBuilder().setName("metric1").setLabels([]string{"label1"}).
 					setDataType(metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION).
 					addTimeseries(1, []string{"label1-value1"}).
-					addDistributionPoints(0, 15, 30, []float64{1, 2, 3}, []int64{0, 6, 3, 6}). // pointGroupCombined: {1, 2, 3, 1, 2, 3, 3, 1, 1, 1, 2, 3, 3, 1, 3}, SumOfSquaredDeviation = 12
+					addDistributionPoints(0, 15, 30, []float64{1, 2, 3}, []int64{0, 6, 3, 6}). // pointGroupCombined: {1, 2, 3, 1, 2, 3, 3, 1, 1, 1, 2, 3, 3, 1, 3}
 					build(),
 			},
 		},
@@ -603,6 +602,121 @@ var (
 					build(),
 			},
 		},
+		{
+			name: "metric_label_values_aggregation_sum_distribution_label_values_update",
+			transforms: []internalTransform{
+				{
+					MetricIncludeFilter: internalFilterStrict{include: "metric1"},
+					Action:              Update,
+					Operations: []internalOperation{
+						{
+							configOperation: Operation{
+								Action:           AggregateLabelValues,
+								AggregationType:  Sum,
+								Label:            "label1",
+								AggregatedValues: []string{"current", "used"},
+								NewValue:         "current+used",
+							},
+							aggregatedValuesSet: map[string]bool{"current": true, "used": true},
+						},
+					},
+				},
+			},
+			in: []*metricspb.Metric{
+				metricBuilder().setName("metric1").setLabels([]string{"label1"}).
+					setDataType(metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION).
+					addTimeseries(1, []string{"current"}).
+					addTimeseries(1, []string{"used"}).
+					addTimeseries(2, []string{"free"}).
+					addDistributionPoints(0, 3, 6, []float64{1, 2, 3}, []int64{0, 1, 1, 1}).
+					addDistributionPoints(1, 5, 10, []float64{1, 2, 3}, []int64{0, 2, 1, 2}).
+					addDistributionPoints(2, 7, 14, []float64{1, 2, 3}, []int64{0, 3, 1, 3}).
+					build(),
+			},
+			out: []*metricspb.Metric{
+				metricBuilder().setName("metric1").setLabels([]string{"label1"}).
+					setDataType(metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION).
+					addTimeseries(1, []string{"current+used"}).
+					addTimeseries(2, []string{"free"}).
+					addDistributionPoints(0, 8, 16, []float64{1, 2, 3}, []int64{0, 3, 2, 3}).
+					addDistributionPoints(1, 7, 14, []float64{1, 2, 3}, []int64{0, 3, 1, 3}).
+					build(),
+			},
+		},
+		{
+			name: "metric_label_values_aggregation_sum_distribution_different_bounds_update",
+			transforms: []internalTransform{
+				{
+					MetricIncludeFilter: internalFilterStrict{include: "metric1"},
+					Action:              Update,
+					Operations: []internalOperation{
+						{
+							configOperation: Operation{
+								Action:          AggregateLabels,
+								AggregationType: Sum,
+								LabelSet:        []string{"label1"},
+							},
+							labelSetMap: map[string]bool{"label1": true},
+						},
+					},
+				},
+			},
+			in: []*metricspb.Metric{
+				metricBuilder().setName("metric1").setLabels([]string{"label1", "label2"}).
+					setDataType(metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION).
+					addTimeseries(1, []string{"label1-value1", "label2-value1"}).
+					addTimeseries(1, []string{"label1-value1", "label2-value2"}).
+					addDistributionPoints(0, 3, 6, []float64{1, 2, 3}, []int64{0, 1, 1, 1}).
+					addDistributionPoints(1, 5, 10, []float64{1, 2, 4}, []int64{0, 2, 1, 2}).
+					build(),
+			},
+			out: []*metricspb.Metric{
+				metricBuilder().setName("metric1").setLabels([]string{"label1", "label2"}).
+					setDataType(metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION).
+					addTimeseries(1, []string{"label1-value1", "label2-value1"}).
+					addTimeseries(1, []string{"label1-value1", "label2-value2"}).
+					addDistributionPoints(0, 3, 6, []float64{1, 2, 3}, []int64{0, 1, 1, 1}).
+					addDistributionPoints(1, 5, 10, []float64{1, 2, 4}, []int64{0, 2, 1, 2}).
+					build(),
+			},
+		},
+		{
+			name: "metric_label_values_aggregation_not_sum_distribution_merge_update",
+			transforms: []internalTransform{
+				{
+					MetricIncludeFilter: internalFilterStrict{include: "metric1"},
+					Action:              Update,
+					Operations: []internalOperation{
+						{
+							configOperation: Operation{
+								Action:          AggregateLabels,
+								AggregationType: Max,
+								LabelSet:        []string{"label1"},
+							},
+							labelSetMap: map[string]bool{"label1": true},
+						},
+					},
+				},
+			},
+			in: []*metricspb.Metric{
+				metricBuilder().setName("metric1").setLabels([]string{"label1", "label2"}).
+					setDataType(metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION).
+					addTimeseries(1, []string{"label1-value1", "label2-value1"}).
+					addTimeseries(1, []string{"label1-value1", "label2-value2"}).
+					addDistributionPoints(0, 3, 6, []float64{1, 2, 3}, []int64{0, 1, 1, 1}).
+					addDistributionPoints(1, 5, 10, []float64{1, 2, 3}, []int64{0, 2, 1, 2}).
+					build(),
+			},
+			out: []*metricspb.Metric{
+				metricBuilder().setName("metric1").setLabels([]string{"label1", "label2"}).
+					setDataType(metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION).
+					addTimeseries(1, []string{"label1-value1", "label2-value1"}).
+					addTimeseries(1, []string{"label1-value1", "label2-value2"}).
+					addDistributionPoints(0, 3, 6, []float64{1, 2, 3}, []int64{0, 1, 1, 1}).
+					addDistributionPoints(1, 5, 10, []float64{1, 2, 3}, []int64{0, 2, 1, 2}).
+					build(),
+			},
+		},
 		// INSERT
 		{
 			name: "metric_name_insert",
@@ -946,6 +1060,45 @@ var (
 					addInt64Point(0, 3, 2).build(),
 			},
 		},
+		{
+			name: "metric_name_update_with_match_label_regexp_two_datapoints",
+			transforms: []internalTransform{
+				{
+					MetricIncludeFilter: internalFilterRegexp{include: regexp.MustCompile("metric1"), matchLabels: map[string]StringMatcher{"label1": regexp.MustCompile("value3")}},
+					Action:              Update,
+					NewName:             "new/metric1",
+					Operations: []internalOperation{
+						{
+							configOperation: Operation{
+								Action: ScaleValue,
+								Scale:  10,
+							},
+						},
+					},
+				},
+			},
+			in: []*metricspb.Metric{
+				metricBuilder().setName("metric1").
+					setLabels([]string{"label1", "label2"}).
+					setDataType(metricspb.MetricDescriptor_GAUGE_INT64).
+					addTimeseries(1, []string{"value1", "value2"}).
+					addTimeseries(2, []string{"value3", "value4"}).
+					addInt64Point(0, 3, 2).
+					addInt64Point(1, 3, 2).build(),
+			},
+			out: []*metricspb.Metric{
+				metricBuilder().setName("metric1").
+					setLabels([]string{"label1", "label2"}).
+					setDataType(metricspb.MetricDescriptor_GAUGE_INT64).
+					addTimeseries(1, []string{"value1", "value2"}).
+					addInt64Point(0, 3, 2).build(),
+				metricBuilder().setName("new/metric1").
+					setLabels([]string{"label1", "label2"}).
+					setDataType(metricspb.MetricDescriptor_GAUGE_INT64).
+					addTimeseries(2, []string{"value3", "value4"}).
+					addInt64Point(0, 30, 2).build(),
+			},
+		},
 		{
 			name: "metric_label_update_with_metric_insert",
 			transforms: []internalTransform{
@@ -14,18 +14,10 @@
 
 package metricstransformprocessor
 
-import metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+import "go.opentelemetry.io/collector/model/pdata"
 
-func (mtp *metricsTransformProcessor) addLabelOp(metric *metricspb.Metric, op internalOperation) {
-	var lb = metricspb.LabelKey{
-		Key: op.configOperation.NewLabel,
-	}
-	metric.MetricDescriptor.LabelKeys = append(metric.MetricDescriptor.LabelKeys, &lb)
-	for _, ts := range metric.Timeseries {
-		lv := &metricspb.LabelValue{
-			Value:    op.configOperation.NewValue,
-			HasValue: true,
-		}
-		ts.LabelValues = append(ts.LabelValues, lv)
-	}
+func (mtp *metricsTransformProcessor) addLabelOp(metric pdata.Metric, op internalOperation) {
+	rangeDataPointLabels(metric, func(labels pdata.StringMap) {
+		labels.Insert(op.configOperation.NewLabel, op.configOperation.NewValue)
+	})
 }
@@ -0,0 +1,39 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricstransformprocessor
+
+import (
+	"go.opentelemetry.io/collector/model/pdata"
+	tracetranslator "go.opentelemetry.io/collector/translator/trace"
+)
+
+// copyResourceAttributeToLabelOp adds the value of a resource attribute as a label to all the data points of the
+// metric, unless they already have this label
+func (mtp *metricsTransformProcessor) copyResourceAttributeToLabelOp(ref metricRef, mtpOp internalOperation) {
+	op := mtpOp.configOperation
+	value, ok := ref.resource.Attributes().Get(op.ResourceAttribute)
+	if !ok {
+		return
+	}
+
+	label := op.NewLabel
+	if label == "" {
+		label = op.ResourceAttribute
+	}
+	labelValue := tracetranslator.AttributeValueToString(value)
+	rangeDataPointLabels(ref.metric, func(labels pdata.StringMap) {
+		labels.Insert(label, labelValue)
+	})
+}
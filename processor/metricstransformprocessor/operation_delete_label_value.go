@@ -15,24 +15,14 @@
 package metricstransformprocessor
 
 import (
-	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"go.opentelemetry.io/collector/model/pdata"
 )
 
 // deleteLabelValueOp deletes a label value and all data associated with it
-func (mtp *metricsTransformProcessor) deleteLabelValueOp(metric *metricspb.Metric, mtpOp internalOperation) {
+func (mtp *metricsTransformProcessor) deleteLabelValueOp(metric pdata.Metric, mtpOp internalOperation) {
 	op := mtpOp.configOperation
-	for idx, label := range metric.MetricDescriptor.LabelKeys {
-		if label.Key != op.Label {
-			continue
-		}
-
-		newTimeseries := make([]*metricspb.TimeSeries, 0)
-		for _, timeseries := range metric.Timeseries {
-			if timeseries.LabelValues[idx].Value == op.LabelValue {
-				continue
-			}
-			newTimeseries = append(newTimeseries, timeseries)
-		}
-		metric.Timeseries = newTimeseries
-	}
+	removeDataPoints(metric, func(labels pdata.StringMap) bool {
+		value, ok := labels.Get(op.Label)
+		return ok && value == op.LabelValue
+	})
 }
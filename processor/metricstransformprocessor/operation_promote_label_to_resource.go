@@ -0,0 +1,77 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricstransformprocessor
+
+import (
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+// promoteLabelToResourceOp moves the data points that have the label to new metrics, one per label value, under
+// a copy of the resource with the label value as resource attribute, and removes the label from them. Data points
+// without the label are left in the metric, which is removed if none are left.
+// Returns the metrics holding the data points of the metric once promoted
+func (mtp *metricsTransformProcessor) promoteLabelToResourceOp(ref metricRef, mtpOp internalOperation, moved *movedResourceMetrics,
+	nameToMetricMapping metricNameMapping) []metricRef {
+	op := mtpOp.configOperation
+	attribute := op.ResourceAttribute
+	if attribute == "" {
+		attribute = op.Label
+	}
+
+	var values []string
+	seenValues := make(map[string]bool)
+	rangeDataPointLabels(ref.metric, func(labels pdata.StringMap) {
+		if value, ok := labels.Get(op.Label); ok && !seenValues[value] {
+			seenValues[value] = true
+			values = append(values, value)
+		}
+	})
+	if len(values) == 0 {
+		return []metricRef{ref}
+	}
+
+	promotedRefs := make([]metricRef, 0, len(values))
+	for _, value := range values {
+		rm := moved.promote(ref.resource, attribute, value)
+		ilm := instrumentationLibraryMetrics(rm, ref.ilm.InstrumentationLibrary())
+		promoted := appendMetric(ilm, ref.metric)
+
+		labelValue := value
+		removeDataPoints(promoted, func(labels pdata.StringMap) bool {
+			value, ok := labels.Get(op.Label)
+			return !ok || value != labelValue
+		})
+		rangeDataPointLabels(promoted, func(labels pdata.StringMap) {
+			labels.Delete(op.Label)
+		})
+
+		promotedRef := metricRef{metric: promoted, ilm: ilm, resource: rm.Resource()}
+		nameToMetricMapping.add(promoted.Name(), promotedRef)
+		promotedRefs = append(promotedRefs, promotedRef)
+	}
+
+	removeDataPoints(ref.metric, func(labels pdata.StringMap) bool {
+		_, ok := labels.Get(op.Label)
+		return ok
+	})
+	if dataPointCount(ref.metric) == 0 {
+		nameToMetricMapping.remove(ref.metric.Name(), ref.metric)
+		ref.ilm.Metrics().RemoveIf(func(metric pdata.Metric) bool {
+			return metric == ref.metric
+		})
+		return promotedRefs
+	}
+	return append([]metricRef{ref}, promotedRefs...)
+}
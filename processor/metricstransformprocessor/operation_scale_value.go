@@ -1,4 +1,4 @@
-// Copyright 2021 OpenTelemetry Authors
+// Copyright 2020 OpenTelemetry Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -14,17 +14,26 @@
 
 package metricstransformprocessor
 
-import metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+import "go.opentelemetry.io/collector/model/pdata"
 
-func (mtp *metricsTransformProcessor) scaleValueOp(metric *metricspb.Metric, op internalOperation) {
-	for _, ts := range metric.Timeseries {
-		for _, dp := range ts.Points {
-			switch metric.MetricDescriptor.Type {
-			case metricspb.MetricDescriptor_GAUGE_INT64, metricspb.MetricDescriptor_CUMULATIVE_INT64:
-				dp.Value = &metricspb.Point_Int64Value{Int64Value: int64(float64(dp.GetInt64Value()) * op.configOperation.Scale)}
-			case metricspb.MetricDescriptor_GAUGE_DOUBLE, metricspb.MetricDescriptor_CUMULATIVE_DOUBLE:
-				dp.Value = &metricspb.Point_DoubleValue{DoubleValue: dp.GetDoubleValue() * op.configOperation.Scale}
-			}
+func (mtp *metricsTransformProcessor) scaleValueOp(metric pdata.Metric, op internalOperation) {
+	var dps pdata.NumberDataPointSlice
+	switch metric.DataType() {
+	case pdata.MetricDataTypeGauge:
+		dps = metric.Gauge().DataPoints()
+	case pdata.MetricDataTypeSum:
+		dps = metric.Sum().DataPoints()
+	default:
+		return
+	}
+
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		switch dp.Type() {
+		case pdata.MetricValueTypeInt:
+			dp.SetIntVal(int64(float64(dp.IntVal()) * op.configOperation.Scale))
+		case pdata.MetricValueTypeDouble:
+			dp.SetDoubleVal(dp.DoubleVal() * op.configOperation.Scale)
 		}
 	}
 }
@@ -14,28 +14,26 @@
 
 package metricstransformprocessor
 
-import metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+import "go.opentelemetry.io/collector/model/pdata"
 
-func (mtp *metricsTransformProcessor) ToggleScalarDataType(metric *metricspb.Metric) {
-	for _, ts := range metric.Timeseries {
-		for _, dp := range ts.Points {
-			switch metric.MetricDescriptor.Type {
-			case metricspb.MetricDescriptor_GAUGE_INT64, metricspb.MetricDescriptor_CUMULATIVE_INT64:
-				dp.Value = &metricspb.Point_DoubleValue{DoubleValue: float64(dp.GetInt64Value())}
-			case metricspb.MetricDescriptor_GAUGE_DOUBLE, metricspb.MetricDescriptor_CUMULATIVE_DOUBLE:
-				dp.Value = &metricspb.Point_Int64Value{Int64Value: int64(dp.GetDoubleValue())}
-			}
-		}
+func (mtp *metricsTransformProcessor) ToggleScalarDataType(metric pdata.Metric) {
+	var dps pdata.NumberDataPointSlice
+	switch metric.DataType() {
+	case pdata.MetricDataTypeGauge:
+		dps = metric.Gauge().DataPoints()
+	case pdata.MetricDataTypeSum:
+		dps = metric.Sum().DataPoints()
+	default:
+		return
 	}
 
-	switch metric.MetricDescriptor.Type {
-	case metricspb.MetricDescriptor_GAUGE_INT64:
-		metric.MetricDescriptor.Type = metricspb.MetricDescriptor_GAUGE_DOUBLE
-	case metricspb.MetricDescriptor_CUMULATIVE_INT64:
-		metric.MetricDescriptor.Type = metricspb.MetricDescriptor_CUMULATIVE_DOUBLE
-	case metricspb.MetricDescriptor_GAUGE_DOUBLE:
-		metric.MetricDescriptor.Type = metricspb.MetricDescriptor_GAUGE_INT64
-	case metricspb.MetricDescriptor_CUMULATIVE_DOUBLE:
-		metric.MetricDescriptor.Type = metricspb.MetricDescriptor_CUMULATIVE_INT64
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		switch dp.Type() {
+		case pdata.MetricValueTypeInt:
+			dp.SetDoubleVal(float64(dp.IntVal()))
+		case pdata.MetricValueTypeDouble:
+			dp.SetIntVal(int64(dp.DoubleVal()))
+		}
 	}
 }
@@ -15,27 +15,27 @@
 package metricstransformprocessor
 
 import (
-	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"go.opentelemetry.io/collector/model/pdata"
 )
 
 // updateLabelOp updates labels and label values in metric based on given operation
-func (mtp *metricsTransformProcessor) updateLabelOp(metric *metricspb.Metric, mtpOp internalOperation) {
+func (mtp *metricsTransformProcessor) updateLabelOp(metric pdata.Metric, mtpOp internalOperation) {
 	op := mtpOp.configOperation
-	for idx, label := range metric.MetricDescriptor.LabelKeys {
-		if label.Key != op.Label {
-			continue
+	rangeDataPointLabels(metric, func(labels pdata.StringMap) {
+		value, ok := labels.Get(op.Label)
+		if !ok {
+			return
 		}
 
-		if op.NewLabel != "" {
-			label.Key = op.NewLabel
+		if newValue, ok := mtpOp.valueActionsMapping[value]; ok {
+			value = newValue
 		}
 
-		labelValuesMapping := mtpOp.valueActionsMapping
-		for _, timeseries := range metric.Timeseries {
-			newValue, ok := labelValuesMapping[timeseries.LabelValues[idx].Value]
-			if ok {
-				timeseries.LabelValues[idx].Value = newValue
-			}
+		if op.NewLabel != "" {
+			labels.Delete(op.Label)
+			labels.Upsert(op.NewLabel, value)
+			return
 		}
-	}
+		labels.Update(op.Label, value)
+	})
 }
@@ -21,6 +21,16 @@ import (
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/aws/ec2"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/aws/ecs"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/aws/eks"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/aws/elasticbeanstalk"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/azure"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/azure/aks"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/docker"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/env"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/gcp/gce"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/gcp/gke"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/system"
 )
 
 // Config defines configuration for Resource processor.
@@ -36,20 +46,74 @@ type Config struct {
 	// Override indicates whether any existing resource attributes
 	// should be overridden or preserved. Defaults to true.
 	Override bool `mapstructure:"override"`
+	// RefreshInterval specifies the interval on which the resource information
+	// is detected again. Defaults to 0, detecting it only once at start.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
 	// DetectorConfig is a list of settings specific to all detectors
 	DetectorConfig DetectorConfig `mapstructure:",squash"`
 }
 
-// DetectorConfig contains user-specified configurations unique to all individual detectors
+// DetectorConfig contains user-specified configurations unique to all individual detectors. Detectors
+// without settings of their own only accept the attributes allow-list.
 type DetectorConfig struct {
 	// EC2Config contains user-specified configurations for the EC2 detector
 	EC2Config ec2.Config `mapstructure:"ec2"`
+
+	// ECSConfig contains user-specified configurations for the ECS detector
+	ECSConfig internal.AttributesConfig `mapstructure:"ecs"`
+
+	// EKSConfig contains user-specified configurations for the EKS detector
+	EKSConfig internal.AttributesConfig `mapstructure:"eks"`
+
+	// ElasticBeanstalkConfig contains user-specified configurations for the Elastic Beanstalk detector
+	ElasticBeanstalkConfig internal.AttributesConfig `mapstructure:"elastic_beanstalk"`
+
+	// AzureConfig contains user-specified configurations for the Azure detector
+	AzureConfig internal.AttributesConfig `mapstructure:"azure"`
+
+	// AKSConfig contains user-specified configurations for the AKS detector
+	AKSConfig internal.AttributesConfig `mapstructure:"aks"`
+
+	// DockerConfig contains user-specified configurations for the Docker detector
+	DockerConfig internal.AttributesConfig `mapstructure:"docker"`
+
+	// EnvConfig contains user-specified configurations for the environment variable detector
+	EnvConfig internal.AttributesConfig `mapstructure:"env"`
+
+	// GCEConfig contains user-specified configurations for the GCE detector
+	GCEConfig internal.AttributesConfig `mapstructure:"gce"`
+
+	// GKEConfig contains user-specified configurations for the GKE detector
+	GKEConfig internal.AttributesConfig `mapstructure:"gke"`
+
+	// SystemConfig contains user-specified configurations for the System detector
+	SystemConfig system.Config `mapstructure:"system"`
 }
 
 func (d *DetectorConfig) GetConfigFromType(detectorType internal.DetectorType) internal.DetectorConfig {
 	switch detectorType {
 	case ec2.TypeStr:
 		return d.EC2Config
+	case ecs.TypeStr:
+		return d.ECSConfig
+	case eks.TypeStr:
+		return d.EKSConfig
+	case elasticbeanstalk.TypeStr:
+		return d.ElasticBeanstalkConfig
+	case azure.TypeStr:
+		return d.AzureConfig
+	case aks.TypeStr:
+		return d.AKSConfig
+	case docker.TypeStr:
+		return d.DockerConfig
+	case env.TypeStr:
+		return d.EnvConfig
+	case gce.TypeStr:
+		return d.GCEConfig
+	case gke.TypeStr:
+		return d.GKEConfig
+	case system.TypeStr:
+		return d.SystemConfig
 	default:
 		return nil
 	}
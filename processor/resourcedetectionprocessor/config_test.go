@@ -26,6 +26,7 @@ import (
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/aws/ec2"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal/system"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -62,6 +63,21 @@ func TestLoadConfig(t *testing.T) {
 		Timeout:  2 * time.Second,
 		Override: false,
 	})
+
+	p4 := cfg.Processors[config.NewIDWithName(typeStr, "system")]
+	assert.Equal(t, p4, &Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewIDWithName(typeStr, "system")),
+		Detectors:         []string{"env", "system"},
+		DetectorConfig: DetectorConfig{
+			SystemConfig: system.Config{
+				AttributesConfig: internal.AttributesConfig{Attributes: []string{"host.name"}},
+				HostnameSources:  []string{"os"},
+			},
+		},
+		Timeout:         2 * time.Second,
+		Override:        false,
+		RefreshInterval: 5 * time.Minute,
+	})
 }
 
 func TestGetConfigFromType(t *testing.T) {
@@ -83,6 +99,18 @@ func TestGetConfigFromType(t *testing.T) {
 				Tags: []string{"tag1", "tag2"},
 			},
 		},
+		{
+			name:         "Get System Config",
+			detectorType: system.TypeStr,
+			inputDetectorConfig: DetectorConfig{
+				SystemConfig: system.Config{
+					HostnameSources: []string{"os"},
+				},
+			},
+			expectedConfig: system.Config{
+				HostnameSources: []string{"os"},
+			},
+		},
 		{
 			name:         "Get Nil Config",
 			detectorType: internal.DetectorType("invalid input"),
@@ -14,8 +14,14 @@
 
 package ec2
 
+import (
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal"
+)
+
 // Config defines user-specified configurations unique to the EC2 detector
 type Config struct {
+	internal.AttributesConfig `mapstructure:",squash"`
+
 	// Tags is a list of regex's to match ec2 instance tag keys that users want
 	// to add as resource attributes to processed data
 	Tags []string `mapstructure:"tags"`
@@ -36,6 +36,23 @@ type Detector interface {
 
 type DetectorConfig interface{}
 
+// AttributesConfig restricts the resource attributes added by a detector. It is embedded
+// in the detector configurations.
+type AttributesConfig struct {
+	// Attributes is an allow-list of the resource attributes to add. All the detected
+	// attributes are added if empty.
+	Attributes []string `mapstructure:"attributes"`
+}
+
+// AllowedAttributes returns the allow-list of the resource attributes to add.
+func (c AttributesConfig) AllowedAttributes() []string {
+	return c.Attributes
+}
+
+type attributesAllowList interface {
+	AllowedAttributes() []string
+}
+
 type ResourceDetectorConfig interface {
 	GetConfigFromType(DetectorType) DetectorConfig
 }
@@ -54,6 +71,7 @@ func NewProviderFactory(detectors map[DetectorType]DetectorFactory) *ResourcePro
 func (f *ResourceProviderFactory) CreateResourceProvider(
 	params component.ProcessorCreateSettings,
 	timeout time.Duration,
+	refreshInterval time.Duration,
 	detectorConfigs ResourceDetectorConfig,
 	detectorTypes ...DetectorType) (*ResourceProvider, error) {
 	detectors, err := f.getDetectors(params, detectorConfigs, detectorTypes)
@@ -61,7 +79,7 @@ func (f *ResourceProviderFactory) CreateResourceProvider(
 		return nil, err
 	}
 
-	provider := NewResourceProvider(params.Logger, timeout, detectors...)
+	provider := NewResourceProvider(params.Logger, timeout, refreshInterval, detectors...)
 	return provider, nil
 }
 
@@ -73,23 +91,65 @@ func (f *ResourceProviderFactory) getDetectors(params component.ProcessorCreateS
 			return nil, fmt.Errorf("invalid detector key: %v", detectorType)
 		}
 
-		detector, err := detectorFactory(params, detectorConfigs.GetConfigFromType(detectorType))
+		detectorConfig := detectorConfigs.GetConfigFromType(detectorType)
+		detector, err := detectorFactory(params, detectorConfig)
 		if err != nil {
 			return nil, fmt.Errorf("failed creating detector type %q: %w", detectorType, err)
 		}
 
+		if allowList, ok := detectorConfig.(attributesAllowList); ok && len(allowList.AllowedAttributes()) > 0 {
+			detector = newFilteredDetector(detector, allowList.AllowedAttributes())
+		}
+
 		detectors = append(detectors, detector)
 	}
 
 	return detectors, nil
 }
 
+// filteredDetector only keeps the allowed attributes of the resource detected by another detector.
+type filteredDetector struct {
+	detector          Detector
+	allowedAttributes map[string]struct{}
+}
+
+func newFilteredDetector(detector Detector, allowedAttributes []string) *filteredDetector {
+	allowed := make(map[string]struct{}, len(allowedAttributes))
+	for _, attribute := range allowedAttributes {
+		allowed[attribute] = struct{}{}
+	}
+	return &filteredDetector{detector: detector, allowedAttributes: allowed}
+}
+
+func (d *filteredDetector) Detect(ctx context.Context) (resource pdata.Resource, schemaURL string, err error) {
+	res, schemaURL, err := d.detector.Detect(ctx)
+	if err != nil {
+		return res, schemaURL, err
+	}
+
+	filtered := pdata.NewResource()
+	res.Attributes().Range(func(k string, v pdata.AttributeValue) bool {
+		if _, ok := d.allowedAttributes[k]; ok {
+			filtered.Attributes().Insert(k, v)
+		}
+		return true
+	})
+	return filtered, schemaURL, nil
+}
+
 type ResourceProvider struct {
-	logger           *zap.Logger
-	timeout          time.Duration
-	detectors        []Detector
+	logger          *zap.Logger
+	timeout         time.Duration
+	refreshInterval time.Duration
+	detectors       []Detector
+	once            sync.Once
+
+	// lock protects detectedResource, which is replaced on each refresh
+	lock             sync.RWMutex
 	detectedResource *resourceResult
-	once             sync.Once
+
+	stopRefresh     chan struct{}
+	stopRefreshOnce sync.Once
 }
 
 type resourceResult struct {
@@ -98,11 +158,15 @@ type resourceResult struct {
 	err       error
 }
 
-func NewResourceProvider(logger *zap.Logger, timeout time.Duration, detectors ...Detector) *ResourceProvider {
+// NewResourceProvider creates a provider detecting the resource with the given detectors. When refreshInterval
+// is positive, the resource is detected again on this interval once first detected, until Shutdown is called.
+func NewResourceProvider(logger *zap.Logger, timeout time.Duration, refreshInterval time.Duration, detectors ...Detector) *ResourceProvider {
 	return &ResourceProvider{
-		logger:    logger,
-		timeout:   timeout,
-		detectors: detectors,
+		logger:          logger,
+		timeout:         timeout,
+		refreshInterval: refreshInterval,
+		detectors:       detectors,
+		stopRefresh:     make(chan struct{}),
 	}
 }
 
@@ -111,14 +175,58 @@ func (p *ResourceProvider) Get(ctx context.Context) (resource pdata.Resource, sc
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, p.timeout)
 		defer cancel()
-		p.detectResource(ctx)
+
+		result := p.detectResource(ctx)
+		p.lock.Lock()
+		p.detectedResource = result
+		p.lock.Unlock()
+
+		if result.err == nil && p.refreshInterval > 0 {
+			go p.refreshResource()
+		}
 	})
 
+	p.lock.RLock()
+	defer p.lock.RUnlock()
 	return p.detectedResource.resource, p.detectedResource.schemaURL, p.detectedResource.err
 }
 
-func (p *ResourceProvider) detectResource(ctx context.Context) {
-	p.detectedResource = &resourceResult{}
+// Shutdown stops the periodic detection of the resource, if any.
+func (p *ResourceProvider) Shutdown() {
+	p.stopRefreshOnce.Do(func() {
+		close(p.stopRefresh)
+	})
+}
+
+// refreshResource detects the resource on each refresh interval, keeping the previously detected
+// resource when the detection fails.
+func (p *ResourceProvider) refreshResource() {
+	ticker := time.NewTicker(p.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+			result := p.detectResource(ctx)
+			cancel()
+
+			if result.err != nil {
+				p.logger.Warn("failed refreshing resource information, keeping the previous one", zap.Error(result.err))
+				continue
+			}
+
+			p.lock.Lock()
+			p.detectedResource = result
+			p.lock.Unlock()
+		case <-p.stopRefresh:
+			return
+		}
+	}
+}
+
+func (p *ResourceProvider) detectResource(ctx context.Context) *resourceResult {
+	detectedResource := &resourceResult{}
 
 	res := pdata.NewResource()
 	mergedSchemaURL := ""
@@ -128,8 +236,8 @@ func (p *ResourceProvider) detectResource(ctx context.Context) {
 	for _, detector := range p.detectors {
 		r, schemaURL, err := detector.Detect(ctx)
 		if err != nil {
-			p.detectedResource.err = err
-			return
+			detectedResource.err = err
+			return detectedResource
 		}
 
 		mergedSchemaURL = MergeSchemaURL(mergedSchemaURL, schemaURL)
@@ -138,8 +246,9 @@ func (p *ResourceProvider) detectResource(ctx context.Context) {
 
 	p.logger.Info("detected resource information", zap.Any("resource", AttributesToMap(res.Attributes())))
 
-	p.detectedResource.resource = res
-	p.detectedResource.schemaURL = mergedSchemaURL
+	detectedResource.resource = res
+	detectedResource.schemaURL = mergedSchemaURL
+	return detectedResource
 }
 
 func AttributesToMap(am pdata.AttributeMap) map[string]interface{} {
@@ -96,7 +96,7 @@ func TestDetect(t *testing.T) {
 			}
 
 			f := NewProviderFactory(mockDetectors)
-			p, err := f.CreateResourceProvider(componenttest.NewNopProcessorCreateSettings(), time.Second, &mockDetectorConfig{}, mockDetectorTypes...)
+			p, err := f.CreateResourceProvider(componenttest.NewNopProcessorCreateSettings(), time.Second, 0, &mockDetectorConfig{}, mockDetectorTypes...)
 			require.NoError(t, err)
 
 			got, _, err := p.Get(context.Background())
@@ -112,7 +112,7 @@ func TestDetect(t *testing.T) {
 func TestDetectResource_InvalidDetectorType(t *testing.T) {
 	mockDetectorKey := DetectorType("mock")
 	p := NewProviderFactory(map[DetectorType]DetectorFactory{})
-	_, err := p.CreateResourceProvider(componenttest.NewNopProcessorCreateSettings(), time.Second, &mockDetectorConfig{}, mockDetectorKey)
+	_, err := p.CreateResourceProvider(componenttest.NewNopProcessorCreateSettings(), time.Second, 0, &mockDetectorConfig{}, mockDetectorKey)
 	require.EqualError(t, err, fmt.Sprintf("invalid detector key: %v", mockDetectorKey))
 }
 
@@ -123,7 +123,7 @@ func TestDetectResource_DetectoryFactoryError(t *testing.T) {
 			return nil, errors.New("creation failed")
 		},
 	})
-	_, err := p.CreateResourceProvider(componenttest.NewNopProcessorCreateSettings(), time.Second, &mockDetectorConfig{}, mockDetectorKey)
+	_, err := p.CreateResourceProvider(componenttest.NewNopProcessorCreateSettings(), time.Second, 0, &mockDetectorConfig{}, mockDetectorKey)
 	require.EqualError(t, err, fmt.Sprintf("failed creating detector type %q: %v", mockDetectorKey, "creation failed"))
 }
 
@@ -134,11 +134,63 @@ func TestDetectResource_Error(t *testing.T) {
 	md2 := &MockDetector{}
 	md2.On("Detect").Return(pdata.NewResource(), errors.New("err1"))
 
-	p := NewResourceProvider(zap.NewNop(), time.Second, md1, md2)
+	p := NewResourceProvider(zap.NewNop(), time.Second, 0, md1, md2)
 	_, _, err := p.Get(context.Background())
 	require.EqualError(t, err, "err1")
 }
 
+type allowListDetectorConfig struct{}
+
+func (d *allowListDetectorConfig) GetConfigFromType(detectorType DetectorType) DetectorConfig {
+	return AttributesConfig{Attributes: []string{"a", "c"}}
+}
+
+func TestDetectResource_AllowedAttributes(t *testing.T) {
+	md := &MockDetector{}
+	md.On("Detect").Return(NewResource(map[string]interface{}{"a": "1", "b": "2", "c": "3"}), nil)
+
+	mockDetectorKey := DetectorType("mock")
+	f := NewProviderFactory(map[DetectorType]DetectorFactory{
+		mockDetectorKey: func(component.ProcessorCreateSettings, DetectorConfig) (Detector, error) {
+			return md, nil
+		},
+	})
+	p, err := f.CreateResourceProvider(componenttest.NewNopProcessorCreateSettings(), time.Second, 0, &allowListDetectorConfig{}, mockDetectorKey)
+	require.NoError(t, err)
+
+	got, _, err := p.Get(context.Background())
+	require.NoError(t, err)
+
+	expectedResource := NewResource(map[string]interface{}{"a": "1", "c": "3"})
+	expectedResource.Attributes().Sort()
+	got.Attributes().Sort()
+	assert.Equal(t, expectedResource, got)
+}
+
+func TestDetectResource_Refresh(t *testing.T) {
+	md := &MockDetector{}
+	md.On("Detect").Return(NewResource(map[string]interface{}{"a": "1"}), nil).Once()
+	// Failed refreshes keep the previously detected resource
+	md.On("Detect").Return(pdata.NewResource(), errors.New("err1")).Once()
+	md.On("Detect").Return(NewResource(map[string]interface{}{"a": "2"}), nil)
+
+	p := NewResourceProvider(zap.NewNop(), time.Second, 10*time.Millisecond, md)
+	defer p.Shutdown()
+
+	got, _, err := p.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, NewResource(map[string]interface{}{"a": "1"}), got)
+
+	assert.Eventually(t, func() bool {
+		got, _, err = p.Get(context.Background())
+		return err == nil && got.Attributes().Len() == 1 && AttributesToMap(got.Attributes())["a"] == "2"
+	}, time.Second, 5*time.Millisecond)
+
+	p.Shutdown()
+	// Shutdown can be called multiple times, one per processor sharing the provider
+	p.Shutdown()
+}
+
 func TestMergeResource(t *testing.T) {
 	for _, tt := range []struct {
 		name       string
@@ -201,7 +253,7 @@ func TestDetectResource_Parallel(t *testing.T) {
 	expectedResource := NewResource(map[string]interface{}{"a": "1", "b": "2", "c": "3"})
 	expectedResource.Attributes().Sort()
 
-	p := NewResourceProvider(zap.NewNop(), time.Second, md1, md2)
+	p := NewResourceProvider(zap.NewNop(), time.Second, 0, md1, md2)
 
 	// call p.Get multiple times
 	wg := &sync.WaitGroup{}
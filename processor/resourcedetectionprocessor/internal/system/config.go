@@ -0,0 +1,29 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system
+
+import (
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/resourcedetectionprocessor/internal"
+)
+
+// Config defines user-specified configurations unique to the system detector
+type Config struct {
+	internal.AttributesConfig `mapstructure:",squash"`
+
+	// HostnameSources is a priority list of sources from which the hostname will be fetched:
+	// "dns" for the fully qualified domain name, "os" for the hostname reported by the OS.
+	// The first source that succeeds is used. Defaults to ["dns", "os"].
+	HostnameSources []string `mapstructure:"hostname_sources"`
+}
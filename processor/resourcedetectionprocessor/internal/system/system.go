@@ -29,19 +29,31 @@ import (
 const (
 	// TypeStr is type of detector.
 	TypeStr = "system"
+
+	dnsHostnameSource = "dns"
+	osHostnameSource  = "os"
 )
 
+var defaultHostnameSources = []string{dnsHostnameSource, osHostnameSource}
+
 var _ internal.Detector = (*Detector)(nil)
 
 // Detector is a system metadata detector
 type Detector struct {
-	provider systemMetadata
-	logger   *zap.Logger
+	provider        systemMetadata
+	logger          *zap.Logger
+	hostnameSources []string
 }
 
 // NewDetector creates a new system metadata detector
-func NewDetector(p component.ProcessorCreateSettings, _ internal.DetectorConfig) (internal.Detector, error) {
-	return &Detector{provider: &systemMetadataImpl{}, logger: p.Logger}, nil
+func NewDetector(p component.ProcessorCreateSettings, dcfg internal.DetectorConfig) (internal.Detector, error) {
+	cfg, _ := dcfg.(Config)
+	for _, source := range cfg.HostnameSources {
+		if source != dnsHostnameSource && source != osHostnameSource {
+			return nil, fmt.Errorf("invalid hostname source %q, must be one of %q or %q", source, dnsHostnameSource, osHostnameSource)
+		}
+	}
+	return &Detector{provider: &systemMetadataImpl{}, logger: p.Logger, hostnameSources: cfg.HostnameSources}, nil
 }
 
 // Detect detects system metadata and returns a resource with the available ones
@@ -54,14 +66,9 @@ func (d *Detector) Detect(_ context.Context) (resource pdata.Resource, schemaURL
 		return res, "", fmt.Errorf("failed getting OS type: %w", err)
 	}
 
-	hostname, err := d.provider.FQDN()
+	hostname, err := d.hostname()
 	if err != nil {
-		// Fallback to OS hostname
-		d.logger.Debug("FQDN query failed, falling back to OS hostname", zap.Error(err))
-		hostname, err = d.provider.Hostname()
-		if err != nil {
-			return res, "", fmt.Errorf("failed getting OS hostname: %w", err)
-		}
+		return res, "", err
 	}
 
 	attrs.InsertString(conventions.AttributeHostName, hostname)
@@ -69,3 +76,33 @@ func (d *Detector) Detect(_ context.Context) (resource pdata.Resource, schemaURL
 
 	return res, conventions.SchemaURL, nil
 }
+
+// hostname returns the hostname from the first of the hostname sources that succeeds
+func (d *Detector) hostname() (string, error) {
+	sources := d.hostnameSources
+	if len(sources) == 0 {
+		sources = defaultHostnameSources
+	}
+
+	var err error
+	for _, source := range sources {
+		var hostname string
+		switch source {
+		case dnsHostnameSource:
+			hostname, err = d.provider.FQDN()
+			if err != nil {
+				err = fmt.Errorf("failed getting FQDN: %w", err)
+			}
+		case osHostnameSource:
+			hostname, err = d.provider.Hostname()
+			if err != nil {
+				err = fmt.Errorf("failed getting OS hostname: %w", err)
+			}
+		}
+		if err == nil {
+			return hostname, nil
+		}
+		d.logger.Debug("hostname source failed, falling back to the next one", zap.String("source", source), zap.Error(err))
+	}
+	return "", err
+}
@@ -49,7 +49,15 @@ func (m *mockMetadata) OSType() (string, error) {
 }
 
 func TestNewDetector(t *testing.T) {
-	d, err := NewDetector(componenttest.NewNopProcessorCreateSettings(), nil)
+	d, err := NewDetector(componenttest.NewNopProcessorCreateSettings(), Config{})
+	require.NoError(t, err)
+	assert.NotNil(t, d)
+
+	d, err = NewDetector(componenttest.NewNopProcessorCreateSettings(), Config{HostnameSources: []string{"os", "invalid"}})
+	assert.EqualError(t, err, `invalid hostname source "invalid", must be one of "dns" or "os"`)
+	assert.Nil(t, d)
+
+	d, err = NewDetector(componenttest.NewNopProcessorCreateSettings(), nil)
 	require.NoError(t, err)
 	assert.NotNil(t, d)
 }
@@ -98,6 +106,33 @@ func TestFallbackHostname(t *testing.T) {
 	assert.Equal(t, expected, res)
 }
 
+func TestHostnameSources(t *testing.T) {
+	md := &mockMetadata{}
+	md.On("Hostname").Return("hostname", nil)
+	md.On("OSType").Return("DARWIN", nil)
+
+	// FQDN is not queried as the OS hostname has the priority
+	detector := &Detector{provider: md, logger: zap.NewNop(), hostnameSources: []string{"os", "dns"}}
+	res, _, err := detector.Detect(context.Background())
+	require.NoError(t, err)
+	md.AssertExpectations(t)
+	md.AssertNotCalled(t, "FQDN")
+
+	hostname, ok := res.Attributes().Get(conventions.AttributeHostName)
+	require.True(t, ok)
+	assert.Equal(t, "hostname", hostname.StringVal())
+
+	// No fallback when only the FQDN is allowed
+	mdFQDN := &mockMetadata{}
+	mdFQDN.On("FQDN").Return("", errors.New("err"))
+	mdFQDN.On("OSType").Return("DARWIN", nil)
+
+	detector = &Detector{provider: mdFQDN, logger: zap.NewNop(), hostnameSources: []string{"dns"}}
+	_, _, err = detector.Detect(context.Background())
+	assert.EqualError(t, err, "failed getting FQDN: err")
+	mdFQDN.AssertNotCalled(t, "Hostname")
+}
+
 func TestDetectError(t *testing.T) {
 	// FQDN and hostname fail
 	mdFQDN := &mockMetadata{}
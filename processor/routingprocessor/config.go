@@ -15,6 +15,8 @@
 package routingprocessor
 
 import (
+	"fmt"
+
 	"go.opentelemetry.io/collector/config"
 )
 
@@ -31,19 +33,64 @@ type Config struct {
 	// this could be the HTTP/gRPC header from the original request/RPC. Typically, aggregation processors (batch, groupbytrace)
 	// will create a new context, so, those should be avoided when using this processor.Although the HTTP spec allows headers to be repeated,
 	// this processor will only use the first value.
-	// Required.
+	// Required, unless all the routing table items are expressions.
 	FromAttribute string `mapstructure:"from_attribute"`
 
+	// AttributeSource defines where the FromAttribute is looked up. When set to "context" (the default), the value is read
+	// from the incoming request context. When set to "resource", the value is read from the resource attributes of each
+	// ResourceSpans, ResourceMetrics or ResourceLogs, meaning that a single batch might be split and sent to different exporters.
+	// The "resource" source is the one to use when aggregation processors (batch, groupbytrace) are placed before this processor.
+	// Optional.
+	AttributeSource AttributeSource `mapstructure:"attribute_source"`
+
 	// Table contains the routing table for this processor.
 	// Required.
 	Table []RoutingTableItem `mapstructure:"table"`
 }
 
-// RoutingTableItem specifies how data should be routed to the different exporters
+var _ config.Processor = (*Config)(nil)
+
+// Validate checks that every routing table item defines what it matches, and that its regex or expression compiles.
+func (c *Config) Validate() error {
+	for i, item := range c.Table {
+		if item.Value == "" && item.Regex == "" && item.Expression == "" {
+			return fmt.Errorf("invalid routing table item at index %d: %w", i, errEmptyRoute)
+		}
+		if _, err := newRoutingRule(item); err != nil {
+			return fmt.Errorf("invalid routing table item at index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// AttributeSource specifies where the routing attribute is read from.
+type AttributeSource string
+
+const (
+	// ContextAttributeSource reads the routing attribute from the request context, such as the HTTP/gRPC headers.
+	ContextAttributeSource AttributeSource = "context"
+
+	// ResourceAttributeSource reads the routing attribute from the resource attributes.
+	ResourceAttributeSource AttributeSource = "resource"
+)
+
+// RoutingTableItem specifies how data should be routed to the different exporters.
+// Only one of Value, Regex and Expression can be specified for each item. The items are evaluated in the order they are
+// defined, and the first matching item wins.
 type RoutingTableItem struct {
-	// Value represents a possible value for the field specified under FromAttribute. Required.
+	// Value represents a possible value for the field specified under FromAttribute.
 	Value string `mapstructure:"value"`
 
+	// Regex is a regular expression matched against the value of the field specified under FromAttribute.
+	// Optional.
+	Regex string `mapstructure:"regex"`
+
+	// Expression is a boolean expression evaluated against the resource attributes, which are available under
+	// the "attributes" variable, such as: attributes["k8s.namespace.name"] matches "team-a-.*".
+	// Expressions can only be used when the AttributeSource is "resource".
+	// Optional.
+	Expression string `mapstructure:"expression"`
+
 	// Exporters contains the list of exporters to use when the value from the FromAttribute field matches this table item.
 	// When no exporters are specified, the ones specified under DefaultExporters are used, if any.
 	// The routing processor will fail upon the first failure from these exporters.
@@ -15,6 +15,7 @@
 package routingprocessor
 
 import (
+	"errors"
 	"path"
 	"testing"
 
@@ -49,6 +50,7 @@ func TestLoadConfig(t *testing.T) {
 			ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
 			DefaultExporters:  []string{"otlp"},
 			FromAttribute:     "X-Tenant",
+			AttributeSource:   ContextAttributeSource,
 			Table: []RoutingTableItem{
 				{
 					Value:     "acme",
@@ -60,4 +62,76 @@ func TestLoadConfig(t *testing.T) {
 				},
 			},
 		})
+
+	parsed = cfg.Processors[config.NewIDWithName(typeStr, "resource")]
+	assert.Equal(t, parsed,
+		&Config{
+			ProcessorSettings: config.NewProcessorSettings(config.NewIDWithName(typeStr, "resource")),
+			DefaultExporters:  []string{"otlp"},
+			FromAttribute:     "tenant",
+			AttributeSource:   ResourceAttributeSource,
+			Table: []RoutingTableItem{
+				{
+					Value:     "acme",
+					Exporters: []string{"otlp/acme"},
+				},
+				{
+					Regex:     "^globex-.*",
+					Exporters: []string{"otlp/globex"},
+				},
+				{
+					Expression: `attributes["k8s.namespace.name"] matches "team-a-.*"`,
+					Exporters:  []string{"otlp/globex"},
+				},
+			},
+		})
+}
+
+func TestValidateConfig(t *testing.T) {
+	cfg := &Config{
+		Table: []RoutingTableItem{
+			{Value: "acme", Exporters: []string{"otlp/acme"}},
+			{Regex: "^globex-.*", Exporters: []string{"otlp/globex"}},
+			{Exporters: []string{"otlp/initech"}},
+		},
+	}
+	err := cfg.Validate()
+	assert.True(t, errors.Is(err, errEmptyRoute))
+	assert.EqualError(t, err, "invalid routing table item at index 2: one of value, regex or expression must be specified for a route")
+
+	cfg.Table = cfg.Table[:2]
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateConfigCompilesRoutes(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		item RoutingTableItem
+	}{
+		{
+			name: "invalid regex",
+			item: RoutingTableItem{Regex: "team-a-("},
+		},
+		{
+			name: "invalid expression",
+			item: RoutingTableItem{Expression: `attributes["tenant"] ==`},
+		},
+		{
+			name: "unknown variable",
+			item: RoutingTableItem{Expression: `tenant == "acme"`},
+		},
+		{
+			name: "non-boolean expression",
+			item: RoutingTableItem{Expression: `attributes["tenant"]`},
+		},
+		{
+			name: "value and expression",
+			item: RoutingTableItem{Value: "acme", Expression: `attributes["tenant"] == "acme"`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Table: []RoutingTableItem{tt.item}}
+			assert.Error(t, cfg.Validate())
+		})
+	}
 }
@@ -52,6 +52,34 @@ func TestProcessorGetsCreatedWithValidConfiguration(t *testing.T) {
 	assert.NotNil(t, exp)
 }
 
+func TestMetricsAndLogsProcessorsGetCreated(t *testing.T) {
+	// prepare
+	factory := NewFactory()
+	creationParams := componenttest.NewNopProcessorCreateSettings()
+	cfg := &Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+		DefaultExporters:  []string{"otlp"},
+		FromAttribute:     "tenant",
+		AttributeSource:   ResourceAttributeSource,
+		Table: []RoutingTableItem{
+			{
+				Value:     "acme",
+				Exporters: []string{"otlp"},
+			},
+		},
+	}
+
+	// test
+	mp, mErr := factory.CreateMetricsProcessor(context.Background(), creationParams, cfg, consumertest.NewNop())
+	lp, lErr := factory.CreateLogsProcessor(context.Background(), creationParams, cfg, consumertest.NewNop())
+
+	// verify
+	assert.NoError(t, mErr)
+	assert.NotNil(t, mp)
+	assert.NoError(t, lErr)
+	assert.NotNil(t, lp)
+}
+
 func TestFailOnEmptyConfiguration(t *testing.T) {
 	// prepare
 	factory := NewFactory()
@@ -0,0 +1,146 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routingprocessor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"go.opentelemetry.io/collector/model/pdata"
+	tracetranslator "go.opentelemetry.io/collector/translator/trace"
+)
+
+var (
+	errAmbiguousRoute             = errors.New("only one of value, regex and expression can be specified for a route")
+	errEmptyRoute                 = errors.New("one of value, regex or expression must be specified for a route")
+	errExpressionRequiresResource = errors.New("expressions can only be used when the attribute source is resource")
+	errExpressionNotBoolean       = errors.New("expression did not return a boolean")
+)
+
+// routeKind is the kind of match of a routing table item.
+type routeKind string
+
+const (
+	valueRoute      routeKind = "value"
+	regexRoute      routeKind = "regex"
+	expressionRoute routeKind = "expression"
+)
+
+// routeKey identifies the exporters of a route. The kind of the item is part of the key, so that a value can't
+// collide with a regex or an expression written the same way.
+type routeKey struct {
+	kind  routeKind
+	match string
+}
+
+func (k routeKey) String() string {
+	return fmt.Sprintf("%s %q", k.kind, k.match)
+}
+
+// routingRule is the compiled form of a routing table item.
+type routingRule struct {
+	// key identifies the route's exporters
+	key routeKey
+
+	value      string
+	regex      *regexp.Regexp
+	expression *vm.Program
+}
+
+// newRoutingRule compiles the given routing table item.
+func newRoutingRule(item RoutingTableItem) (routingRule, error) {
+	set := 0
+	for _, s := range []string{item.Value, item.Regex, item.Expression} {
+		if s != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return routingRule{}, errAmbiguousRoute
+	}
+
+	rule := routingRule{key: newRouteKey(item), value: item.Value}
+	switch {
+	case item.Regex != "":
+		re, err := regexp.Compile(item.Regex)
+		if err != nil {
+			return routingRule{}, err
+		}
+		rule.regex = re
+	case item.Expression != "":
+		program, err := expr.Compile(item.Expression, expr.Env(expressionEnv(nil)), expr.AsBool())
+		if err != nil {
+			return routingRule{}, err
+		}
+		rule.expression = program
+	}
+
+	return rule, nil
+}
+
+// newRouteKey returns the key used to register the exporters for the given routing table item.
+func newRouteKey(item RoutingTableItem) routeKey {
+	switch {
+	case item.Regex != "":
+		return routeKey{kind: regexRoute, match: item.Regex}
+	case item.Expression != "":
+		return routeKey{kind: expressionRoute, match: item.Expression}
+	default:
+		return routeKey{kind: valueRoute, match: item.Value}
+	}
+}
+
+// expressionEnv returns the environment the expressions are evaluated against, the resource attributes being
+// available under the "attributes" variable.
+func expressionEnv(attributes map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"attributes": attributes}
+}
+
+// matches returns whether the rule matches the value of the routing attribute or the given resource attributes.
+// An empty value means that the routing attribute wasn't found, in which case only expressions can match.
+func (r *routingRule) matches(value string, attributes func() map[string]interface{}) (bool, error) {
+	switch {
+	case r.expression != nil:
+		res, err := expr.Run(r.expression, expressionEnv(attributes()))
+		if err != nil {
+			return false, err
+		}
+		if ret, ok := res.(bool); ok {
+			return ret, nil
+		}
+		return false, errExpressionNotBoolean
+	case value == "":
+		return false, nil
+	case r.regex != nil:
+		return r.regex.MatchString(value), nil
+	default:
+		return r.value == value, nil
+	}
+}
+
+// lazyAttributes returns a function converting the given attributes to a map on its first call, so that the conversion
+// only happens when an expression needs to be evaluated.
+func lazyAttributes(attrs pdata.AttributeMap) func() map[string]interface{} {
+	var converted map[string]interface{}
+	return func() map[string]interface{} {
+		if converted == nil {
+			converted = tracetranslator.AttributeMapToMap(attrs)
+		}
+		return converted
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routingprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+func TestNewRoutingRule(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		item        RoutingTableItem
+		expectedKey routeKey
+		expectedErr bool
+	}{
+		{
+			name:        "value",
+			item:        RoutingTableItem{Value: "acme"},
+			expectedKey: routeKey{kind: valueRoute, match: "acme"},
+		},
+		{
+			name:        "regex",
+			item:        RoutingTableItem{Regex: "team-a-.*"},
+			expectedKey: routeKey{kind: regexRoute, match: "team-a-.*"},
+		},
+		{
+			name:        "expression",
+			item:        RoutingTableItem{Expression: `attributes["tenant"] == "acme"`},
+			expectedKey: routeKey{kind: expressionRoute, match: `attributes["tenant"] == "acme"`},
+		},
+		{
+			name:        "value and regex",
+			item:        RoutingTableItem{Value: "acme", Regex: "acme"},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid regex",
+			item:        RoutingTableItem{Regex: "team-a-("},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid expression",
+			item:        RoutingTableItem{Expression: `attributes["tenant"] ==`},
+			expectedErr: true,
+		},
+		{
+			name:        "non-boolean expression",
+			item:        RoutingTableItem{Expression: `attributes["tenant"]`},
+			expectedErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := newRoutingRule(tt.item)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedKey, rule.key)
+		})
+	}
+}
+
+func TestRoutingRuleMatches(t *testing.T) {
+	attrs := pdata.NewAttributeMap()
+	attrs.InsertString("k8s.namespace.name", "team-a-frontend")
+	attrs.InsertInt("priority", 3)
+
+	for _, tt := range []struct {
+		name     string
+		item     RoutingTableItem
+		value    string
+		expected bool
+	}{
+		{
+			name:     "value matches",
+			item:     RoutingTableItem{Value: "acme"},
+			value:    "acme",
+			expected: true,
+		},
+		{
+			name:  "value doesn't match",
+			item:  RoutingTableItem{Value: "acme"},
+			value: "globex",
+		},
+		{
+			name:     "regex matches",
+			item:     RoutingTableItem{Regex: "^team-a-.*"},
+			value:    "team-a-backend",
+			expected: true,
+		},
+		{
+			name:  "regex doesn't match",
+			item:  RoutingTableItem{Regex: "^team-a-.*"},
+			value: "team-b-backend",
+		},
+		{
+			name: "regex doesn't match missing value",
+			item: RoutingTableItem{Regex: ".*"},
+		},
+		{
+			name:     "expression matches",
+			item:     RoutingTableItem{Expression: `attributes["k8s.namespace.name"] matches "team-a-.*" and attributes["priority"] > 2`},
+			expected: true,
+		},
+		{
+			name: "expression doesn't match",
+			item: RoutingTableItem{Expression: `attributes["k8s.namespace.name"] == "team-b"`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := newRoutingRule(tt.item)
+			require.NoError(t, err)
+
+			matched, err := rule.matches(tt.value, lazyAttributes(attrs))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, matched)
+		})
+	}
+}
+
+func TestRouteKeyDependsOnKind(t *testing.T) {
+	value := newRouteKey(RoutingTableItem{Value: "regex:foo"})
+	regex := newRouteKey(RoutingTableItem{Regex: "foo"})
+	assert.NotEqual(t, value, regex)
+}
+
+func TestRoutingRuleExpressionEvaluationError(t *testing.T) {
+	rule, err := newRoutingRule(RoutingTableItem{Expression: `attributes["priority"] > 2`})
+	require.NoError(t, err)
+
+	attrs := pdata.NewAttributeMap()
+	attrs.InsertString("priority", "high")
+
+	_, err = rule.matches("", lazyAttributes(attrs))
+	assert.Error(t, err)
+}
@@ -23,7 +23,9 @@ import (
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/model/pdata"
+	tracetranslator "go.opentelemetry.io/collector/translator/trace"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/metadata"
 )
@@ -33,20 +35,34 @@ var (
 	errNoTableItems           = errors.New("the routing table is empty")
 	errNoMissingFromAttribute = errors.New("the FromAttribute property is empty")
 	errExporterNotFound       = errors.New("exporter not found")
+	errInvalidAttributeSource = errors.New("invalid attribute source")
+	errUnsupportedDataType    = errors.New("unsupported data type")
 )
 
 var _ component.TracesProcessor = (*processorImp)(nil)
+var _ component.MetricsProcessor = (*processorImp)(nil)
+var _ component.LogsProcessor = (*processorImp)(nil)
 
 type processorImp struct {
-	logger *zap.Logger
-	config Config
+	logger   *zap.Logger
+	config   Config
+	dataType config.DataType
+
+	// routes are the compiled routing table items, in the order they were defined
+	routes []routingRule
 
 	defaultTracesExporters []component.TracesExporter
-	traceExporters         map[string][]component.TracesExporter
+	traceExporters         map[routeKey][]component.TracesExporter
+
+	defaultMetricsExporters []component.MetricsExporter
+	metricsExporters        map[routeKey][]component.MetricsExporter
+
+	defaultLogsExporters []component.LogsExporter
+	logsExporters        map[routeKey][]component.LogsExporter
 }
 
 // Crete new processor
-func newProcessor(logger *zap.Logger, cfg config.Processor) (*processorImp, error) {
+func newProcessor(logger *zap.Logger, dataType config.DataType, cfg config.Processor) (*processorImp, error) {
 	logger.Info("building processor")
 
 	oCfg := cfg.(*Config)
@@ -54,7 +70,7 @@ func newProcessor(logger *zap.Logger, cfg config.Processor) (*processorImp, erro
 	// validate that every route has at least one exporter
 	for _, item := range oCfg.Table {
 		if len(item.Exporters) == 0 {
-			return nil, fmt.Errorf("invalid route %s: %w", item.Value, errNoExporters)
+			return nil, fmt.Errorf("invalid route %s: %w", newRouteKey(item), errNoExporters)
 		}
 	}
 
@@ -63,28 +79,61 @@ func newProcessor(logger *zap.Logger, cfg config.Processor) (*processorImp, erro
 		return nil, fmt.Errorf("invalid routing table: %w", errNoTableItems)
 	}
 
-	// we also need a "FromAttribute" value
-	if len(oCfg.FromAttribute) == 0 {
+	// the attribute source is optional, and defaults to the context
+	switch oCfg.AttributeSource {
+	case "", ContextAttributeSource, ResourceAttributeSource:
+	default:
+		return nil, fmt.Errorf("%w: %q", errInvalidAttributeSource, oCfg.AttributeSource)
+	}
+
+	// compile the routes, and check whether a "FromAttribute" value is needed
+	routes := make([]routingRule, 0, len(oCfg.Table))
+	needsFromAttribute := false
+	for _, item := range oCfg.Table {
+		rule, err := newRoutingRule(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %s: %w", newRouteKey(item), err)
+		}
+		if rule.expression != nil && oCfg.AttributeSource != ResourceAttributeSource {
+			return nil, fmt.Errorf("invalid route %s: %w", newRouteKey(item), errExpressionRequiresResource)
+		}
+		if rule.expression == nil {
+			needsFromAttribute = true
+		}
+		routes = append(routes, rule)
+	}
+
+	// we also need a "FromAttribute" value, unless all routes are expressions
+	if needsFromAttribute && len(oCfg.FromAttribute) == 0 {
 		return nil, fmt.Errorf("invalid attribute to read the route's value from: %w", errNoMissingFromAttribute)
 	}
 
+	switch dataType {
+	case config.TracesDataType, config.MetricsDataType, config.LogsDataType:
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnsupportedDataType, dataType)
+	}
+
 	return &processorImp{
-		logger:         logger,
-		config:         *oCfg,
-		traceExporters: make(map[string][]component.TracesExporter),
+		logger:           logger,
+		config:           *oCfg,
+		dataType:         dataType,
+		routes:           routes,
+		traceExporters:   make(map[routeKey][]component.TracesExporter),
+		metricsExporters: make(map[routeKey][]component.MetricsExporter),
+		logsExporters:    make(map[routeKey][]component.LogsExporter),
 	}, nil
 }
 
 func (e *processorImp) Start(_ context.Context, host component.Host) error {
 	// first, let's build a map of exporter names with the exporter instances
 	source := host.GetExporters()
-	availableExporters := map[string]component.TracesExporter{}
-	for k, exp := range source[config.TracesDataType] {
-		traceExp, ok := exp.(component.TracesExporter)
-		if !ok {
-			return fmt.Errorf("the exporter %q isn't a trace exporter", k.Name())
+	availableExporters := map[string]component.Exporter{}
+	for k, exp := range source[e.dataType] {
+		if !e.supportsDataType(exp) {
+			return fmt.Errorf("the exporter %q isn't a %s exporter", k.Name(), e.dataType)
 		}
-		availableExporters[k.String()] = traceExp
+		availableExporters[k.String()] = exp
 	}
 
 	// default exporters
@@ -92,9 +141,9 @@ func (e *processorImp) Start(_ context.Context, host component.Host) error {
 		return err
 	}
 
-	// exporters for each defined value
-	for _, item := range e.config.Table {
-		if err := e.registerExportersForRoute(item.Value, availableExporters, item.Exporters); err != nil {
+	// exporters for each defined route
+	for i, item := range e.config.Table {
+		if err := e.registerExportersForRoute(e.routes[i].key, availableExporters, item.Exporters); err != nil {
 			return err
 		}
 	}
@@ -102,25 +151,54 @@ func (e *processorImp) Start(_ context.Context, host component.Host) error {
 	return nil
 }
 
-func (e *processorImp) registerExportersForDefaultRoute(available map[string]component.TracesExporter, requested []string) error {
+func (e *processorImp) supportsDataType(exp component.Exporter) bool {
+	var ok bool
+	switch e.dataType {
+	case config.TracesDataType:
+		_, ok = exp.(component.TracesExporter)
+	case config.MetricsDataType:
+		_, ok = exp.(component.MetricsExporter)
+	case config.LogsDataType:
+		_, ok = exp.(component.LogsExporter)
+	}
+	return ok
+}
+
+func (e *processorImp) registerExportersForDefaultRoute(available map[string]component.Exporter, requested []string) error {
 	for _, exp := range requested {
 		v, ok := available[exp]
 		if !ok {
 			return fmt.Errorf("error registering default exporter %q: %w", exp, errExporterNotFound)
 		}
-		e.defaultTracesExporters = append(e.defaultTracesExporters, v)
+
+		switch e.dataType {
+		case config.TracesDataType:
+			e.defaultTracesExporters = append(e.defaultTracesExporters, v.(component.TracesExporter))
+		case config.MetricsDataType:
+			e.defaultMetricsExporters = append(e.defaultMetricsExporters, v.(component.MetricsExporter))
+		case config.LogsDataType:
+			e.defaultLogsExporters = append(e.defaultLogsExporters, v.(component.LogsExporter))
+		}
 	}
 
 	return nil
 }
 
-func (e *processorImp) registerExportersForRoute(route string, available map[string]component.TracesExporter, requested []string) error {
+func (e *processorImp) registerExportersForRoute(route routeKey, available map[string]component.Exporter, requested []string) error {
 	for _, exp := range requested {
 		v, ok := available[exp]
 		if !ok {
-			return fmt.Errorf("error registering route %q for exporter %q: %w", route, exp, errExporterNotFound)
+			return fmt.Errorf("error registering route %s for exporter %q: %w", route, exp, errExporterNotFound)
+		}
+
+		switch e.dataType {
+		case config.TracesDataType:
+			e.traceExporters[route] = append(e.traceExporters[route], v.(component.TracesExporter))
+		case config.MetricsDataType:
+			e.metricsExporters[route] = append(e.metricsExporters[route], v.(component.MetricsExporter))
+		case config.LogsDataType:
+			e.logsExporters[route] = append(e.logsExporters[route], v.(component.LogsExporter))
 		}
-		e.traceExporters[route] = append(e.traceExporters[route], v)
 	}
 
 	return nil
@@ -131,25 +209,138 @@ func (e *processorImp) Shutdown(context.Context) error {
 }
 
 func (e *processorImp) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
-	value := e.extractValueFromContext(ctx)
-	if len(value) == 0 {
-		// the attribute's value hasn't been found, send data to the default exporter
-		return e.pushDataToExporters(ctx, td, e.defaultTracesExporters)
+	if e.config.AttributeSource == ResourceAttributeSource {
+		return e.routeTracesByResource(ctx, td)
+	}
+
+	// when no route could be determined, the data is sent to the default exporters
+	route := e.findRouteFromContext(ctx)
+	return e.pushDataToExporters(ctx, td, e.tracesExportersFor(route))
+}
+
+func (e *processorImp) ConsumeMetrics(ctx context.Context, md pdata.Metrics) error {
+	if e.config.AttributeSource == ResourceAttributeSource {
+		return e.routeMetricsByResource(ctx, md)
 	}
 
-	if _, ok := e.traceExporters[value]; !ok {
-		// the value has been found, but there are no exporters for the value
-		return e.pushDataToExporters(ctx, td, e.defaultTracesExporters)
+	route := e.findRouteFromContext(ctx)
+	return e.pushMetricsToExporters(ctx, md, e.metricsExportersFor(route))
+}
+
+func (e *processorImp) ConsumeLogs(ctx context.Context, ld pdata.Logs) error {
+	if e.config.AttributeSource == ResourceAttributeSource {
+		return e.routeLogsByResource(ctx, ld)
 	}
 
-	// found the appropriate router, using it
-	return e.pushDataToExporters(ctx, td, e.traceExporters[value])
+	route := e.findRouteFromContext(ctx)
+	return e.pushLogsToExporters(ctx, ld, e.logsExportersFor(route))
 }
 
 func (e *processorImp) Capabilities() consumer.Capabilities {
 	return consumer.Capabilities{MutatesData: false}
 }
 
+// routeTracesByResource sends each ResourceSpans to the exporters matching its resource attribute, or to the default
+// exporters when there's no matching route. When all the resources in the batch share the same route, the batch is sent
+// as-is, otherwise it's split into one batch per route.
+func (e *processorImp) routeTracesByResource(ctx context.Context, td pdata.Traces) error {
+	rss := td.ResourceSpans()
+	groups := e.groupResourcesByRoute(rss.Len(), func(i int) pdata.Resource { return rss.At(i).Resource() })
+
+	var errs []error
+	for route, indexes := range groups {
+		group := td
+		if len(groups) > 1 {
+			group = pdata.NewTraces()
+			for _, i := range indexes {
+				rss.At(i).CopyTo(group.ResourceSpans().AppendEmpty())
+			}
+		}
+		if err := e.pushDataToExporters(ctx, group, e.tracesExportersFor(route)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return consumererror.Combine(errs)
+}
+
+// routeMetricsByResource is the metrics counterpart of routeTracesByResource.
+func (e *processorImp) routeMetricsByResource(ctx context.Context, md pdata.Metrics) error {
+	rms := md.ResourceMetrics()
+	groups := e.groupResourcesByRoute(rms.Len(), func(i int) pdata.Resource { return rms.At(i).Resource() })
+
+	var errs []error
+	for route, indexes := range groups {
+		group := md
+		if len(groups) > 1 {
+			group = pdata.NewMetrics()
+			for _, i := range indexes {
+				rms.At(i).CopyTo(group.ResourceMetrics().AppendEmpty())
+			}
+		}
+		if err := e.pushMetricsToExporters(ctx, group, e.metricsExportersFor(route)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return consumererror.Combine(errs)
+}
+
+// routeLogsByResource is the logs counterpart of routeTracesByResource.
+func (e *processorImp) routeLogsByResource(ctx context.Context, ld pdata.Logs) error {
+	rls := ld.ResourceLogs()
+	groups := e.groupResourcesByRoute(rls.Len(), func(i int) pdata.Resource { return rls.At(i).Resource() })
+
+	var errs []error
+	for route, indexes := range groups {
+		group := ld
+		if len(groups) > 1 {
+			group = pdata.NewLogs()
+			for _, i := range indexes {
+				rls.At(i).CopyTo(group.ResourceLogs().AppendEmpty())
+			}
+		}
+		if err := e.pushLogsToExporters(ctx, group, e.logsExportersFor(route)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return consumererror.Combine(errs)
+}
+
+// groupResourcesByRoute resolves the route of each of the count resources, and returns the indexes of the resources
+// sharing each route, in order. The nil route stands for the default exporters.
+func (e *processorImp) groupResourcesByRoute(count int, resourceAt func(int) pdata.Resource) map[*routingRule][]int {
+	groups := map[*routingRule][]int{}
+	for i := 0; i < count; i++ {
+		route := e.findRouteFromResource(resourceAt(i))
+		groups[route] = append(groups[route], i)
+	}
+	return groups
+}
+
+// tracesExportersFor returns the exporters of the given route, or the default exporters for the nil route.
+func (e *processorImp) tracesExportersFor(route *routingRule) []component.TracesExporter {
+	if route == nil {
+		return e.defaultTracesExporters
+	}
+	return e.traceExporters[route.key]
+}
+
+func (e *processorImp) metricsExportersFor(route *routingRule) []component.MetricsExporter {
+	if route == nil {
+		return e.defaultMetricsExporters
+	}
+	return e.metricsExporters[route.key]
+}
+
+func (e *processorImp) logsExportersFor(route *routingRule) []component.LogsExporter {
+	if route == nil {
+		return e.defaultLogsExporters
+	}
+	return e.logsExporters[route.key]
+}
+
 func (e *processorImp) pushDataToExporters(ctx context.Context, td pdata.Traces, exporters []component.TracesExporter) error {
 	// TODO: determine the proper action when errors happen
 	for _, exp := range exporters {
@@ -161,6 +352,70 @@ func (e *processorImp) pushDataToExporters(ctx context.Context, td pdata.Traces,
 	return nil
 }
 
+func (e *processorImp) pushMetricsToExporters(ctx context.Context, md pdata.Metrics, exporters []component.MetricsExporter) error {
+	for _, exp := range exporters {
+		if err := exp.ConsumeMetrics(ctx, md); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *processorImp) pushLogsToExporters(ctx context.Context, ld pdata.Logs, exporters []component.LogsExporter) error {
+	for _, exp := range exporters {
+		if err := exp.ConsumeLogs(ctx, ld); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findRouteFromContext returns the first route matching the attribute's value from the context, or nil when the value
+// isn't available or no route matches it.
+func (e *processorImp) findRouteFromContext(ctx context.Context) *routingRule {
+	value := e.extractValueFromContext(ctx)
+	if len(value) == 0 {
+		return nil
+	}
+
+	return e.findRoute(value, lazyAttributes(pdata.NewAttributeMap()))
+}
+
+// findRouteFromResource returns the first route matching the given resource, or nil when no route matches it.
+func (e *processorImp) findRouteFromResource(resource pdata.Resource) *routingRule {
+	return e.findRoute(e.extractValueFromResource(resource), lazyAttributes(resource.Attributes()))
+}
+
+func (e *processorImp) findRoute(value string, attributes func() map[string]interface{}) *routingRule {
+	for i := range e.routes {
+		matched, err := e.routes[i].matches(value, attributes)
+		if err != nil {
+			e.logger.Warn("failed to evaluate the route, skipping it", zap.Stringer("route", e.routes[i].key), zap.Error(err))
+			continue
+		}
+		if matched {
+			return &e.routes[i]
+		}
+	}
+
+	return nil
+}
+
+func (e *processorImp) extractValueFromResource(resource pdata.Resource) string {
+	if len(e.config.FromAttribute) == 0 {
+		return ""
+	}
+
+	value, ok := resource.Attributes().Get(e.config.FromAttribute)
+	if !ok {
+		return ""
+	}
+
+	return tracetranslator.AttributeValueToString(value)
+}
+
 func (e *processorImp) extractValueFromContext(ctx context.Context) string {
 	// right now, we only support looking up attributes from requests that have gone through the gRPC server
 	// in that case, it will add the HTTP headers as context metadata
@@ -30,6 +30,7 @@ import (
 	"go.opentelemetry.io/collector/exporter/otlpexporter"
 	"go.opentelemetry.io/collector/model/pdata"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -43,8 +44,9 @@ func TestRouteIsFoundForGRPCContexts(t *testing.T) {
 			FromAttribute: "X-Tenant",
 		},
 		logger: zap.NewNop(),
-		traceExporters: map[string][]component.TracesExporter{
-			"acme": {
+		routes: []routingRule{{key: routeKey{kind: valueRoute, match: "acme"}, value: "acme"}},
+		traceExporters: map[routeKey][]component.TracesExporter{
+			{kind: valueRoute, match: "acme"}: {
 				&mockExporter{
 					ConsumeTracesFunc: func(context.Context, pdata.Traces) error {
 						wg.Done()
@@ -113,7 +115,7 @@ func TestDefaultRouteIsUsedWhenRouteCantBeDetermined(t *testing.T) {
 
 func TestRegisterExportersForValidRoute(t *testing.T) {
 	//  prepare
-	exp, err := newProcessor(zap.NewNop(), &Config{
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
 		DefaultExporters: []string{"otlp"},
 		FromAttribute:    "X-Tenant",
 		Table: []RoutingTableItem{
@@ -149,12 +151,12 @@ func TestRegisterExportersForValidRoute(t *testing.T) {
 	exp.Start(context.Background(), host)
 
 	// verify
-	assert.Contains(t, exp.traceExporters["acme"], otlpExp)
+	assert.Contains(t, exp.traceExporters[routeKey{kind: valueRoute, match: "acme"}], otlpExp)
 }
 
 func TestErrorRequestedExporterNotFoundForRoute(t *testing.T) {
 	//  prepare
-	exp, err := newProcessor(zap.NewNop(), &Config{
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
 		FromAttribute: "X-Tenant",
 		Table: []RoutingTableItem{
 			{
@@ -177,7 +179,7 @@ func TestErrorRequestedExporterNotFoundForRoute(t *testing.T) {
 
 func TestErrorRequestedExporterNotFoundForDefaultRoute(t *testing.T) {
 	//  prepare
-	exp, err := newProcessor(zap.NewNop(), &Config{
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
 		DefaultExporters: []string{"non-existing"},
 		FromAttribute:    "X-Tenant",
 		Table: []RoutingTableItem{
@@ -218,7 +220,7 @@ func TestErrorRequestedExporterNotFoundForDefaultRoute(t *testing.T) {
 
 func TestInvalidExporter(t *testing.T) {
 	//  prepare
-	exp, err := newProcessor(zap.NewNop(), &Config{
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
 		DefaultExporters: []string{"otlp"},
 		FromAttribute:    "X-Tenant",
 		Table: []RoutingTableItem{
@@ -250,7 +252,7 @@ func TestInvalidExporter(t *testing.T) {
 
 func TestValueFromExistingGRPCAttribute(t *testing.T) {
 	// prepare
-	exp, err := newProcessor(zap.NewNop(), &Config{
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
 		DefaultExporters: []string{"otlp"},
 		FromAttribute:    "X-Tenant",
 		Table: []RoutingTableItem{
@@ -272,7 +274,7 @@ func TestValueFromExistingGRPCAttribute(t *testing.T) {
 
 func TestMultipleValuesFromExistingGRPCAttribute(t *testing.T) {
 	// prepare
-	exp, err := newProcessor(zap.NewNop(), &Config{
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
 		DefaultExporters: []string{"otlp"},
 		FromAttribute:    "X-Tenant",
 		Table: []RoutingTableItem{
@@ -294,7 +296,7 @@ func TestMultipleValuesFromExistingGRPCAttribute(t *testing.T) {
 
 func TestNoValuesFromExistingGRPCAttribute(t *testing.T) {
 	// prepare
-	exp, err := newProcessor(zap.NewNop(), &Config{
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
 		DefaultExporters: []string{"otlp"},
 		FromAttribute:    "X-Tenant",
 		Table: []RoutingTableItem{
@@ -316,7 +318,7 @@ func TestNoValuesFromExistingGRPCAttribute(t *testing.T) {
 
 func TestAttributeFromExistingGRPCContext(t *testing.T) {
 	// prepare
-	exp, err := newProcessor(zap.NewNop(), &Config{
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
 		DefaultExporters: []string{"otlp"},
 		FromAttribute:    "X-Tenant",
 		Table: []RoutingTableItem{
@@ -338,7 +340,7 @@ func TestAttributeFromExistingGRPCContext(t *testing.T) {
 
 func TestNoAttributeInContext(t *testing.T) {
 	// prepare
-	exp, err := newProcessor(zap.NewNop(), &Config{
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
 		DefaultExporters: []string{"otlp"},
 		FromAttribute:    "X-Tenant",
 		Table: []RoutingTableItem{
@@ -364,8 +366,8 @@ func TestFailedToPushDataToExporter(t *testing.T) {
 	wg.Add(2)
 	exp := &processorImp{
 		logger: zap.NewNop(),
-		traceExporters: map[string][]component.TracesExporter{
-			"acme": {
+		traceExporters: map[routeKey][]component.TracesExporter{
+			{kind: valueRoute, match: "acme"}: {
 				&mockExporter{
 					ConsumeTracesFunc: func(context.Context, pdata.Traces) error {
 						wg.Done()
@@ -384,7 +386,7 @@ func TestFailedToPushDataToExporter(t *testing.T) {
 	traces := pdata.NewTraces()
 
 	// test
-	err := exp.pushDataToExporters(context.Background(), traces, exp.traceExporters["acme"])
+	err := exp.pushDataToExporters(context.Background(), traces, exp.traceExporters[routeKey{kind: valueRoute, match: "acme"}])
 
 	// verify
 	wg.Wait() // ensure that the exporter has been called
@@ -393,7 +395,7 @@ func TestFailedToPushDataToExporter(t *testing.T) {
 
 func TestProcessorCapabilities(t *testing.T) {
 	// prepare
-	config := &Config{
+	cfg := &Config{
 		FromAttribute: "X-Tenant",
 		Table: []RoutingTableItem{{
 			Exporters: []string{"otlp"},
@@ -401,7 +403,7 @@ func TestProcessorCapabilities(t *testing.T) {
 	}
 
 	// test
-	p, err := newProcessor(zap.NewNop(), config)
+	p, err := newProcessor(zap.NewNop(), config.TracesDataType, cfg)
 	caps := p.Capabilities()
 
 	// verify
@@ -410,6 +412,468 @@ func TestProcessorCapabilities(t *testing.T) {
 	assert.Equal(t, false, caps.MutatesData)
 }
 
+func TestTracesAreSplitByResourceAttribute(t *testing.T) {
+	// prepare
+	var acmeSpans, defaultSpans []pdata.Traces
+	exp := &processorImp{
+		config: Config{
+			FromAttribute:   "tenant",
+			AttributeSource: ResourceAttributeSource,
+		},
+		logger: zap.NewNop(),
+		defaultTracesExporters: []component.TracesExporter{
+			&mockExporter{
+				ConsumeTracesFunc: func(_ context.Context, td pdata.Traces) error {
+					defaultSpans = append(defaultSpans, td)
+					return nil
+				},
+			},
+		},
+		routes: []routingRule{{key: routeKey{kind: valueRoute, match: "acme"}, value: "acme"}},
+		traceExporters: map[routeKey][]component.TracesExporter{
+			{kind: valueRoute, match: "acme"}: {
+				&mockExporter{
+					ConsumeTracesFunc: func(_ context.Context, td pdata.Traces) error {
+						acmeSpans = append(acmeSpans, td)
+						return nil
+					},
+				},
+			},
+		},
+	}
+
+	traces := pdata.NewTraces()
+	for _, tenant := range []string{"acme", "globex", "acme", ""} {
+		rs := traces.ResourceSpans().AppendEmpty()
+		if tenant != "" {
+			rs.Resource().Attributes().InsertString("tenant", tenant)
+		}
+		rs.InstrumentationLibrarySpans().AppendEmpty().Spans().AppendEmpty().SetName(tenant)
+	}
+
+	// test
+	err := exp.ConsumeTraces(context.Background(), traces)
+
+	// verify
+	require.NoError(t, err)
+	require.Len(t, acmeSpans, 1)
+	require.Len(t, defaultSpans, 1)
+	assert.Equal(t, 2, acmeSpans[0].ResourceSpans().Len())
+	assert.Equal(t, 2, acmeSpans[0].SpanCount())
+	assert.Equal(t, 2, defaultSpans[0].ResourceSpans().Len())
+	assert.Equal(t, 2, defaultSpans[0].SpanCount())
+}
+
+func TestTracesWithSingleRouteAreNotSplit(t *testing.T) {
+	// prepare
+	var received []pdata.Traces
+	exp := &processorImp{
+		config: Config{
+			FromAttribute:   "tenant",
+			AttributeSource: ResourceAttributeSource,
+		},
+		logger: zap.NewNop(),
+		routes: []routingRule{{key: routeKey{kind: valueRoute, match: "acme"}, value: "acme"}},
+		traceExporters: map[routeKey][]component.TracesExporter{
+			{kind: valueRoute, match: "acme"}: {
+				&mockExporter{
+					ConsumeTracesFunc: func(_ context.Context, td pdata.Traces) error {
+						received = append(received, td)
+						return nil
+					},
+				},
+			},
+		},
+	}
+
+	traces := pdata.NewTraces()
+	for i := 0; i < 2; i++ {
+		traces.ResourceSpans().AppendEmpty().Resource().Attributes().InsertString("tenant", "acme")
+	}
+
+	// test
+	err := exp.ConsumeTraces(context.Background(), traces)
+
+	// verify
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, traces, received[0])
+}
+
+func TestResourceRoutingIgnoresContext(t *testing.T) {
+	// prepare
+	var called bool
+	exp := &processorImp{
+		config: Config{
+			FromAttribute:   "X-Tenant",
+			AttributeSource: ResourceAttributeSource,
+		},
+		logger: zap.NewNop(),
+		defaultTracesExporters: []component.TracesExporter{
+			&mockExporter{
+				ConsumeTracesFunc: func(context.Context, pdata.Traces) error {
+					called = true
+					return nil
+				},
+			},
+		},
+		routes: []routingRule{{key: routeKey{kind: valueRoute, match: "acme"}, value: "acme"}},
+		traceExporters: map[routeKey][]component.TracesExporter{
+			{kind: valueRoute, match: "acme"}: {&mockExporter{}},
+		},
+	}
+	traces := pdata.NewTraces()
+	traces.ResourceSpans().AppendEmpty()
+
+	// test
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("X-Tenant", "acme"))
+	err := exp.ConsumeTraces(ctx, traces)
+
+	// verify
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestResourceRoutingReportsExporterErrors(t *testing.T) {
+	// prepare
+	expectedErr := errors.New("boom")
+	exp := &processorImp{
+		config: Config{
+			FromAttribute:   "tenant",
+			AttributeSource: ResourceAttributeSource,
+		},
+		logger:                 zap.NewNop(),
+		defaultTracesExporters: []component.TracesExporter{&mockExporter{}},
+		routes:                 []routingRule{{key: routeKey{kind: valueRoute, match: "acme"}, value: "acme"}},
+		traceExporters: map[routeKey][]component.TracesExporter{
+			{kind: valueRoute, match: "acme"}: {
+				&mockExporter{
+					ConsumeTracesFunc: func(context.Context, pdata.Traces) error {
+						return expectedErr
+					},
+				},
+			},
+		},
+	}
+	traces := pdata.NewTraces()
+	traces.ResourceSpans().AppendEmpty().Resource().Attributes().InsertString("tenant", "acme")
+	traces.ResourceSpans().AppendEmpty()
+
+	// test
+	err := exp.ConsumeTraces(context.Background(), traces)
+
+	// verify
+	assert.True(t, errors.Is(err, expectedErr))
+}
+
+func TestMetricsAreSplitByResourceAttribute(t *testing.T) {
+	// prepare
+	var acme, fallback []pdata.Metrics
+	exp := &processorImp{
+		config: Config{
+			FromAttribute:   "tenant",
+			AttributeSource: ResourceAttributeSource,
+		},
+		logger: zap.NewNop(),
+		defaultMetricsExporters: []component.MetricsExporter{
+			&mockExporter{
+				ConsumeMetricsFunc: func(_ context.Context, md pdata.Metrics) error {
+					fallback = append(fallback, md)
+					return nil
+				},
+			},
+		},
+		routes: []routingRule{{key: routeKey{kind: valueRoute, match: "acme"}, value: "acme"}},
+		metricsExporters: map[routeKey][]component.MetricsExporter{
+			{kind: valueRoute, match: "acme"}: {
+				&mockExporter{
+					ConsumeMetricsFunc: func(_ context.Context, md pdata.Metrics) error {
+						acme = append(acme, md)
+						return nil
+					},
+				},
+			},
+		},
+	}
+
+	metrics := pdata.NewMetrics()
+	metrics.ResourceMetrics().AppendEmpty().Resource().Attributes().InsertString("tenant", "acme")
+	metrics.ResourceMetrics().AppendEmpty().Resource().Attributes().InsertString("tenant", "globex")
+
+	// test
+	err := exp.ConsumeMetrics(context.Background(), metrics)
+
+	// verify
+	require.NoError(t, err)
+	require.Len(t, acme, 1)
+	require.Len(t, fallback, 1)
+	assert.Equal(t, 1, acme[0].ResourceMetrics().Len())
+	assert.Equal(t, 1, fallback[0].ResourceMetrics().Len())
+	v, _ := fallback[0].ResourceMetrics().At(0).Resource().Attributes().Get("tenant")
+	assert.Equal(t, "globex", v.StringVal())
+}
+
+func TestLogsAreSplitByResourceAttribute(t *testing.T) {
+	// prepare
+	var acme, fallback []pdata.Logs
+	exp := &processorImp{
+		config: Config{
+			FromAttribute:   "tenant",
+			AttributeSource: ResourceAttributeSource,
+		},
+		logger: zap.NewNop(),
+		defaultLogsExporters: []component.LogsExporter{
+			&mockExporter{
+				ConsumeLogsFunc: func(_ context.Context, ld pdata.Logs) error {
+					fallback = append(fallback, ld)
+					return nil
+				},
+			},
+		},
+		routes: []routingRule{{key: routeKey{kind: valueRoute, match: "acme"}, value: "acme"}},
+		logsExporters: map[routeKey][]component.LogsExporter{
+			{kind: valueRoute, match: "acme"}: {
+				&mockExporter{
+					ConsumeLogsFunc: func(_ context.Context, ld pdata.Logs) error {
+						acme = append(acme, ld)
+						return nil
+					},
+				},
+			},
+		},
+	}
+
+	logs := pdata.NewLogs()
+	logs.ResourceLogs().AppendEmpty().Resource().Attributes().InsertString("tenant", "acme")
+	logs.ResourceLogs().AppendEmpty().Resource().Attributes().InsertString("tenant", "acme")
+	logs.ResourceLogs().AppendEmpty()
+
+	// test
+	err := exp.ConsumeLogs(context.Background(), logs)
+
+	// verify
+	require.NoError(t, err)
+	require.Len(t, acme, 1)
+	require.Len(t, fallback, 1)
+	assert.Equal(t, 2, acme[0].ResourceLogs().Len())
+	assert.Equal(t, 1, fallback[0].ResourceLogs().Len())
+}
+
+func TestMetricsRouteIsFoundForGRPCContexts(t *testing.T) {
+	// prepare
+	var called bool
+	exp := &processorImp{
+		config: Config{
+			FromAttribute: "X-Tenant",
+		},
+		logger: zap.NewNop(),
+		routes: []routingRule{{key: routeKey{kind: valueRoute, match: "acme"}, value: "acme"}},
+		metricsExporters: map[routeKey][]component.MetricsExporter{
+			{kind: valueRoute, match: "acme"}: {
+				&mockExporter{
+					ConsumeMetricsFunc: func(context.Context, pdata.Metrics) error {
+						called = true
+						return nil
+					},
+				},
+			},
+		},
+	}
+
+	// test
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("X-Tenant", "acme"))
+	err := exp.ConsumeMetrics(ctx, pdata.NewMetrics())
+
+	// verify
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestRegisterExportersForMetricsPipeline(t *testing.T) {
+	// prepare
+	exp, err := newProcessor(zap.NewNop(), config.MetricsDataType, &Config{
+		DefaultExporters: []string{"otlp"},
+		FromAttribute:    "tenant",
+		AttributeSource:  ResourceAttributeSource,
+		Table: []RoutingTableItem{
+			{
+				Value:     "acme",
+				Exporters: []string{"otlp"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	metricsExp := &mockExporter{}
+	host := &mockHost{
+		Host: componenttest.NewNopHost(),
+		GetExportersFunc: func() map[config.DataType]map[config.ComponentID]component.Exporter {
+			return map[config.DataType]map[config.ComponentID]component.Exporter{
+				config.MetricsDataType: {
+					config.NewID("otlp"): metricsExp,
+				},
+			}
+		},
+	}
+
+	// test
+	err = exp.Start(context.Background(), host)
+
+	// verify
+	require.NoError(t, err)
+	assert.Contains(t, exp.metricsExporters[routeKey{kind: valueRoute, match: "acme"}], metricsExp)
+	assert.Contains(t, exp.defaultMetricsExporters, metricsExp)
+	assert.Empty(t, exp.traceExporters)
+}
+
+func TestInvalidAttributeSource(t *testing.T) {
+	// test
+	_, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
+		FromAttribute:   "tenant",
+		AttributeSource: "span",
+		Table: []RoutingTableItem{
+			{
+				Value:     "acme",
+				Exporters: []string{"otlp"},
+			},
+		},
+	})
+
+	// verify
+	assert.True(t, errors.Is(err, errInvalidAttributeSource))
+}
+
+func TestFirstMatchingRouteWins(t *testing.T) {
+	// prepare
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
+		DefaultExporters: []string{"otlp/default"},
+		FromAttribute:    "k8s.namespace.name",
+		AttributeSource:  ResourceAttributeSource,
+		Table: []RoutingTableItem{
+			{
+				Value:     "team-a-special",
+				Exporters: []string{"otlp/special"},
+			},
+			{
+				Regex:     "^team-a-.*",
+				Exporters: []string{"otlp/team-a"},
+			},
+			{
+				Expression: `attributes["priority"] == "high"`,
+				Exporters:  []string{"otlp/special"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	received := map[string]int{}
+	newExporter := func(name string) *mockExporter {
+		return &mockExporter{
+			ConsumeTracesFunc: func(_ context.Context, td pdata.Traces) error {
+				received[name] += td.ResourceSpans().Len()
+				return nil
+			},
+		}
+	}
+	host := &mockHost{
+		Host: componenttest.NewNopHost(),
+		GetExportersFunc: func() map[config.DataType]map[config.ComponentID]component.Exporter {
+			return map[config.DataType]map[config.ComponentID]component.Exporter{
+				config.TracesDataType: {
+					config.NewIDWithName("otlp", "default"): newExporter("default"),
+					config.NewIDWithName("otlp", "special"): newExporter("special"),
+					config.NewIDWithName("otlp", "team-a"):  newExporter("team-a"),
+				},
+			}
+		},
+	}
+	require.NoError(t, exp.Start(context.Background(), host))
+
+	traces := pdata.NewTraces()
+	for _, attrs := range []map[string]string{
+		{"k8s.namespace.name": "team-a-special"},
+		{"k8s.namespace.name": "team-a-frontend", "priority": "high"},
+		{"k8s.namespace.name": "team-b-frontend", "priority": "high"},
+		{"k8s.namespace.name": "team-b-frontend"},
+		{},
+	} {
+		rs := traces.ResourceSpans().AppendEmpty()
+		for k, v := range attrs {
+			rs.Resource().Attributes().InsertString(k, v)
+		}
+	}
+
+	// test
+	err = exp.ConsumeTraces(context.Background(), traces)
+
+	// verify
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"special": 2, "team-a": 1, "default": 2}, received)
+}
+
+func TestRegexRouteFromContext(t *testing.T) {
+	// prepare
+	var called bool
+	exp, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
+		FromAttribute: "X-Tenant",
+		Table: []RoutingTableItem{
+			{
+				Regex:     "^acme-.*",
+				Exporters: []string{"otlp"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	exp.traceExporters[routeKey{kind: regexRoute, match: "^acme-.*"}] = []component.TracesExporter{
+		&mockExporter{
+			ConsumeTracesFunc: func(context.Context, pdata.Traces) error {
+				called = true
+				return nil
+			},
+		},
+	}
+
+	// test
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("X-Tenant", "acme-eu"))
+	err = exp.ConsumeTraces(ctx, pdata.NewTraces())
+
+	// verify
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestExpressionRequiresResourceAttributeSource(t *testing.T) {
+	// test
+	_, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
+		FromAttribute: "X-Tenant",
+		Table: []RoutingTableItem{
+			{
+				Expression: `attributes["tenant"] == "acme"`,
+				Exporters:  []string{"otlp"},
+			},
+		},
+	})
+
+	// verify
+	assert.True(t, errors.Is(err, errExpressionRequiresResource))
+}
+
+func TestFromAttributeIsOptionalForExpressions(t *testing.T) {
+	// test
+	_, err := newProcessor(zap.NewNop(), config.TracesDataType, &Config{
+		AttributeSource: ResourceAttributeSource,
+		Table: []RoutingTableItem{
+			{
+				Expression: `attributes["tenant"] == "acme"`,
+				Exporters:  []string{"otlp"},
+			},
+		},
+	})
+
+	// verify
+	assert.NoError(t, err)
+}
+
 type mockHost struct {
 	component.Host
 	GetExportersFunc func() map[config.DataType]map[config.ComponentID]component.Exporter
@@ -433,7 +897,9 @@ func (m *mockComponent) Shutdown(context.Context) error {
 
 type mockExporter struct {
 	mockComponent
-	ConsumeTracesFunc func(ctx context.Context, td pdata.Traces) error
+	ConsumeTracesFunc  func(ctx context.Context, td pdata.Traces) error
+	ConsumeMetricsFunc func(ctx context.Context, md pdata.Metrics) error
+	ConsumeLogsFunc    func(ctx context.Context, ld pdata.Logs) error
 }
 
 func (m *mockExporter) Capabilities() consumer.Capabilities {
@@ -446,3 +912,60 @@ func (m *mockExporter) ConsumeTraces(ctx context.Context, td pdata.Traces) error
 	}
 	return nil
 }
+
+func (m *mockExporter) ConsumeMetrics(ctx context.Context, md pdata.Metrics) error {
+	if m.ConsumeMetricsFunc != nil {
+		return m.ConsumeMetricsFunc(ctx, md)
+	}
+	return nil
+}
+
+func (m *mockExporter) ConsumeLogs(ctx context.Context, ld pdata.Logs) error {
+	if m.ConsumeLogsFunc != nil {
+		return m.ConsumeLogsFunc(ctx, ld)
+	}
+	return nil
+}
+
+func TestGroupResourcesByRoute(t *testing.T) {
+	exp := &processorImp{
+		config: Config{
+			FromAttribute:   "tenant",
+			AttributeSource: ResourceAttributeSource,
+		},
+		logger: zap.NewNop(),
+		routes: []routingRule{{key: routeKey{kind: valueRoute, match: "acme"}, value: "acme"}},
+	}
+
+	resources := make([]pdata.Resource, 3)
+	for i, tenant := range []string{"acme", "globex", "acme"} {
+		resources[i] = pdata.NewResource()
+		resources[i].Attributes().InsertString("tenant", tenant)
+	}
+
+	groups := exp.groupResourcesByRoute(len(resources), func(i int) pdata.Resource { return resources[i] })
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, []int{0, 2}, groups[&exp.routes[0]])
+	assert.Equal(t, []int{1}, groups[nil])
+}
+
+func TestFailedExpressionIsLogged(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	rule, err := newRoutingRule(RoutingTableItem{Expression: `attributes["priority"] > 2`})
+	require.NoError(t, err)
+	exp := &processorImp{
+		config: Config{
+			AttributeSource: ResourceAttributeSource,
+		},
+		logger: zap.New(core),
+		routes: []routingRule{rule},
+	}
+
+	resource := pdata.NewResource()
+	resource.Attributes().InsertString("priority", "high")
+
+	assert.Nil(t, exp.findRouteFromResource(resource))
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, "failed to evaluate the route, skipping it", logs.All()[0].Message)
+}
@@ -0,0 +1,47 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraphprocessor
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/config"
+)
+
+// Config defines the configuration options for servicegraphprocessor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct
+
+	// MetricsExporter is the name of the metrics exporter to use to ship metrics.
+	MetricsExporter string `mapstructure:"metrics_exporter"`
+
+	// LatencyHistogramBuckets is the list of durations representing latency histogram buckets.
+	// See defaultLatencyHistogramBucketsMs in processor.go for the default value.
+	LatencyHistogramBuckets []time.Duration `mapstructure:"latency_histogram_buckets"`
+
+	// Store contains the settings of the in-memory store holding the edges waiting for their counterpart span.
+	Store StoreConfig `mapstructure:"store"`
+}
+
+// StoreConfig defines the settings of the in-memory store of incomplete edges.
+type StoreConfig struct {
+	// TTL is the time an edge waits for its counterpart span before being discarded.
+	// See defaultStoreTTL in factory.go for the default value.
+	TTL time.Duration `mapstructure:"ttl"`
+
+	// MaxItems is the maximum number of edges waiting for their counterpart span. New edges are discarded
+	// while the store is full. See defaultStoreMaxItems in factory.go for the default value.
+	MaxItems int `mapstructure:"max_items"`
+}
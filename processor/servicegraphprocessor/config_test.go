@@ -0,0 +1,72 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraphprocessor
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/configtest"
+)
+
+func TestLoadConfig(t *testing.T) {
+	// Prepare
+	factories, err := componenttest.NopFactories()
+	require.NoError(t, err)
+
+	factories.Processors[typeStr] = NewFactory()
+
+	// Test
+	cfg, err := configtest.LoadConfigAndValidate(path.Join(".", "testdata", "config.yaml"), factories)
+
+	// Verify
+	require.NoError(t, err)
+	require.NotNil(t, cfg)
+
+	assert.Equal(t,
+		&Config{
+			ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+			MetricsExporter:   "nop/servicegraph",
+			Store: StoreConfig{
+				TTL:      defaultStoreTTL,
+				MaxItems: defaultStoreMaxItems,
+			},
+		},
+		cfg.Processors[config.NewID(typeStr)],
+	)
+
+	assert.Equal(t,
+		&Config{
+			ProcessorSettings: config.NewProcessorSettings(config.NewIDWithName(typeStr, "full")),
+			MetricsExporter:   "nop/servicegraph",
+			LatencyHistogramBuckets: []time.Duration{
+				2 * time.Millisecond,
+				10 * time.Millisecond,
+				100 * time.Millisecond,
+				1 * time.Second,
+			},
+			Store: StoreConfig{
+				TTL:      5 * time.Second,
+				MaxItems: 500,
+			},
+		},
+		cfg.Processors[config.NewIDWithName(typeStr, "full")],
+	)
+}
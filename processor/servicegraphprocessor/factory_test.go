@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraphprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+)
+
+func TestNewProcessor(t *testing.T) {
+	for _, tc := range []struct {
+		name                        string
+		latencyHistogramBuckets     []time.Duration
+		wantLatencyHistogramBuckets []float64
+	}{
+		{
+			name:                        "simplest config (use defaults)",
+			wantLatencyHistogramBuckets: defaultLatencyHistogramBucketsMs,
+		},
+		{
+			name:                        "latency histogram configured with catch-all bucket to check no additional catch-all bucket inserted",
+			latencyHistogramBuckets:     []time.Duration{2 * time.Millisecond, maxDuration},
+			wantLatencyHistogramBuckets: []float64{2, maxDurationMs},
+		},
+		{
+			name:                        "latency histogram with no catch-all bucket and check the catch-all bucket is inserted",
+			latencyHistogramBuckets:     []time.Duration{2 * time.Millisecond},
+			wantLatencyHistogramBuckets: []float64{2, maxDurationMs},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Prepare
+			factory := NewFactory()
+
+			creationParams := componenttest.NewNopProcessorCreateSettings()
+			cfg := factory.CreateDefaultConfig().(*Config)
+			cfg.LatencyHistogramBuckets = tc.latencyHistogramBuckets
+
+			// Test
+			traceProcessor, err := factory.CreateTracesProcessor(context.Background(), creationParams, cfg, consumertest.NewNop())
+
+			// Verify
+			require.NoError(t, err)
+			sgp := traceProcessor.(*processorImp)
+			assert.Equal(t, tc.wantLatencyHistogramBuckets, sgp.latencyBounds)
+		})
+	}
+}
+
+func TestNewProcessorInvalidStore(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		store StoreConfig
+	}{
+		{
+			name:  "no ttl",
+			store: StoreConfig{MaxItems: 10},
+		},
+		{
+			name:  "no max items",
+			store: StoreConfig{TTL: time.Second},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Prepare
+			factory := NewFactory()
+			cfg := factory.CreateDefaultConfig().(*Config)
+			cfg.Store = tc.store
+
+			// Test
+			_, err := factory.CreateTracesProcessor(context.Background(), componenttest.NewNopProcessorCreateSettings(), cfg, consumertest.NewNop())
+
+			// Verify
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraphprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/model/pdata"
+	conventions "go.opentelemetry.io/collector/translator/conventions/v1.5.0"
+	"go.uber.org/zap"
+)
+
+const (
+	clientKey          = "client"
+	serverKey          = "server"
+	metricKeySeparator = string(byte(0))
+)
+
+var (
+	maxDuration   = time.Duration(math.MaxInt64)
+	maxDurationMs = durationToMillis(maxDuration)
+
+	defaultLatencyHistogramBucketsMs = []float64{
+		2, 4, 6, 8, 10, 50, 100, 200, 400, 800, 1000, 1400, 2000, 5000, 10_000, 15_000, maxDurationMs,
+	}
+)
+
+// metricKey identifies an edge of the service graph, as the concatenation of the client and server services.
+type metricKey string
+
+// histogram holds the raw data of a latency histogram.
+type histogram struct {
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+type processorImp struct {
+	lock   sync.Mutex
+	logger *zap.Logger
+	config Config
+
+	metricsExporter component.MetricsExporter
+	nextConsumer    consumer.Traces
+
+	// The edges waiting for their counterpart span.
+	store *store
+
+	// The starting time of the data points.
+	startTime time.Time
+
+	// Request & failed request counts.
+	requestCounts       map[metricKey]int64
+	failedRequestCounts map[metricKey]int64
+
+	// Latency histograms, as seen by the client and by the server.
+	clientLatency map[metricKey]*histogram
+	serverLatency map[metricKey]*histogram
+	latencyBounds []float64
+
+	// The client and server services of each edge.
+	metricKeyToServices map[metricKey][2]string
+}
+
+func newProcessor(logger *zap.Logger, config config.Processor, nextConsumer consumer.Traces) (*processorImp, error) {
+	logger.Info("Building servicegraphprocessor")
+	pConfig := config.(*Config)
+
+	bounds := defaultLatencyHistogramBucketsMs
+	if pConfig.LatencyHistogramBuckets != nil {
+		bounds = mapDurationsToMillis(pConfig.LatencyHistogramBuckets)
+
+		// "Catch-all" bucket.
+		if bounds[len(bounds)-1] != maxDurationMs {
+			bounds = append(bounds, maxDurationMs)
+		}
+	}
+
+	if pConfig.Store.TTL <= 0 {
+		return nil, fmt.Errorf("invalid store ttl %v, must be positive", pConfig.Store.TTL)
+	}
+	if pConfig.Store.MaxItems <= 0 {
+		return nil, fmt.Errorf("invalid store max_items %d, must be positive", pConfig.Store.MaxItems)
+	}
+
+	p := &processorImp{
+		logger:              logger,
+		config:              *pConfig,
+		nextConsumer:        nextConsumer,
+		startTime:           time.Now(),
+		requestCounts:       make(map[metricKey]int64),
+		failedRequestCounts: make(map[metricKey]int64),
+		clientLatency:       make(map[metricKey]*histogram),
+		serverLatency:       make(map[metricKey]*histogram),
+		latencyBounds:       bounds,
+		metricKeyToServices: make(map[metricKey][2]string),
+	}
+	p.store = newStore(pConfig.Store.TTL, pConfig.Store.MaxItems, p.onComplete, p.onExpire)
+
+	return p, nil
+}
+
+// durationToMillis converts the given duration to the number of milliseconds it represents.
+// Note that this can return sub-millisecond (i.e. < 1ms) values as well.
+func durationToMillis(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / float64(time.Millisecond.Nanoseconds())
+}
+
+func mapDurationsToMillis(vs []time.Duration) []float64 {
+	vsm := make([]float64, len(vs))
+	for i, v := range vs {
+		vsm[i] = durationToMillis(v)
+	}
+	return vsm
+}
+
+// Start implements the component.Component interface.
+func (p *processorImp) Start(_ context.Context, host component.Host) error {
+	p.logger.Info("Starting servicegraphprocessor")
+	exporters := host.GetExporters()
+
+	var availableMetricsExporters []string
+
+	// The available list of exporters come from any configured metrics pipelines' exporters.
+	for k, exp := range exporters[config.MetricsDataType] {
+		metricsExp, ok := exp.(component.MetricsExporter)
+		if !ok {
+			return fmt.Errorf("the exporter %q isn't a metrics exporter", k.String())
+		}
+
+		availableMetricsExporters = append(availableMetricsExporters, k.String())
+
+		if k.String() == p.config.MetricsExporter {
+			p.metricsExporter = metricsExp
+			p.logger.Info("Found exporter", zap.String("servicegraph-exporter", p.config.MetricsExporter))
+			break
+		}
+	}
+	if p.metricsExporter == nil {
+		return fmt.Errorf("failed to find metrics exporter: '%s'; please configure metrics_exporter from one of: %+v",
+			p.config.MetricsExporter, availableMetricsExporters)
+	}
+	p.logger.Info("Started servicegraphprocessor")
+	return nil
+}
+
+// Shutdown implements the component.Component interface.
+func (p *processorImp) Shutdown(context.Context) error {
+	p.logger.Info("Shutting down servicegraphprocessor")
+	return nil
+}
+
+// Capabilities implements the consumer interface.
+func (p *processorImp) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+// ConsumeTraces implements the consumer.Traces interface.
+// It pairs the client and server spans to build the edges of the service graph, forwarding the edges' metrics
+// to the discovered metrics exporter. The original input trace data will be forwarded to the next consumer, unmodified.
+func (p *processorImp) ConsumeTraces(ctx context.Context, traces pdata.Traces) error {
+	p.aggregateMetrics(traces)
+	p.store.expire()
+
+	m := p.buildMetrics()
+
+	// Firstly, export metrics to avoid being impacted by downstream trace processor errors/latency.
+	if err := p.metricsExporter.ConsumeMetrics(ctx, m); err != nil {
+		return err
+	}
+
+	// Forward trace data unmodified.
+	return p.nextConsumer.ConsumeTraces(ctx, traces)
+}
+
+// aggregateMetrics pairs the client (or producer) spans with the server (or consumer) spans from the input
+// trace data. A server span is the counterpart of the client span with its parent span ID, both spans forming
+// an edge, whose metrics are recorded once both sides have been seen.
+func (p *processorImp) aggregateMetrics(traces pdata.Traces) {
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		rspans := traces.ResourceSpans().At(i)
+
+		attr, ok := rspans.Resource().Attributes().Get(conventions.AttributeServiceName)
+		if !ok {
+			continue
+		}
+		serviceName := attr.StringVal()
+
+		ilsSlice := rspans.InstrumentationLibrarySpans()
+		for j := 0; j < ilsSlice.Len(); j++ {
+			spans := ilsSlice.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				p.aggregateMetricsForSpan(serviceName, spans.At(k))
+			}
+		}
+	}
+}
+
+func (p *processorImp) aggregateMetricsForSpan(serviceName string, span pdata.Span) {
+	latencyInMilliseconds := float64(span.EndTimestamp()-span.StartTimestamp()) / float64(time.Millisecond.Nanoseconds())
+	failed := span.Status().Code() == pdata.StatusCodeError
+
+	var err error
+	switch span.Kind() {
+	case pdata.SpanKindClient, pdata.SpanKindProducer:
+		err = p.store.upsertEdge(buildEdgeKey(span.TraceID(), span.SpanID()), func(e *edge) {
+			e.clientService = serviceName
+			e.clientLatencyMs = latencyInMilliseconds
+			e.failed = e.failed || failed
+		})
+	case pdata.SpanKindServer, pdata.SpanKindConsumer:
+		// a server span without parent has no client counterpart
+		if span.ParentSpanID().IsEmpty() {
+			return
+		}
+		err = p.store.upsertEdge(buildEdgeKey(span.TraceID(), span.ParentSpanID()), func(e *edge) {
+			e.serverService = serviceName
+			e.serverLatencyMs = latencyInMilliseconds
+			e.failed = e.failed || failed
+		})
+	}
+
+	if errors.Is(err, errTooManyItems) {
+		p.logger.Debug("Too many edges waiting for their counterpart span, discarding the span",
+			zap.String("trace-id", span.TraceID().HexString()), zap.String("span-id", span.SpanID().HexString()))
+	}
+}
+
+// onComplete records the metrics of a complete edge.
+func (p *processorImp) onComplete(e *edge) {
+	key := buildKey(e.clientService, e.serverService)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.metricKeyToServices[key] = [2]string{e.clientService, e.serverService}
+	p.requestCounts[key]++
+	if e.failed {
+		p.failedRequestCounts[key]++
+	}
+	p.updateLatency(p.clientLatency, key, e.clientLatencyMs)
+	p.updateLatency(p.serverLatency, key, e.serverLatencyMs)
+}
+
+// onExpire is called for the edges whose counterpart span hasn't been seen in time,
+// typically because the other side isn't instrumented.
+func (p *processorImp) onExpire(e *edge) {
+	p.logger.Debug("Edge expired before its counterpart span was seen",
+		zap.String("client", e.clientService), zap.String("server", e.serverService))
+}
+
+// updateLatency records the latency in the histogram for the given metric key.
+func (p *processorImp) updateLatency(histograms map[metricKey]*histogram, key metricKey, latency float64) {
+	h, ok := histograms[key]
+	if !ok {
+		h = &histogram{bucketCounts: make([]uint64, len(p.latencyBounds))}
+		histograms[key] = h
+	}
+
+	// Binary search to find the latency bucket index.
+	index := sort.SearchFloat64s(p.latencyBounds, latency)
+	h.bucketCounts[index]++
+	h.count++
+	h.sum += latency
+}
+
+// buildMetrics builds the metrics of all the edges seen so far.
+func (p *processorImp) buildMetrics() pdata.Metrics {
+	m := pdata.NewMetrics()
+	ilm := m.ResourceMetrics().AppendEmpty().InstrumentationLibraryMetrics().AppendEmpty()
+	ilm.InstrumentationLibrary().SetName("servicegraphprocessor")
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := pdata.TimestampFromTime(time.Now())
+	start := pdata.TimestampFromTime(p.startTime)
+	for key, services := range p.metricKeyToServices {
+		labels := map[string]string{clientKey: services[0], serverKey: services[1]}
+
+		p.collectCount(ilm, "service_graph_request_total", p.requestCounts[key], labels, start, now)
+		p.collectCount(ilm, "service_graph_request_failed_total", p.failedRequestCounts[key], labels, start, now)
+		p.collectLatency(ilm, "service_graph_request_client_latency", p.clientLatency[key], labels, start, now)
+		p.collectLatency(ilm, "service_graph_request_server_latency", p.serverLatency[key], labels, start, now)
+	}
+
+	return m
+}
+
+// collectCount writes the given count as a cumulative sum into the given instrumentation library metrics.
+func (p *processorImp) collectCount(ilm pdata.InstrumentationLibraryMetrics, name string, count int64, labels map[string]string, start, now pdata.Timestamp) {
+	mCount := ilm.Metrics().AppendEmpty()
+	mCount.SetDataType(pdata.MetricDataTypeSum)
+	mCount.SetName(name)
+	mCount.Sum().SetIsMonotonic(true)
+	mCount.Sum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+
+	dp := mCount.Sum().DataPoints().AppendEmpty()
+	dp.SetStartTimestamp(start)
+	dp.SetTimestamp(now)
+	dp.SetIntVal(count)
+	dp.LabelsMap().InitFromMap(labels)
+}
+
+// collectLatency writes the given histogram into the given instrumentation library metrics.
+func (p *processorImp) collectLatency(ilm pdata.InstrumentationLibraryMetrics, name string, h *histogram, labels map[string]string, start, now pdata.Timestamp) {
+	mLatency := ilm.Metrics().AppendEmpty()
+	mLatency.SetDataType(pdata.MetricDataTypeHistogram)
+	mLatency.SetName(name)
+	mLatency.Histogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+
+	dp := mLatency.Histogram().DataPoints().AppendEmpty()
+	dp.SetStartTimestamp(start)
+	dp.SetTimestamp(now)
+	dp.SetExplicitBounds(p.latencyBounds)
+	dp.SetBucketCounts(append([]uint64(nil), h.bucketCounts...))
+	dp.SetCount(h.count)
+	dp.SetSum(h.sum)
+	dp.LabelsMap().InitFromMap(labels)
+}
+
+// buildEdgeKey builds the key of the edge, as the trace ID along with the client span ID.
+func buildEdgeKey(traceID pdata.TraceID, clientSpanID pdata.SpanID) string {
+	return traceID.HexString() + clientSpanID.HexString()
+}
+
+// buildKey builds the metric key from the client and server services.
+func buildKey(client, server string) metricKey {
+	return metricKey(client + metricKeySeparator + server)
+}
@@ -0,0 +1,254 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraphprocessor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenthelper"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/model/pdata"
+	conventions "go.opentelemetry.io/collector/translator/conventions/v1.5.0"
+	"go.uber.org/zap"
+)
+
+var (
+	sampleTraceID = pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	clientSpanID  = pdata.NewSpanID([8]byte{1, 1, 1, 1, 1, 1, 1, 1})
+	serverSpanID  = pdata.NewSpanID([8]byte{2, 2, 2, 2, 2, 2, 2, 2})
+)
+
+func TestProcessorStart(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		exporter        component.Exporter
+		metricsExporter string
+		wantErrorMsg    string
+	}{
+		{"export to active metrics exporter", newMetricsExporter(), "otlp", ""},
+		{"unable to find configured exporter in active exporter list", newMetricsExporter(), "prometheus", "failed to find metrics exporter: 'prometheus'; please configure metrics_exporter from one of: [otlp]"},
+		{"export to a non metrics exporter should error", componenthelper.New(), "otlp", "the exporter \"otlp\" isn't a metrics exporter"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Prepare
+			host := &mockHost{
+				Host: componenttest.NewNopHost(),
+				exporters: map[config.DataType]map[config.ComponentID]component.Exporter{
+					config.MetricsDataType: {
+						config.NewID("otlp"): tc.exporter,
+					},
+				},
+			}
+
+			factory := NewFactory()
+			cfg := factory.CreateDefaultConfig().(*Config)
+			cfg.MetricsExporter = tc.metricsExporter
+
+			p, err := newProcessor(zap.NewNop(), cfg, consumertest.NewNop())
+			require.NoError(t, err)
+
+			// Test
+			err = p.Start(context.Background(), host)
+
+			// Verify
+			if tc.wantErrorMsg != "" {
+				assert.EqualError(t, err, tc.wantErrorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProcessorPairsClientAndServerSpans(t *testing.T) {
+	// Prepare
+	exporter := newMetricsExporter()
+	next := new(consumertest.TracesSink)
+	p := newStartedProcessor(t, exporter, next)
+
+	// Test: the client and server spans arrive in different batches, the server one first.
+	server := buildTrace("service-b", pdata.SpanKindServer, serverSpanID, clientSpanID, 10*time.Millisecond, pdata.StatusCodeOk)
+	require.NoError(t, p.ConsumeTraces(context.Background(), server))
+	assert.Equal(t, 0, exporter.AllMetrics()[0].MetricCount(), "the edge should not be complete yet")
+
+	client := buildTrace("service-a", pdata.SpanKindClient, clientSpanID, pdata.SpanID{}, 15*time.Millisecond, pdata.StatusCodeOk)
+	require.NoError(t, p.ConsumeTraces(context.Background(), client))
+
+	// Verify
+	assert.Equal(t, 2, next.SpanCount(), "traces should be forwarded unmodified")
+	assert.Equal(t, 0, p.store.len())
+
+	metrics := metricsByName(exporter.AllMetrics()[1])
+	require.Len(t, metrics, 4)
+
+	requests := metrics["service_graph_request_total"].Sum().DataPoints().At(0)
+	assert.Equal(t, int64(1), requests.IntVal())
+	assert.Equal(t, map[string]string{clientKey: "service-a", serverKey: "service-b"}, labelsToMap(requests.LabelsMap()))
+
+	failed := metrics["service_graph_request_failed_total"].Sum().DataPoints().At(0)
+	assert.Equal(t, int64(0), failed.IntVal())
+
+	clientLatency := metrics["service_graph_request_client_latency"].Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(1), clientLatency.Count())
+	assert.Equal(t, float64(15), clientLatency.Sum())
+
+	serverLatency := metrics["service_graph_request_server_latency"].Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(1), serverLatency.Count())
+	assert.Equal(t, float64(10), serverLatency.Sum())
+	assert.Equal(t, pdata.AggregationTemporalityCumulative, metrics["service_graph_request_server_latency"].Histogram().AggregationTemporality())
+}
+
+func TestProcessorPairsProducerAndConsumerSpans(t *testing.T) {
+	// Prepare
+	exporter := newMetricsExporter()
+	p := newStartedProcessor(t, exporter, consumertest.NewNop())
+
+	traces := buildTrace("producer", pdata.SpanKindProducer, clientSpanID, pdata.SpanID{}, 1*time.Millisecond, pdata.StatusCodeOk)
+	buildTrace("consumer", pdata.SpanKindConsumer, serverSpanID, clientSpanID, 5*time.Millisecond, pdata.StatusCodeError).
+		ResourceSpans().MoveAndAppendTo(traces.ResourceSpans())
+
+	// Test
+	require.NoError(t, p.ConsumeTraces(context.Background(), traces))
+
+	// Verify
+	metrics := metricsByName(exporter.AllMetrics()[0])
+	requests := metrics["service_graph_request_total"].Sum().DataPoints().At(0)
+	assert.Equal(t, int64(1), requests.IntVal())
+	assert.Equal(t, map[string]string{clientKey: "producer", serverKey: "consumer"}, labelsToMap(requests.LabelsMap()))
+
+	failed := metrics["service_graph_request_failed_total"].Sum().DataPoints().At(0)
+	assert.Equal(t, int64(1), failed.IntVal())
+}
+
+func TestProcessorIgnoresUnpairedSpans(t *testing.T) {
+	// Prepare
+	exporter := newMetricsExporter()
+	p := newStartedProcessor(t, exporter, consumertest.NewNop())
+	p.store.ttl = time.Millisecond
+
+	// Test
+	client := buildTrace("service-a", pdata.SpanKindClient, clientSpanID, pdata.SpanID{}, time.Millisecond, pdata.StatusCodeOk)
+	require.NoError(t, p.ConsumeTraces(context.Background(), client))
+	time.Sleep(5 * time.Millisecond)
+	internal := buildTrace("service-a", pdata.SpanKindInternal, serverSpanID, clientSpanID, time.Millisecond, pdata.StatusCodeOk)
+	require.NoError(t, p.ConsumeTraces(context.Background(), internal))
+
+	// Verify
+	assert.Equal(t, 0, p.store.len(), "the client span should have expired")
+	for _, m := range exporter.AllMetrics() {
+		assert.Equal(t, 0, m.MetricCount())
+	}
+}
+
+func TestProcessorConsumeTracesErrors(t *testing.T) {
+	// Prepare
+	p := newStartedProcessor(t, &failingMetricsExporter{newMetricsExporter()}, consumertest.NewNop())
+
+	// Test
+	err := p.ConsumeTraces(context.Background(), pdata.NewTraces())
+
+	// Verify
+	assert.EqualError(t, err, "metricsExporter error")
+}
+
+func newStartedProcessor(t *testing.T, exporter component.MetricsExporter, next consumer.Traces) *processorImp {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.MetricsExporter = "otlp"
+
+	p, err := newProcessor(zap.NewNop(), cfg, next)
+	require.NoError(t, err)
+
+	host := &mockHost{
+		Host: componenttest.NewNopHost(),
+		exporters: map[config.DataType]map[config.ComponentID]component.Exporter{
+			config.MetricsDataType: {
+				config.NewID("otlp"): exporter,
+			},
+		},
+	}
+	require.NoError(t, p.Start(context.Background(), host))
+	return p
+}
+
+func buildTrace(service string, kind pdata.SpanKind, spanID, parentSpanID pdata.SpanID, latency time.Duration, code pdata.StatusCode) pdata.Traces {
+	traces := pdata.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().InsertString(conventions.AttributeServiceName, service)
+
+	span := rs.InstrumentationLibrarySpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID(sampleTraceID)
+	span.SetSpanID(spanID)
+	span.SetParentSpanID(parentSpanID)
+	span.SetKind(kind)
+	span.Status().SetCode(code)
+	now := time.Now()
+	span.SetStartTimestamp(pdata.TimestampFromTime(now))
+	span.SetEndTimestamp(pdata.TimestampFromTime(now.Add(latency)))
+	return traces
+}
+
+func metricsByName(md pdata.Metrics) map[string]pdata.Metric {
+	metrics := map[string]pdata.Metric{}
+	ms := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+	for i := 0; i < ms.Len(); i++ {
+		metrics[ms.At(i).Name()] = ms.At(i)
+	}
+	return metrics
+}
+
+func labelsToMap(labels pdata.StringMap) map[string]string {
+	m := map[string]string{}
+	labels.Range(func(k string, v string) bool {
+		m[k] = v
+		return true
+	})
+	return m
+}
+
+type mockHost struct {
+	component.Host
+	exporters map[config.DataType]map[config.ComponentID]component.Exporter
+}
+
+func (m *mockHost) GetExporters() map[config.DataType]map[config.ComponentID]component.Exporter {
+	return m.exporters
+}
+
+type metricsExporter struct {
+	component.Component
+	*consumertest.MetricsSink
+}
+
+func newMetricsExporter() *metricsExporter {
+	return &metricsExporter{Component: componenthelper.New(), MetricsSink: new(consumertest.MetricsSink)}
+}
+
+type failingMetricsExporter struct {
+	*metricsExporter
+}
+
+func (e *failingMetricsExporter) ConsumeMetrics(context.Context, pdata.Metrics) error {
+	return errors.New("metricsExporter error")
+}
@@ -0,0 +1,129 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraphprocessor
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+var errTooManyItems = errors.New("too many items")
+
+// edge is a request between two services, built from the client (or producer) span and the
+// server (or consumer) span.
+type edge struct {
+	key string
+
+	clientService, serverService     string
+	clientLatencyMs, serverLatencyMs float64
+
+	// failed is set when either side of the request has an error status.
+	failed bool
+
+	expiration time.Time
+}
+
+// isComplete returns whether both sides of the edge have been seen.
+func (e *edge) isComplete() bool {
+	return len(e.clientService) != 0 && len(e.serverService) != 0
+}
+
+// store holds the edges waiting for their counterpart span, until they are complete or expired.
+type store struct {
+	mtx sync.Mutex
+	// edges are ordered by insertion, hence by expiration
+	l *list.List
+	m map[string]*list.Element
+
+	onComplete func(e *edge)
+	onExpire   func(e *edge)
+
+	ttl      time.Duration
+	maxItems int
+}
+
+func newStore(ttl time.Duration, maxItems int, onComplete, onExpire func(e *edge)) *store {
+	return &store{
+		l:          list.New(),
+		m:          make(map[string]*list.Element),
+		onComplete: onComplete,
+		onExpire:   onExpire,
+		ttl:        ttl,
+		maxItems:   maxItems,
+	}
+}
+
+// len returns the number of edges in the store.
+func (s *store) len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.l.Len()
+}
+
+// upsertEdge fetches the edge with the given key, creating it when needed, and updates it with the
+// given function. Once updated, complete edges are removed from the store and passed to onComplete.
+// It returns errTooManyItems when the edge has to be created while the store is full.
+func (s *store) upsertEdge(key string, update func(e *edge)) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if el, ok := s.m[key]; ok {
+		e := el.Value.(*edge)
+		update(e)
+
+		if e.isComplete() {
+			s.l.Remove(el)
+			delete(s.m, key)
+			s.onComplete(e)
+		}
+		return nil
+	}
+
+	e := &edge{key: key, expiration: time.Now().Add(s.ttl)}
+	update(e)
+
+	if e.isComplete() {
+		s.onComplete(e)
+		return nil
+	}
+
+	if s.l.Len() >= s.maxItems {
+		return errTooManyItems
+	}
+
+	s.m[key] = s.l.PushBack(e)
+	return nil
+}
+
+// expire removes the edges whose counterpart span hasn't been seen in time, passing them to onExpire.
+func (s *store) expire() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	for el := s.l.Front(); el != nil; el = s.l.Front() {
+		e := el.Value.(*edge)
+		if e.expiration.After(now) {
+			return
+		}
+
+		s.l.Remove(el)
+		delete(s.m, e.key)
+		s.onExpire(e)
+	}
+}
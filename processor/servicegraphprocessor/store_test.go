@@ -0,0 +1,72 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraphprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreCompletesEdges(t *testing.T) {
+	var completed []*edge
+	s := newStore(time.Hour, 10, func(e *edge) { completed = append(completed, e) }, func(e *edge) {
+		assert.Fail(t, "no edge should expire")
+	})
+
+	require.NoError(t, s.upsertEdge("key", func(e *edge) { e.clientService = "client" }))
+	assert.Equal(t, 1, s.len())
+	assert.Empty(t, completed)
+
+	require.NoError(t, s.upsertEdge("key", func(e *edge) { e.serverService = "server" }))
+	assert.Equal(t, 0, s.len())
+	require.Len(t, completed, 1)
+	assert.Equal(t, "client", completed[0].clientService)
+	assert.Equal(t, "server", completed[0].serverService)
+}
+
+func TestStoreExpiresEdges(t *testing.T) {
+	var expired []*edge
+	s := newStore(time.Millisecond, 10, func(e *edge) {
+		assert.Fail(t, "no edge should be completed")
+	}, func(e *edge) { expired = append(expired, e) })
+
+	require.NoError(t, s.upsertEdge("key", func(e *edge) { e.clientService = "client" }))
+	time.Sleep(5 * time.Millisecond)
+	s.expire()
+
+	assert.Equal(t, 0, s.len())
+	require.Len(t, expired, 1)
+	assert.Equal(t, "client", expired[0].clientService)
+
+	// the counterpart arriving late starts a new edge
+	require.NoError(t, s.upsertEdge("key", func(e *edge) { e.serverService = "server" }))
+	assert.Equal(t, 1, s.len())
+}
+
+func TestStoreMaxItems(t *testing.T) {
+	var completed int
+	s := newStore(time.Hour, 1, func(e *edge) { completed++ }, func(e *edge) {})
+
+	require.NoError(t, s.upsertEdge("key-1", func(e *edge) { e.clientService = "client" }))
+	assert.ErrorIs(t, s.upsertEdge("key-2", func(e *edge) { e.clientService = "client" }), errTooManyItems)
+
+	// existing edges can still be completed
+	require.NoError(t, s.upsertEdge("key-1", func(e *edge) { e.serverService = "server" }))
+	assert.Equal(t, 1, completed)
+	assert.Equal(t, 0, s.len())
+}
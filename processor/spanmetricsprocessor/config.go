@@ -42,7 +42,26 @@ type Config struct {
 	// - operation
 	// - span.kind
 	// - status.code
-	// The dimensions will be fetched from the span's attributes. Examples of some conventionally used attributes:
+	// The dimensions will be fetched from the span's attributes or, when missing from them, from the resource's
+	// attributes. Examples of some conventionally used attributes:
 	// https://github.com/open-telemetry/opentelemetry-collector/blob/main/translator/conventions/opentelemetry.go.
 	Dimensions []Dimension `mapstructure:"dimensions"`
+
+	// DimensionsCacheSize defines the maximum number of metric series, and their dimensions, kept in memory.
+	// When the cache is full, the least recently updated series is evicted.
+	// Optional. The default, 0, means that the number of series isn't limited.
+	DimensionsCacheSize int `mapstructure:"dimensions_cache_size"`
+
+	// MetricsExpiration defines the time after which a series that hasn't received any span is evicted, and
+	// is therefore no longer exported. The default value of 0 means that series never expire.
+	MetricsExpiration time.Duration `mapstructure:"metrics_expiration"`
+
+	// AggregationTemporality defines the aggregation temporality of the generated metrics. One of "cumulative"
+	// (default) or "delta". With "delta", the counts are reset after each export.
+	AggregationTemporality string `mapstructure:"aggregation_temporality"`
 }
+
+const (
+	cumulative = "cumulative"
+	delta      = "delta"
+)
@@ -24,6 +24,7 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/hashicorp/golang-lru/simplelru"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/consumer"
@@ -39,6 +40,10 @@ const (
 	spanKindKey        = tracetranslator.TagSpanKind
 	statusCodeKey      = tracetranslator.TagStatusCode
 	metricKeySeparator = string(byte(0))
+
+	// The labels holding the trace and span IDs of the latency exemplars.
+	exemplarTraceIDKey = "trace_id"
+	exemplarSpanIDKey  = "span_id"
 )
 
 var (
@@ -55,6 +60,21 @@ type dimKV map[string]string
 
 type metricKey string
 
+// exemplar is an example span for a latency histogram bucket.
+type exemplar struct {
+	traceID   pdata.TraceID
+	spanID    pdata.SpanID
+	timestamp pdata.Timestamp
+	latency   float64
+}
+
+// series holds the dimensions of a metric series, along with the times it was first and last seen.
+type series struct {
+	dimensions dimKV
+	startTime  time.Time
+	lastSeen   time.Time
+}
+
 type processorImp struct {
 	lock   sync.RWMutex
 	logger *zap.Logger
@@ -66,9 +86,12 @@ type processorImp struct {
 	// Additional dimensions to add to metrics.
 	dimensions []Dimension
 
-	// The starting time of the data points.
+	// The starting time of the data points. With delta temporality, this is the time of the last export.
 	startTime time.Time
 
+	// Whether the counts are reset after each export.
+	delta bool
+
 	// Call & Error counts.
 	callSum map[metricKey]int64
 
@@ -78,9 +101,14 @@ type processorImp struct {
 	latencyBucketCounts map[metricKey][]uint64
 	latencyBounds       []float64
 
-	// A cache of dimension key-value maps keyed by a unique identifier formed by a concatenation of its values:
+	// The most recent span for each latency histogram bucket since the last export, indexed as the bucket counts.
+	latencyExemplars map[metricKey][]*exemplar
+
+	// A LRU cache of series, holding the dimension key-value maps keyed by a unique identifier formed by a
+	// concatenation of its values:
 	// e.g. { "foo/barOK": { "serviceName": "foo", "operation": "/bar", "status_code": "OK" }}
-	metricKeyToDimensions map[metricKey]dimKV
+	// Evicting a series from the cache also discards its call and latency metrics.
+	metricKeyToDimensions *simplelru.LRU
 }
 
 func newProcessor(logger *zap.Logger, config config.Processor, nextConsumer consumer.Traces) (*processorImp, error) {
@@ -101,19 +129,47 @@ func newProcessor(logger *zap.Logger, config config.Processor, nextConsumer cons
 		return nil, err
 	}
 
-	return &processorImp{
-		logger:                logger,
-		config:                *pConfig,
-		startTime:             time.Now(),
-		callSum:               make(map[metricKey]int64),
-		latencyBounds:         bounds,
-		latencySum:            make(map[metricKey]float64),
-		latencyCount:          make(map[metricKey]uint64),
-		latencyBucketCounts:   make(map[metricKey][]uint64),
-		nextConsumer:          nextConsumer,
-		dimensions:            pConfig.Dimensions,
-		metricKeyToDimensions: make(map[metricKey]dimKV),
-	}, nil
+	var isDelta bool
+	switch pConfig.AggregationTemporality {
+	case "", cumulative:
+	case delta:
+		isDelta = true
+	default:
+		return nil, fmt.Errorf("invalid aggregation temporality %q, must be one of %q or %q",
+			pConfig.AggregationTemporality, cumulative, delta)
+	}
+
+	p := &processorImp{
+		logger:              logger,
+		config:              *pConfig,
+		startTime:           time.Now(),
+		delta:               isDelta,
+		callSum:             make(map[metricKey]int64),
+		latencyBounds:       bounds,
+		latencySum:          make(map[metricKey]float64),
+		latencyCount:        make(map[metricKey]uint64),
+		latencyBucketCounts: make(map[metricKey][]uint64),
+		latencyExemplars:    make(map[metricKey][]*exemplar),
+		nextConsumer:        nextConsumer,
+		dimensions:          pConfig.Dimensions,
+	}
+
+	cache, err := newSeriesCache(pConfig.DimensionsCacheSize, p.onEvict)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dimensions cache size %d: %w", pConfig.DimensionsCacheSize, err)
+	}
+	p.metricKeyToDimensions = cache
+
+	return p, nil
+}
+
+// newSeriesCache returns a cache holding at most size series, or an unbounded one when size is 0.
+func newSeriesCache(size int, onEvict simplelru.EvictCallback) (*simplelru.LRU, error) {
+	if size == 0 {
+		// the cache doesn't preallocate its entries, so the largest size doesn't cost more than a small one
+		size = math.MaxInt32
+	}
+	return simplelru.NewLRU(size, onEvict)
 }
 
 // durationToMillis converts the given duration to the number of milliseconds it represents.
@@ -228,32 +284,105 @@ func (p *processorImp) buildMetrics() *pdata.Metrics {
 	ilm := m.ResourceMetrics().AppendEmpty().InstrumentationLibraryMetrics().AppendEmpty()
 	ilm.InstrumentationLibrary().SetName("spanmetricsprocessor")
 
-	p.lock.RLock()
+	p.lock.Lock()
+	p.removeStaleSeries()
 	p.collectCallMetrics(ilm)
 	p.collectLatencyMetrics(ilm)
-	p.lock.RUnlock()
+	if p.delta {
+		p.resetAccumulatedMetrics()
+	}
+	p.latencyExemplars = make(map[metricKey][]*exemplar)
+	p.lock.Unlock()
 
 	return &m
 }
 
+// removeStaleSeries evicts the series that haven't been updated within the configured expiration.
+// As the cache is ordered by the last update, the stale series are the oldest ones.
+func (p *processorImp) removeStaleSeries() {
+	if p.config.MetricsExpiration <= 0 {
+		return
+	}
+
+	expiredBefore := time.Now().Add(-p.config.MetricsExpiration)
+	for {
+		_, v, ok := p.metricKeyToDimensions.GetOldest()
+		if !ok || !v.(*series).lastSeen.Before(expiredBefore) {
+			return
+		}
+		p.metricKeyToDimensions.RemoveOldest()
+	}
+}
+
+// resetAccumulatedMetrics discards the counts accumulated since the last export, so that the next export
+// only contains the spans received in the meantime.
+func (p *processorImp) resetAccumulatedMetrics() {
+	p.callSum = make(map[metricKey]int64)
+	p.latencyCount = make(map[metricKey]uint64)
+	p.latencySum = make(map[metricKey]float64)
+	p.latencyBucketCounts = make(map[metricKey][]uint64)
+	p.startTime = time.Now()
+}
+
+// onEvict discards the metrics of a series evicted from the cache.
+func (p *processorImp) onEvict(key interface{}, _ interface{}) {
+	k := key.(metricKey)
+	delete(p.callSum, k)
+	delete(p.latencyCount, k)
+	delete(p.latencySum, k)
+	delete(p.latencyBucketCounts, k)
+	delete(p.latencyExemplars, k)
+}
+
+// aggregationTemporality returns the aggregation temporality of the generated metrics.
+func (p *processorImp) aggregationTemporality() pdata.AggregationTemporality {
+	if p.delta {
+		return pdata.AggregationTemporalityDelta
+	}
+	return pdata.AggregationTemporalityCumulative
+}
+
+// seriesStartTime returns the start time of the data points for the given series.
+func (p *processorImp) seriesStartTime(s *series) time.Time {
+	if p.delta {
+		return p.startTime
+	}
+	return s.startTime
+}
+
 // collectLatencyMetrics collects the raw latency metrics, writing the data
 // into the given instrumentation library metrics.
 func (p *processorImp) collectLatencyMetrics(ilm pdata.InstrumentationLibraryMetrics) {
 	for key := range p.latencyCount {
+		s, ok := p.metricKeyToDimensions.Peek(key)
+		if !ok {
+			continue
+		}
+
 		mLatency := ilm.Metrics().AppendEmpty()
 		mLatency.SetDataType(pdata.MetricDataTypeHistogram)
 		mLatency.SetName("latency")
-		mLatency.Histogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+		mLatency.Histogram().SetAggregationTemporality(p.aggregationTemporality())
 
 		dpLatency := mLatency.Histogram().DataPoints().AppendEmpty()
-		dpLatency.SetStartTimestamp(pdata.TimestampFromTime(p.startTime))
+		dpLatency.SetStartTimestamp(pdata.TimestampFromTime(p.seriesStartTime(s.(*series))))
 		dpLatency.SetTimestamp(pdata.TimestampFromTime(time.Now()))
 		dpLatency.SetExplicitBounds(p.latencyBounds)
 		dpLatency.SetBucketCounts(p.latencyBucketCounts[key])
 		dpLatency.SetCount(p.latencyCount[key])
 		dpLatency.SetSum(p.latencySum[key])
+		for _, e := range p.latencyExemplars[key] {
+			if e == nil {
+				continue
+			}
+			ex := dpLatency.Exemplars().AppendEmpty()
+			ex.SetTimestamp(e.timestamp)
+			ex.SetDoubleVal(e.latency)
+			ex.FilteredLabels().Insert(exemplarTraceIDKey, e.traceID.HexString())
+			ex.FilteredLabels().Insert(exemplarSpanIDKey, e.spanID.HexString())
+		}
 
-		dpLatency.LabelsMap().InitFromMap(p.metricKeyToDimensions[key])
+		dpLatency.LabelsMap().InitFromMap(s.(*series).dimensions)
 	}
 }
 
@@ -261,18 +390,23 @@ func (p *processorImp) collectLatencyMetrics(ilm pdata.InstrumentationLibraryMet
 // into the given instrumentation library metrics.
 func (p *processorImp) collectCallMetrics(ilm pdata.InstrumentationLibraryMetrics) {
 	for key := range p.callSum {
+		s, ok := p.metricKeyToDimensions.Peek(key)
+		if !ok {
+			continue
+		}
+
 		mCalls := ilm.Metrics().AppendEmpty()
 		mCalls.SetDataType(pdata.MetricDataTypeSum)
 		mCalls.SetName("calls_total")
 		mCalls.Sum().SetIsMonotonic(true)
-		mCalls.Sum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+		mCalls.Sum().SetAggregationTemporality(p.aggregationTemporality())
 
 		dpCalls := mCalls.Sum().DataPoints().AppendEmpty()
-		dpCalls.SetStartTimestamp(pdata.TimestampFromTime(p.startTime))
+		dpCalls.SetStartTimestamp(pdata.TimestampFromTime(p.seriesStartTime(s.(*series))))
 		dpCalls.SetTimestamp(pdata.TimestampFromTime(time.Now()))
 		dpCalls.SetIntVal(p.callSum[key])
 
-		dpCalls.LabelsMap().InitFromMap(p.metricKeyToDimensions[key])
+		dpCalls.LabelsMap().InitFromMap(s.(*series).dimensions)
 	}
 }
 
@@ -290,34 +424,35 @@ func (p *processorImp) aggregateMetrics(traces pdata.Traces) {
 			continue
 		}
 		serviceName := attr.StringVal()
-		p.aggregateMetricsForServiceSpans(rspans, serviceName)
+		p.aggregateMetricsForServiceSpans(rspans, serviceName, r.Attributes())
 	}
 }
 
-func (p *processorImp) aggregateMetricsForServiceSpans(rspans pdata.ResourceSpans, serviceName string) {
+func (p *processorImp) aggregateMetricsForServiceSpans(rspans pdata.ResourceSpans, serviceName string, resourceAttr pdata.AttributeMap) {
 	ilsSlice := rspans.InstrumentationLibrarySpans()
 	for j := 0; j < ilsSlice.Len(); j++ {
 		ils := ilsSlice.At(j)
 		spans := ils.Spans()
 		for k := 0; k < spans.Len(); k++ {
 			span := spans.At(k)
-			p.aggregateMetricsForSpan(serviceName, span)
+			p.aggregateMetricsForSpan(serviceName, span, resourceAttr)
 		}
 	}
 }
 
-func (p *processorImp) aggregateMetricsForSpan(serviceName string, span pdata.Span) {
+func (p *processorImp) aggregateMetricsForSpan(serviceName string, span pdata.Span, resourceAttr pdata.AttributeMap) {
 	latencyInMilliseconds := float64(span.EndTimestamp()-span.StartTimestamp()) / float64(time.Millisecond.Nanoseconds())
 
 	// Binary search to find the latencyInMilliseconds bucket index.
 	index := sort.SearchFloat64s(p.latencyBounds, latencyInMilliseconds)
 
-	key := buildKey(serviceName, span, p.dimensions)
+	key := buildKey(serviceName, span, p.dimensions, resourceAttr)
 
 	p.lock.Lock()
-	p.cache(serviceName, span, key)
+	p.cache(serviceName, span, key, resourceAttr)
 	p.updateCallMetrics(key)
 	p.updateLatencyMetrics(key, latencyInMilliseconds, index)
+	p.updateLatencyExemplars(key, latencyInMilliseconds, index, span)
 	p.lock.Unlock()
 }
 
@@ -336,7 +471,35 @@ func (p *processorImp) updateLatencyMetrics(key metricKey, latency float64, inde
 	p.latencyBucketCounts[key][index]++
 }
 
-func buildDimensionKVs(serviceName string, span pdata.Span, optionalDims []Dimension) dimKV {
+// updateLatencyExemplars records the span as the exemplar of the given metric key and bucket index.
+func (p *processorImp) updateLatencyExemplars(key metricKey, latency float64, index int, span pdata.Span) {
+	if _, ok := p.latencyExemplars[key]; !ok {
+		p.latencyExemplars[key] = make([]*exemplar, len(p.latencyBounds))
+	}
+	p.latencyExemplars[key][index] = &exemplar{
+		traceID:   span.TraceID(),
+		spanID:    span.SpanID(),
+		timestamp: span.EndTimestamp(),
+		latency:   latency,
+	}
+}
+
+// getDimensionValue gets the value of the given dimension, looked up in the span's attributes first, then in
+// the resource's attributes. The dimension's default is used when the attribute is found in neither of them.
+func getDimensionValue(d Dimension, spanAttr pdata.AttributeMap, resourceAttr pdata.AttributeMap) (string, bool) {
+	if attr, ok := spanAttr.Get(d.Name); ok {
+		return tracetranslator.AttributeValueToString(attr), true
+	}
+	if attr, ok := resourceAttr.Get(d.Name); ok {
+		return tracetranslator.AttributeValueToString(attr), true
+	}
+	if d.Default != nil {
+		return *d.Default, true
+	}
+	return "", false
+}
+
+func buildDimensionKVs(serviceName string, span pdata.Span, optionalDims []Dimension, resourceAttr pdata.AttributeMap) dimKV {
 	dims := make(dimKV)
 	dims[serviceNameKey] = serviceName
 	dims[operationKey] = span.Name()
@@ -344,11 +507,9 @@ func buildDimensionKVs(serviceName string, span pdata.Span, optionalDims []Dimen
 	dims[statusCodeKey] = span.Status().Code().String()
 	spanAttr := span.Attributes()
 	for _, d := range optionalDims {
-		if attr, ok := spanAttr.Get(d.Name); ok {
-			dims[d.Name] = tracetranslator.AttributeValueToString(attr)
-		} else if d.Default != nil {
-			// Set the default if configured, otherwise this metric should have no value set for the dimension.
-			dims[d.Name] = *d.Default
+		// Set the default if configured, otherwise this metric should have no value set for the dimension.
+		if value, ok := getDimensionValue(d, spanAttr, resourceAttr); ok {
+			dims[d.Name] = value
 		}
 	}
 	return dims
@@ -366,7 +527,7 @@ func concatDimensionValue(metricKeyBuilder *strings.Builder, value string, prefi
 // buildKey builds the metric key from the service name and span metadata such as operation, kind, status_code and
 // any additional dimensions the user has configured.
 // The metric key is a simple concatenation of dimension values.
-func buildKey(serviceName string, span pdata.Span, optionalDims []Dimension) metricKey {
+func buildKey(serviceName string, span pdata.Span, optionalDims []Dimension, resourceAttr pdata.AttributeMap) metricKey {
 	var metricKeyBuilder strings.Builder
 	concatDimensionValue(&metricKeyBuilder, serviceName, false)
 	concatDimensionValue(&metricKeyBuilder, span.Name(), true)
@@ -374,15 +535,9 @@ func buildKey(serviceName string, span pdata.Span, optionalDims []Dimension) met
 	concatDimensionValue(&metricKeyBuilder, span.Status().Code().String(), true)
 
 	spanAttr := span.Attributes()
-	var value string
 	for _, d := range optionalDims {
 		// Set the default if configured, otherwise this metric will have no value set for the dimension.
-		if d.Default != nil {
-			value = *d.Default
-		}
-		if attr, ok := spanAttr.Get(d.Name); ok {
-			value = tracetranslator.AttributeValueToString(attr)
-		}
+		value, _ := getDimensionValue(d, spanAttr, resourceAttr)
 		concatDimensionValue(&metricKeyBuilder, value, true)
 	}
 
@@ -390,13 +545,20 @@ func buildKey(serviceName string, span pdata.Span, optionalDims []Dimension) met
 	return k
 }
 
-// cache the dimension key-value map for the metricKey if there is a cache miss.
-// This enables a lookup of the dimension key-value map when constructing the metric like so:
-//   LabelsMap().InitFromMap(p.metricKeyToDimensions[key])
-func (p *processorImp) cache(serviceName string, span pdata.Span, k metricKey) {
-	if _, ok := p.metricKeyToDimensions[k]; !ok {
-		p.metricKeyToDimensions[k] = buildDimensionKVs(serviceName, span, p.dimensions)
+// cache the dimension key-value map for the metricKey if there is a cache miss, and marks the series as
+// recently updated otherwise. This enables a lookup of the dimension key-value map when constructing the metric.
+// Caching a new series might evict the least recently updated one when the cache is full.
+func (p *processorImp) cache(serviceName string, span pdata.Span, k metricKey, resourceAttr pdata.AttributeMap) {
+	now := time.Now()
+	if s, ok := p.metricKeyToDimensions.Get(k); ok {
+		s.(*series).lastSeen = now
+		return
 	}
+	p.metricKeyToDimensions.Add(k, &series{
+		dimensions: buildDimensionKVs(serviceName, span, p.dimensions, resourceAttr),
+		startTime:  now,
+		lastSeen:   now,
+	})
 }
 
 // copied from prometheus-go-metric-exporter
@@ -17,9 +17,11 @@ package spanmetricsprocessor
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/golang-lru/simplelru"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -52,6 +54,11 @@ const (
 	sampleLatencyDuration = time.Duration(sampleLatency) * time.Millisecond
 )
 
+var (
+	sampleTraceID = pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	sampleSpanID  = pdata.NewSpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+)
+
 // metricID represents the minimum attributes that uniquely identifies a metric in our tests.
 type metricID struct {
 	service    string
@@ -257,13 +264,162 @@ func TestMetricKeyCache(t *testing.T) {
 	// Validate
 	require.NoError(t, err)
 
-	origKeyCache := make(map[metricKey]dimKV)
-	for k, v := range p.metricKeyToDimensions {
-		origKeyCache[k] = v
-	}
+	origKeys := p.metricKeyToDimensions.Keys()
 	err = p.ConsumeTraces(ctx, traces)
 	require.NoError(t, err)
-	assert.Equal(t, origKeyCache, p.metricKeyToDimensions)
+	assert.Equal(t, origKeys, p.metricKeyToDimensions.Keys())
+}
+
+func TestDimensionsCacheIsBounded(t *testing.T) {
+	// Prepare
+	mexp := &mocks.MetricsExporter{}
+	tcon := &mocks.TracesConsumer{}
+
+	var exported []pdata.Metrics
+	mexp.On("ConsumeMetrics", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		exported = append(exported, args.Get(1).(pdata.Metrics))
+	}).Return(nil)
+	tcon.On("ConsumeTraces", mock.Anything, mock.Anything).Return(nil)
+
+	p := newProcessorImp(mexp, tcon, nil)
+	p.metricKeyToDimensions, _ = simplelru.NewLRU(2, p.onEvict)
+
+	// Test
+	ctx := metadata.NewIncomingContext(context.Background(), nil)
+	err := p.ConsumeTraces(ctx, buildSampleTrace())
+
+	// Verify
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.metricKeyToDimensions.Len())
+	assert.Len(t, p.callSum, 2)
+	assert.Len(t, p.latencyCount, 2)
+	assert.Len(t, p.latencySum, 2)
+	assert.Len(t, p.latencyBucketCounts, 2)
+	require.Len(t, exported, 1)
+	assert.Equal(t, 4, exported[0].MetricCount())
+}
+
+func TestStaleSeriesAreRemoved(t *testing.T) {
+	// Prepare
+	mexp := &mocks.MetricsExporter{}
+	tcon := &mocks.TracesConsumer{}
+
+	var exported []pdata.Metrics
+	mexp.On("ConsumeMetrics", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		exported = append(exported, args.Get(1).(pdata.Metrics))
+	}).Return(nil)
+	tcon.On("ConsumeTraces", mock.Anything, mock.Anything).Return(nil)
+
+	p := newProcessorImp(mexp, tcon, nil)
+	p.config.MetricsExpiration = time.Minute
+
+	ctx := metadata.NewIncomingContext(context.Background(), nil)
+	require.NoError(t, p.ConsumeTraces(ctx, buildSampleTrace()))
+	require.Equal(t, 3, p.metricKeyToDimensions.Len())
+
+	// Make the existing series stale.
+	for _, k := range p.metricKeyToDimensions.Keys() {
+		s, _ := p.metricKeyToDimensions.Peek(k)
+		s.(*series).lastSeen = time.Now().Add(-2 * time.Minute)
+	}
+
+	// Test
+	require.NoError(t, p.ConsumeTraces(ctx, pdata.NewTraces()))
+
+	// Verify
+	assert.Equal(t, 0, p.metricKeyToDimensions.Len())
+	assert.Empty(t, p.callSum)
+	assert.Empty(t, p.latencyCount)
+	require.Len(t, exported, 2)
+	assert.Equal(t, 0, exported[1].MetricCount())
+}
+
+func TestDeltaAggregationTemporality(t *testing.T) {
+	// Prepare
+	mexp := &mocks.MetricsExporter{}
+	tcon := &mocks.TracesConsumer{}
+
+	var exported []pdata.Metrics
+	mexp.On("ConsumeMetrics", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		exported = append(exported, args.Get(1).(pdata.Metrics))
+	}).Return(nil)
+	tcon.On("ConsumeTraces", mock.Anything, mock.Anything).Return(nil)
+
+	p := newProcessorImp(mexp, tcon, nil)
+	p.delta = true
+
+	ctx := metadata.NewIncomingContext(context.Background(), nil)
+
+	// Test
+	require.NoError(t, p.ConsumeTraces(ctx, buildSampleTrace()))
+	require.NoError(t, p.ConsumeTraces(ctx, buildSampleTrace()))
+	require.NoError(t, p.ConsumeTraces(ctx, pdata.NewTraces()))
+
+	// Verify
+	require.Len(t, exported, 3)
+	for _, md := range exported[:2] {
+		require.Equal(t, 6, md.MetricCount())
+		metrics := md.ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+		for i := 0; i < metrics.Len(); i++ {
+			m := metrics.At(i)
+			switch m.DataType() {
+			case pdata.MetricDataTypeSum:
+				assert.Equal(t, pdata.AggregationTemporalityDelta, m.Sum().AggregationTemporality())
+				assert.Equal(t, int64(1), m.Sum().DataPoints().At(0).IntVal(), "counts should be reset after each export")
+			case pdata.MetricDataTypeHistogram:
+				assert.Equal(t, pdata.AggregationTemporalityDelta, m.Histogram().AggregationTemporality())
+				assert.Equal(t, uint64(1), m.Histogram().DataPoints().At(0).Count(), "counts should be reset after each export")
+			}
+		}
+	}
+	assert.Equal(t, 0, exported[2].MetricCount(), "no spans were received since the last export")
+	assert.Equal(t, 3, p.metricKeyToDimensions.Len(), "dimensions should be kept across exports")
+}
+
+func TestProcessorInvalidSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(cfg *Config)
+	}{
+		{
+			name:   "invalid aggregation temporality",
+			modify: func(cfg *Config) { cfg.AggregationTemporality = "monthly" },
+		},
+		{
+			name:   "invalid dimensions cache size",
+			modify: func(cfg *Config) { cfg.DimensionsCacheSize = -1 },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Prepare
+			factory := NewFactory()
+			cfg := factory.CreateDefaultConfig().(*Config)
+			tc.modify(cfg)
+
+			// Test
+			p, err := newProcessor(zap.NewNop(), cfg, new(consumertest.TracesSink))
+
+			// Verify
+			assert.Error(t, err)
+			assert.Nil(t, p)
+		})
+	}
+}
+
+func TestDimensionsCacheUnboundedByDefault(t *testing.T) {
+	// Prepare
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	p, err := newProcessor(zap.NewNop(), cfg, new(consumertest.TracesSink))
+	require.NoError(t, err)
+
+	// Test
+	for i := 0; i < 2000; i++ {
+		p.metricKeyToDimensions.Add(metricKey(fmt.Sprintf("key-%d", i)), &series{})
+	}
+
+	// Verify
+	assert.Equal(t, 2000, p.metricKeyToDimensions.Len())
 }
 
 func BenchmarkProcessorConsumeTraces(b *testing.B) {
@@ -288,7 +444,7 @@ func BenchmarkProcessorConsumeTraces(b *testing.B) {
 
 func newProcessorImp(mexp *mocks.MetricsExporter, tcon *mocks.TracesConsumer, defaultNullValue *string) *processorImp {
 	defaultNotInSpanAttrVal := "defaultNotInSpanAttrVal"
-	return &processorImp{
+	p := &processorImp{
 		logger:          zap.NewNop(),
 		metricsExporter: mexp,
 		nextConsumer:    tcon,
@@ -299,6 +455,7 @@ func newProcessorImp(mexp *mocks.MetricsExporter, tcon *mocks.TracesConsumer, de
 		latencyCount:        make(map[metricKey]uint64),
 		latencyBucketCounts: make(map[metricKey][]uint64),
 		latencyBounds:       defaultLatencyHistogramBucketsMs,
+		latencyExemplars:    make(map[metricKey][]*exemplar),
 		dimensions: []Dimension{
 			// Set nil defaults to force a lookup for the attribute in the span.
 			{stringAttrName, nil},
@@ -313,8 +470,9 @@ func newProcessorImp(mexp *mocks.MetricsExporter, tcon *mocks.TracesConsumer, de
 			// Leave the default value unset to test that this dimension should not be added to the metric.
 			{notInSpanAttrName1, nil},
 		},
-		metricKeyToDimensions: make(map[metricKey]dimKV),
 	}
+	p.metricKeyToDimensions, _ = newSeriesCache(0, p.onEvict)
+	return p
 }
 
 // verifyConsumeMetricsInput verifies the input of the ConsumeMetrics call from this processor.
@@ -388,6 +546,17 @@ func verifyConsumeMetricsInput(input pdata.Metrics, t *testing.T) bool {
 			}
 			assert.Equal(t, wantBucketCount, dp.BucketCounts()[bi])
 		}
+
+		// Verify the exemplar of the span, found in the same bucket.
+		require.Equal(t, 1, dp.Exemplars().Len())
+		exemplar := dp.Exemplars().At(0)
+		assert.Equal(t, sampleLatency, exemplar.DoubleVal())
+		assert.NotZero(t, exemplar.Timestamp())
+		traceID, _ := exemplar.FilteredLabels().Get(exemplarTraceIDKey)
+		assert.Equal(t, sampleTraceID.HexString(), traceID)
+		spanID, _ := exemplar.FilteredLabels().Get(exemplarSpanIDKey)
+		assert.Equal(t, sampleSpanID.HexString(), spanID)
+
 		verifyMetricLabels(dp, t, seenMetricIDs)
 	}
 	return true
@@ -483,6 +652,8 @@ func initServiceSpans(serviceSpans serviceSpans, spans pdata.ResourceSpans) {
 func initSpan(span span, s pdata.Span) {
 	s.SetName(span.operation)
 	s.SetKind(span.kind)
+	s.SetTraceID(sampleTraceID)
+	s.SetSpanID(sampleSpanID)
 	s.Status().SetCode(span.statusCode)
 	now := time.Now()
 	s.SetStartTimestamp(pdata.TimestampFromTime(now))
@@ -515,15 +686,83 @@ func newOTLPExporters(t *testing.T) (*otlpexporter.Config, component.MetricsExpo
 func TestBuildKey(t *testing.T) {
 	span0 := pdata.NewSpan()
 	span0.SetName("c")
-	k0 := buildKey("ab", span0, nil)
+	k0 := buildKey("ab", span0, nil, pdata.NewAttributeMap())
 
 	span1 := pdata.NewSpan()
 	span1.SetName("bc")
-	k1 := buildKey("a", span1, nil)
+	k1 := buildKey("a", span1, nil, pdata.NewAttributeMap())
 
 	assert.NotEqual(t, k0, k1)
 }
 
+func TestBuildKeyWithResourceAttributes(t *testing.T) {
+	defaultEnv := "unknown"
+	dims := []Dimension{
+		{Name: "deployment.environment", Default: &defaultEnv},
+		{Name: "k8s.namespace.name"},
+	}
+
+	span := pdata.NewSpan()
+	span.SetName("c")
+	resourceAttr := pdata.NewAttributeMap()
+	resourceAttr.InsertString("deployment.environment", "production")
+	resourceAttr.InsertString("k8s.namespace.name", "default")
+
+	// Resource attributes are used when missing from the span.
+	assert.Equal(t,
+		metricKey(strings.Join([]string{"a", "c", "SPAN_KIND_UNSPECIFIED", "STATUS_CODE_UNSET", "production", "default"}, metricKeySeparator)),
+		buildKey("a", span, dims, resourceAttr),
+	)
+	assert.Equal(t,
+		dimKV{
+			serviceNameKey:           "a",
+			operationKey:             "c",
+			spanKindKey:              "SPAN_KIND_UNSPECIFIED",
+			statusCodeKey:            "STATUS_CODE_UNSET",
+			"deployment.environment": "production",
+			"k8s.namespace.name":     "default",
+		},
+		buildDimensionKVs("a", span, dims, resourceAttr),
+	)
+
+	// Span attributes take precedence over resource attributes.
+	span.Attributes().InsertString("k8s.namespace.name", "kube-system")
+	assert.Equal(t, "kube-system", buildDimensionKVs("a", span, dims, resourceAttr)["k8s.namespace.name"])
+
+	// The default is used when the attribute is missing from both.
+	assert.Equal(t, "unknown", buildDimensionKVs("a", span, dims, pdata.NewAttributeMap())["deployment.environment"])
+}
+
+func TestExemplarsAreResetAfterExport(t *testing.T) {
+	// Prepare
+	mexp := &mocks.MetricsExporter{}
+	tcon := &mocks.TracesConsumer{}
+
+	var exported []pdata.Metrics
+	mexp.On("ConsumeMetrics", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		exported = append(exported, args.Get(1).(pdata.Metrics))
+	}).Return(nil)
+	tcon.On("ConsumeTraces", mock.Anything, mock.Anything).Return(nil)
+
+	p := newProcessorImp(mexp, tcon, nil)
+	ctx := metadata.NewIncomingContext(context.Background(), nil)
+
+	// Test
+	require.NoError(t, p.ConsumeTraces(ctx, buildSampleTrace()))
+	require.NoError(t, p.ConsumeTraces(ctx, pdata.NewTraces()))
+
+	// Verify
+	require.Len(t, exported, 2)
+	for i, wantExemplars := range []int{1, 0} {
+		metrics := exported[i].ResourceMetrics().At(0).InstrumentationLibraryMetrics().At(0).Metrics()
+		for j := 0; j < metrics.Len(); j++ {
+			if metrics.At(j).DataType() == pdata.MetricDataTypeHistogram {
+				assert.Equal(t, wantExemplars, metrics.At(j).Histogram().DataPoints().At(0).Exemplars().Len())
+			}
+		}
+	}
+}
+
 func TestProcessorDuplicateDimensions(t *testing.T) {
 	// Prepare
 	factory := NewFactory()
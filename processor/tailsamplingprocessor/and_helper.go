@@ -0,0 +1,48 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsamplingprocessor
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+var errEmptyAndPolicy = errors.New("and policy must have at least one sub-policy")
+
+func getNewAndPolicy(logger *zap.Logger, config *AndCfg) (sampling.PolicyEvaluator, error) {
+	if len(config.SubPolicyCfg) == 0 {
+		return nil, errEmptyAndPolicy
+	}
+	var subPolicyEvaluators []sampling.PolicyEvaluator
+	for i := range config.SubPolicyCfg {
+		policy, err := getAndSubPolicyEvaluator(logger, &config.SubPolicyCfg[i])
+		if err != nil {
+			return nil, err
+		}
+		subPolicyEvaluators = append(subPolicyEvaluators, policy)
+	}
+	return sampling.NewAnd(logger, subPolicyEvaluators), nil
+}
+
+// getAndSubPolicyEvaluator returns the evaluator of a policy nested in an and policy.
+func getAndSubPolicyEvaluator(logger *zap.Logger, cfg *AndSubPolicyCfg) (sampling.PolicyEvaluator, error) {
+	if cfg.Type == Not {
+		return getNewNotPolicy(logger, &cfg.NotCfg)
+	}
+	return getSharedPolicyEvaluator(logger, &cfg.sharedPolicyCfg)
+}
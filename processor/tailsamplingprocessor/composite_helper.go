@@ -0,0 +1,99 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsamplingprocessor
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+func getNewCompositePolicy(logger *zap.Logger, config *CompositeCfg) (sampling.PolicyEvaluator, error) {
+	if err := validateRateAllocation(config.RateAllocation); err != nil {
+		return nil, err
+	}
+	rateAllocationsMap := getRateAllocationMap(config)
+	var subPolicyEvalParams []sampling.SubPolicyEvalParams
+	for i := range config.SubPolicyCfg {
+		policyCfg := &config.SubPolicyCfg[i]
+		policy, err := getCompositeSubPolicyEvaluator(logger, policyCfg)
+		if err != nil {
+			return nil, err
+		}
+		subPolicyEvalParams = append(subPolicyEvalParams, sampling.SubPolicyEvalParams{
+			Evaluator:         policy,
+			MaxSpansPerSecond: rateAllocationsMap[policyCfg.Name],
+		})
+	}
+	return sampling.NewComposite(logger, config.MaxTotalSpansPerSecond, subPolicyEvalParams, sampling.MonotonicClock{}), nil
+}
+
+// getRateAllocationMap returns the spans per second allocated to each sub-policy. Sub-policies
+// without an explicit allocation equally share the rate left by the explicit allocations.
+// validateRateAllocation checks that each percentage is between 0 and 100, and that
+// they don't add up to more than 100.
+func validateRateAllocation(rateAllocation []RateAllocationCfg) error {
+	total := int64(0)
+	for _, rAlloc := range rateAllocation {
+		if rAlloc.Percent < 0 || rAlloc.Percent > 100 {
+			return fmt.Errorf("invalid rate allocation for policy %q: percent must be between 0 and 100, got %d", rAlloc.Policy, rAlloc.Percent)
+		}
+		total += rAlloc.Percent
+	}
+	if total > 100 {
+		return errors.New("invalid rate allocation: the percentages add up to more than 100")
+	}
+	return nil
+}
+
+func getRateAllocationMap(config *CompositeCfg) map[string]int64 {
+	rateAllocationsMap := make(map[string]int64)
+	maxTotalSPS := config.MaxTotalSpansPerSecond
+	allocatedSPS := int64(0)
+	for _, rAlloc := range config.RateAllocation {
+		sps := rAlloc.Percent * maxTotalSPS / 100
+		rateAllocationsMap[rAlloc.Policy] = sps
+		allocatedSPS += sps
+	}
+
+	var unallocated []string
+	for _, policyCfg := range config.SubPolicyCfg {
+		if _, ok := rateAllocationsMap[policyCfg.Name]; !ok {
+			unallocated = append(unallocated, policyCfg.Name)
+		}
+	}
+	if len(unallocated) > 0 && allocatedSPS < maxTotalSPS {
+		defaultSPS := (maxTotalSPS - allocatedSPS) / int64(len(unallocated))
+		for _, name := range unallocated {
+			rateAllocationsMap[name] = defaultSPS
+		}
+	}
+	return rateAllocationsMap
+}
+
+// getCompositeSubPolicyEvaluator returns the evaluator of a policy nested in a composite policy.
+func getCompositeSubPolicyEvaluator(logger *zap.Logger, cfg *CompositeSubPolicyCfg) (sampling.PolicyEvaluator, error) {
+	switch cfg.Type {
+	case And:
+		return getNewAndPolicy(logger, &cfg.AndCfg)
+	case Not:
+		return getNewNotPolicy(logger, &cfg.NotCfg)
+	default:
+		return getSharedPolicyEvaluator(logger, &cfg.sharedPolicyCfg)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsamplingprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCompositeHelper(t *testing.T) {
+	cfg := &CompositeCfg{
+		MaxTotalSpansPerSecond: 1000,
+		SubPolicyCfg: []CompositeSubPolicyCfg{
+			{
+				sharedPolicyCfg: sharedPolicyCfg{
+					Name:       "test-composite-policy-1",
+					Type:       Latency,
+					LatencyCfg: LatencyCfg{ThresholdMs: 100},
+				},
+			},
+			{
+				sharedPolicyCfg: sharedPolicyCfg{
+					Name: "test-composite-policy-2",
+					Type: And,
+				},
+				AndCfg: AndCfg{
+					SubPolicyCfg: []AndSubPolicyCfg{
+						{
+							sharedPolicyCfg: sharedPolicyCfg{
+								Name:          "test-and-policy-1",
+								Type:          StatusCode,
+								StatusCodeCfg: StatusCodeCfg{StatusCodes: []string{"ERROR"}},
+							},
+						},
+					},
+				},
+			},
+			{
+				sharedPolicyCfg: sharedPolicyCfg{
+					Name: "test-composite-policy-3",
+					Type: AlwaysSample,
+				},
+			},
+		},
+		RateAllocation: []RateAllocationCfg{
+			{
+				Policy:  "test-composite-policy-1",
+				Percent: 50,
+			},
+		},
+	}
+
+	assert.Equal(t, map[string]int64{
+		"test-composite-policy-1": 500,
+		"test-composite-policy-2": 250,
+		"test-composite-policy-3": 250,
+	}, getRateAllocationMap(cfg))
+
+	evaluator, err := getNewCompositePolicy(zap.NewNop(), cfg)
+	require.NoError(t, err)
+	assert.NotNil(t, evaluator)
+}
+
+func TestCompositeHelperUnknownSubPolicy(t *testing.T) {
+	cfg := &CompositeCfg{
+		MaxTotalSpansPerSecond: 1000,
+		SubPolicyCfg: []CompositeSubPolicyCfg{
+			{
+				sharedPolicyCfg: sharedPolicyCfg{
+					Name: "test-composite-policy-1",
+					Type: "unknown",
+				},
+			},
+		},
+	}
+
+	_, err := getNewCompositePolicy(zap.NewNop(), cfg)
+	assert.EqualError(t, err, "unknown sampling policy type unknown")
+}
+
+func TestCompositeHelperInvalidRateAllocation(t *testing.T) {
+	tests := []struct {
+		name           string
+		rateAllocation []RateAllocationCfg
+		wantErr        string
+	}{
+		{
+			name:           "negative percent",
+			rateAllocation: []RateAllocationCfg{{Policy: "test-composite-policy-1", Percent: -10}},
+			wantErr:        `invalid rate allocation for policy "test-composite-policy-1": percent must be between 0 and 100, got -10`,
+		},
+		{
+			name:           "percent above 100",
+			rateAllocation: []RateAllocationCfg{{Policy: "test-composite-policy-1", Percent: 150}},
+			wantErr:        `invalid rate allocation for policy "test-composite-policy-1": percent must be between 0 and 100, got 150`,
+		},
+		{
+			name: "total above 100",
+			rateAllocation: []RateAllocationCfg{
+				{Policy: "test-composite-policy-1", Percent: 60},
+				{Policy: "test-composite-policy-2", Percent: 50},
+			},
+			wantErr: "invalid rate allocation: the percentages add up to more than 100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &CompositeCfg{
+				MaxTotalSpansPerSecond: 1000,
+				SubPolicyCfg: []CompositeSubPolicyCfg{
+					{
+						sharedPolicyCfg: sharedPolicyCfg{
+							Name: "test-composite-policy-1",
+							Type: AlwaysSample,
+						},
+					},
+					{
+						sharedPolicyCfg: sharedPolicyCfg{
+							Name: "test-composite-policy-2",
+							Type: AlwaysSample,
+						},
+					},
+				},
+				RateAllocation: tt.rateAllocation,
+			}
+
+			_, err := getNewCompositePolicy(zap.NewNop(), cfg)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCompositeHelperEmptyAndSubPolicy(t *testing.T) {
+	cfg := &CompositeCfg{
+		MaxTotalSpansPerSecond: 1000,
+		SubPolicyCfg: []CompositeSubPolicyCfg{
+			{
+				sharedPolicyCfg: sharedPolicyCfg{
+					Name: "test-composite-policy-1",
+					Type: And,
+				},
+			},
+		},
+	}
+
+	_, err := getNewCompositePolicy(zap.NewNop(), cfg)
+	assert.Equal(t, errEmptyAndPolicy, err)
+}
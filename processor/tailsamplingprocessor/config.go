@@ -38,10 +38,23 @@ const (
 	StringAttribute PolicyType = "string_attribute"
 	// RateLimiting allows all traces until the specified limits are satisfied.
 	RateLimiting PolicyType = "rate_limiting"
+	// Probabilistic samples a given percentage of traces, based on a hash of their trace ID.
+	Probabilistic PolicyType = "probabilistic"
+	// SpanCount sample traces that have a number of spans in a specified range.
+	SpanCount PolicyType = "span_count"
+	// And samples traces for which all of the listed sub-policies decide to sample.
+	And PolicyType = "and"
+	// Not inverts the decision of its sub-policy, i.e.: samples traces that the
+	// sub-policy does not sample and vice versa.
+	Not PolicyType = "not"
+	// Composite evaluates a list of sub-policies in order and samples a trace once
+	// a sub-policy decides to sample it, within the rate allocated to that sub-policy.
+	Composite PolicyType = "composite"
 )
 
-// PolicyCfg holds the common configuration to all policies.
-type PolicyCfg struct {
+// sharedPolicyCfg holds the configuration common to top-level policies and the
+// policies nested in the and, not and composite policies.
+type sharedPolicyCfg struct {
 	// Name given to the instance of the policy to make easy to identify it in metrics and logs.
 	Name string `mapstructure:"name"`
 	// Type of the policy this will be used to match the proper configuration of the policy.
@@ -56,6 +69,42 @@ type PolicyCfg struct {
 	StringAttributeCfg StringAttributeCfg `mapstructure:"string_attribute"`
 	// Configs for rate limiting filter sampling policy evaluator.
 	RateLimitingCfg RateLimitingCfg `mapstructure:"rate_limiting"`
+	// Configs for probabilistic sampling policy evaluator.
+	ProbabilisticCfg ProbabilisticCfg `mapstructure:"probabilistic"`
+	// Configs for span count filter sampling policy evaluator.
+	SpanCountCfg SpanCountCfg `mapstructure:"span_count"`
+}
+
+// NotSubPolicyCfg holds the configuration of the policy inverted by a not policy.
+type NotSubPolicyCfg struct {
+	sharedPolicyCfg `mapstructure:",squash"`
+}
+
+// AndSubPolicyCfg holds the configuration of a policy that is part of an and policy.
+type AndSubPolicyCfg struct {
+	sharedPolicyCfg `mapstructure:",squash"`
+	// Configs for not sampling policy evaluator.
+	NotCfg NotCfg `mapstructure:"not"`
+}
+
+// CompositeSubPolicyCfg holds the configuration of a policy that is part of a composite policy.
+type CompositeSubPolicyCfg struct {
+	sharedPolicyCfg `mapstructure:",squash"`
+	// Configs for and sampling policy evaluator.
+	AndCfg AndCfg `mapstructure:"and"`
+	// Configs for not sampling policy evaluator.
+	NotCfg NotCfg `mapstructure:"not"`
+}
+
+// PolicyCfg holds the common configuration to all policies.
+type PolicyCfg struct {
+	sharedPolicyCfg `mapstructure:",squash"`
+	// Configs for and sampling policy evaluator.
+	AndCfg AndCfg `mapstructure:"and"`
+	// Configs for not sampling policy evaluator.
+	NotCfg NotCfg `mapstructure:"not"`
+	// Configs for composite sampling policy evaluator.
+	CompositeCfg CompositeCfg `mapstructure:"composite"`
 }
 
 // LatencyCfg holds the configurable settings to create a latency filter sampling policy
@@ -105,6 +154,62 @@ type RateLimitingCfg struct {
 	SpansPerSecond int64 `mapstructure:"spans_per_second"`
 }
 
+// ProbabilisticCfg holds the configurable settings to create a probabilistic
+// sampling policy evaluator.
+type ProbabilisticCfg struct {
+	// HashSalt allows one to configure the hashing salts. This is important in scenarios where multiple layers of collectors
+	// have different sampling rates: if they use the same salt all passing one layer may pass the other even if they have
+	// different sampling rates, configuring different salts avoids that.
+	HashSalt string `mapstructure:"hash_salt"`
+	// SamplingPercentage is the percentage rate at which traces are going to be sampled. Defaults to zero, i.e.: no sample.
+	// Values greater or equal 100 are treated as "sample all traces".
+	SamplingPercentage float64 `mapstructure:"sampling_percentage"`
+}
+
+// SpanCountCfg holds the configurable settings to create a span count filter
+// sampling policy evaluator.
+type SpanCountCfg struct {
+	// MinSpans is the minimum number of spans a trace must have to be sampled.
+	MinSpans int64 `mapstructure:"min_spans"`
+	// MaxSpans is the maximum number of spans a trace may have to be sampled, zero means no limit.
+	MaxSpans int64 `mapstructure:"max_spans"`
+}
+
+// AndCfg holds the configurable settings to create an and sampling policy evaluator.
+type AndCfg struct {
+	// SubPolicyCfg lists the policies that must all decide to sample a trace. They are
+	// evaluated in order and the evaluation stops at the first one not sampling the trace.
+	SubPolicyCfg []AndSubPolicyCfg `mapstructure:"policies"`
+}
+
+// NotCfg holds the configurable settings to create a not sampling policy evaluator.
+type NotCfg struct {
+	// SubPolicyCfg is the policy whose decision is inverted.
+	SubPolicyCfg NotSubPolicyCfg `mapstructure:"policy"`
+}
+
+// CompositeCfg holds the configurable settings to create a composite sampling
+// policy evaluator.
+type CompositeCfg struct {
+	// MaxTotalSpansPerSecond is the limit on the number of spans sampled each second
+	// across all sub-policies.
+	MaxTotalSpansPerSecond int64 `mapstructure:"max_total_spans_per_second"`
+	// SubPolicyCfg lists the policies evaluated, in order, by the composite policy.
+	SubPolicyCfg []CompositeSubPolicyCfg `mapstructure:"policies"`
+	// RateAllocation sets the share of MaxTotalSpansPerSecond given to each sub-policy.
+	// Sub-policies without an allocation share the rate not allocated to other sub-policies.
+	RateAllocation []RateAllocationCfg `mapstructure:"rate_allocation"`
+}
+
+// RateAllocationCfg holds the share of the composite policy rate given to one
+// of its sub-policies.
+type RateAllocationCfg struct {
+	// Policy is the name of the sub-policy.
+	Policy string `mapstructure:"policy"`
+	// Percent of MaxTotalSpansPerSecond allocated to the sub-policy.
+	Percent int64 `mapstructure:"percent"`
+}
+
 // Config holds the configuration for tail-based sampling.
 type Config struct {
 	config.ProcessorSettings `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct
@@ -120,4 +225,9 @@ type Config struct {
 	// PolicyCfgs sets the tail-based sampling policy which makes a sampling decision
 	// for a given trace when requested.
 	PolicyCfgs []PolicyCfg `mapstructure:"policies"`
+	// Storage is the name of the storage extension, e.g. "file_storage", used to store the
+	// spans of the traces waiting for a sampling decision. When set, the spans are written to
+	// the storage once per second instead of being kept in memory until the decision, and the
+	// traces waiting for a decision are restored when the processor is restarted.
+	Storage string `mapstructure:"storage"`
 }
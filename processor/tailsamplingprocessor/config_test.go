@@ -45,33 +45,153 @@ func TestLoadConfig(t *testing.T) {
 			ExpectedNewTracesPerSec: 10,
 			PolicyCfgs: []PolicyCfg{
 				{
-					Name: "test-policy-1",
-					Type: AlwaysSample,
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name: "test-policy-1",
+						Type: AlwaysSample,
+					},
 				},
 				{
-					Name:       "test-policy-2",
-					Type:       Latency,
-					LatencyCfg: LatencyCfg{ThresholdMs: 5000},
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name:       "test-policy-2",
+						Type:       Latency,
+						LatencyCfg: LatencyCfg{ThresholdMs: 5000},
+					},
 				},
 				{
-					Name:                "test-policy-3",
-					Type:                NumericAttribute,
-					NumericAttributeCfg: NumericAttributeCfg{Key: "key1", MinValue: 50, MaxValue: 100},
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name:                "test-policy-3",
+						Type:                NumericAttribute,
+						NumericAttributeCfg: NumericAttributeCfg{Key: "key1", MinValue: 50, MaxValue: 100},
+					},
 				},
 				{
-					Name:          "test-policy-4",
-					Type:          StatusCode,
-					StatusCodeCfg: StatusCodeCfg{StatusCodes: []string{"ERROR", "UNSET"}},
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name:          "test-policy-4",
+						Type:          StatusCode,
+						StatusCodeCfg: StatusCodeCfg{StatusCodes: []string{"ERROR", "UNSET"}},
+					},
 				},
 				{
-					Name:               "test-policy-5",
-					Type:               StringAttribute,
-					StringAttributeCfg: StringAttributeCfg{Key: "key2", Values: []string{"value1", "value2"}},
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name:               "test-policy-5",
+						Type:               StringAttribute,
+						StringAttributeCfg: StringAttributeCfg{Key: "key2", Values: []string{"value1", "value2"}},
+					},
 				},
 				{
-					Name:            "test-policy-6",
-					Type:            RateLimiting,
-					RateLimitingCfg: RateLimitingCfg{SpansPerSecond: 35},
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name:            "test-policy-6",
+						Type:            RateLimiting,
+						RateLimitingCfg: RateLimitingCfg{SpansPerSecond: 35},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name: "test-policy-7",
+						Type: And,
+					},
+					AndCfg: AndCfg{
+						SubPolicyCfg: []AndSubPolicyCfg{
+							{
+								sharedPolicyCfg: sharedPolicyCfg{
+									Name:          "test-and-policy-1",
+									Type:          StatusCode,
+									StatusCodeCfg: StatusCodeCfg{StatusCodes: []string{"ERROR"}},
+								},
+							},
+							{
+								sharedPolicyCfg: sharedPolicyCfg{
+									Name:               "test-and-policy-2",
+									Type:               StringAttribute,
+									StringAttributeCfg: StringAttributeCfg{Key: "service.name", Values: []string{"checkout"}},
+								},
+							},
+						},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name: "test-policy-8",
+						Type: Not,
+					},
+					NotCfg: NotCfg{
+						SubPolicyCfg: NotSubPolicyCfg{
+							sharedPolicyCfg: sharedPolicyCfg{
+								Name:               "test-not-policy-1",
+								Type:               StringAttribute,
+								StringAttributeCfg: StringAttributeCfg{Key: "http.target", Values: []string{"/health"}},
+							},
+						},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name: "test-policy-9",
+						Type: Composite,
+					},
+					CompositeCfg: CompositeCfg{
+						MaxTotalSpansPerSecond: 1000,
+						SubPolicyCfg: []CompositeSubPolicyCfg{
+							{
+								sharedPolicyCfg: sharedPolicyCfg{
+									Name: "test-composite-policy-1",
+									Type: And,
+								},
+								AndCfg: AndCfg{
+									SubPolicyCfg: []AndSubPolicyCfg{
+										{
+											sharedPolicyCfg: sharedPolicyCfg{
+												Name:       "test-and-policy-1",
+												Type:       Latency,
+												LatencyCfg: LatencyCfg{ThresholdMs: 5000},
+											},
+										},
+										{
+											sharedPolicyCfg: sharedPolicyCfg{
+												Name: "test-and-policy-2",
+												Type: Not,
+											},
+											NotCfg: NotCfg{
+												SubPolicyCfg: NotSubPolicyCfg{
+													sharedPolicyCfg: sharedPolicyCfg{
+														Name:               "test-not-policy-1",
+														Type:               StringAttribute,
+														StringAttributeCfg: StringAttributeCfg{Key: "http.target", Values: []string{"/health"}},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+							{
+								sharedPolicyCfg: sharedPolicyCfg{
+									Name: "test-composite-policy-2",
+									Type: AlwaysSample,
+								},
+							},
+						},
+						RateAllocation: []RateAllocationCfg{
+							{
+								Policy:  "test-composite-policy-1",
+								Percent: 90,
+							},
+						},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name:             "test-policy-10",
+						Type:             Probabilistic,
+						ProbabilisticCfg: ProbabilisticCfg{HashSalt: "custom-salt", SamplingPercentage: 10},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name:         "test-policy-11",
+						Type:         SpanCount,
+						SpanCountCfg: SpanCountCfg{MinSpans: 2, MaxSpans: 20},
+					},
 				},
 			},
 		})
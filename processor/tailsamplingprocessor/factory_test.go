@@ -38,8 +38,10 @@ func TestCreateProcessor(t *testing.T) {
 	cfg.ExpectedNewTracesPerSec = 64
 	cfg.PolicyCfgs = []PolicyCfg{
 		{
-			Name: "test-policy",
-			Type: AlwaysSample,
+			sharedPolicyCfg: sharedPolicyCfg{
+				Name: "test-policy",
+				Type: AlwaysSample,
+			},
 		},
 	}
 
@@ -28,6 +28,8 @@ var (
 	ErrInvalidNumBatches = errors.New("invalid number of batches, it must be greater than zero")
 	// ErrInvalidBatchChannelSize occurs when an invalid batch channel size is specified.
 	ErrInvalidBatchChannelSize = errors.New("invalid batch channel size, it must be greater than zero")
+	// ErrTooManyInitialBatches occurs when more initial batches than the number of batches are specified.
+	ErrTooManyInitialBatches = errors.New("invalid initial batches, there can't be more than the number of batches")
 )
 
 // Batch is the type of batches held by the Batcher.
@@ -75,19 +77,33 @@ type batcher struct {
 // batchChannelSize to receive new items. New batches will be created with capacity set to
 // newBatchesInitialCapacity.
 func New(numBatches, newBatchesInitialCapacity, batchChannelSize uint64) (Batcher, error) {
+	return NewWithBatches(nil, numBatches, newBatchesInitialCapacity, batchChannelSize)
+}
+
+// NewWithBatches creates a Batcher like New, but with the given batches at the front of its
+// pipeline, e.g.: to restore the batches of a previous Batcher. The first of the given batches
+// is the first one returned by CloseCurrentAndTakeFirstBatch. At most numBatches batches can
+// be given.
+func NewWithBatches(initialBatches []Batch, numBatches, newBatchesInitialCapacity, batchChannelSize uint64) (Batcher, error) {
 	if numBatches < 1 {
 		return nil, ErrInvalidNumBatches
 	}
 	if batchChannelSize < 1 {
 		return nil, ErrInvalidBatchChannelSize
 	}
+	if uint64(len(initialBatches)) > numBatches {
+		return nil, ErrTooManyInitialBatches
+	}
 
 	batches := make(chan Batch, numBatches)
-	// First numBatches batches will be empty in order to simplify clients that are running
+	for _, batch := range initialBatches {
+		batches <- batch
+	}
+	// Remaining batches will be empty in order to simplify clients that are running
 	// CloseCurrentAndTakeFirstBatch on a timer and want to delay the processing of the first
 	// batch with actual data. This way there is no need for accounting on the client side and
 	// a single timer can be started immediately.
-	for i := uint64(0); i < numBatches; i++ {
+	for i := uint64(len(initialBatches)); i < numBatches; i++ {
 		batches <- nil
 	}
 
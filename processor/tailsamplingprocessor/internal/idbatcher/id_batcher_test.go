@@ -52,6 +52,27 @@ func TestBatcherNew(t *testing.T) {
 	}
 }
 
+func TestBatcherNewWithBatches(t *testing.T) {
+	ids := generateSequentialIds(3)
+	initialBatches := []Batch{{ids[0]}, nil, {ids[1], ids[2]}}
+
+	_, err := NewWithBatches(initialBatches, 2, 0, 1)
+	require.Equal(t, ErrTooManyInitialBatches, err)
+
+	batcher, err := NewWithBatches(initialBatches, 4, 0, 1)
+	require.NoError(t, err)
+	defer batcher.Stop()
+
+	for _, want := range initialBatches {
+		got, ok := batcher.CloseCurrentAndTakeFirstBatch()
+		require.True(t, ok)
+		require.Equal(t, want, got)
+	}
+	got, ok := batcher.CloseCurrentAndTakeFirstBatch()
+	require.True(t, ok)
+	require.Nil(t, got)
+}
+
 func TestTypicalConfig(t *testing.T) {
 	concurrencyTest(t, 10, 100, uint64(4*runtime.NumCPU()))
 }
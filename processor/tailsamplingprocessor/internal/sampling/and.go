@@ -0,0 +1,67 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+)
+
+type and struct {
+	subpolicies []PolicyEvaluator
+	logger      *zap.Logger
+}
+
+var _ PolicyEvaluator = (*and)(nil)
+
+// NewAnd creates a policy evaluator that samples traces for which all the given
+// sub-policies decide to sample.
+func NewAnd(logger *zap.Logger, subpolicies []PolicyEvaluator) PolicyEvaluator {
+	return &and{
+		subpolicies: subpolicies,
+		logger:      logger,
+	}
+}
+
+// OnLateArrivingSpans notifies the evaluator that the given list of spans arrived
+// after the sampling decision was already taken for the trace.
+// This gives the evaluator a chance to log any message/metrics and/or update any
+// related internal state.
+func (a *and) OnLateArrivingSpans(earlyDecision Decision, spans []*pdata.Span) error {
+	a.logger.Debug("Triggering action for late arriving spans in and filter")
+	for _, sub := range a.subpolicies {
+		if err := sub.OnLateArrivingSpans(earlyDecision, spans); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+// The sub-policies are evaluated in order and the evaluation stops at the first
+// one that doesn't sample the trace.
+func (a *and) Evaluate(traceID pdata.TraceID, trace *TraceData) (Decision, error) {
+	a.logger.Debug("Evaluating spans in and filter")
+	for _, sub := range a.subpolicies {
+		decision, err := sub.Evaluate(traceID, trace)
+		if err != nil {
+			return Unspecified, err
+		}
+		if decision != Sampled {
+			return NotSampled, nil
+		}
+	}
+	return Sampled, nil
+}
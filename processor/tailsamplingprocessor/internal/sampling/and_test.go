@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+)
+
+func TestEvaluate_And(t *testing.T) {
+	traceID := pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	trace := newTraceStringAttrs(map[string]pdata.AttributeValue{"service.name": pdata.NewAttributeValueString("checkout")}, "http.target", "/cart")
+
+	cases := []struct {
+		Desc        string
+		Subpolicies []PolicyEvaluator
+		Decision    Decision
+	}{
+		{
+			Desc: "all sub-policies sample",
+			Subpolicies: []PolicyEvaluator{
+				NewStringAttributeFilter(zap.NewNop(), "service.name", []string{"checkout"}, false, 0),
+				NewStringAttributeFilter(zap.NewNop(), "http.target", []string{"/cart"}, false, 0),
+			},
+			Decision: Sampled,
+		},
+		{
+			Desc: "one sub-policy does not sample",
+			Subpolicies: []PolicyEvaluator{
+				NewStringAttributeFilter(zap.NewNop(), "service.name", []string{"checkout"}, false, 0),
+				NewStringAttributeFilter(zap.NewNop(), "http.target", []string{"/health"}, false, 0),
+			},
+			Decision: NotSampled,
+		},
+		{
+			Desc: "no sub-policy samples",
+			Subpolicies: []PolicyEvaluator{
+				NewStringAttributeFilter(zap.NewNop(), "service.name", []string{"frontend"}, false, 0),
+				NewStringAttributeFilter(zap.NewNop(), "http.target", []string{"/health"}, false, 0),
+			},
+			Decision: NotSampled,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Desc, func(t *testing.T) {
+			and := NewAnd(zap.NewNop(), c.Subpolicies)
+			decision, err := and.Evaluate(traceID, trace)
+			assert.NoError(t, err)
+			assert.Equal(t, c.Decision, decision)
+		})
+	}
+}
+
+func TestEvaluate_AndStopsAtFirstNotSampled(t *testing.T) {
+	traceID := pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	trace := newTraceStringAttrs(map[string]pdata.AttributeValue{}, "http.target", "/cart")
+	trace.SpanCount = 1
+
+	rateLimiter := NewRateLimiting(zap.NewNop(), 2)
+	and := NewAnd(zap.NewNop(), []PolicyEvaluator{
+		NewStringAttributeFilter(zap.NewNop(), "http.target", []string{"/health"}, false, 0),
+		rateLimiter,
+	})
+
+	decision, err := and.Evaluate(traceID, trace)
+	assert.NoError(t, err)
+	assert.Equal(t, NotSampled, decision)
+
+	// the rate limiter has not been charged by the evaluation above
+	decision, err = rateLimiter.Evaluate(traceID, trace)
+	assert.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+}
+
+func TestOnLateArrivingSpans_And(t *testing.T) {
+	and := NewAnd(zap.NewNop(), []PolicyEvaluator{NewAlwaysSample(zap.NewNop())})
+	err := and.OnLateArrivingSpans(Sampled, nil)
+	assert.Nil(t, err)
+}
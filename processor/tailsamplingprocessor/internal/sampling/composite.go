@@ -0,0 +1,139 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+)
+
+// SubPolicyEvalParams defines the evaluator and the rate allocated to a
+// sub-policy of the composite policy.
+type SubPolicyEvalParams struct {
+	// Evaluator of the sub-policy.
+	Evaluator PolicyEvaluator
+	// MaxSpansPerSecond is the maximum number of spans the sub-policy can sample each second.
+	MaxSpansPerSecond int64
+}
+
+// TimeProvider provides the current time in seconds, it allows the time used by
+// the composite policy to be controlled in tests.
+type TimeProvider interface {
+	CurrentSecond() int64
+}
+
+// MonotonicClock is a TimeProvider based on the wall clock.
+type MonotonicClock struct{}
+
+// CurrentSecond returns the current Unix time in seconds.
+func (MonotonicClock) CurrentSecond() int64 {
+	return time.Now().Unix()
+}
+
+type subpolicy struct {
+	evaluator    PolicyEvaluator
+	allocatedSPS int64
+	sampledSPS   int64
+}
+
+type composite struct {
+	subpolicies  []*subpolicy
+	maxTotalSPS  int64
+	logger       *zap.Logger
+	timeProvider TimeProvider
+
+	currentSecond        int64
+	spansInCurrentSecond int64
+}
+
+var _ PolicyEvaluator = (*composite)(nil)
+
+// NewComposite creates a policy evaluator that samples traces according to the first
+// sub-policy deciding to sample them, within the rate allocated to that sub-policy
+// and the overall maxTotalSPS rate.
+func NewComposite(logger *zap.Logger, maxTotalSPS int64, subPolicyParams []SubPolicyEvalParams, timeProvider TimeProvider) PolicyEvaluator {
+	var subpolicies []*subpolicy
+	for _, params := range subPolicyParams {
+		subpolicies = append(subpolicies, &subpolicy{
+			evaluator:    params.Evaluator,
+			allocatedSPS: params.MaxSpansPerSecond,
+		})
+	}
+
+	return &composite{
+		subpolicies:  subpolicies,
+		maxTotalSPS:  maxTotalSPS,
+		logger:       logger,
+		timeProvider: timeProvider,
+	}
+}
+
+// OnLateArrivingSpans notifies the evaluator that the given list of spans arrived
+// after the sampling decision was already taken for the trace.
+// This gives the evaluator a chance to log any message/metrics and/or update any
+// related internal state.
+func (c *composite) OnLateArrivingSpans(earlyDecision Decision, spans []*pdata.Span) error {
+	c.logger.Debug("Triggering action for late arriving spans in composite filter")
+	for _, sub := range c.subpolicies {
+		if err := sub.evaluator.OnLateArrivingSpans(earlyDecision, spans); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+//
+// Sub-policies are evaluated in order until one of them decides to sample the trace.
+// The trace is then sampled if the spans sampled by that sub-policy and by the composite
+// policy as a whole during the current second stay within their allocated rates.
+// The counters restart at the beginning of each second.
+func (c *composite) Evaluate(traceID pdata.TraceID, trace *TraceData) (Decision, error) {
+	c.logger.Debug("Evaluating spans in composite filter")
+	currSecond := c.timeProvider.CurrentSecond()
+	if c.currentSecond != currSecond {
+		c.currentSecond = currSecond
+		c.spansInCurrentSecond = 0
+		for _, sub := range c.subpolicies {
+			sub.sampledSPS = 0
+		}
+	}
+
+	for _, sub := range c.subpolicies {
+		decision, err := sub.evaluator.Evaluate(traceID, trace)
+		if err != nil {
+			return Unspecified, err
+		}
+		if decision != Sampled {
+			continue
+		}
+
+		subSPSIfSampled := sub.sampledSPS + trace.SpanCount
+		totalSPSIfSampled := c.spansInCurrentSecond + trace.SpanCount
+		if subSPSIfSampled <= sub.allocatedSPS && totalSPSIfSampled <= c.maxTotalSPS {
+			sub.sampledSPS = subSPSIfSampled
+			c.spansInCurrentSecond = totalSPSIfSampled
+			return Sampled, nil
+		}
+
+		// The rate allocated to the sub-policy is exhausted. The counters are left
+		// untouched so that smaller traces may still be sampled during this second.
+		return NotSampled, nil
+	}
+
+	return NotSampled, nil
+}
@@ -0,0 +1,147 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+)
+
+type fakeTimeProvider struct {
+	second int64
+}
+
+func (f *fakeTimeProvider) CurrentSecond() int64 {
+	return f.second
+}
+
+var traceIDForComposite = pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+
+func newTraceWithSpanCount(spanAttrValue string, spanCount int64) *TraceData {
+	trace := newTraceStringAttrs(map[string]pdata.AttributeValue{}, "http.target", spanAttrValue)
+	trace.SpanCount = spanCount
+	return trace
+}
+
+func newTestComposite(timeProvider TimeProvider) PolicyEvaluator {
+	return NewComposite(zap.NewNop(), 10, []SubPolicyEvalParams{
+		{
+			Evaluator:         NewStringAttributeFilter(zap.NewNop(), "http.target", []string{"/checkout"}, false, 0),
+			MaxSpansPerSecond: 8,
+		},
+		{
+			Evaluator:         NewAlwaysSample(zap.NewNop()),
+			MaxSpansPerSecond: 2,
+		},
+	}, timeProvider)
+}
+
+func TestEvaluate_CompositeFirstMatchingSubPolicy(t *testing.T) {
+	composite := newTestComposite(&fakeTimeProvider{second: 1})
+
+	// sampled by the first sub-policy within its allocation
+	decision, err := composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/checkout", 5))
+	assert.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+
+	// sampled by the second sub-policy within its allocation
+	decision, err = composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/cart", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+
+	// the second sub-policy exhausted its allocation
+	decision, err = composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/cart", 1))
+	assert.NoError(t, err)
+	assert.Equal(t, NotSampled, decision)
+
+	// the first sub-policy still has room
+	decision, err = composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/checkout", 3))
+	assert.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+}
+
+func TestEvaluate_CompositeRateExceeded(t *testing.T) {
+	composite := newTestComposite(&fakeTimeProvider{second: 1})
+
+	decision, err := composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/checkout", 9))
+	assert.NoError(t, err)
+	assert.Equal(t, NotSampled, decision)
+
+	// a smaller trace may still be sampled in the same second
+	decision, err = composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/checkout", 8))
+	assert.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+}
+
+func TestEvaluate_CompositeMaxTotalSpansPerSecond(t *testing.T) {
+	composite := NewComposite(zap.NewNop(), 5, []SubPolicyEvalParams{
+		{
+			Evaluator:         NewStringAttributeFilter(zap.NewNop(), "http.target", []string{"/checkout"}, false, 0),
+			MaxSpansPerSecond: 5,
+		},
+		{
+			Evaluator:         NewAlwaysSample(zap.NewNop()),
+			MaxSpansPerSecond: 5,
+		},
+	}, &fakeTimeProvider{second: 1})
+
+	decision, err := composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/checkout", 4))
+	assert.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+
+	decision, err = composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/cart", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, NotSampled, decision)
+}
+
+func TestEvaluate_CompositeResetsEverySecond(t *testing.T) {
+	timeProvider := &fakeTimeProvider{second: 1}
+	composite := newTestComposite(timeProvider)
+
+	decision, err := composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/cart", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+
+	decision, err = composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/cart", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, NotSampled, decision)
+
+	timeProvider.second = 2
+	decision, err = composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/cart", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+}
+
+func TestEvaluate_CompositeNoSubPolicySamples(t *testing.T) {
+	composite := NewComposite(zap.NewNop(), 10, []SubPolicyEvalParams{
+		{
+			Evaluator:         NewStringAttributeFilter(zap.NewNop(), "http.target", []string{"/checkout"}, false, 0),
+			MaxSpansPerSecond: 10,
+		},
+	}, &fakeTimeProvider{second: 1})
+
+	decision, err := composite.Evaluate(traceIDForComposite, newTraceWithSpanCount("/cart", 1))
+	assert.NoError(t, err)
+	assert.Equal(t, NotSampled, decision)
+}
+
+func TestOnLateArrivingSpans_Composite(t *testing.T) {
+	composite := newTestComposite(MonotonicClock{})
+	err := composite.OnLateArrivingSpans(Sampled, nil)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+)
+
+type not struct {
+	subpolicy PolicyEvaluator
+	logger    *zap.Logger
+}
+
+var _ PolicyEvaluator = (*not)(nil)
+
+// NewNot creates a policy evaluator that inverts the decision of the given sub-policy.
+func NewNot(logger *zap.Logger, subpolicy PolicyEvaluator) PolicyEvaluator {
+	return &not{
+		subpolicy: subpolicy,
+		logger:    logger,
+	}
+}
+
+// OnLateArrivingSpans notifies the evaluator that the given list of spans arrived
+// after the sampling decision was already taken for the trace.
+// This gives the evaluator a chance to log any message/metrics and/or update any
+// related internal state.
+func (n *not) OnLateArrivingSpans(earlyDecision Decision, spans []*pdata.Span) error {
+	n.logger.Debug("Triggering action for late arriving spans in not filter")
+	return n.subpolicy.OnLateArrivingSpans(invert(earlyDecision), spans)
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+func (n *not) Evaluate(traceID pdata.TraceID, trace *TraceData) (Decision, error) {
+	n.logger.Debug("Evaluating spans in not filter")
+	decision, err := n.subpolicy.Evaluate(traceID, trace)
+	if err != nil {
+		return Unspecified, err
+	}
+	return invert(decision), nil
+}
+
+// invert swaps the Sampled and NotSampled decisions, any other decision is returned as is.
+func invert(decision Decision) Decision {
+	switch decision {
+	case Sampled:
+		return NotSampled
+	case NotSampled:
+		return Sampled
+	default:
+		return decision
+	}
+}
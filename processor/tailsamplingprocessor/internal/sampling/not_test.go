@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+)
+
+func TestEvaluate_Not(t *testing.T) {
+	traceID := pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+
+	cases := []struct {
+		Desc     string
+		Trace    *TraceData
+		Decision Decision
+	}{
+		{
+			Desc:     "sub-policy samples",
+			Trace:    newTraceStringAttrs(map[string]pdata.AttributeValue{}, "http.target", "/health"),
+			Decision: NotSampled,
+		},
+		{
+			Desc:     "sub-policy does not sample",
+			Trace:    newTraceStringAttrs(map[string]pdata.AttributeValue{}, "http.target", "/cart"),
+			Decision: Sampled,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Desc, func(t *testing.T) {
+			not := NewNot(zap.NewNop(), NewStringAttributeFilter(zap.NewNop(), "http.target", []string{"/health"}, false, 0))
+			decision, err := not.Evaluate(traceID, c.Trace)
+			assert.NoError(t, err)
+			assert.Equal(t, c.Decision, decision)
+		})
+	}
+}
+
+func TestEvaluate_NotPropagatesError(t *testing.T) {
+	traceID := pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	not := NewNot(zap.NewNop(), NewAnd(zap.NewNop(), []PolicyEvaluator{&erroringPolicy{}}))
+	decision, err := not.Evaluate(traceID, &TraceData{})
+	assert.Error(t, err)
+	assert.Equal(t, Unspecified, decision)
+}
+
+func TestOnLateArrivingSpans_Not(t *testing.T) {
+	not := NewNot(zap.NewNop(), NewAlwaysSample(zap.NewNop()))
+	err := not.OnLateArrivingSpans(NotSampled, nil)
+	assert.Nil(t, err)
+}
+
+type erroringPolicy struct{}
+
+func (*erroringPolicy) OnLateArrivingSpans(Decision, []*pdata.Span) error {
+	return nil
+}
+
+func (*erroringPolicy) Evaluate(pdata.TraceID, *TraceData) (Decision, error) {
+	return Unspecified, errors.New("evaluation failed")
+}
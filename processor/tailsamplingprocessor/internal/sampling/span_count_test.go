@@ -0,0 +1,83 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+)
+
+func TestEvaluate_SpanCount(t *testing.T) {
+	traceID := pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+
+	cases := []struct {
+		Desc      string
+		MinSpans  int64
+		MaxSpans  int64
+		SpanCount int64
+		Decision  Decision
+	}{
+		{
+			Desc:      "span count below min",
+			MinSpans:  3,
+			SpanCount: 2,
+			Decision:  NotSampled,
+		},
+		{
+			Desc:      "span count equal to min",
+			MinSpans:  3,
+			SpanCount: 3,
+			Decision:  Sampled,
+		},
+		{
+			Desc:      "span count without upper limit",
+			MinSpans:  3,
+			SpanCount: 1000,
+			Decision:  Sampled,
+		},
+		{
+			Desc:      "span count equal to max",
+			MinSpans:  3,
+			MaxSpans:  10,
+			SpanCount: 10,
+			Decision:  Sampled,
+		},
+		{
+			Desc:      "span count above max",
+			MinSpans:  3,
+			MaxSpans:  10,
+			SpanCount: 11,
+			Decision:  NotSampled,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Desc, func(t *testing.T) {
+			filter := NewSpanCount(zap.NewNop(), c.MinSpans, c.MaxSpans)
+			decision, err := filter.Evaluate(traceID, &TraceData{SpanCount: c.SpanCount})
+			assert.NoError(t, err)
+			assert.Equal(t, c.Decision, decision)
+		})
+	}
+}
+
+func TestOnLateArrivingSpans_SpanCount(t *testing.T) {
+	filter := NewSpanCount(zap.NewNop(), 1, 0)
+	err := filter.OnLateArrivingSpans(NotSampled, nil)
+	assert.Nil(t, err)
+}
@@ -26,10 +26,12 @@ import (
 	"go.opencensus.io/tag"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenterror"
+	"go.opentelemetry.io/collector/config"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/model/pdata"
 	"go.uber.org/zap"
 
+	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/storage/storagehelper"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/idbatcher"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
 )
@@ -59,6 +61,14 @@ type tailSamplingSpanProcessor struct {
 	decisionBatcher idbatcher.Batcher
 	deleteChan      chan pdata.TraceID
 	numTracesOnMap  uint64
+
+	id                      config.ComponentID
+	decisionWait            time.Duration
+	expectedNewTracesPerSec uint64
+	// storageName is the name of the storage extension used to store the traces waiting
+	// for a decision, if any.
+	storageName  string
+	traceStorage *traceStorage
 }
 
 const (
@@ -72,8 +82,7 @@ func newTracesProcessor(logger *zap.Logger, nextConsumer consumer.Traces, cfg Co
 		return nil, componenterror.ErrNilNextConsumer
 	}
 
-	numDecisionBatches := uint64(cfg.DecisionWait.Seconds())
-	inBatcher, err := idbatcher.New(numDecisionBatches, cfg.ExpectedNewTracesPerSec, uint64(2*runtime.NumCPU()))
+	inBatcher, err := idbatcher.New(numDecisionBatches(cfg.DecisionWait), cfg.ExpectedNewTracesPerSec, uint64(2*runtime.NumCPU()))
 	if err != nil {
 		return nil, err
 	}
@@ -99,12 +108,16 @@ func newTracesProcessor(logger *zap.Logger, nextConsumer consumer.Traces, cfg Co
 	}
 
 	tsp := &tailSamplingSpanProcessor{
-		ctx:             ctx,
-		nextConsumer:    nextConsumer,
-		maxNumTraces:    cfg.NumTraces,
-		logger:          logger,
-		decisionBatcher: inBatcher,
-		policies:        policies,
+		ctx:                     ctx,
+		nextConsumer:            nextConsumer,
+		maxNumTraces:            cfg.NumTraces,
+		logger:                  logger,
+		decisionBatcher:         inBatcher,
+		policies:                policies,
+		id:                      cfg.ID(),
+		decisionWait:            cfg.DecisionWait,
+		expectedNewTracesPerSec: cfg.ExpectedNewTracesPerSec,
+		storageName:             cfg.Storage,
 	}
 
 	tsp.policyTicker = &policyTicker{onTickFunc: tsp.samplingPolicyOnTick}
@@ -113,7 +126,26 @@ func newTracesProcessor(logger *zap.Logger, nextConsumer consumer.Traces, cfg Co
 	return tsp, nil
 }
 
+// numDecisionBatches returns the number of batches held by the decision batcher, one per
+// second of decision wait.
+func numDecisionBatches(decisionWait time.Duration) uint64 {
+	return uint64(decisionWait.Seconds())
+}
+
 func getPolicyEvaluator(logger *zap.Logger, cfg *PolicyCfg) (sampling.PolicyEvaluator, error) {
+	switch cfg.Type {
+	case And:
+		return getNewAndPolicy(logger, &cfg.AndCfg)
+	case Not:
+		return getNewNotPolicy(logger, &cfg.NotCfg)
+	case Composite:
+		return getNewCompositePolicy(logger, &cfg.CompositeCfg)
+	default:
+		return getSharedPolicyEvaluator(logger, &cfg.sharedPolicyCfg)
+	}
+}
+
+func getSharedPolicyEvaluator(logger *zap.Logger, cfg *sharedPolicyCfg) (sampling.PolicyEvaluator, error) {
 	switch cfg.Type {
 	case AlwaysSample:
 		return sampling.NewAlwaysSample(logger), nil
@@ -132,6 +164,12 @@ func getPolicyEvaluator(logger *zap.Logger, cfg *PolicyCfg) (sampling.PolicyEval
 	case RateLimiting:
 		rlfCfg := cfg.RateLimitingCfg
 		return sampling.NewRateLimiting(logger, rlfCfg.SpansPerSecond), nil
+	case Probabilistic:
+		pCfg := cfg.ProbabilisticCfg
+		return sampling.NewProbabilisticSampler(logger, pCfg.HashSalt, pCfg.SamplingPercentage), nil
+	case SpanCount:
+		scCfg := cfg.SpanCountCfg
+		return sampling.NewSpanCount(logger, scCfg.MinSpans, scCfg.MaxSpans), nil
 	default:
 		return nil, fmt.Errorf("unknown sampling policy type %s", cfg.Type)
 	}
@@ -157,6 +195,14 @@ func (tsp *tailSamplingSpanProcessor) samplingPolicyOnTick() {
 		trace := d.(*sampling.TraceData)
 		trace.DecisionTime = time.Now()
 
+		if tsp.traceStorage != nil {
+			if err := tsp.traceStorage.restoreBatches(tsp.ctx, id, trace); err != nil {
+				tsp.logger.Warn("Error restoring stored spans of the trace, making decision on the spans in memory",
+					zap.String("traceID", id.HexString()),
+					zap.Error(err))
+			}
+		}
+
 		decision, policy := tsp.makeDecision(id, trace, &metrics)
 
 		// Sampled or not, remove the batches
@@ -179,6 +225,12 @@ func (tsp *tailSamplingSpanProcessor) samplingPolicyOnTick() {
 		}
 	}
 
+	if tsp.traceStorage != nil {
+		if err := tsp.traceStorage.flush(tsp.ctx, &tsp.idToTrace); err != nil {
+			tsp.logger.Warn("Error storing the traces waiting for a decision", zap.Error(err))
+		}
+	}
+
 	stats.Record(tsp.ctx,
 		statOverallDecisionLatencyUs.M(int64(time.Since(startTime)/time.Microsecond)),
 		statDroppedTooEarlyCount.M(metrics.idNotFoundOnMapCount),
@@ -360,13 +412,90 @@ func (tsp *tailSamplingSpanProcessor) Capabilities() consumer.Capabilities {
 }
 
 // Start is invoked during service startup.
-func (tsp *tailSamplingSpanProcessor) Start(context.Context, component.Host) error {
+func (tsp *tailSamplingSpanProcessor) Start(ctx context.Context, host component.Host) error {
+	if tsp.storageName == "" {
+		return nil
+	}
+
+	client, err := storagehelper.GetClient(ctx, host, tsp.storageName, component.KindProcessor, tsp.id)
+	if err != nil {
+		return err
+	}
+	tsp.traceStorage = newTraceStorage(tsp.logger, client)
+	return tsp.restoreTraces(ctx)
+}
+
+// restoreTraces puts back in memory the traces that were waiting for a decision when the
+// processor was last shutdown, and schedules their decision according to their arrival time.
+func (tsp *tailSamplingSpanProcessor) restoreTraces(ctx context.Context) error {
+	entries, err := tsp.traceStorage.loadIndex(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load the stored traces: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	numBatches := numDecisionBatches(tsp.decisionWait)
+	batches := make([]idbatcher.Batch, numBatches)
+	now := time.Now()
+	var restored int
+	for _, entry := range entries {
+		id, err := parseTraceID(entry.TraceID)
+		if err != nil {
+			return fmt.Errorf("failed to load the stored traces: %w", err)
+		}
+		if uint64(restored) >= tsp.maxNumTraces {
+			tsp.traceStorage.delete(id)
+			continue
+		}
+
+		decisions := make([]sampling.Decision, len(tsp.policies))
+		for i := range decisions {
+			decisions[i] = sampling.Pending
+		}
+		tsp.idToTrace.Store(id, &sampling.TraceData{
+			Decisions:   decisions,
+			ArrivalTime: entry.ArrivalTime,
+			SpanCount:   entry.SpanCount,
+		})
+		tsp.deleteChan <- id
+		restored++
+
+		// The decision is taken on the tick following the batch, traces that already
+		// waited for longer than the decision wait are decided on the next tick.
+		slot := int64((tsp.decisionWait - now.Sub(entry.ArrivalTime)) / time.Second)
+		if slot < 0 {
+			slot = 0
+		} else if slot >= int64(numBatches) {
+			slot = int64(numBatches) - 1
+		}
+		batches[slot] = append(batches[slot], id)
+	}
+	atomic.AddUint64(&tsp.numTracesOnMap, uint64(restored))
+
+	tsp.decisionBatcher.Stop()
+	tsp.decisionBatcher, err = idbatcher.NewWithBatches(batches, numBatches, tsp.expectedNewTracesPerSec, uint64(2*runtime.NumCPU()))
+	if err != nil {
+		return err
+	}
+
+	tsp.logger.Info("Restored traces waiting for a sampling decision", zap.Int("traces", restored))
+	tsp.start.Do(func() {
+		tsp.logger.Info("Traces restored from storage, starting tail_sampling timers")
+		tsp.policyTicker.start(1 * time.Second)
+	})
 	return nil
 }
 
 // Shutdown is invoked during service shutdown.
-func (tsp *tailSamplingSpanProcessor) Shutdown(context.Context) error {
-	return nil
+func (tsp *tailSamplingSpanProcessor) Shutdown(ctx context.Context) error {
+	// Stop taking decisions first, so that the ticker doesn't flush to a closed storage
+	tsp.policyTicker.stop()
+	if tsp.traceStorage == nil {
+		return nil
+	}
+	return tsp.traceStorage.shutdown(ctx, &tsp.idToTrace)
 }
 
 func (tsp *tailSamplingSpanProcessor) dropTrace(traceID pdata.TraceID, deletionTime time.Time) {
@@ -377,6 +506,9 @@ func (tsp *tailSamplingSpanProcessor) dropTrace(traceID pdata.TraceID, deletionT
 		// Subtract one from numTracesOnMap per https://godoc.org/sync/atomic#AddUint64
 		atomic.AddUint64(&tsp.numTracesOnMap, ^uint64(0))
 	}
+	if tsp.traceStorage != nil {
+		tsp.traceStorage.delete(traceID)
+	}
 	if trace == nil {
 		tsp.logger.Error("Attempt to delete traceID not on table")
 		return
@@ -424,7 +556,9 @@ func (pt *policyTicker) onTick() {
 	pt.onTickFunc()
 }
 func (pt *policyTicker) stop() {
-	pt.ticker.Stop()
+	if pt.ticker != nil {
+		pt.ticker.Stop()
+	}
 }
 
 var _ tTicker = (*policyTicker)(nil)
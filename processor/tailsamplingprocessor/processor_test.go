@@ -36,7 +36,7 @@ const (
 	defaultTestDecisionWait = 30 * time.Second
 )
 
-var testPolicy = []PolicyCfg{{Name: "test-policy", Type: AlwaysSample}}
+var testPolicy = []PolicyCfg{{sharedPolicyCfg: sharedPolicyCfg{Name: "test-policy", Type: AlwaysSample}}}
 
 func TestSequentialTraceArrival(t *testing.T) {
 	traceIds, batches := generateIdsAndBatches(128)
@@ -477,6 +477,7 @@ func (m *mockPolicyEvaluator) Evaluate(pdata.TraceID, *sampling.TraceData) (samp
 
 type manualTTicker struct {
 	Started bool
+	Stopped bool
 }
 
 var _ tTicker = (*manualTTicker)(nil)
@@ -489,6 +490,7 @@ func (t *manualTTicker) onTick() {
 }
 
 func (t *manualTTicker) stop() {
+	t.Stopped = true
 }
 
 type syncIDBatcher struct {
@@ -0,0 +1,400 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/extension/storage"
+	"go.opentelemetry.io/collector/model/otlp"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+const (
+	// indexKey is the key holding the number of index segments. Each flush writes a segment
+	// with the changes it made to the index of the traces waiting for a decision.
+	indexKey = "index"
+	// maxIndexSegments is the number of segments after which they are compacted into one.
+	maxIndexSegments = 60
+)
+
+var errStorageClosed = errors.New("trace storage is closed")
+
+// indexEntry holds what is needed to schedule the decision of a stored trace after a restart.
+type indexEntry struct {
+	TraceID     string    `json:"trace_id"`
+	ArrivalTime time.Time `json:"arrival_time"`
+	SpanCount   int64     `json:"span_count"`
+	Batches     int       `json:"batches"`
+}
+
+// indexSegment holds the changes made to the index by a flush: the traces removed since the
+// previous flush, and the traces with new batches.
+type indexSegment struct {
+	Deleted []string     `json:"deleted,omitempty"`
+	Updated []indexEntry `json:"updated,omitempty"`
+}
+
+// traceStorage spills the span batches of the traces waiting for a sampling decision to
+// a storage extension, so that they don't need to be kept in memory until the decision
+// and can be recovered after a restart.
+//
+// Batches are accumulated in memory and written once per decision tick, in a single storage
+// batch operation together with an index segment recording the traces that changed. The
+// segments are replayed to rebuild the index after a restart.
+type traceStorage struct {
+	sync.Mutex
+	client      storage.Client
+	logger      *zap.Logger
+	marshaler   pdata.TracesMarshaler
+	unmarshaler pdata.TracesUnmarshaler
+	// index holds the stored traces, with their number of stored batches.
+	index map[pdata.TraceID]indexEntry
+	// segments is the number of index segments written to the storage.
+	segments int
+	// pendingDeletes are the keys to be deleted with the next flush.
+	pendingDeletes []string
+	// deletedTraces are the traces to be removed from the index with the next flush.
+	deletedTraces []string
+	closed        bool
+}
+
+func newTraceStorage(logger *zap.Logger, client storage.Client) *traceStorage {
+	return &traceStorage{
+		client:      client,
+		logger:      logger,
+		marshaler:   otlp.NewProtobufTracesMarshaler(),
+		unmarshaler: otlp.NewProtobufTracesUnmarshaler(),
+		index:       make(map[pdata.TraceID]indexEntry),
+	}
+}
+
+func batchKey(traceID pdata.TraceID, i int) string {
+	return fmt.Sprintf("trace/%s/%d", traceID.HexString(), i)
+}
+
+func segmentKey(i int) string {
+	return fmt.Sprintf("%s/%d", indexKey, i)
+}
+
+// loadIndex replays the index segments and returns the traces that were waiting for a
+// decision when the index was last flushed. The segments are then compacted into one.
+func (s *traceStorage) loadIndex(ctx context.Context) ([]indexEntry, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	buf, err := s.client.Get(ctx, indexKey)
+	if err != nil || buf == nil {
+		return nil, err
+	}
+	if s.segments, err = strconv.Atoi(string(buf)); err != nil {
+		return nil, err
+	}
+
+	ops := make([]storage.Operation, s.segments)
+	for i := range ops {
+		ops[i] = storage.GetOperation(segmentKey(i))
+	}
+	if err = s.client.Batch(ctx, ops...); err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if op.Value == nil {
+			continue
+		}
+		var segment indexSegment
+		if err = json.Unmarshal(op.Value, &segment); err != nil {
+			return nil, err
+		}
+		if err = s.applyLocked(segment); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]indexEntry, 0, len(s.index))
+	for _, entry := range s.index {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ArrivalTime.Before(entries[j].ArrivalTime)
+	})
+
+	if s.segments > 1 {
+		compactOps, err := s.compactIndexLocked(s.index)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.client.Batch(ctx, compactOps...); err != nil {
+			return nil, err
+		}
+		s.segments = 1
+	}
+	return entries, nil
+}
+
+// applyLocked applies the changes of an index segment to the index.
+func (s *traceStorage) applyLocked(segment indexSegment) error {
+	for _, hexTraceID := range segment.Deleted {
+		traceID, err := parseTraceID(hexTraceID)
+		if err != nil {
+			return err
+		}
+		delete(s.index, traceID)
+	}
+	for _, entry := range segment.Updated {
+		traceID, err := parseTraceID(entry.TraceID)
+		if err != nil {
+			return err
+		}
+		s.index[traceID] = entry
+	}
+	return nil
+}
+
+// compactIndexLocked returns the operations replacing all the index segments with a single
+// one holding the given index.
+func (s *traceStorage) compactIndexLocked(index map[pdata.TraceID]indexEntry) ([]storage.Operation, error) {
+	segment := indexSegment{Updated: make([]indexEntry, 0, len(index))}
+	for _, entry := range index {
+		segment.Updated = append(segment.Updated, entry)
+	}
+	buf, err := json.Marshal(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := []storage.Operation{storage.SetOperation(segmentKey(0), buf)}
+	for i := 1; i < s.segments; i++ {
+		ops = append(ops, storage.DeleteOperation(segmentKey(i)))
+	}
+	return append(ops, storage.SetOperation(indexKey, []byte("1"))), nil
+}
+
+// restoreBatches loads the stored batches of the given trace back into its received batches,
+// ahead of the batches received since the last flush. The stored batches are deleted with the
+// next flush.
+func (s *traceStorage) restoreBatches(ctx context.Context, traceID pdata.TraceID, trace *sampling.TraceData) error {
+	s.Lock()
+	defer s.Unlock()
+
+	numBatches := s.index[traceID].Batches
+	if numBatches == 0 {
+		return nil
+	}
+	if s.closed {
+		return errStorageClosed
+	}
+
+	ops := make([]storage.Operation, numBatches)
+	for i := range ops {
+		ops[i] = storage.GetOperation(batchKey(traceID, i))
+	}
+	if err := s.client.Batch(ctx, ops...); err != nil {
+		return err
+	}
+	s.deleteLocked(traceID)
+
+	batches := make([]pdata.Traces, 0, numBatches)
+	for _, op := range ops {
+		if op.Value == nil {
+			continue
+		}
+		td, err := s.unmarshaler.UnmarshalTraces(op.Value)
+		if err != nil {
+			return err
+		}
+		batches = append(batches, td)
+	}
+
+	trace.Lock()
+	trace.ReceivedBatches = append(batches, trace.ReceivedBatches...)
+	trace.Unlock()
+	return nil
+}
+
+// delete removes the stored batches of the given trace with the next flush.
+func (s *traceStorage) delete(traceID pdata.TraceID) {
+	s.Lock()
+	defer s.Unlock()
+	s.deleteLocked(traceID)
+}
+
+func (s *traceStorage) deleteLocked(traceID pdata.TraceID) {
+	entry, ok := s.index[traceID]
+	if !ok {
+		return
+	}
+	for i := 0; i < entry.Batches; i++ {
+		s.pendingDeletes = append(s.pendingDeletes, batchKey(traceID, i))
+	}
+	s.deletedTraces = append(s.deletedTraces, entry.TraceID)
+	delete(s.index, traceID)
+}
+
+// flush writes the batches received since the last flush by the traces waiting for a decision,
+// releasing them from memory, along with an index segment for the traces with new batches and
+// the deleted traces.
+func (s *traceStorage) flush(ctx context.Context, idToTrace *sync.Map) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return errStorageClosed
+	}
+
+	type takenBatches struct {
+		trace   *sampling.TraceData
+		batches []pdata.Traces
+	}
+	var taken []takenBatches
+	var ops []storage.Operation
+	segment := indexSegment{Deleted: s.deletedTraces}
+	updated := make(map[pdata.TraceID]indexEntry)
+
+	var marshalErr error
+	idToTrace.Range(func(key, value interface{}) bool {
+		traceID := key.(pdata.TraceID)
+		trace := value.(*sampling.TraceData)
+		if !trace.DecisionTime.IsZero() {
+			return true
+		}
+
+		trace.Lock()
+		batches := trace.ReceivedBatches
+		trace.ReceivedBatches = nil
+		trace.Unlock()
+		if len(batches) == 0 {
+			return true
+		}
+		taken = append(taken, takenBatches{trace: trace, batches: batches})
+
+		numBatches := s.index[traceID].Batches
+		for _, batch := range batches {
+			buf, err := s.marshaler.MarshalTraces(batch)
+			if err != nil {
+				marshalErr = err
+				return false
+			}
+			ops = append(ops, storage.SetOperation(batchKey(traceID, numBatches), buf))
+			numBatches++
+		}
+
+		entry := indexEntry{
+			TraceID:     traceID.HexString(),
+			ArrivalTime: trace.ArrivalTime,
+			SpanCount:   atomic.LoadInt64(&trace.SpanCount),
+			Batches:     numBatches,
+		}
+		updated[traceID] = entry
+		segment.Updated = append(segment.Updated, entry)
+		return true
+	})
+
+	err := marshalErr
+	if err == nil && (len(segment.Deleted) > 0 || len(segment.Updated) > 0) {
+		for _, key := range s.pendingDeletes {
+			ops = append(ops, storage.DeleteOperation(key))
+		}
+
+		var indexOps []storage.Operation
+		if s.segments+1 >= maxIndexSegments {
+			index := make(map[pdata.TraceID]indexEntry, len(s.index)+len(updated))
+			for traceID, entry := range s.index {
+				index[traceID] = entry
+			}
+			for traceID, entry := range updated {
+				index[traceID] = entry
+			}
+			indexOps, err = s.compactIndexLocked(index)
+		} else {
+			var buf []byte
+			if buf, err = json.Marshal(segment); err == nil {
+				indexOps = []storage.Operation{
+					storage.SetOperation(segmentKey(s.segments), buf),
+					storage.SetOperation(indexKey, []byte(strconv.Itoa(s.segments+1))),
+				}
+			}
+		}
+		if err == nil {
+			err = s.client.Batch(ctx, append(ops, indexOps...)...)
+		}
+	}
+
+	if err != nil {
+		// Nothing was written, keep the batches in memory until the next flush.
+		for _, t := range taken {
+			t.trace.Lock()
+			t.trace.ReceivedBatches = append(t.batches, t.trace.ReceivedBatches...)
+			t.trace.Unlock()
+		}
+		return err
+	}
+	if len(segment.Deleted) == 0 && len(segment.Updated) == 0 {
+		return nil
+	}
+
+	for traceID, entry := range updated {
+		s.index[traceID] = entry
+	}
+	if s.segments+1 >= maxIndexSegments {
+		s.segments = 1
+	} else {
+		s.segments++
+	}
+	s.pendingDeletes = nil
+	s.deletedTraces = nil
+	return nil
+}
+
+// shutdown flushes the traces waiting for a decision and closes the storage client.
+func (s *traceStorage) shutdown(ctx context.Context, idToTrace *sync.Map) error {
+	flushErr := s.flush(ctx, idToTrace)
+
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if err := s.client.Close(ctx); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+func parseTraceID(hexTraceID string) (pdata.TraceID, error) {
+	var traceID [16]byte
+	buf, err := hex.DecodeString(hexTraceID)
+	if err != nil {
+		return pdata.InvalidTraceID(), err
+	}
+	if len(buf) != len(traceID) {
+		return pdata.InvalidTraceID(), fmt.Errorf("invalid trace ID %q", hexTraceID)
+	}
+	copy(traceID[:], buf)
+	return pdata.NewTraceID(traceID), nil
+}
@@ -0,0 +1,296 @@
+// Copyright The OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/extension/storage"
+	"go.opentelemetry.io/collector/model/pdata"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+func TestStorageFlushAndRestore(t *testing.T) {
+	client := newMemoryStorageClient()
+	host := newStorageHost(client)
+	sink := new(consumertest.TracesSink)
+
+	traceIds, batches := generateIdsAndBatches(2)
+	tsp := newStorageTestProcessor(t, sink)
+	require.NoError(t, tsp.Start(context.Background(), host))
+	for _, batch := range batches {
+		require.NoError(t, tsp.ConsumeTraces(context.Background(), batch))
+	}
+
+	// the received batches are moved from memory to the storage
+	tsp.samplingPolicyOnTick()
+	for _, traceID := range traceIds {
+		d, ok := tsp.idToTrace.Load(traceID)
+		require.True(t, ok)
+		assert.Empty(t, d.(*sampling.TraceData).ReceivedBatches)
+	}
+	assert.Len(t, client.traceKeys(), len(batches))
+	assert.Equal(t, 0, sink.SpanCount())
+	require.NoError(t, tsp.Shutdown(context.Background()))
+
+	// a new instance restores the traces and takes the decisions
+	restored := newStorageTestProcessor(t, sink)
+	require.NoError(t, restored.Start(context.Background(), host))
+	assert.EqualValues(t, len(traceIds), restored.numTracesOnMap)
+	assert.True(t, restored.policyTicker.(*manualTTicker).Started)
+
+	for i := 0; i < int(numDecisionBatches(restored.decisionWait)); i++ {
+		restored.samplingPolicyOnTick()
+	}
+	assert.Equal(t, len(batches), sink.SpanCount())
+	assert.Len(t, sink.AllTraces(), len(traceIds))
+
+	// the decided traces are removed from the storage
+	assert.Empty(t, client.traceKeys())
+	require.NoError(t, restored.Shutdown(context.Background()))
+	assert.True(t, restored.policyTicker.(*manualTTicker).Stopped)
+
+	entries, err := newTraceStorage(zap.NewNop(), client).loadIndex(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestStorageIndexSegments(t *testing.T) {
+	ctx := context.Background()
+	client := newMemoryStorageClient()
+	s := newTraceStorage(zap.NewNop(), client)
+
+	traceIds, batches := generateIdsAndBatches(3)
+	var idToTrace sync.Map
+	for i, traceID := range traceIds {
+		idToTrace.Store(traceID, &sampling.TraceData{
+			ArrivalTime:     time.Unix(int64(i), 0).UTC(),
+			SpanCount:       1,
+			ReceivedBatches: []pdata.Traces{batches[i]},
+		})
+	}
+	require.NoError(t, s.flush(ctx, &idToTrace))
+	assert.Len(t, readSegment(t, client, 0).Updated, len(traceIds))
+
+	// only the traces with new batches and the deleted traces are written
+	d, _ := idToTrace.Load(traceIds[0])
+	d.(*sampling.TraceData).ReceivedBatches = []pdata.Traces{batches[0]}
+	s.delete(traceIds[1])
+	idToTrace.Delete(traceIds[1])
+	require.NoError(t, s.flush(ctx, &idToTrace))
+
+	segment := readSegment(t, client, 1)
+	require.Len(t, segment.Updated, 1)
+	assert.Equal(t, traceIds[0].HexString(), segment.Updated[0].TraceID)
+	assert.Equal(t, 2, segment.Updated[0].Batches)
+	assert.Equal(t, []string{traceIds[1].HexString()}, segment.Deleted)
+
+	// nothing is written without changes
+	require.NoError(t, s.flush(ctx, &idToTrace))
+	assert.Equal(t, []byte("2"), client.data[indexKey])
+
+	// a restart replays the segments and compacts them into one
+	restored := newTraceStorage(zap.NewNop(), client)
+	entries, err := restored.loadIndex(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, traceIds[0].HexString(), entries[0].TraceID)
+	assert.Equal(t, 2, entries[0].Batches)
+	assert.Equal(t, traceIds[2].HexString(), entries[1].TraceID)
+	assert.Equal(t, []byte("1"), client.data[indexKey])
+	assert.NotContains(t, client.keys(), segmentKey(1))
+}
+
+func TestStorageIndexCompaction(t *testing.T) {
+	ctx := context.Background()
+	client := newMemoryStorageClient()
+	s := newTraceStorage(zap.NewNop(), client)
+
+	traceIds, batches := generateIdsAndBatches(1)
+	trace := &sampling.TraceData{ArrivalTime: time.Unix(0, 0).UTC(), SpanCount: 1}
+	var idToTrace sync.Map
+	idToTrace.Store(traceIds[0], trace)
+
+	for i := 0; i < maxIndexSegments; i++ {
+		trace.ReceivedBatches = []pdata.Traces{batches[0]}
+		require.NoError(t, s.flush(ctx, &idToTrace))
+	}
+
+	// the segments were compacted into one once the limit was reached
+	assert.Equal(t, []byte("1"), client.data[indexKey])
+	assert.Len(t, readSegment(t, client, 0).Updated, 1)
+	assert.NotContains(t, client.keys(), segmentKey(1))
+
+	entries, err := newTraceStorage(zap.NewNop(), client).loadIndex(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, maxIndexSegments, entries[0].Batches)
+}
+
+func TestStorageFlushError(t *testing.T) {
+	client := newMemoryStorageClient()
+	sink := new(consumertest.TracesSink)
+
+	_, batches := generateIdsAndBatches(1)
+	tsp := newStorageTestProcessor(t, sink)
+	require.NoError(t, tsp.Start(context.Background(), newStorageHost(client)))
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), batches[0]))
+
+	client.batchErr = errors.New("storage is full")
+	require.Error(t, tsp.traceStorage.flush(context.Background(), &tsp.idToTrace))
+
+	// the batches are kept in memory until they can be stored
+	d, ok := tsp.idToTrace.Load(batches[0].ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).TraceID())
+	require.True(t, ok)
+	assert.Len(t, d.(*sampling.TraceData).ReceivedBatches, 1)
+}
+
+func TestStorageNotFound(t *testing.T) {
+	tsp := newStorageTestProcessor(t, new(consumertest.TracesSink))
+	err := tsp.Start(context.Background(), componenttest.NewNopHost())
+	assert.EqualError(t, err, "failed to find storage extension: 'memory_storage'; please configure storage from one of: []")
+}
+
+func newStorageTestProcessor(t *testing.T, sink *consumertest.TracesSink) *tailSamplingSpanProcessor {
+	cfg := Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewID(typeStr)),
+		DecisionWait:      2 * time.Second,
+		NumTraces:         100,
+		PolicyCfgs:        testPolicy,
+		Storage:           "memory_storage",
+	}
+	sp, err := newTracesProcessor(zap.NewNop(), sink, cfg)
+	require.NoError(t, err)
+	tsp := sp.(*tailSamplingSpanProcessor)
+	tsp.policyTicker = &manualTTicker{}
+	return tsp
+}
+
+type storageHost struct {
+	component.Host
+	extension component.Extension
+}
+
+func newStorageHost(client storage.Client) component.Host {
+	return &storageHost{
+		Host:      componenttest.NewNopHost(),
+		extension: &memoryStorageExtension{client: client},
+	}
+}
+
+func (h *storageHost) GetExtensions() map[config.ComponentID]component.Extension {
+	return map[config.ComponentID]component.Extension{
+		config.NewID("memory_storage"): h.extension,
+	}
+}
+
+type memoryStorageExtension struct {
+	component.Extension
+	client storage.Client
+}
+
+func (m *memoryStorageExtension) GetClient(context.Context, component.Kind, config.ComponentID, string) (storage.Client, error) {
+	return m.client, nil
+}
+
+// memoryStorageClient is a storage client keeping the data in memory, it
+// outlives a call to Close so that it can be used to simulate a restart.
+type memoryStorageClient struct {
+	sync.Mutex
+	data     map[string][]byte
+	batchErr error
+}
+
+func newMemoryStorageClient() *memoryStorageClient {
+	return &memoryStorageClient{data: make(map[string][]byte)}
+}
+
+func (m *memoryStorageClient) Get(ctx context.Context, key string) ([]byte, error) {
+	op := storage.GetOperation(key)
+	err := m.Batch(ctx, op)
+	return op.Value, err
+}
+
+func (m *memoryStorageClient) Set(ctx context.Context, key string, value []byte) error {
+	return m.Batch(ctx, storage.SetOperation(key, value))
+}
+
+func (m *memoryStorageClient) Delete(ctx context.Context, key string) error {
+	return m.Batch(ctx, storage.DeleteOperation(key))
+}
+
+func (m *memoryStorageClient) Batch(_ context.Context, ops ...storage.Operation) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.batchErr != nil {
+		return m.batchErr
+	}
+	for _, op := range ops {
+		switch op.Type {
+		case storage.Get:
+			op.Value = m.data[op.Key]
+		case storage.Set:
+			m.data[op.Key] = op.Value
+		case storage.Delete:
+			delete(m.data, op.Key)
+		}
+	}
+	return nil
+}
+
+func (m *memoryStorageClient) Close(context.Context) error {
+	return nil
+}
+
+// traceKeys returns the keys holding trace batches.
+func (m *memoryStorageClient) traceKeys() []string {
+	var keys []string
+	for _, key := range m.keys() {
+		if strings.HasPrefix(key, "trace/") {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func readSegment(t *testing.T, client *memoryStorageClient, i int) indexSegment {
+	var segment indexSegment
+	require.NoError(t, json.Unmarshal(client.data[segmentKey(i)], &segment))
+	return segment
+}
+
+func (m *memoryStorageClient) keys() []string {
+	m.Lock()
+	defer m.Unlock()
+	var keys []string
+	for key := range m.data {
+		keys = append(keys, key)
+	}
+	return keys
+}